@@ -1,22 +1,32 @@
 package main
 
 import (
+	"context"
 	"fmt"
 	"os"
+	"time"
 
 	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/labels"
 	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/selection"
 	utilruntime "k8s.io/apimachinery/pkg/util/runtime"
 	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
 	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/cache"
+	"sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/healthz"
 	"sigs.k8s.io/controller-runtime/pkg/metrics"
 	metricsserver "sigs.k8s.io/controller-runtime/pkg/metrics/server"
+	ctrlwebhook "sigs.k8s.io/controller-runtime/pkg/webhook"
 
 	"aks-azureFiles-controller/internal/azure"
 	"aks-azureFiles-controller/internal/config"
 	"aks-azureFiles-controller/internal/controller"
+	"aks-azureFiles-controller/internal/k8s"
 	"aks-azureFiles-controller/internal/logging"
+	"aks-azureFiles-controller/internal/snapshotv1"
+	"aks-azureFiles-controller/internal/webhook"
 )
 
 var scheme = runtime.NewScheme()
@@ -24,6 +34,7 @@ var scheme = runtime.NewScheme()
 func init() {
 	utilruntime.Must(clientgoscheme.AddToScheme(scheme))
 	utilruntime.Must(corev1.AddToScheme(scheme))
+	utilruntime.Must(snapshotv1.AddToScheme(scheme))
 }
 
 func main() {
@@ -37,8 +48,12 @@ func main() {
 	}
 
 	mgr, err := ctrl.NewManager(ctrl.GetConfigOrDie(), ctrl.Options{
-		Scheme:                 scheme,
+		Scheme: scheme,
+		Cache: cache.Options{ByObject: map[client.Object]cache.ByObject{
+			&corev1.Secret{}: secretCache(cfg),
+		}},
 		Metrics:                metricsserver.Options{BindAddress: cfg.MetricsAddr},
+		WebhookServer:          ctrlwebhook.NewServer(ctrlwebhook.Options{Port: cfg.WebhookPort, CertDir: cfg.WebhookCertDir}),
 		HealthProbeBindAddress: cfg.HealthAddr,
 		LeaderElection:         cfg.LeaderElectionEnabled,
 		LeaderElectionID:       cfg.LeaderElectionID,
@@ -59,11 +74,73 @@ func main() {
 	}
 	logger.Info("azure authentication configured", "mode", authMode)
 
-	shareClient, err := azure.NewClientWithCredential(cfg.StorageAccount, cred)
+	newShareClient := func(resourceGroup, accountName string) (azure.ShareClient, error) {
+		if cfg.ShareAPI == config.ShareAPIARM {
+			return azure.NewARMShareClient(cfg.SubscriptionID, resourceGroup, accountName, cred, nil)
+		}
+		return azure.NewClientWithCredential(accountName, cred)
+	}
+	shareClient, err := newShareClient(cfg.ResourceGroup, cfg.StorageAccount)
 	if err != nil {
 		logger.Error(err, "create share client")
 		os.Exit(1)
 	}
+	logger.Info("share client configured", "api", cfg.ShareAPI)
+
+	// Additional accounts named by StorageClasses or PVC annotations get clients on first use.
+	accounts := azure.NewRegistry(newShareClient)
+	accounts.Register(cfg.StorageAccount, shareClient)
+
+	var placer *azure.Placer
+	if cfg.StorageAccountPool != "" {
+		pool, err := azure.ParsePoolAccounts(cfg.StorageAccountPool, cfg.ResourceGroup)
+		if err != nil {
+			logger.Error(err, "parse storage account pool")
+			os.Exit(1)
+		}
+		for i := range pool {
+			pool[i].MaxCapacityGiB = cfg.PoolMaxCapacityGiB
+			pool[i].MaxShares = int(cfg.PoolMaxShares)
+			pool[i].MaxIOPS = cfg.PoolMaxIOPS
+		}
+		placer = azure.NewPlacer(pool)
+		logger.Info("storage account pool configured", "accounts", len(pool))
+	}
+
+	var verifier *azure.AccountVerifier
+	var accountManager *azure.ARMAccountManager
+	if cfg.SubscriptionID != "" {
+		accountManager, err = azure.NewARMAccountManager(cfg.SubscriptionID, cred, nil)
+		if err != nil {
+			logger.Error(err, "create account manager")
+			os.Exit(1)
+		}
+		verifier = azure.NewAccountVerifier(accountManager, azure.AccountSpec{
+			Location: cfg.Location,
+			Kind:     cfg.AccountKind,
+			SkuName:  cfg.AccountSku,
+		}, cfg.AccountAutoCreate)
+		if err := verifyStartupAccounts(verifier, cfg, placer); err != nil {
+			logger.Error(err, "verify storage accounts")
+			os.Exit(1)
+		}
+	} else {
+		logger.Info("AZURE_SUBSCRIPTION_ID not set; storage account verification disabled")
+	}
+
+	var nodeSecrets *controller.NodeSecretConfig
+	if cfg.NodeSecretsEnabled {
+		nodeSecrets = &controller.NodeSecretConfig{Keys: accountManager, Namespace: cfg.NodeSecretNamespace}
+		rotator := &controller.KeySecretRotator{
+			Client:   mgr.GetClient(),
+			Keys:     accountManager,
+			Interval: cfg.NodeSecretRotation,
+		}
+		if err := mgr.Add(rotator); err != nil {
+			logger.Error(err, "add key secret rotator")
+			os.Exit(1)
+		}
+	}
 
 	reconcileMetrics := controller.NewReconcileMetrics()
 	if err := reconcileMetrics.Register(metrics.Registry); err != nil {
@@ -71,17 +148,56 @@ func main() {
 		os.Exit(1)
 	}
 
+	scanned, err := managedAccounts(cfg, shareClient, accounts, placer)
+	if err != nil {
+		logger.Error(err, "create pool share clients")
+		os.Exit(1)
+	}
+	if cfg.SoftDeleteMetrics {
+		reporter := &controller.SoftDeleteReporter{
+			Shares:   scanned,
+			Metrics:  reconcileMetrics,
+			Interval: cfg.SoftDeleteScanEvery,
+		}
+		if err := mgr.Add(reporter); err != nil {
+			logger.Error(err, "add soft delete reporter")
+			os.Exit(1)
+		}
+	}
+	if cfg.DeletionGracePeriod > 0 {
+		reaper := &controller.ShareReaper{
+			Client:        mgr.GetClient(),
+			Shares:        scanned,
+			Accounts:      accounts,
+			ResourceGroup: cfg.ResourceGroup,
+			ClusterID:     cfg.ClusterID,
+			Interval:      cfg.DeletionScanEvery,
+		}
+		if err := mgr.Add(reaper); err != nil {
+			logger.Error(err, "add share reaper")
+			os.Exit(1)
+		}
+	}
+
 	reconciler := &controller.PVCReconciler{
 		Client:   mgr.GetClient(),
 		Scheme:   mgr.GetScheme(),
 		Recorder: mgr.GetEventRecorderFor("azurefile-provisioner"),
 		Config: controller.ReconcilerConfig{
-			ResourceGroup:  cfg.ResourceGroup,
-			StorageAccount: cfg.StorageAccount,
-			Server:         cfg.Server,
+			ResourceGroup:       cfg.ResourceGroup,
+			StorageAccount:      cfg.StorageAccount,
+			Server:              cfg.Server,
+			ClusterID:           cfg.ClusterID,
+			PVMismatchPolicy:    controller.PVMismatchPolicy(cfg.PVMismatchPolicy),
+			DeletionGracePeriod: cfg.DeletionGracePeriod,
+			VolumeSnapshots:     cfg.VolumeSnapshots,
 		},
-		Shares:  shareClient,
-		Metrics: reconcileMetrics,
+		Shares:      shareClient,
+		Accounts:    accounts,
+		Placer:      placer,
+		Verifier:    verifier,
+		NodeSecrets: nodeSecrets,
+		Metrics:     reconcileMetrics,
 	}
 
 	if err := reconciler.SetupWithManager(mgr); err != nil {
@@ -89,6 +205,26 @@ func main() {
 		os.Exit(1)
 	}
 
+	if cfg.VolumeSnapshots {
+		snapshots := &controller.VolumeSnapshotContentReconciler{
+			Client:   mgr.GetClient(),
+			Recorder: mgr.GetEventRecorderFor("azurefile-provisioner"),
+			Accounts: accounts,
+		}
+		if err := snapshots.SetupWithManager(mgr); err != nil {
+			logger.Error(err, "setup snapshot controller")
+			os.Exit(1)
+		}
+	}
+
+	if cfg.WebhookEnabled {
+		if err := setupWebhook(mgr, cfg); err != nil {
+			logger.Error(err, "setup webhook")
+			os.Exit(1)
+		}
+		logger.Info("validating webhook configured", "port", cfg.WebhookPort)
+	}
+
 	if err := mgr.AddHealthzCheck("healthz", healthz.Ping); err != nil {
 		logger.Error(err, "add health check")
 		os.Exit(1)
@@ -104,3 +240,96 @@ func main() {
 		os.Exit(1)
 	}
 }
+
+// secretCache limits the cached Secrets to the key Secrets managed by the controller, not every Secret in the
+// cluster, and to the namespace they are written to, where the controller's Role grants access.
+func secretCache(cfg config.Config) cache.ByObject {
+	byObject := cache.ByObject{Label: managedSecretSelector()}
+	if cfg.NodeSecretNamespace != "" {
+		byObject.Namespaces = map[string]cache.Config{cfg.NodeSecretNamespace: {}}
+	}
+	return byObject
+}
+
+// managedSecretSelector matches the key Secrets written for NodeStageSecretRef.
+func managedSecretSelector() labels.Selector {
+	requirement, err := labels.NewRequirement(k8s.LabelStorageAccount, selection.Exists, nil)
+	utilruntime.Must(err)
+	return labels.NewSelector().Add(*requirement)
+}
+
+// setupWebhook issues the serving certificate, keeps it renewed and registers the validators.
+// The certificate must be on disk before the manager starts the webhook server.
+func setupWebhook(mgr ctrl.Manager, cfg config.Config) error {
+	// The manager's cache only holds key Secrets, so the certificate Secret is read directly.
+	directClient, err := client.New(mgr.GetConfig(), client.Options{Scheme: mgr.GetScheme()})
+	if err != nil {
+		return fmt.Errorf("create client: %w", err)
+	}
+	certs := &webhook.CertManager{
+		Client:      directClient,
+		Namespace:   cfg.PodNamespace,
+		ServiceName: cfg.WebhookServiceName,
+		SecretName:  cfg.WebhookServiceName + "-cert",
+		WebhookName: cfg.WebhookConfigName,
+		CertDir:     cfg.WebhookCertDir,
+		Interval:    24 * time.Hour,
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+	if err := certs.Ensure(ctx); err != nil {
+		return fmt.Errorf("ensure certificate: %w", err)
+	}
+	if err := mgr.Add(certs); err != nil {
+		return fmt.Errorf("add certificate manager: %w", err)
+	}
+
+	username := fmt.Sprintf("system:serviceaccount:%s:%s", cfg.PodNamespace, cfg.ServiceAccountName)
+	webhook.Register(mgr.GetWebhookServer(), mgr.GetScheme(), mgr.GetClient(), username, cfg.DeletionGracePeriod)
+	if err := mgr.AddReadyzCheck("webhook", mgr.GetWebhookServer().StartedChecker()); err != nil {
+		return fmt.Errorf("add webhook ready check: %w", err)
+	}
+	return nil
+}
+
+// managedAccounts returns the clients of the default account and every pool account, which the
+// background runnables scan.
+func managedAccounts(cfg config.Config, shareClient azure.ShareClient, accounts *azure.Registry, placer *azure.Placer) (map[string]azure.ShareClient, error) {
+	scanned := map[string]azure.ShareClient{}
+	if cfg.StorageAccount != "" {
+		scanned[cfg.StorageAccount] = shareClient
+	}
+	if placer != nil {
+		for _, account := range placer.Accounts() {
+			shares, err := accounts.ForAccount(account.ResourceGroup, account.Name)
+			if err != nil {
+				return nil, err
+			}
+			scanned[account.Name] = shares
+		}
+	}
+	return scanned, nil
+}
+
+// verifyStartupAccounts checks the default account and every pool account before the controller starts.
+// Classes pick the protocol, so HTTPS-only is left to the checks made when a share is placed on the account.
+func verifyStartupAccounts(verifier *azure.AccountVerifier, cfg config.Config, placer *azure.Placer) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Minute)
+	defer cancel()
+
+	var specs []azure.AccountSpec
+	if cfg.StorageAccount != "" {
+		specs = append(specs, azure.AccountSpec{Name: cfg.StorageAccount, ResourceGroup: cfg.ResourceGroup, AnyProtocol: true})
+	}
+	if placer != nil {
+		for _, account := range placer.Accounts() {
+			specs = append(specs, azure.AccountSpec{Name: account.Name, ResourceGroup: account.ResourceGroup, Location: account.Region, AnyProtocol: true})
+		}
+	}
+	for _, spec := range specs {
+		if err := verifier.Verify(ctx, spec); err != nil {
+			return fmt.Errorf("account %q: %w", spec.Name, err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,162 @@
+package azure
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// Storage account kinds that can host Azure File shares.
+const (
+	AccountKindStorageV2   = "StorageV2"
+	AccountKindFileStorage = "FileStorage"
+
+	// MinimumTLSVersion is the oldest TLS version accepted on managed accounts.
+	MinimumTLSVersion = "TLS1_2"
+)
+
+var ErrAccountNotFound = errors.New("storage account not found")
+var ErrAccountMisconfigured = errors.New("storage account misconfigured")
+var ErrNoAccountKey = errors.New("storage account has no usable key")
+
+// AccountSpec describes the storage account a share needs.
+// Empty Kind and SkuName accept any value on existing accounts; Location is only used on creation.
+// NFS accounts must accept non-HTTPS traffic because NFS 4.1 mounts do not use TLS. AnyProtocol skips that
+// check for callers that do not know which protocol the account serves; new accounts are still created for SMB.
+type AccountSpec struct {
+	Name          string
+	ResourceGroup string
+	Location      string
+	Kind          string
+	SkuName       string
+	NFS           bool
+	AnyProtocol   bool
+}
+
+// AccountProperties are the management-plane properties relevant to hosting shares.
+type AccountProperties struct {
+	Name              string
+	ResourceGroup     string
+	Location          string
+	Kind              string
+	SkuName           string
+	HTTPSOnly         bool
+	MinimumTLSVersion string
+}
+
+// AccountManager reads and creates storage accounts through the management plane.
+type AccountManager interface {
+	GetAccount(ctx context.Context, resourceGroup, accountName string) (*AccountProperties, error)
+	CreateAccount(ctx context.Context, spec AccountSpec) (*AccountProperties, error)
+	// GetAccountKey returns the first account key with full permissions.
+	GetAccountKey(ctx context.Context, resourceGroup, accountName string) (string, error)
+}
+
+// DefaultAccountKind returns the account kind that hosts shares of the given SKU.
+func DefaultAccountKind(skuName string) string {
+	if strings.HasPrefix(skuName, "Premium_") {
+		return AccountKindFileStorage
+	}
+	return AccountKindStorageV2
+}
+
+// CheckAccount verifies that an existing account matches the spec and enforces HTTPS with TLS 1.2 or newer.
+// NFS specs instead require HTTPS-only traffic to be disabled, which NFS mounts depend on.
+func CheckAccount(props *AccountProperties, spec AccountSpec) error {
+	var problems []string
+	if spec.Kind != "" && !strings.EqualFold(props.Kind, spec.Kind) {
+		problems = append(problems, fmt.Sprintf("kind %q, want %q", props.Kind, spec.Kind))
+	}
+	if spec.SkuName != "" && !strings.EqualFold(props.SkuName, spec.SkuName) {
+		problems = append(problems, fmt.Sprintf("sku %q, want %q", props.SkuName, spec.SkuName))
+	}
+	switch {
+	case spec.AnyProtocol:
+	case !spec.NFS && !props.HTTPSOnly:
+		problems = append(problems, "HTTPS-only traffic disabled")
+	case spec.NFS && props.HTTPSOnly:
+		problems = append(problems, "HTTPS-only traffic enabled, which blocks NFS mounts")
+	}
+	if props.MinimumTLSVersion < MinimumTLSVersion {
+		problems = append(problems, fmt.Sprintf("minimum TLS version %q, want %s or newer", props.MinimumTLSVersion, MinimumTLSVersion))
+	}
+	if len(problems) > 0 {
+		return fmt.Errorf("account %q: %s: %w", props.Name, strings.Join(problems, ", "), ErrAccountMisconfigured)
+	}
+	return nil
+}
+
+// AccountVerifier checks storage accounts through an AccountManager before shares are placed on them.
+// Successful checks are cached for the lifetime of the process; failures are retried on the next call.
+type AccountVerifier struct {
+	manager  AccountManager
+	defaults AccountSpec
+	create   bool
+
+	mu       sync.Mutex
+	verified map[string]bool
+}
+
+// NewAccountVerifier creates an AccountVerifier. defaults fills Location, Kind and SkuName when a spec leaves them empty;
+// create enables creation of missing accounts.
+func NewAccountVerifier(manager AccountManager, defaults AccountSpec, create bool) *AccountVerifier {
+	return &AccountVerifier{
+		manager:  manager,
+		defaults: defaults,
+		create:   create,
+		verified: map[string]bool{},
+	}
+}
+
+// Verify checks that the account exists with the expected settings, creating it when allowed.
+func (v *AccountVerifier) Verify(ctx context.Context, spec AccountSpec) error {
+	if spec.Name == "" || spec.ResourceGroup == "" {
+		return fmt.Errorf("account name and resource group required: %w", ErrInvalidShareInput)
+	}
+	if spec.Location == "" {
+		spec.Location = v.defaults.Location
+	}
+	if spec.SkuName == "" {
+		spec.SkuName = v.defaults.SkuName
+	}
+	if spec.Kind == "" {
+		spec.Kind = v.defaults.Kind
+	}
+
+	key := strings.Join([]string{spec.ResourceGroup, spec.Name, spec.Kind, spec.SkuName, strconv.FormatBool(spec.NFS), strconv.FormatBool(spec.AnyProtocol)}, "/")
+	v.mu.Lock()
+	done := v.verified[key]
+	v.mu.Unlock()
+	if done {
+		return nil
+	}
+
+	props, err := v.manager.GetAccount(ctx, spec.ResourceGroup, spec.Name)
+	if errors.Is(err, ErrAccountNotFound) && v.create {
+		props, err = v.manager.CreateAccount(ctx, v.creationSpec(spec))
+	}
+	if err != nil {
+		return err
+	}
+	if err := CheckAccount(props, spec); err != nil {
+		return err
+	}
+
+	v.mu.Lock()
+	v.verified[key] = true
+	v.mu.Unlock()
+	return nil
+}
+
+func (v *AccountVerifier) creationSpec(spec AccountSpec) AccountSpec {
+	if spec.SkuName == "" {
+		spec.SkuName = "Standard_LRS"
+	}
+	if spec.Kind == "" {
+		spec.Kind = DefaultAccountKind(spec.SkuName)
+	}
+	return spec
+}
@@ -0,0 +1,170 @@
+package azure
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore"
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore/arm"
+	azfake "github.com/Azure/azure-sdk-for-go/sdk/azcore/fake"
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore/to"
+	"github.com/Azure/azure-sdk-for-go/sdk/resourcemanager/storage/armstorage"
+	"github.com/Azure/azure-sdk-for-go/sdk/resourcemanager/storage/armstorage/fake"
+)
+
+func TestAccountVerifierChecksExistingAccount(t *testing.T) {
+	manager := &FakeAccountManager{Accounts: map[string]AccountProperties{
+		"acct": {Name: "acct", ResourceGroup: "rg", Kind: AccountKindStorageV2, SkuName: "Standard_LRS", HTTPSOnly: true, MinimumTLSVersion: "TLS1_2"},
+	}}
+	verifier := NewAccountVerifier(manager, AccountSpec{Kind: AccountKindStorageV2}, false)
+
+	ctx := context.Background()
+	if err := verifier.Verify(ctx, AccountSpec{Name: "acct", ResourceGroup: "rg", SkuName: "Standard_LRS"}); err != nil {
+		t.Fatalf("Verify error = %v", err)
+	}
+	if err := verifier.Verify(ctx, AccountSpec{Name: "acct", ResourceGroup: "rg", SkuName: "Standard_LRS"}); err != nil {
+		t.Fatalf("Verify error = %v", err)
+	}
+	if manager.GetCount != 1 {
+		t.Fatalf("GetCount = %d, want 1", manager.GetCount)
+	}
+
+	err := verifier.Verify(ctx, AccountSpec{Name: "acct", ResourceGroup: "rg", SkuName: "Premium_LRS"})
+	if !errors.Is(err, ErrAccountMisconfigured) {
+		t.Fatalf("Verify premium error = %v, want %v", err, ErrAccountMisconfigured)
+	}
+
+	if err := verifier.Verify(ctx, AccountSpec{Name: "missing", ResourceGroup: "rg"}); !errors.Is(err, ErrAccountNotFound) {
+		t.Fatalf("Verify missing error = %v, want %v", err, ErrAccountNotFound)
+	}
+	if manager.CreateCount != 0 {
+		t.Fatalf("CreateCount = %d, want 0", manager.CreateCount)
+	}
+}
+
+func TestAccountVerifierCreatesMissingAccount(t *testing.T) {
+	manager := &FakeAccountManager{}
+	verifier := NewAccountVerifier(manager, AccountSpec{Location: "westeurope"}, true)
+
+	if err := verifier.Verify(context.Background(), AccountSpec{Name: "premacct", ResourceGroup: "rg", SkuName: "Premium_LRS"}); err != nil {
+		t.Fatalf("Verify error = %v", err)
+	}
+	created := manager.Accounts["premacct"]
+	if created.Kind != AccountKindFileStorage || created.Location != "westeurope" {
+		t.Fatalf("created = %#v, want FileStorage in westeurope", created)
+	}
+}
+
+func TestCheckAccountRejectsInsecureSettings(t *testing.T) {
+	props := &AccountProperties{Name: "acct", Kind: AccountKindStorageV2, SkuName: "Standard_LRS", MinimumTLSVersion: "TLS1_0"}
+	if err := CheckAccount(props, AccountSpec{}); !errors.Is(err, ErrAccountMisconfigured) {
+		t.Fatalf("CheckAccount error = %v, want %v", err, ErrAccountMisconfigured)
+	}
+}
+
+func TestARMAccountManager(t *testing.T) {
+	var created armstorage.AccountCreateParameters
+	server := fake.AccountsServer{
+		GetProperties: func(_ context.Context, resourceGroupName, accountName string, _ *armstorage.AccountsClientGetPropertiesOptions) (resp azfake.Responder[armstorage.AccountsClientGetPropertiesResponse], errResp azfake.ErrorResponder) {
+			if accountName != "acct" {
+				errResp.SetResponseError(http.StatusNotFound, "ResourceNotFound")
+				return
+			}
+			resp.SetResponse(http.StatusOK, armstorage.AccountsClientGetPropertiesResponse{Account: armstorage.Account{
+				Name:     to.Ptr("acct"),
+				Location: to.Ptr("westeurope"),
+				Kind:     to.Ptr(armstorage.KindStorageV2),
+				SKU:      &armstorage.SKU{Name: to.Ptr(armstorage.SKUNameStandardLRS)},
+				Properties: &armstorage.AccountProperties{
+					EnableHTTPSTrafficOnly: to.Ptr(true),
+					MinimumTLSVersion:      to.Ptr(armstorage.MinimumTLSVersionTLS12),
+				},
+			}}, nil)
+			return
+		},
+		BeginCreate: func(_ context.Context, resourceGroupName, accountName string, parameters armstorage.AccountCreateParameters, _ *armstorage.AccountsClientBeginCreateOptions) (resp azfake.PollerResponder[armstorage.AccountsClientCreateResponse], errResp azfake.ErrorResponder) {
+			created = parameters
+			resp.SetTerminalResponse(http.StatusOK, armstorage.AccountsClientCreateResponse{Account: armstorage.Account{
+				Name:       to.Ptr(accountName),
+				Location:   parameters.Location,
+				Kind:       parameters.Kind,
+				SKU:        parameters.SKU,
+				Properties: &armstorage.AccountProperties{EnableHTTPSTrafficOnly: parameters.Properties.EnableHTTPSTrafficOnly, MinimumTLSVersion: parameters.Properties.MinimumTLSVersion},
+			}}, nil)
+			return
+		},
+	}
+	server.ListKeys = func(_ context.Context, _, _ string, _ *armstorage.AccountsClientListKeysOptions) (resp azfake.Responder[armstorage.AccountsClientListKeysResponse], errResp azfake.ErrorResponder) {
+		resp.SetResponse(http.StatusOK, armstorage.AccountsClientListKeysResponse{AccountListKeysResult: armstorage.AccountListKeysResult{Keys: []*armstorage.AccountKey{
+			{KeyName: to.Ptr("key0"), Permissions: to.Ptr(armstorage.KeyPermissionRead), Value: to.Ptr("read-only")},
+			{KeyName: to.Ptr("key1"), Permissions: to.Ptr(armstorage.KeyPermissionFull), Value: to.Ptr("full")},
+		}}}, nil)
+		return
+	}
+	manager, err := NewARMAccountManager("sub", &azfake.TokenCredential{}, &arm.ClientOptions{
+		ClientOptions: azcore.ClientOptions{Transport: fake.NewAccountsServerTransport(&server)},
+	})
+	if err != nil {
+		t.Fatalf("NewARMAccountManager error = %v", err)
+	}
+
+	ctx := context.Background()
+	props, err := manager.GetAccount(ctx, "rg", "acct")
+	if err != nil {
+		t.Fatalf("GetAccount error = %v", err)
+	}
+	want := AccountProperties{Name: "acct", ResourceGroup: "rg", Location: "westeurope", Kind: AccountKindStorageV2, SkuName: "Standard_LRS", HTTPSOnly: true, MinimumTLSVersion: "TLS1_2"}
+	if *props != want {
+		t.Fatalf("GetAccount = %#v, want %#v", *props, want)
+	}
+
+	if _, err := manager.GetAccount(ctx, "rg", "missing"); !errors.Is(err, ErrAccountNotFound) {
+		t.Fatalf("GetAccount missing error = %v, want %v", err, ErrAccountNotFound)
+	}
+
+	props, err = manager.CreateAccount(ctx, AccountSpec{Name: "newacct", ResourceGroup: "rg", Location: "westeurope", Kind: AccountKindFileStorage, SkuName: "Premium_LRS"})
+	if err != nil {
+		t.Fatalf("CreateAccount error = %v", err)
+	}
+	if err := CheckAccount(props, AccountSpec{Kind: AccountKindFileStorage, SkuName: "Premium_LRS"}); err != nil {
+		t.Fatalf("CheckAccount created error = %v", err)
+	}
+	if created.Properties.AllowBlobPublicAccess == nil || *created.Properties.AllowBlobPublicAccess {
+		t.Fatalf("AllowBlobPublicAccess = %v, want false", created.Properties.AllowBlobPublicAccess)
+	}
+
+	key, err := manager.GetAccountKey(ctx, "rg", "acct")
+	if err != nil {
+		t.Fatalf("GetAccountKey error = %v", err)
+	}
+	if key != "full" {
+		t.Fatalf("GetAccountKey = %q, want the full-permission key", key)
+	}
+}
+
+func TestCheckAccountNFS(t *testing.T) {
+	props := &AccountProperties{Name: "acct", Kind: AccountKindFileStorage, SkuName: "Premium_LRS", HTTPSOnly: true, MinimumTLSVersion: "TLS1_2"}
+	if err := CheckAccount(props, AccountSpec{NFS: true}); !errors.Is(err, ErrAccountMisconfigured) {
+		t.Fatalf("CheckAccount error = %v, want %v", err, ErrAccountMisconfigured)
+	}
+	props.HTTPSOnly = false
+	if err := CheckAccount(props, AccountSpec{NFS: true}); err != nil {
+		t.Fatalf("CheckAccount error = %v", err)
+	}
+}
+
+func TestCheckAccountAnyProtocol(t *testing.T) {
+	props := &AccountProperties{Name: "acct", Kind: AccountKindFileStorage, SkuName: "Premium_LRS", MinimumTLSVersion: "TLS1_2"}
+	for _, httpsOnly := range []bool{true, false} {
+		props.HTTPSOnly = httpsOnly
+		if err := CheckAccount(props, AccountSpec{AnyProtocol: true}); err != nil {
+			t.Fatalf("CheckAccount with HTTPS-only %v error = %v", httpsOnly, err)
+		}
+	}
+	props.MinimumTLSVersion = "TLS1_0"
+	if err := CheckAccount(props, AccountSpec{AnyProtocol: true}); !errors.Is(err, ErrAccountMisconfigured) {
+		t.Fatalf("CheckAccount error = %v, want %v", err, ErrAccountMisconfigured)
+	}
+}
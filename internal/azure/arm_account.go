@@ -0,0 +1,121 @@
+package azure
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore"
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore/arm"
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore/to"
+	"github.com/Azure/azure-sdk-for-go/sdk/resourcemanager/storage/armstorage"
+)
+
+// ARMAccountManager implements AccountManager using the Azure Resource Manager storage client.
+type ARMAccountManager struct {
+	accounts *armstorage.AccountsClient
+}
+
+// NewARMAccountManager builds an AccountManager for the subscription.
+func NewARMAccountManager(subscriptionID string, credential azcore.TokenCredential, options *arm.ClientOptions) (*ARMAccountManager, error) {
+	if subscriptionID == "" {
+		return nil, fmt.Errorf("subscription id required: %w", ErrInvalidShareInput)
+	}
+	if credential == nil {
+		return nil, fmt.Errorf("credential required: %w", ErrInvalidShareInput)
+	}
+
+	accounts, err := armstorage.NewAccountsClient(subscriptionID, credential, options)
+	if err != nil {
+		return nil, fmt.Errorf("create accounts client: %w", err)
+	}
+	return &ARMAccountManager{accounts: accounts}, nil
+}
+
+// GetAccount returns the account properties or ErrAccountNotFound.
+func (m *ARMAccountManager) GetAccount(ctx context.Context, resourceGroup, accountName string) (*AccountProperties, error) {
+	resp, err := m.accounts.GetProperties(ctx, resourceGroup, accountName, nil)
+	if err != nil {
+		if isResponseStatus(err, http.StatusNotFound) {
+			return nil, fmt.Errorf("get account %q: %w", accountName, ErrAccountNotFound)
+		}
+		return nil, fmt.Errorf("get account %q: %w", accountName, err)
+	}
+	return accountProperties(resourceGroup, &resp.Account), nil
+}
+
+// CreateAccount creates an account for the spec with TLS 1.2, no public blob access and HTTPS-only traffic
+// (except for NFS accounts), and waits for provisioning to finish.
+func (m *ARMAccountManager) CreateAccount(ctx context.Context, spec AccountSpec) (*AccountProperties, error) {
+	if spec.Name == "" || spec.ResourceGroup == "" || spec.Location == "" {
+		return nil, fmt.Errorf("account name, resource group and location required: %w", ErrInvalidShareInput)
+	}
+
+	poller, err := m.accounts.BeginCreate(ctx, spec.ResourceGroup, spec.Name, armstorage.AccountCreateParameters{
+		Location: to.Ptr(spec.Location),
+		Kind:     to.Ptr(armstorage.Kind(spec.Kind)),
+		SKU:      &armstorage.SKU{Name: to.Ptr(armstorage.SKUName(spec.SkuName))},
+		Properties: &armstorage.AccountPropertiesCreateParameters{
+			EnableHTTPSTrafficOnly: to.Ptr(!spec.NFS),
+			MinimumTLSVersion:      to.Ptr(armstorage.MinimumTLSVersion(MinimumTLSVersion)),
+			AllowBlobPublicAccess:  to.Ptr(false),
+		},
+	}, nil)
+	if err != nil {
+		return nil, fmt.Errorf("create account %q: %w", spec.Name, err)
+	}
+	resp, err := poller.PollUntilDone(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("create account %q: %w", spec.Name, err)
+	}
+	return accountProperties(spec.ResourceGroup, &resp.Account), nil
+}
+
+// GetAccountKey returns the first account key with full permissions.
+func (m *ARMAccountManager) GetAccountKey(ctx context.Context, resourceGroup, accountName string) (string, error) {
+	resp, err := m.accounts.ListKeys(ctx, resourceGroup, accountName, nil)
+	if err != nil {
+		if isResponseStatus(err, http.StatusNotFound) {
+			return "", fmt.Errorf("list keys of account %q: %w", accountName, ErrAccountNotFound)
+		}
+		return "", fmt.Errorf("list keys of account %q: %w", accountName, err)
+	}
+	for _, key := range resp.Keys {
+		if key == nil || key.Value == nil || *key.Value == "" {
+			continue
+		}
+		if key.Permissions != nil && *key.Permissions != armstorage.KeyPermissionFull {
+			continue
+		}
+		return *key.Value, nil
+	}
+	return "", fmt.Errorf("account %q: %w", accountName, ErrNoAccountKey)
+}
+
+func accountProperties(resourceGroup string, account *armstorage.Account) *AccountProperties {
+	props := &AccountProperties{
+		Name:          derefString(account.Name),
+		ResourceGroup: resourceGroup,
+		Location:      derefString(account.Location),
+	}
+	if account.Kind != nil {
+		props.Kind = string(*account.Kind)
+	}
+	if account.SKU != nil && account.SKU.Name != nil {
+		props.SkuName = string(*account.SKU.Name)
+	}
+	if p := account.Properties; p != nil {
+		props.HTTPSOnly = p.EnableHTTPSTrafficOnly != nil && *p.EnableHTTPSTrafficOnly
+		if p.MinimumTLSVersion != nil {
+			props.MinimumTLSVersion = string(*p.MinimumTLSVersion)
+		}
+	}
+	return props
+}
+
+func derefString(value *string) string {
+	if value == nil {
+		return ""
+	}
+	return *value
+}
@@ -0,0 +1,333 @@
+package azure
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore"
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore/arm"
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore/to"
+	"github.com/Azure/azure-sdk-for-go/sdk/resourcemanager/storage/armstorage"
+)
+
+// ARMShareClient implements ShareClient through the Azure Resource Manager FileShares API.
+// It only needs management-plane RBAC on the account and can set management-only properties
+// such as access tier, enabled protocols and root squash.
+type ARMShareClient struct {
+	shares        *armstorage.FileSharesClient
+	resourceGroup string
+	accountName   string
+}
+
+// NewARMShareClient builds a ShareClient for an account in a resource group of the subscription.
+func NewARMShareClient(subscriptionID, resourceGroup, accountName string, credential azcore.TokenCredential, options *arm.ClientOptions) (*ARMShareClient, error) {
+	if subscriptionID == "" || resourceGroup == "" || accountName == "" {
+		return nil, fmt.Errorf("subscription id, resource group and account name required: %w", ErrInvalidShareInput)
+	}
+	if credential == nil {
+		return nil, fmt.Errorf("credential required: %w", ErrInvalidShareInput)
+	}
+
+	shares, err := armstorage.NewFileSharesClient(subscriptionID, credential, options)
+	if err != nil {
+		return nil, fmt.Errorf("create file shares client: %w", err)
+	}
+	return &ARMShareClient{
+		shares:        shares,
+		resourceGroup: resourceGroup,
+		accountName:   accountName,
+	}, nil
+}
+
+// EnsureShare creates the share if it does not already exist.
+// Options only apply on creation; an existing share keeps its properties. A share of the same name
+// that is still being deleted fails with ErrShareBeingDeleted.
+func (c *ARMShareClient) EnsureShare(ctx context.Context, shareName string, quotaGiB int32, opts *EnsureShareOptions) error {
+	if shareName == "" {
+		return fmt.Errorf("share name required: %w", ErrInvalidShareInput)
+	}
+	if quotaGiB < 0 {
+		return fmt.Errorf("quota must be non-negative: %w", ErrInvalidShareInput)
+	}
+
+	_, err := c.shares.Get(ctx, c.resourceGroup, c.accountName, shareName, nil)
+	if err == nil {
+		return nil
+	}
+	if !isResponseStatus(err, http.StatusNotFound) {
+		return fmt.Errorf("get share %q: %w", shareName, classifyError(err))
+	}
+
+	_, err = c.shares.Create(ctx, c.resourceGroup, c.accountName, shareName, armstorage.FileShare{
+		FileShareProperties: armShareProperties(quotaGiB, opts),
+	}, nil)
+	if err != nil {
+		if shareAlreadyExists(err) {
+			return nil
+		}
+		return fmt.Errorf("create share %q: %w", shareName, classifyError(err))
+	}
+	return nil
+}
+
+// DeleteShare deletes the share if it exists and, with RequireMetadata set, carries the expected metadata.
+func (c *ARMShareClient) DeleteShare(ctx context.Context, shareName string, opts *DeleteShareOptions) error {
+	if shareName == "" {
+		return fmt.Errorf("share name required: %w", ErrInvalidShareInput)
+	}
+	if opts != nil && len(opts.RequireMetadata) > 0 {
+		props, err := c.GetShare(ctx, shareName)
+		if err != nil {
+			if errors.Is(err, ErrShareNotFound) {
+				return nil
+			}
+			return err
+		}
+		if err := CheckProvenance(shareName, props.Metadata, opts); err != nil {
+			return err
+		}
+	}
+
+	_, err := c.shares.Delete(ctx, c.resourceGroup, c.accountName, shareName, nil)
+	if err != nil {
+		if isResponseStatus(err, http.StatusNotFound) {
+			return nil
+		}
+		return fmt.Errorf("delete share %q: %w", shareName, classifyError(err))
+	}
+	return nil
+}
+
+// GetShare returns the current properties of the share.
+func (c *ARMShareClient) GetShare(ctx context.Context, shareName string) (*ShareProperties, error) {
+	if shareName == "" {
+		return nil, fmt.Errorf("share name required: %w", ErrInvalidShareInput)
+	}
+
+	resp, err := c.shares.Get(ctx, c.resourceGroup, c.accountName, shareName, nil)
+	if err != nil {
+		if isResponseStatus(err, http.StatusNotFound) {
+			return nil, fmt.Errorf("get share %q: %w", shareName, ErrShareNotFound)
+		}
+		return nil, fmt.Errorf("get share %q: %w", shareName, classifyError(err))
+	}
+
+	props := &ShareProperties{Name: shareName}
+	if p := resp.FileShareProperties; p != nil {
+		if p.ShareQuota != nil {
+			props.QuotaGiB = *p.ShareQuota
+		}
+		if p.AccessTier != nil {
+			props.AccessTier = string(*p.AccessTier)
+		}
+		if p.EnabledProtocols != nil {
+			props.EnabledProtocol = string(*p.EnabledProtocols)
+		}
+		props.Metadata = fromMetadata(p.Metadata)
+	}
+	return props, nil
+}
+
+// SetShareQuota updates the provisioned quota of an existing share.
+func (c *ARMShareClient) SetShareQuota(ctx context.Context, shareName string, quotaGiB int32) error {
+	if shareName == "" {
+		return fmt.Errorf("share name required: %w", ErrInvalidShareInput)
+	}
+	if quotaGiB <= 0 {
+		return fmt.Errorf("quota must be positive: %w", ErrInvalidShareInput)
+	}
+
+	_, err := c.shares.Update(ctx, c.resourceGroup, c.accountName, shareName, armstorage.FileShare{
+		FileShareProperties: &armstorage.FileShareProperties{ShareQuota: to.Ptr(quotaGiB)},
+	}, nil)
+	if err != nil {
+		if isResponseStatus(err, http.StatusNotFound) {
+			return fmt.Errorf("set quota on share %q: %w", shareName, ErrShareNotFound)
+		}
+		return fmt.Errorf("set quota on share %q: %w", shareName, classifyError(err))
+	}
+	return nil
+}
+
+// ListShares returns the live shares of the account with their metadata.
+func (c *ARMShareClient) ListShares(ctx context.Context) ([]ShareProperties, error) {
+	var shares []ShareProperties
+	pager := c.shares.NewListPager(c.resourceGroup, c.accountName, nil)
+	for pager.More() {
+		page, err := pager.NextPage(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("list shares: %w", classifyError(err))
+		}
+		for _, item := range page.Value {
+			if item == nil || item.Name == nil {
+				continue
+			}
+			props := ShareProperties{Name: *item.Name}
+			if p := item.Properties; p != nil {
+				if p.Deleted != nil && *p.Deleted {
+					continue
+				}
+				if p.ShareQuota != nil {
+					props.QuotaGiB = *p.ShareQuota
+				}
+				if p.AccessTier != nil {
+					props.AccessTier = string(*p.AccessTier)
+				}
+				if p.EnabledProtocols != nil {
+					props.EnabledProtocol = string(*p.EnabledProtocols)
+				}
+				props.Metadata = fromMetadata(p.Metadata)
+			}
+			shares = append(shares, props)
+		}
+	}
+	return shares, nil
+}
+
+// SetShareMetadata replaces the metadata of an existing share.
+func (c *ARMShareClient) SetShareMetadata(ctx context.Context, shareName string, metadata map[string]string) error {
+	if shareName == "" {
+		return fmt.Errorf("share name required: %w", ErrInvalidShareInput)
+	}
+
+	// An empty map clears the metadata; a nil one would leave it unchanged.
+	converted := toMetadata(metadata)
+	if converted == nil {
+		converted = map[string]*string{}
+	}
+	_, err := c.shares.Update(ctx, c.resourceGroup, c.accountName, shareName, armstorage.FileShare{
+		FileShareProperties: &armstorage.FileShareProperties{Metadata: converted},
+	}, nil)
+	if err != nil {
+		if isResponseStatus(err, http.StatusNotFound) {
+			return fmt.Errorf("set metadata on share %q: %w", shareName, ErrShareNotFound)
+		}
+		return fmt.Errorf("set metadata on share %q: %w", shareName, classifyError(err))
+	}
+	return nil
+}
+
+// ListDeletedShares returns the soft-deleted shares the account still retains.
+func (c *ARMShareClient) ListDeletedShares(ctx context.Context) ([]DeletedShare, error) {
+	var deleted []DeletedShare
+	pager := c.shares.NewListPager(c.resourceGroup, c.accountName, &armstorage.FileSharesClientListOptions{Expand: to.Ptr("deleted")})
+	for pager.More() {
+		page, err := pager.NextPage(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("list shares: %w", classifyError(err))
+		}
+		for _, item := range page.Value {
+			if item == nil || item.Name == nil || item.Properties == nil {
+				continue
+			}
+			p := item.Properties
+			if p.Deleted == nil || !*p.Deleted {
+				continue
+			}
+			share := DeletedShare{Name: *item.Name, Metadata: fromMetadata(p.Metadata)}
+			if p.Version != nil {
+				share.Version = *p.Version
+			}
+			if p.DeletedTime != nil {
+				share.DeletedTime = *p.DeletedTime
+			}
+			if p.RemainingRetentionDays != nil {
+				share.RemainingRetentionDays = *p.RemainingRetentionDays
+			}
+			deleted = append(deleted, share)
+		}
+	}
+	return deleted, nil
+}
+
+// RestoreShare undeletes a soft-deleted share version under its original name.
+func (c *ARMShareClient) RestoreShare(ctx context.Context, shareName, version string) error {
+	if shareName == "" || version == "" {
+		return fmt.Errorf("share name and version required: %w", ErrInvalidShareInput)
+	}
+
+	_, err := c.shares.Restore(ctx, c.resourceGroup, c.accountName, shareName, armstorage.DeletedShare{
+		DeletedShareName:    to.Ptr(shareName),
+		DeletedShareVersion: to.Ptr(version),
+	}, nil)
+	if err != nil {
+		if isResponseStatus(err, http.StatusNotFound) {
+			return fmt.Errorf("restore share %q version %q: %w", shareName, version, ErrShareNotFound)
+		}
+		return fmt.Errorf("restore share %q version %q: %w", shareName, version, classifyError(err))
+	}
+	return nil
+}
+
+// CreateSnapshot takes a snapshot of the share.
+func (c *ARMShareClient) CreateSnapshot(ctx context.Context, shareName string) (string, error) {
+	if shareName == "" {
+		return "", fmt.Errorf("share name required: %w", ErrInvalidShareInput)
+	}
+
+	resp, err := c.shares.Create(ctx, c.resourceGroup, c.accountName, shareName, armstorage.FileShare{}, &armstorage.FileSharesClientCreateOptions{Expand: to.Ptr("snapshots")})
+	if err != nil {
+		if isResponseStatus(err, http.StatusNotFound) {
+			return "", fmt.Errorf("snapshot share %q: %w", shareName, ErrShareNotFound)
+		}
+		return "", fmt.Errorf("snapshot share %q: %w", shareName, classifyError(err))
+	}
+	if resp.FileShareProperties == nil || resp.FileShareProperties.SnapshotTime == nil {
+		return "", fmt.Errorf("snapshot share %q: no snapshot time returned", shareName)
+	}
+	return resp.FileShareProperties.SnapshotTime.UTC().Format(snapshotTimeFormat), nil
+}
+
+// DeleteSnapshot deletes a snapshot of the share if it exists.
+func (c *ARMShareClient) DeleteSnapshot(ctx context.Context, shareName, snapshot string) error {
+	if shareName == "" || snapshot == "" {
+		return fmt.Errorf("share name and snapshot required: %w", ErrInvalidShareInput)
+	}
+
+	_, err := c.shares.Delete(ctx, c.resourceGroup, c.accountName, shareName, &armstorage.FileSharesClientDeleteOptions{XMSSnapshot: to.Ptr(snapshot)})
+	if err != nil {
+		if isResponseStatus(err, http.StatusNotFound) {
+			return nil
+		}
+		return fmt.Errorf("delete snapshot %q of share %q: %w", snapshot, shareName, classifyError(err))
+	}
+	return nil
+}
+
+// CopyShare is not available through the management plane, which has no file-level operations.
+func (c *ARMShareClient) CopyShare(_ context.Context, _, _, targetShare string, _ *CopyShareOptions) (*CopyProgress, error) {
+	return nil, fmt.Errorf("copy into share %q: the ARM share API cannot copy files, use the data-plane API: %w", targetShare, ErrInvalidShareInput)
+}
+
+// EnsureDirectory is not available through the management plane, which has no file-level operations.
+func (c *ARMShareClient) EnsureDirectory(_ context.Context, shareName, dir string) error {
+	return fmt.Errorf("create directory %q in share %q: the ARM share API cannot manage directories, use the data-plane API: %w", dir, shareName, ErrInvalidShareInput)
+}
+
+// DeleteDirectory is not available through the management plane, which has no file-level operations.
+func (c *ARMShareClient) DeleteDirectory(_ context.Context, shareName, dir string) error {
+	return fmt.Errorf("delete directory %q of share %q: the ARM share API cannot manage directories, use the data-plane API: %w", dir, shareName, ErrInvalidShareInput)
+}
+
+func armShareProperties(quotaGiB int32, opts *EnsureShareOptions) *armstorage.FileShareProperties {
+	props := &armstorage.FileShareProperties{}
+	if quotaGiB > 0 {
+		props.ShareQuota = to.Ptr(quotaGiB)
+	}
+	if opts == nil {
+		return props
+	}
+	if opts.AccessTier != "" {
+		props.AccessTier = to.Ptr(armstorage.ShareAccessTier(opts.AccessTier))
+	}
+	if opts.EnabledProtocol != "" {
+		props.EnabledProtocols = to.Ptr(armstorage.EnabledProtocols(opts.EnabledProtocol))
+	}
+	if opts.RootSquash != "" {
+		props.RootSquash = to.Ptr(armstorage.RootSquashType(opts.RootSquash))
+	}
+	props.Metadata = toMetadata(opts.Metadata)
+	return props
+}
@@ -5,19 +5,25 @@ import (
 	"errors"
 	"fmt"
 	"net/http"
+	"strings"
 
 	"github.com/Azure/azure-sdk-for-go/sdk/azcore"
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore/to"
+	"github.com/Azure/azure-sdk-for-go/sdk/storage/azfile/service"
 	"github.com/Azure/azure-sdk-for-go/sdk/storage/azfile/share"
 )
 
-// Client implements ShareClient using Azure SDK for Go.
+// Client implements ShareClient against the Azure Files data plane using Azure SDK for Go.
+// Entra tokens need a data-plane role such as "Storage File Data Privileged Contributor" on the account;
+// file and directory requests, used to copy shares, are sent with the backup intent such tokens require.
 type Client struct {
-	accountName string
-	endpoint    string
-	credential  azcore.TokenCredential
+	endpoint   string
+	credential azcore.TokenCredential
+	options    *share.ClientOptions
 }
 
 var ErrInvalidShareInput = errors.New("invalid share input")
+var ErrShareNotFound = errors.New("share not found")
 
 // NewClientWithCredential builds a ShareClient with the provided credential.
 func NewClientWithCredential(accountName string, credential azcore.TokenCredential) (*Client, error) {
@@ -28,16 +34,37 @@ func NewClientWithCredential(accountName string, credential azcore.TokenCredenti
 		return nil, fmt.Errorf("credential required: %w", ErrInvalidShareInput)
 	}
 
-	endpoint := fmt.Sprintf("https://%s.file.core.windows.net", accountName)
+	return NewClientWithEndpoint("https://"+FileServiceHost(accountName), credential, nil)
+}
+
+// NewClientWithEndpoint builds a ShareClient for a file service endpoint, e.g. for sovereign clouds.
+func NewClientWithEndpoint(endpoint string, credential azcore.TokenCredential, options *share.ClientOptions) (*Client, error) {
+	if endpoint == "" {
+		return nil, fmt.Errorf("endpoint required: %w", ErrInvalidShareInput)
+	}
+	if credential == nil {
+		return nil, fmt.Errorf("credential required: %w", ErrInvalidShareInput)
+	}
+
+	intended := share.ClientOptions{}
+	if options != nil {
+		intended = *options
+	}
+	if intended.FileRequestIntent == nil {
+		intended.FileRequestIntent = to.Ptr(share.TokenIntentBackup)
+	}
+
 	return &Client{
-		accountName: accountName,
-		endpoint:    endpoint,
-		credential:  credential,
+		endpoint:   strings.TrimSuffix(endpoint, "/"),
+		credential: credential,
+		options:    &intended,
 	}, nil
 }
 
 // EnsureShare creates the share if it does not already exist.
-func (c *Client) EnsureShare(ctx context.Context, shareName string, quotaGiB int32) error {
+// Options only apply on creation; an existing share keeps its properties. A share of the same name
+// that is still being deleted fails with ErrShareBeingDeleted.
+func (c *Client) EnsureShare(ctx context.Context, shareName string, quotaGiB int32, opts *EnsureShareOptions) error {
 	if shareName == "" {
 		return fmt.Errorf("share name required: %w", ErrInvalidShareInput)
 	}
@@ -50,23 +77,146 @@ func (c *Client) EnsureShare(ctx context.Context, shareName string, quotaGiB int
 		return fmt.Errorf("create share client: %w", err)
 	}
 
-	var options *share.CreateOptions
-	if quotaGiB > 0 {
-		options = &share.CreateOptions{Quota: &quotaGiB}
+	_, err = shareClient.Create(ctx, createOptions(quotaGiB, opts))
+	if err != nil {
+		if shareAlreadyExists(err) {
+			return nil
+		}
+		return fmt.Errorf("create share %q: %w", shareName, classifyError(err))
+	}
+	return nil
+}
+
+// DeleteShare deletes the share if it exists and, with RequireMetadata set, carries the expected metadata.
+func (c *Client) DeleteShare(ctx context.Context, shareName string, opts *DeleteShareOptions) error {
+	if shareName == "" {
+		return fmt.Errorf("share name required: %w", ErrInvalidShareInput)
+	}
+	if opts != nil && len(opts.RequireMetadata) > 0 {
+		props, err := c.GetShare(ctx, shareName)
+		if err != nil {
+			if errors.Is(err, ErrShareNotFound) {
+				return nil
+			}
+			return err
+		}
+		if err := CheckProvenance(shareName, props.Metadata, opts); err != nil {
+			return err
+		}
 	}
 
-	_, err = shareClient.Create(ctx, options)
+	shareClient, err := c.newShareClient(shareName)
 	if err != nil {
-		if isResponseStatus(err, http.StatusConflict) {
+		return fmt.Errorf("create share client: %w", err)
+	}
+
+	_, err = shareClient.Delete(ctx, &share.DeleteOptions{DeleteSnapshots: to.Ptr(share.DeleteSnapshotsOptionTypeInclude)})
+	if err != nil {
+		if isResponseStatus(err, http.StatusNotFound) {
 			return nil
 		}
-		return fmt.Errorf("create share %q: %w", shareName, err)
+		return fmt.Errorf("delete share %q: %w", shareName, classifyError(err))
 	}
 	return nil
 }
 
-// DeleteShare deletes the share if it exists.
-func (c *Client) DeleteShare(ctx context.Context, shareName string) error {
+// GetShare returns the current properties of the share.
+func (c *Client) GetShare(ctx context.Context, shareName string) (*ShareProperties, error) {
+	if shareName == "" {
+		return nil, fmt.Errorf("share name required: %w", ErrInvalidShareInput)
+	}
+
+	shareClient, err := c.newShareClient(shareName)
+	if err != nil {
+		return nil, fmt.Errorf("create share client: %w", err)
+	}
+
+	resp, err := shareClient.GetProperties(ctx, nil)
+	if err != nil {
+		if isResponseStatus(err, http.StatusNotFound) {
+			return nil, fmt.Errorf("get share %q: %w", shareName, ErrShareNotFound)
+		}
+		return nil, fmt.Errorf("get share %q: %w", shareName, classifyError(err))
+	}
+
+	props := &ShareProperties{Name: shareName}
+	if resp.Quota != nil {
+		props.QuotaGiB = *resp.Quota
+	}
+	if resp.AccessTier != nil {
+		props.AccessTier = *resp.AccessTier
+	}
+	if resp.EnabledProtocols != nil {
+		props.EnabledProtocol = *resp.EnabledProtocols
+	}
+	props.Metadata = fromMetadata(resp.Metadata)
+	return props, nil
+}
+
+// SetShareQuota updates the provisioned quota of an existing share.
+func (c *Client) SetShareQuota(ctx context.Context, shareName string, quotaGiB int32) error {
+	if shareName == "" {
+		return fmt.Errorf("share name required: %w", ErrInvalidShareInput)
+	}
+	if quotaGiB <= 0 {
+		return fmt.Errorf("quota must be positive: %w", ErrInvalidShareInput)
+	}
+
+	shareClient, err := c.newShareClient(shareName)
+	if err != nil {
+		return fmt.Errorf("create share client: %w", err)
+	}
+
+	_, err = shareClient.SetProperties(ctx, &share.SetPropertiesOptions{Quota: &quotaGiB})
+	if err != nil {
+		if isResponseStatus(err, http.StatusNotFound) {
+			return fmt.Errorf("set quota on share %q: %w", shareName, ErrShareNotFound)
+		}
+		return fmt.Errorf("set quota on share %q: %w", shareName, classifyError(err))
+	}
+	return nil
+}
+
+// ListShares returns the live shares of the account with their metadata.
+func (c *Client) ListShares(ctx context.Context) ([]ShareProperties, error) {
+	serviceClient, err := c.newServiceClient()
+	if err != nil {
+		return nil, err
+	}
+
+	var shares []ShareProperties
+	pager := serviceClient.NewListSharesPager(&service.ListSharesOptions{
+		Include: service.ListSharesInclude{Metadata: true},
+	})
+	for pager.More() {
+		page, err := pager.NextPage(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("list shares: %w", classifyError(err))
+		}
+		for _, item := range page.Shares {
+			if item == nil || item.Name == nil {
+				continue
+			}
+			props := ShareProperties{Name: *item.Name, Metadata: fromMetadata(item.Metadata)}
+			if p := item.Properties; p != nil {
+				if p.Quota != nil {
+					props.QuotaGiB = *p.Quota
+				}
+				if p.AccessTier != nil {
+					props.AccessTier = *p.AccessTier
+				}
+				if p.EnabledProtocols != nil {
+					props.EnabledProtocol = *p.EnabledProtocols
+				}
+			}
+			shares = append(shares, props)
+		}
+	}
+	return shares, nil
+}
+
+// SetShareMetadata replaces the metadata of an existing share.
+func (c *Client) SetShareMetadata(ctx context.Context, shareName string, metadata map[string]string) error {
 	if shareName == "" {
 		return fmt.Errorf("share name required: %w", ErrInvalidShareInput)
 	}
@@ -76,25 +226,165 @@ func (c *Client) DeleteShare(ctx context.Context, shareName string) error {
 		return fmt.Errorf("create share client: %w", err)
 	}
 
-	_, err = shareClient.Delete(ctx, nil)
+	_, err = shareClient.SetMetadata(ctx, &share.SetMetadataOptions{Metadata: toMetadata(metadata)})
+	if err != nil {
+		if isResponseStatus(err, http.StatusNotFound) {
+			return fmt.Errorf("set metadata on share %q: %w", shareName, ErrShareNotFound)
+		}
+		return fmt.Errorf("set metadata on share %q: %w", shareName, classifyError(err))
+	}
+	return nil
+}
+
+// ListDeletedShares returns the soft-deleted shares the account still retains.
+func (c *Client) ListDeletedShares(ctx context.Context) ([]DeletedShare, error) {
+	serviceClient, err := c.newServiceClient()
+	if err != nil {
+		return nil, err
+	}
+
+	var deleted []DeletedShare
+	pager := serviceClient.NewListSharesPager(&service.ListSharesOptions{
+		Include: service.ListSharesInclude{Deleted: true, Metadata: true},
+	})
+	for pager.More() {
+		page, err := pager.NextPage(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("list shares: %w", classifyError(err))
+		}
+		for _, item := range page.Shares {
+			if item == nil || item.Name == nil || item.Deleted == nil || !*item.Deleted {
+				continue
+			}
+			share := DeletedShare{Name: *item.Name, Metadata: fromMetadata(item.Metadata)}
+			if item.Version != nil {
+				share.Version = *item.Version
+			}
+			if p := item.Properties; p != nil {
+				if p.DeletedTime != nil {
+					share.DeletedTime = *p.DeletedTime
+				}
+				if p.RemainingRetentionDays != nil {
+					share.RemainingRetentionDays = *p.RemainingRetentionDays
+				}
+			}
+			deleted = append(deleted, share)
+		}
+	}
+	return deleted, nil
+}
+
+// RestoreShare undeletes a soft-deleted share version under its original name.
+func (c *Client) RestoreShare(ctx context.Context, shareName, version string) error {
+	if shareName == "" || version == "" {
+		return fmt.Errorf("share name and version required: %w", ErrInvalidShareInput)
+	}
+
+	shareClient, err := c.newShareClient(shareName)
 	if err != nil {
+		return fmt.Errorf("create share client: %w", err)
+	}
+
+	if _, err := shareClient.Restore(ctx, version, nil); err != nil {
+		if isResponseStatus(err, http.StatusNotFound) {
+			return fmt.Errorf("restore share %q version %q: %w", shareName, version, ErrShareNotFound)
+		}
+		return fmt.Errorf("restore share %q version %q: %w", shareName, version, classifyError(err))
+	}
+	return nil
+}
+
+// CreateSnapshot takes a snapshot of the share.
+func (c *Client) CreateSnapshot(ctx context.Context, shareName string) (string, error) {
+	if shareName == "" {
+		return "", fmt.Errorf("share name required: %w", ErrInvalidShareInput)
+	}
+
+	shareClient, err := c.newShareClient(shareName)
+	if err != nil {
+		return "", fmt.Errorf("create share client: %w", err)
+	}
+
+	resp, err := shareClient.CreateSnapshot(ctx, nil)
+	if err != nil {
+		if isResponseStatus(err, http.StatusNotFound) {
+			return "", fmt.Errorf("snapshot share %q: %w", shareName, ErrShareNotFound)
+		}
+		return "", fmt.Errorf("snapshot share %q: %w", shareName, classifyError(err))
+	}
+	if resp.Snapshot == nil {
+		return "", fmt.Errorf("snapshot share %q: no snapshot id returned", shareName)
+	}
+	return *resp.Snapshot, nil
+}
+
+// DeleteSnapshot deletes a snapshot of the share if it exists.
+func (c *Client) DeleteSnapshot(ctx context.Context, shareName, snapshot string) error {
+	if shareName == "" || snapshot == "" {
+		return fmt.Errorf("share name and snapshot required: %w", ErrInvalidShareInput)
+	}
+
+	shareClient, err := c.newShareClient(shareName)
+	if err != nil {
+		return fmt.Errorf("create share client: %w", err)
+	}
+
+	if _, err := shareClient.Delete(ctx, &share.DeleteOptions{ShareSnapshot: &snapshot}); err != nil {
 		if isResponseStatus(err, http.StatusNotFound) {
 			return nil
 		}
-		return fmt.Errorf("delete share %q: %w", shareName, err)
+		return fmt.Errorf("delete snapshot %q of share %q: %w", snapshot, shareName, classifyError(err))
 	}
 	return nil
 }
 
+func createOptions(quotaGiB int32, opts *EnsureShareOptions) *share.CreateOptions {
+	options := &share.CreateOptions{}
+	if quotaGiB > 0 {
+		options.Quota = &quotaGiB
+	}
+	if opts == nil {
+		return options
+	}
+	if opts.AccessTier != "" {
+		tier := share.AccessTier(opts.AccessTier)
+		options.AccessTier = &tier
+	}
+	if opts.EnabledProtocol != "" {
+		protocol := opts.EnabledProtocol
+		options.EnabledProtocols = &protocol
+	}
+	if opts.RootSquash != "" {
+		squash := share.RootSquash(opts.RootSquash)
+		options.RootSquash = &squash
+	}
+	options.Metadata = toMetadata(opts.Metadata)
+	return options
+}
+
 func (c *Client) newShareClient(shareName string) (*share.Client, error) {
 	shareURL := fmt.Sprintf("%s/%s", c.endpoint, shareName)
-	client, err := share.NewClient(shareURL, c.credential, nil)
+	client, err := share.NewClient(shareURL, c.credential, c.options)
 	if err != nil {
 		return nil, fmt.Errorf("new share client: %w", err)
 	}
 	return client, nil
 }
 
+// shareAlreadyExists reports whether a create conflict means the share exists. A share of the same
+// name that is still being deleted also conflicts, but will be gone shortly.
+func shareAlreadyExists(err error) bool {
+	return isResponseStatus(err, http.StatusConflict) && !errors.Is(classifyError(err), ErrShareBeingDeleted)
+}
+
+func (c *Client) newServiceClient() (*service.Client, error) {
+	client, err := service.NewClient(c.endpoint+"/", c.credential, (*service.ClientOptions)(c.options))
+	if err != nil {
+		return nil, fmt.Errorf("create service client: %w", err)
+	}
+	return client, nil
+}
+
 func isResponseStatus(err error, statusCode int) bool {
 	var respErr *azcore.ResponseError
 	if errors.As(err, &respErr) {
@@ -0,0 +1,136 @@
+package azure
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"path"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/storage/azfile/directory"
+	"github.com/Azure/azure-sdk-for-go/sdk/storage/azfile/file"
+	"github.com/Azure/azure-sdk-for-go/sdk/storage/azfile/share"
+)
+
+// CopyShare walks the source share breadth-first and copies every file the target does not have yet.
+// Files already in the target count as copied unless they carry a pending, failed or aborted copy.
+// Listing is paged, but every checked file costs a request, so opts skips the files an earlier pass
+// found copied and caps the files checked in this one.
+func (c *Client) CopyShare(ctx context.Context, sourceShare, sourceSnapshot, targetShare string, opts *CopyShareOptions) (*CopyProgress, error) {
+	if sourceShare == "" || targetShare == "" {
+		return nil, fmt.Errorf("source and target share names required: %w", ErrInvalidShareInput)
+	}
+	if sourceShare == targetShare && sourceSnapshot == "" {
+		return nil, fmt.Errorf("share %q cannot be copied onto itself: %w", sourceShare, ErrInvalidShareInput)
+	}
+
+	source, err := c.newShareClient(sourceShare)
+	if err != nil {
+		return nil, fmt.Errorf("create share client: %w", err)
+	}
+	if sourceSnapshot != "" {
+		source, err = source.WithSnapshot(sourceSnapshot)
+		if err != nil {
+			return nil, fmt.Errorf("create snapshot client: %w", err)
+		}
+	}
+	target, err := c.newShareClient(targetShare)
+	if err != nil {
+		return nil, fmt.Errorf("create share client: %w", err)
+	}
+
+	if opts == nil {
+		opts = &CopyShareOptions{}
+	}
+	progress := &CopyProgress{}
+	checked, contiguous := 0, true
+	pending := []string{""}
+	for len(pending) > 0 {
+		dir := pending[0]
+		pending = pending[1:]
+		sourceDir, targetDir := directoryClient(source, dir), directoryClient(target, dir)
+		if dir != "" {
+			if _, err := targetDir.Create(ctx, nil); err != nil && !isResponseStatus(err, http.StatusConflict) {
+				return nil, fmt.Errorf("create directory %q in share %q: %w", dir, targetShare, classifyError(err))
+			}
+		}
+
+		pager := sourceDir.NewListFilesAndDirectoriesPager(nil)
+		for pager.More() {
+			page, err := pager.NextPage(ctx)
+			if err != nil {
+				if dir == "" && isResponseStatus(err, http.StatusNotFound) {
+					return nil, fmt.Errorf("list share %q: %w", sourceShare, ErrShareNotFound)
+				}
+				return nil, fmt.Errorf("list directory %q of share %q: %w", dir, sourceShare, classifyError(err))
+			}
+			if page.Segment == nil {
+				continue
+			}
+			for _, item := range page.Segment.Directories {
+				if item != nil && item.Name != nil {
+					pending = append(pending, path.Join(dir, *item.Name))
+				}
+			}
+			for _, item := range page.Segment.Files {
+				if item == nil || item.Name == nil {
+					continue
+				}
+				progress.Files++
+				if progress.Files <= opts.Skip {
+					progress.Completed++
+					continue
+				}
+				if opts.MaxFiles > 0 && checked >= opts.MaxFiles {
+					contiguous = false
+					continue
+				}
+				checked++
+				copied, err := copyFile(ctx, sourceDir.NewFileClient(*item.Name), targetDir.NewFileClient(*item.Name))
+				if err != nil {
+					return nil, fmt.Errorf("copy %q to share %q: %w", path.Join(dir, *item.Name), targetShare, err)
+				}
+				if copied && contiguous {
+					progress.Completed++
+				} else {
+					contiguous = false
+				}
+			}
+		}
+	}
+	return progress, nil
+}
+
+// copyFile starts the copy of a file the target lacks and reports whether the target holds a complete copy.
+func copyFile(ctx context.Context, source, target *file.Client) (bool, error) {
+	props, err := target.GetProperties(ctx, nil)
+	if isResponseStatus(err, http.StatusNotFound) {
+		if _, err := target.StartCopyFromURL(ctx, source.URL(), nil); err != nil {
+			return false, fmt.Errorf("start copy: %w", classifyError(err))
+		}
+		return false, nil
+	}
+	if err != nil {
+		return false, fmt.Errorf("get properties: %w", classifyError(err))
+	}
+	if props.CopyStatus == nil {
+		return true, nil
+	}
+	switch *props.CopyStatus {
+	case file.CopyStatusTypeSuccess:
+		return true, nil
+	case file.CopyStatusTypePending:
+		return false, nil
+	}
+	description := ""
+	if props.CopyStatusDescription != nil {
+		description = *props.CopyStatusDescription
+	}
+	return false, fmt.Errorf("copy status %s %q: %w", *props.CopyStatus, description, ErrShareCopyFailed)
+}
+
+func directoryClient(shareClient *share.Client, dir string) *directory.Client {
+	if dir == "" {
+		return shareClient.NewRootDirectoryClient()
+	}
+	return shareClient.NewDirectoryClient(dir)
+}
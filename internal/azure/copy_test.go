@@ -0,0 +1,275 @@
+package azure
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"path"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore"
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore/arm"
+	azfake "github.com/Azure/azure-sdk-for-go/sdk/azcore/fake"
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore/policy"
+	"github.com/Azure/azure-sdk-for-go/sdk/resourcemanager/storage/armstorage/fake"
+	"github.com/Azure/azure-sdk-for-go/sdk/storage/azfile/share"
+)
+
+// testCopyShare copies a share holding a.txt, dir/b.txt and dir/sub/c.txt into an empty one.
+func testCopyShare(t *testing.T, client ShareClient, sourceSnapshot string) {
+	t.Helper()
+	ctx := context.Background()
+
+	if _, err := client.CopyShare(ctx, "", "", "target", nil); !errors.Is(err, ErrInvalidShareInput) {
+		t.Fatalf("CopyShare empty source error = %v, want %v", err, ErrInvalidShareInput)
+	}
+	if _, err := client.CopyShare(ctx, "missing", "", "target", nil); !errors.Is(err, ErrShareNotFound) {
+		t.Fatalf("CopyShare missing source error = %v, want %v", err, ErrShareNotFound)
+	}
+
+	progress, err := client.CopyShare(ctx, "source", sourceSnapshot, "target", nil)
+	if err != nil {
+		t.Fatalf("CopyShare error = %v", err)
+	}
+	if progress.Files != 3 || progress.Done() {
+		t.Fatalf("CopyShare = %+v, want 3 files with copies pending", *progress)
+	}
+	for range 2 {
+		progress, err = client.CopyShare(ctx, "source", sourceSnapshot, "target", nil)
+		if err != nil {
+			t.Fatalf("CopyShare error = %v", err)
+		}
+	}
+	if progress.Files != 3 || !progress.Done() {
+		t.Fatalf("CopyShare = %+v, want all 3 files copied", *progress)
+	}
+
+	// Bounded passes check one file each after the files earlier passes found copied, so every pass
+	// completes at most one more file.
+	opts := &CopyShareOptions{MaxFiles: 1}
+	for pass := 0; pass < 9 && (pass == 0 || !progress.Done()); pass++ {
+		progress, err = client.CopyShare(ctx, "source", sourceSnapshot, "bounded", opts)
+		if err != nil {
+			t.Fatalf("CopyShare bounded error = %v", err)
+		}
+		if progress.Files != 3 || progress.Completed > opts.Skip+1 {
+			t.Fatalf("CopyShare bounded = %+v after %d files, want at most one more copied", *progress, opts.Skip)
+		}
+		opts.Skip = progress.Completed
+	}
+	if !progress.Done() {
+		t.Fatalf("CopyShare bounded = %+v, want all 3 files copied", *progress)
+	}
+}
+
+func TestFakeShareClientCopyShare(t *testing.T) {
+	ctx := context.Background()
+	client := &FakeShareClient{Files: map[string][]string{"source": {"a.txt", "dir/b.txt", "dir/sub/c.txt"}}}
+	for _, name := range []string{"source", "target", "bounded"} {
+		if err := client.EnsureShare(ctx, name, 1, nil); err != nil {
+			t.Fatalf("EnsureShare error = %v", err)
+		}
+	}
+	snapshot, err := client.CreateSnapshot(ctx, "source")
+	if err != nil {
+		t.Fatalf("CreateSnapshot error = %v", err)
+	}
+	client.Files["source"] = append(client.Files["source"], "later.txt")
+
+	testCopyShare(t, client, snapshot)
+	if got := client.Files["target"]; !slices.Equal(got, []string{"a.txt", "dir/b.txt", "dir/sub/c.txt"}) {
+		t.Fatalf("target files = %v, want the snapshot's files", got)
+	}
+}
+
+func TestClientCopyShare(t *testing.T) {
+	stub := newFileCopyStub()
+	server := httptest.NewServer(stub)
+	defer server.Close()
+
+	client, err := NewClientWithEndpoint(server.URL, &azfake.TokenCredential{}, &share.ClientOptions{
+		ClientOptions: azcore.ClientOptions{
+			InsecureAllowCredentialWithHTTP: true,
+			Retry:                           policy.RetryOptions{MaxRetries: -1},
+		},
+	})
+	if err != nil {
+		t.Fatalf("NewClientWithEndpoint error = %v", err)
+	}
+	snapshot := "2024-05-01T10:00:00.0000000Z"
+	testCopyShare(t, client, snapshot)
+
+	if !slices.Contains(stub.dirs["target"], "dir/sub") {
+		t.Fatalf("target directories = %v, want dir/sub created", stub.dirs["target"])
+	}
+	for _, source := range stub.copySources {
+		parsed, err := url.Parse(source)
+		if err != nil || parsed.Query().Get("sharesnapshot") != snapshot {
+			t.Fatalf("copy source %q, want snapshot %s", source, snapshot)
+		}
+	}
+	if stub.intent != "backup" {
+		t.Fatalf("x-ms-file-request-intent = %q, want backup", stub.intent)
+	}
+
+	stub.files["source"]["broken.txt"] = "success"
+	stub.failing = "broken.txt"
+	if _, err := client.CopyShare(context.Background(), "source", "", "other", nil); err != nil {
+		t.Fatalf("CopyShare error = %v", err)
+	}
+	if _, err := client.CopyShare(context.Background(), "source", "", "other", nil); !errors.Is(err, ErrShareCopyFailed) {
+		t.Fatalf("CopyShare failed copy error = %v, want %v", err, ErrShareCopyFailed)
+	}
+}
+
+func TestARMShareClientCopyShare(t *testing.T) {
+	client, err := NewARMShareClient("sub", "rg", "acct", &azfake.TokenCredential{}, &arm.ClientOptions{
+		ClientOptions: azcore.ClientOptions{Transport: fake.NewFileSharesServerTransport(&fake.FileSharesServer{})},
+	})
+	if err != nil {
+		t.Fatalf("NewARMShareClient error = %v", err)
+	}
+	if _, err := client.CopyShare(context.Background(), "source", "", "target", nil); !errors.Is(err, ErrInvalidShareInput) {
+		t.Fatalf("CopyShare error = %v, want %v", err, ErrInvalidShareInput)
+	}
+}
+
+// fileCopyStub emulates the directory and file operations of the Azure Files REST API used to copy shares
+// and manage claim directories.
+// Files map to their copy status; a pending copy succeeds once it has been reported pending.
+type fileCopyStub struct {
+	mu          sync.Mutex
+	files       map[string]map[string]string
+	dirs        map[string][]string
+	copySources []string
+	intent      string
+	failing     string
+}
+
+func newFileCopyStub() *fileCopyStub {
+	return &fileCopyStub{
+		files: map[string]map[string]string{
+			"source":  {"a.txt": "success", "dir/b.txt": "success", "dir/sub/c.txt": "success"},
+			"target":  {},
+			"other":   {},
+			"bounded": {},
+		},
+		dirs: map[string][]string{"source": {"dir", "dir/sub"}},
+	}
+}
+
+func (s *fileCopyStub) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	shareName, filePath, _ := strings.Cut(strings.Trim(r.URL.Path, "/"), "/")
+	files, ok := s.files[shareName]
+	if !ok {
+		w.Header().Set("x-ms-error-code", "ShareNotFound")
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+	if intent := r.Header.Get("x-ms-file-request-intent"); intent != "" {
+		s.intent = intent
+	}
+
+	query := r.URL.Query()
+	switch {
+	case r.Method == http.MethodGet && query.Get("restype") == "directory" && query.Get("comp") == "list":
+		if filePath != "" && !slices.Contains(s.dirs[shareName], filePath) {
+			w.Header().Set("x-ms-error-code", "ResourceNotFound")
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		var body strings.Builder
+		body.WriteString(`<?xml version="1.0" encoding="utf-8"?><EnumerationResults ServiceEndpoint="stub" ShareName="` + shareName + `" DirectoryPath="` + filePath + `"><Entries>`)
+		for name := range files {
+			if parentDir(name) == filePath {
+				body.WriteString("<File><Name>" + path.Base(name) + "</Name><Properties><Content-Length>1</Content-Length></Properties></File>")
+			}
+		}
+		for _, dir := range s.dirs[shareName] {
+			if parentDir(dir) == filePath {
+				body.WriteString("<Directory><Name>" + path.Base(dir) + "</Name></Directory>")
+			}
+		}
+		body.WriteString("</Entries><NextMarker /></EnumerationResults>")
+		w.Header().Set("Content-Type", "application/xml")
+		w.WriteHeader(http.StatusOK)
+		_, _ = w.Write([]byte(body.String()))
+	case r.Method == http.MethodPut && query.Get("restype") == "directory":
+		if slices.Contains(s.dirs[shareName], filePath) {
+			w.Header().Set("x-ms-error-code", "ResourceAlreadyExists")
+			w.WriteHeader(http.StatusConflict)
+			return
+		}
+		s.dirs[shareName] = append(s.dirs[shareName], filePath)
+		w.WriteHeader(http.StatusCreated)
+	case r.Method == http.MethodPut && r.Header.Get("x-ms-copy-source") != "":
+		s.copySources = append(s.copySources, r.Header.Get("x-ms-copy-source"))
+		files[filePath] = "pending"
+		if path.Base(filePath) == s.failing {
+			files[filePath] = "failed"
+		}
+		w.Header().Set("x-ms-copy-id", "copy-"+filePath)
+		w.Header().Set("x-ms-copy-status", "pending")
+		w.WriteHeader(http.StatusAccepted)
+	case r.Method == http.MethodDelete && query.Get("restype") == "directory":
+		if !slices.Contains(s.dirs[shareName], filePath) {
+			w.Header().Set("x-ms-error-code", "ResourceNotFound")
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		for name := range files {
+			if parentDir(name) == filePath {
+				w.Header().Set("x-ms-error-code", "DirectoryNotEmpty")
+				w.WriteHeader(http.StatusConflict)
+				return
+			}
+		}
+		for _, dir := range s.dirs[shareName] {
+			if parentDir(dir) == filePath {
+				w.Header().Set("x-ms-error-code", "DirectoryNotEmpty")
+				w.WriteHeader(http.StatusConflict)
+				return
+			}
+		}
+		s.dirs[shareName] = slices.DeleteFunc(s.dirs[shareName], func(dir string) bool { return dir == filePath })
+		w.WriteHeader(http.StatusAccepted)
+	case r.Method == http.MethodDelete:
+		if _, ok := files[filePath]; !ok {
+			w.Header().Set("x-ms-error-code", "ResourceNotFound")
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		delete(files, filePath)
+		w.WriteHeader(http.StatusAccepted)
+	case r.Method == http.MethodHead:
+		status, ok := files[filePath]
+		if !ok {
+			w.Header().Set("x-ms-error-code", "ResourceNotFound")
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		if status == "pending" {
+			files[filePath] = "success"
+		}
+		w.Header().Set("x-ms-type", "File")
+		w.Header().Set("x-ms-copy-status", status)
+		w.WriteHeader(http.StatusOK)
+	default:
+		w.WriteHeader(http.StatusMethodNotAllowed)
+	}
+}
+
+func parentDir(name string) string {
+	if dir := path.Dir(name); dir != "." {
+		return dir
+	}
+	return ""
+}
@@ -0,0 +1,76 @@
+package azure
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"path"
+)
+
+// EnsureDirectory creates a top-level directory in the share unless it already exists.
+func (c *Client) EnsureDirectory(ctx context.Context, shareName, dir string) error {
+	if shareName == "" || dir == "" {
+		return fmt.Errorf("share and directory names required: %w", ErrInvalidShareInput)
+	}
+	shareClient, err := c.newShareClient(shareName)
+	if err != nil {
+		return fmt.Errorf("create share client: %w", err)
+	}
+	_, err = shareClient.NewDirectoryClient(dir).Create(ctx, nil)
+	switch {
+	case err == nil, isResponseStatus(err, http.StatusConflict):
+		return nil
+	case isResponseStatus(err, http.StatusNotFound):
+		return fmt.Errorf("create directory %q in share %q: %w", dir, shareName, ErrShareNotFound)
+	}
+	return fmt.Errorf("create directory %q in share %q: %w", dir, shareName, classifyError(err))
+}
+
+// DeleteDirectory deletes a directory of the share with everything in it. A missing share or directory
+// counts as deleted. Files are deleted while walking the tree, then the directories deepest first.
+func (c *Client) DeleteDirectory(ctx context.Context, shareName, dir string) error {
+	if shareName == "" || dir == "" {
+		return fmt.Errorf("share and directory names required: %w", ErrInvalidShareInput)
+	}
+	shareClient, err := c.newShareClient(shareName)
+	if err != nil {
+		return fmt.Errorf("create share client: %w", err)
+	}
+
+	dirs := []string{dir}
+	for i := 0; i < len(dirs); i++ {
+		dirClient := shareClient.NewDirectoryClient(dirs[i])
+		pager := dirClient.NewListFilesAndDirectoriesPager(nil)
+		for pager.More() {
+			page, err := pager.NextPage(ctx)
+			if err != nil {
+				if i == 0 && isResponseStatus(err, http.StatusNotFound) {
+					return nil
+				}
+				return fmt.Errorf("list directory %q of share %q: %w", dirs[i], shareName, classifyError(err))
+			}
+			if page.Segment == nil {
+				continue
+			}
+			for _, item := range page.Segment.Directories {
+				if item != nil && item.Name != nil {
+					dirs = append(dirs, path.Join(dirs[i], *item.Name))
+				}
+			}
+			for _, item := range page.Segment.Files {
+				if item == nil || item.Name == nil {
+					continue
+				}
+				if _, err := dirClient.NewFileClient(*item.Name).Delete(ctx, nil); err != nil && !isResponseStatus(err, http.StatusNotFound) {
+					return fmt.Errorf("delete file %q of share %q: %w", path.Join(dirs[i], *item.Name), shareName, classifyError(err))
+				}
+			}
+		}
+	}
+	for i := len(dirs) - 1; i >= 0; i-- {
+		if _, err := shareClient.NewDirectoryClient(dirs[i]).Delete(ctx, nil); err != nil && !isResponseStatus(err, http.StatusNotFound) {
+			return fmt.Errorf("delete directory %q of share %q: %w", dirs[i], shareName, classifyError(err))
+		}
+	}
+	return nil
+}
@@ -0,0 +1,100 @@
+package azure
+
+import (
+	"context"
+	"errors"
+	"net/http/httptest"
+	"slices"
+	"testing"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore"
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore/arm"
+	azfake "github.com/Azure/azure-sdk-for-go/sdk/azcore/fake"
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore/policy"
+	"github.com/Azure/azure-sdk-for-go/sdk/resourcemanager/storage/armstorage/fake"
+	"github.com/Azure/azure-sdk-for-go/sdk/storage/azfile/share"
+)
+
+// testDirectories creates directories in a share holding a.txt, dir/b.txt and dir/sub/c.txt, then deletes dir.
+func testDirectories(t *testing.T, client ShareClient) {
+	t.Helper()
+	ctx := context.Background()
+
+	if err := client.EnsureDirectory(ctx, "source", ""); !errors.Is(err, ErrInvalidShareInput) {
+		t.Fatalf("EnsureDirectory empty name error = %v, want %v", err, ErrInvalidShareInput)
+	}
+	if err := client.EnsureDirectory(ctx, "missing", "claim"); !errors.Is(err, ErrShareNotFound) {
+		t.Fatalf("EnsureDirectory missing share error = %v, want %v", err, ErrShareNotFound)
+	}
+	for _, dir := range []string{"dir", "claim"} {
+		if err := client.EnsureDirectory(ctx, "source", dir); err != nil {
+			t.Fatalf("EnsureDirectory(%q) error = %v", dir, err)
+		}
+	}
+
+	for range 2 {
+		if err := client.DeleteDirectory(ctx, "source", "dir"); err != nil {
+			t.Fatalf("DeleteDirectory error = %v", err)
+		}
+	}
+	if err := client.DeleteDirectory(ctx, "missing", "dir"); err != nil {
+		t.Fatalf("DeleteDirectory missing share error = %v", err)
+	}
+}
+
+func TestFakeShareClientDirectories(t *testing.T) {
+	client := &FakeShareClient{
+		Files:       map[string][]string{"source": {"a.txt", "dir/b.txt", "dir/sub/c.txt"}},
+		Directories: map[string][]string{"source": {"dir"}},
+	}
+	if err := client.EnsureShare(context.Background(), "source", 1, nil); err != nil {
+		t.Fatalf("EnsureShare error = %v", err)
+	}
+
+	testDirectories(t, client)
+	if got := client.Files["source"]; !slices.Equal(got, []string{"a.txt"}) {
+		t.Fatalf("files = %v, want only a.txt", got)
+	}
+	if got := client.Directories["source"]; !slices.Equal(got, []string{"claim"}) {
+		t.Fatalf("directories = %v, want only claim", got)
+	}
+}
+
+func TestClientDirectories(t *testing.T) {
+	stub := newFileCopyStub()
+	server := httptest.NewServer(stub)
+	defer server.Close()
+
+	client, err := NewClientWithEndpoint(server.URL, &azfake.TokenCredential{}, &share.ClientOptions{
+		ClientOptions: azcore.ClientOptions{
+			InsecureAllowCredentialWithHTTP: true,
+			Retry:                           policy.RetryOptions{MaxRetries: -1},
+		},
+	})
+	if err != nil {
+		t.Fatalf("NewClientWithEndpoint error = %v", err)
+	}
+
+	testDirectories(t, client)
+	if _, ok := stub.files["source"]["a.txt"]; !ok || len(stub.files["source"]) != 1 {
+		t.Fatalf("files = %v, want only a.txt", stub.files["source"])
+	}
+	if got := stub.dirs["source"]; !slices.Equal(got, []string{"claim"}) {
+		t.Fatalf("directories = %v, want only claim", got)
+	}
+}
+
+func TestARMShareClientDirectories(t *testing.T) {
+	client, err := NewARMShareClient("sub", "rg", "acct", &azfake.TokenCredential{}, &arm.ClientOptions{
+		ClientOptions: azcore.ClientOptions{Transport: fake.NewFileSharesServerTransport(&fake.FileSharesServer{})},
+	})
+	if err != nil {
+		t.Fatalf("NewARMShareClient error = %v", err)
+	}
+	if err := client.EnsureDirectory(context.Background(), "shared", "claim"); !errors.Is(err, ErrInvalidShareInput) {
+		t.Fatalf("EnsureDirectory error = %v, want %v", err, ErrInvalidShareInput)
+	}
+	if err := client.DeleteDirectory(context.Background(), "shared", "claim"); !errors.Is(err, ErrInvalidShareInput) {
+		t.Fatalf("DeleteDirectory error = %v, want %v", err, ErrInvalidShareInput)
+	}
+}
@@ -0,0 +1,96 @@
+package azure
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"slices"
+	"strconv"
+	"time"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore"
+)
+
+// Typed errors for Azure responses the controller handles differently from a plain retry.
+// Share clients wrap them together with the original *azcore.ResponseError.
+var (
+	ErrShareBeingDeleted = errors.New("share is being deleted")
+	ErrThrottled         = errors.New("azure request throttled")
+	ErrAuthorization     = errors.New("not authorized for the azure storage operation")
+	ErrQuotaExceeded     = errors.New("azure storage quota exceeded")
+)
+
+// Error codes of the data plane (x-ms-error-code) and ARM (error.code) mapped to the typed errors.
+var (
+	authorizationCodes = []string{
+		"AuthenticationFailed",
+		"AuthorizationFailed",
+		"AuthorizationFailure",
+		"AuthorizationPermissionMismatch",
+		"InvalidAuthenticationInfo",
+	}
+	quotaCodes = []string{
+		"QuotaExceeded",
+		"ShareSizeLimitReached",
+		"ShareSnapshotCountExceeded",
+	}
+)
+
+// ThrottledError reports a 429 or 503 response that the SDK's own retries did not get past.
+// RetryAfter is the delay Azure asked for, or zero when the response named none.
+type ThrottledError struct {
+	RetryAfter time.Duration
+	Err        error
+}
+
+func (e *ThrottledError) Error() string {
+	if e.RetryAfter > 0 {
+		return fmt.Sprintf("%s (retry after %s): %s", ErrThrottled, e.RetryAfter, e.Err)
+	}
+	return fmt.Sprintf("%s: %s", ErrThrottled, e.Err)
+}
+
+func (e *ThrottledError) Unwrap() []error {
+	return []error{ErrThrottled, e.Err}
+}
+
+// classifyError wraps Azure response errors into the typed errors; other errors are returned unchanged.
+func classifyError(err error) error {
+	var respErr *azcore.ResponseError
+	if !errors.As(err, &respErr) {
+		return err
+	}
+	switch {
+	case respErr.StatusCode == http.StatusTooManyRequests || respErr.StatusCode == http.StatusServiceUnavailable || respErr.ErrorCode == "ServerBusy":
+		return &ThrottledError{RetryAfter: retryAfter(respErr.RawResponse), Err: err}
+	case respErr.ErrorCode == "ShareBeingDeleted":
+		return fmt.Errorf("%w: %w", ErrShareBeingDeleted, err)
+	case respErr.StatusCode == http.StatusUnauthorized || respErr.StatusCode == http.StatusForbidden || slices.Contains(authorizationCodes, respErr.ErrorCode):
+		return fmt.Errorf("%w: %w", ErrAuthorization, err)
+	case slices.Contains(quotaCodes, respErr.ErrorCode):
+		return fmt.Errorf("%w: %w", ErrQuotaExceeded, err)
+	}
+	return err
+}
+
+// retryAfter reads the delay from the retry-after-ms, x-ms-retry-after-ms or Retry-After headers.
+func retryAfter(resp *http.Response) time.Duration {
+	if resp == nil {
+		return 0
+	}
+	for _, header := range []string{"retry-after-ms", "x-ms-retry-after-ms"} {
+		if ms, err := strconv.Atoi(resp.Header.Get(header)); err == nil && ms > 0 {
+			return time.Duration(ms) * time.Millisecond
+		}
+	}
+	value := resp.Header.Get("Retry-After")
+	if seconds, err := strconv.Atoi(value); err == nil && seconds > 0 {
+		return time.Duration(seconds) * time.Second
+	}
+	if at, err := http.ParseTime(value); err == nil {
+		if delay := time.Until(at); delay > 0 {
+			return delay
+		}
+	}
+	return 0
+}
@@ -0,0 +1,86 @@
+package azure
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore/runtime"
+)
+
+func TestClassifyError(t *testing.T) {
+	cases := map[string]struct {
+		status  int
+		code    string
+		headers map[string]string
+		want    error
+	}{
+		"being deleted":     {status: http.StatusConflict, code: "ShareBeingDeleted", want: ErrShareBeingDeleted},
+		"throttled":         {status: http.StatusTooManyRequests, want: ErrThrottled},
+		"server busy":       {status: http.StatusServiceUnavailable, code: "ServerBusy", want: ErrThrottled},
+		"permission":        {status: http.StatusForbidden, code: "AuthorizationPermissionMismatch", want: ErrAuthorization},
+		"arm authorization": {status: http.StatusForbidden, code: "AuthorizationFailed", want: ErrAuthorization},
+		"share full":        {status: http.StatusInsufficientStorage, code: "ShareSizeLimitReached", want: ErrQuotaExceeded},
+		"unclassified":      {status: http.StatusInternalServerError, code: "InternalError"},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			err := classifyError(responseError(tc.status, tc.code, tc.headers))
+			if tc.want == nil {
+				for _, typed := range []error{ErrShareBeingDeleted, ErrThrottled, ErrAuthorization, ErrQuotaExceeded} {
+					if errors.Is(err, typed) {
+						t.Fatalf("classifyError = %v, want unclassified", err)
+					}
+				}
+				return
+			}
+			if !errors.Is(err, tc.want) {
+				t.Fatalf("classifyError = %v, want %v", err, tc.want)
+			}
+		})
+	}
+}
+
+func TestClassifyErrorRetryAfter(t *testing.T) {
+	cases := map[string]struct {
+		headers map[string]string
+		want    time.Duration
+	}{
+		"seconds":      {headers: map[string]string{"Retry-After": "7"}, want: 7 * time.Second},
+		"milliseconds": {headers: map[string]string{"x-ms-retry-after-ms": "1500", "Retry-After": "7"}, want: 1500 * time.Millisecond},
+		"none":         {},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			var throttled *ThrottledError
+			if err := classifyError(responseError(http.StatusTooManyRequests, "", tc.headers)); !errors.As(err, &throttled) {
+				t.Fatalf("classifyError = %v, want *ThrottledError", err)
+			}
+			if throttled.RetryAfter != tc.want {
+				t.Fatalf("RetryAfter = %s, want %s", throttled.RetryAfter, tc.want)
+			}
+		})
+	}
+}
+
+func responseError(status int, code string, headers map[string]string) error {
+	resp := &http.Response{
+		StatusCode: status,
+		Header:     http.Header{},
+		Body:       io.NopCloser(strings.NewReader("")),
+		Request:    httptest.NewRequest(http.MethodPut, "https://account.file.core.windows.net/share", nil),
+	}
+	if code != "" {
+		resp.Header.Set("x-ms-error-code", code)
+	}
+	for key, value := range headers {
+		resp.Header.Set(key, value)
+	}
+	return runtime.NewResponseError(resp)
+}
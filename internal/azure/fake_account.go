@@ -0,0 +1,89 @@
+package azure
+
+import (
+	"context"
+	"fmt"
+	"sync"
+)
+
+// FakeAccountManager is an in-memory AccountManager for unit tests.
+// Accounts and keys are keyed by account name.
+type FakeAccountManager struct {
+	mu          sync.Mutex
+	Accounts    map[string]AccountProperties
+	Keys        map[string]string
+	GetErr      error
+	CreateErr   error
+	KeyErr      error
+	GetCount    int
+	CreateCount int
+	KeyCount    int
+}
+
+// GetAccount returns the stored account or ErrAccountNotFound.
+func (f *FakeAccountManager) GetAccount(_ context.Context, resourceGroup, accountName string) (*AccountProperties, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	f.GetCount++
+	if f.GetErr != nil {
+		return nil, f.GetErr
+	}
+	props, ok := f.Accounts[accountName]
+	if !ok || props.ResourceGroup != resourceGroup {
+		return nil, fmt.Errorf("get account %q: %w", accountName, ErrAccountNotFound)
+	}
+	return &props, nil
+}
+
+// CreateAccount stores an account the way the ARM manager creates it.
+func (f *FakeAccountManager) CreateAccount(_ context.Context, spec AccountSpec) (*AccountProperties, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	f.CreateCount++
+	if f.CreateErr != nil {
+		return nil, f.CreateErr
+	}
+	if f.Accounts == nil {
+		f.Accounts = map[string]AccountProperties{}
+	}
+	props := AccountProperties{
+		Name:              spec.Name,
+		ResourceGroup:     spec.ResourceGroup,
+		Location:          spec.Location,
+		Kind:              spec.Kind,
+		SkuName:           spec.SkuName,
+		HTTPSOnly:         !spec.NFS,
+		MinimumTLSVersion: MinimumTLSVersion,
+	}
+	f.Accounts[spec.Name] = props
+	return &props, nil
+}
+
+// GetAccountKey returns the stored key or ErrNoAccountKey.
+func (f *FakeAccountManager) GetAccountKey(_ context.Context, _, accountName string) (string, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	f.KeyCount++
+	if f.KeyErr != nil {
+		return "", f.KeyErr
+	}
+	key, ok := f.Keys[accountName]
+	if !ok {
+		return "", fmt.Errorf("account %q: %w", accountName, ErrNoAccountKey)
+	}
+	return key, nil
+}
+
+// SetKey replaces the stored key, e.g. to simulate rotation.
+func (f *FakeAccountManager) SetKey(accountName, key string) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if f.Keys == nil {
+		f.Keys = map[string]string{}
+	}
+	f.Keys[accountName] = key
+}
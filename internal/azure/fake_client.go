@@ -2,23 +2,58 @@ package azure
 
 import (
 	"context"
+	"fmt"
+	"maps"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
 	"sync"
+	"time"
 )
 
 // FakeShareClient is an in-memory ShareClient for unit tests.
+// Like accounts with share soft delete enabled, deleted shares are retained until restored.
 type FakeShareClient struct {
 	mu          sync.Mutex
 	Shares      map[string]int32
 	EnsureErr   map[string]error
 	DeleteErr   map[string]error
+	QuotaErr    map[string]error
 	EnsureCount map[string]int
+	QuotaCount  map[string]int
+	Options     map[string]EnsureShareOptions
+	Snapshots   map[string][]string
+	// Files holds the file paths of each share. Copies complete on the CopyShare call after the one
+	// that started them; CopyErr fails the copies into a target share.
+	Files   map[string][]string
+	CopyErr map[string]error
+	// Directories holds the top-level directories of each share; deleting one removes its Files.
+	Directories map[string][]string
+
+	snapshotFiles map[string][]string
+	copying       map[string][]string
+	deleted       []fakeDeletedShare
+	versions      int
+}
+
+// fakeDeletedShare keeps what a restore brings back.
+type fakeDeletedShare struct {
+	DeletedShare
+	quotaGiB  int32
+	options   EnsureShareOptions
+	snapshots []string
 }
 
 // EnsureShare records the share creation request in memory.
-func (f *FakeShareClient) EnsureShare(_ context.Context, shareName string, quotaGiB int32) error {
+// Like the real client, an existing share keeps its current quota.
+func (f *FakeShareClient) EnsureShare(_ context.Context, shareName string, quotaGiB int32, opts *EnsureShareOptions) error {
 	f.mu.Lock()
 	defer f.mu.Unlock()
 
+	if shareName == "" {
+		return fmt.Errorf("share name required: %w", ErrInvalidShareInput)
+	}
 	if err := f.EnsureErr[shareName]; err != nil {
 		return err
 	}
@@ -28,22 +63,320 @@ func (f *FakeShareClient) EnsureShare(_ context.Context, shareName string, quota
 	if f.EnsureCount == nil {
 		f.EnsureCount = map[string]int{}
 	}
-	f.Shares[shareName] = quotaGiB
+	if f.Options == nil {
+		f.Options = map[string]EnsureShareOptions{}
+	}
+	if _, ok := f.Shares[shareName]; !ok {
+		f.Shares[shareName] = quotaGiB
+		if opts != nil {
+			f.Options[shareName] = *opts
+		}
+	}
 	f.EnsureCount[shareName]++
 	return nil
 }
 
-// DeleteShare removes the share entry in memory.
-func (f *FakeShareClient) DeleteShare(_ context.Context, shareName string) error {
+// DeleteShare removes the share entry and its snapshots in memory, checking RequireMetadata against the stored options.
+func (f *FakeShareClient) DeleteShare(_ context.Context, shareName string, opts *DeleteShareOptions) error {
 	f.mu.Lock()
 	defer f.mu.Unlock()
 
 	if err := f.DeleteErr[shareName]; err != nil {
 		return err
 	}
-	if f.Shares == nil {
+	if _, ok := f.Shares[shareName]; !ok {
 		return nil
 	}
+	if err := CheckProvenance(shareName, f.Options[shareName].Metadata, opts); err != nil {
+		return err
+	}
+	f.versions++
+	f.deleted = append(f.deleted, fakeDeletedShare{
+		DeletedShare: DeletedShare{
+			Name:                   shareName,
+			Version:                strconv.Itoa(f.versions),
+			DeletedTime:            time.Now(),
+			RemainingRetentionDays: 7,
+			Metadata:               lowerKeys(f.Options[shareName].Metadata),
+		},
+		quotaGiB:  f.Shares[shareName],
+		options:   f.Options[shareName],
+		snapshots: f.Snapshots[shareName],
+	})
 	delete(f.Shares, shareName)
+	delete(f.Options, shareName)
+	delete(f.Snapshots, shareName)
+	return nil
+}
+
+// GetShare returns the in-memory share properties.
+func (f *FakeShareClient) GetShare(_ context.Context, shareName string) (*ShareProperties, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	quota, ok := f.Shares[shareName]
+	if !ok {
+		return nil, fmt.Errorf("get share %q: %w", shareName, ErrShareNotFound)
+	}
+	opts := f.Options[shareName]
+	return &ShareProperties{Name: shareName, QuotaGiB: quota, AccessTier: opts.AccessTier, EnabledProtocol: opts.EnabledProtocol, Metadata: lowerKeys(opts.Metadata)}, nil
+}
+
+// SetShareQuota updates the in-memory share quota.
+func (f *FakeShareClient) SetShareQuota(_ context.Context, shareName string, quotaGiB int32) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if err := f.QuotaErr[shareName]; err != nil {
+		return err
+	}
+	if _, ok := f.Shares[shareName]; !ok {
+		return fmt.Errorf("set quota on share %q: %w", shareName, ErrShareNotFound)
+	}
+	if f.QuotaCount == nil {
+		f.QuotaCount = map[string]int{}
+	}
+	f.Shares[shareName] = quotaGiB
+	f.QuotaCount[shareName]++
+	return nil
+}
+
+// ListShares returns the in-memory shares sorted by name.
+func (f *FakeShareClient) ListShares(_ context.Context) ([]ShareProperties, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	names := make([]string, 0, len(f.Shares))
+	for name := range f.Shares {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	shares := make([]ShareProperties, 0, len(names))
+	for _, name := range names {
+		opts := f.Options[name]
+		shares = append(shares, ShareProperties{Name: name, QuotaGiB: f.Shares[name], AccessTier: opts.AccessTier, EnabledProtocol: opts.EnabledProtocol, Metadata: lowerKeys(opts.Metadata)})
+	}
+	return shares, nil
+}
+
+// SetShareMetadata replaces the in-memory share metadata.
+func (f *FakeShareClient) SetShareMetadata(_ context.Context, shareName string, metadata map[string]string) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if _, ok := f.Shares[shareName]; !ok {
+		return fmt.Errorf("set metadata on share %q: %w", shareName, ErrShareNotFound)
+	}
+	if f.Options == nil {
+		f.Options = map[string]EnsureShareOptions{}
+	}
+	opts := f.Options[shareName]
+	opts.Metadata = maps.Clone(metadata)
+	f.Options[shareName] = opts
+	return nil
+}
+
+// ListDeletedShares returns the in-memory soft-deleted shares.
+func (f *FakeShareClient) ListDeletedShares(_ context.Context) ([]DeletedShare, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	deleted := make([]DeletedShare, 0, len(f.deleted))
+	for _, share := range f.deleted {
+		deleted = append(deleted, share.DeletedShare)
+	}
+	return deleted, nil
+}
+
+// RestoreShare brings a soft-deleted share back with its quota, options, metadata and snapshots.
+func (f *FakeShareClient) RestoreShare(_ context.Context, shareName, version string) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if shareName == "" || version == "" {
+		return fmt.Errorf("share name and version required: %w", ErrInvalidShareInput)
+	}
+	for i, share := range f.deleted {
+		if share.Name != shareName || share.Version != version {
+			continue
+		}
+		if _, ok := f.Shares[shareName]; ok {
+			return fmt.Errorf("restore share %q: share already exists", shareName)
+		}
+		if f.Shares == nil {
+			f.Shares = map[string]int32{}
+		}
+		if f.Options == nil {
+			f.Options = map[string]EnsureShareOptions{}
+		}
+		f.Shares[shareName] = share.quotaGiB
+		f.Options[shareName] = share.options
+		if len(share.snapshots) > 0 {
+			if f.Snapshots == nil {
+				f.Snapshots = map[string][]string{}
+			}
+			f.Snapshots[shareName] = share.snapshots
+		}
+		f.deleted = append(f.deleted[:i], f.deleted[i+1:]...)
+		return nil
+	}
+	return fmt.Errorf("restore share %q version %q: %w", shareName, version, ErrShareNotFound)
+}
+
+// CreateSnapshot records a snapshot of the share in memory. IDs are unique per share like Azure's timestamps.
+func (f *FakeShareClient) CreateSnapshot(_ context.Context, shareName string) (string, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if _, ok := f.Shares[shareName]; !ok {
+		return "", fmt.Errorf("snapshot share %q: %w", shareName, ErrShareNotFound)
+	}
+	if f.Snapshots == nil {
+		f.Snapshots = map[string][]string{}
+	}
+	taken := time.Now().UTC()
+	if existing := f.Snapshots[shareName]; len(existing) > 0 {
+		if last, err := time.Parse(snapshotTimeFormat, existing[len(existing)-1]); err == nil && !taken.After(last) {
+			taken = last.Add(100 * time.Nanosecond)
+		}
+	}
+	id := taken.Format(snapshotTimeFormat)
+	f.Snapshots[shareName] = append(f.Snapshots[shareName], id)
+	if f.snapshotFiles == nil {
+		f.snapshotFiles = map[string][]string{}
+	}
+	f.snapshotFiles[shareName+"@"+id] = slices.Clone(f.Files[shareName])
+	return id, nil
+}
+
+// DeleteSnapshot removes a snapshot of the share in memory.
+func (f *FakeShareClient) DeleteSnapshot(_ context.Context, shareName, snapshot string) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if shareName == "" || snapshot == "" {
+		return fmt.Errorf("share name and snapshot required: %w", ErrInvalidShareInput)
+	}
+	if len(f.Snapshots[shareName]) == 0 {
+		return nil
+	}
+	f.Snapshots[shareName] = slices.DeleteFunc(f.Snapshots[shareName], func(id string) bool { return id == snapshot })
+	return nil
+}
+
+// CopyShare copies the in-memory file list of the share or snapshot. Copies started by one call are
+// reported complete by the next.
+func (f *FakeShareClient) CopyShare(_ context.Context, sourceShare, sourceSnapshot, targetShare string, opts *CopyShareOptions) (*CopyProgress, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if sourceShare == "" || targetShare == "" {
+		return nil, fmt.Errorf("source and target share names required: %w", ErrInvalidShareInput)
+	}
+	if err := f.CopyErr[targetShare]; err != nil {
+		return nil, err
+	}
+	if _, ok := f.Shares[sourceShare]; !ok {
+		return nil, fmt.Errorf("list share %q: %w", sourceShare, ErrShareNotFound)
+	}
+	if _, ok := f.Shares[targetShare]; !ok {
+		return nil, fmt.Errorf("copy into share %q: %w", targetShare, ErrShareNotFound)
+	}
+	files := f.Files[sourceShare]
+	if sourceSnapshot != "" {
+		snapshotFiles, ok := f.snapshotFiles[sourceShare+"@"+sourceSnapshot]
+		if !ok && !slices.Contains(f.Snapshots[sourceShare], sourceSnapshot) {
+			return nil, fmt.Errorf("list snapshot %q of share %q: %w", sourceSnapshot, sourceShare, ErrShareNotFound)
+		}
+		files = snapshotFiles
+	}
+	if f.Files == nil {
+		f.Files = map[string][]string{}
+	}
+	if f.copying == nil {
+		f.copying = map[string][]string{}
+	}
+
+	if opts == nil {
+		opts = &CopyShareOptions{}
+	}
+
+	progress := &CopyProgress{Files: len(files)}
+	checked, contiguous := 0, true
+	for i, name := range files {
+		if i < opts.Skip {
+			progress.Completed++
+			continue
+		}
+		if opts.MaxFiles > 0 && checked >= opts.MaxFiles {
+			contiguous = false
+			continue
+		}
+		checked++
+		copied := false
+		switch {
+		case slices.Contains(f.copying[targetShare], name):
+			f.copying[targetShare] = slices.DeleteFunc(f.copying[targetShare], func(copying string) bool { return copying == name })
+			f.Files[targetShare] = append(f.Files[targetShare], name)
+			copied = true
+		case slices.Contains(f.Files[targetShare], name):
+			copied = true
+		default:
+			f.copying[targetShare] = append(f.copying[targetShare], name)
+		}
+		if copied && contiguous {
+			progress.Completed++
+		} else {
+			contiguous = false
+		}
+	}
+	return progress, nil
+}
+
+// EnsureDirectory records the directory in memory.
+func (f *FakeShareClient) EnsureDirectory(_ context.Context, shareName, dir string) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if shareName == "" || dir == "" {
+		return fmt.Errorf("share and directory names required: %w", ErrInvalidShareInput)
+	}
+	if _, ok := f.Shares[shareName]; !ok {
+		return fmt.Errorf("create directory %q in share %q: %w", dir, shareName, ErrShareNotFound)
+	}
+	if f.Directories == nil {
+		f.Directories = map[string][]string{}
+	}
+	if !slices.Contains(f.Directories[shareName], dir) {
+		f.Directories[shareName] = append(f.Directories[shareName], dir)
+	}
+	return nil
+}
+
+// DeleteDirectory removes the directory and the files below it from memory.
+func (f *FakeShareClient) DeleteDirectory(_ context.Context, shareName, dir string) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if shareName == "" || dir == "" {
+		return fmt.Errorf("share and directory names required: %w", ErrInvalidShareInput)
+	}
+	if f.Directories != nil {
+		f.Directories[shareName] = slices.DeleteFunc(f.Directories[shareName], func(existing string) bool { return existing == dir })
+	}
+	if f.Files != nil {
+		f.Files[shareName] = slices.DeleteFunc(f.Files[shareName], func(name string) bool { return strings.HasPrefix(name, dir+"/") })
+	}
 	return nil
 }
+
+func lowerKeys(metadata map[string]string) map[string]string {
+	var lowered map[string]string
+	for key, value := range metadata {
+		if lowered == nil {
+			lowered = map[string]string{}
+		}
+		lowered[strings.ToLower(key)] = value
+	}
+	return lowered
+}
@@ -0,0 +1,173 @@
+package azure
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+const (
+	// Premium file shares get a baseline of 3000 IOPS plus 1 IOPS per provisioned GiB, capped at 100000.
+	premiumBaseIOPS = 3000
+	premiumMaxIOPS  = 100000
+)
+
+var ErrNoAccountCapacity = errors.New("no storage account with free capacity")
+
+// PoolAccount is a storage account eligible for placement together with its limits.
+// Zero limits are unlimited; an empty Region matches any topology.
+type PoolAccount struct {
+	Name           string
+	ResourceGroup  string
+	Region         string
+	MaxCapacityGiB int64
+	MaxShares      int
+	MaxIOPS        int64
+}
+
+// AccountUsage is the load already provisioned on a storage account.
+type AccountUsage struct {
+	CapacityGiB int64
+	Shares      int
+	IOPS        int64
+}
+
+// Add accounts for one more share of the given quota.
+func (u AccountUsage) Add(quotaGiB int64) AccountUsage {
+	return AccountUsage{
+		CapacityGiB: u.CapacityGiB + quotaGiB,
+		Shares:      u.Shares + 1,
+		IOPS:        u.IOPS + EstimateIOPS(quotaGiB),
+	}
+}
+
+// EstimateIOPS returns the baseline IOPS Azure provisions for a premium share of the given quota.
+// Standard shares are not provisioned per GiB, so IOPS limits are only meaningful for premium pools.
+func EstimateIOPS(quotaGiB int64) int64 {
+	iops := premiumBaseIOPS + quotaGiB
+	if iops > premiumMaxIOPS {
+		return premiumMaxIOPS
+	}
+	return iops
+}
+
+// Placer picks the least-loaded storage account from a pool.
+type Placer struct {
+	accounts []PoolAccount
+}
+
+// NewPlacer creates a Placer for the given pool.
+func NewPlacer(accounts []PoolAccount) *Placer {
+	return &Placer{accounts: append([]PoolAccount(nil), accounts...)}
+}
+
+// Accounts returns the pool accounts.
+func (p *Placer) Accounts() []PoolAccount {
+	return append([]PoolAccount(nil), p.accounts...)
+}
+
+// Select returns the eligible account with the lowest utilization after adding a share of quotaGiB.
+// Utilization is the highest ratio across the account's configured limits; unlimited accounts fall back
+// to absolute provisioned capacity. Ties are broken by share count and then by name, so the choice is
+// deterministic for the same usage.
+func (p *Placer) Select(usage map[string]AccountUsage, quotaGiB int32, region string) (PoolAccount, error) {
+	type candidate struct {
+		account PoolAccount
+		score   float64
+		usage   AccountUsage
+	}
+
+	var candidates []candidate
+	for _, account := range p.accounts {
+		if region != "" && account.Region != "" && !strings.EqualFold(account.Region, region) {
+			continue
+		}
+		next := usage[account.Name].Add(int64(quotaGiB))
+		if !account.fits(next) {
+			continue
+		}
+		candidates = append(candidates, candidate{account: account, score: account.utilization(next), usage: next})
+	}
+	if len(candidates) == 0 {
+		return PoolAccount{}, fmt.Errorf("place %d GiB in region %q: %w", quotaGiB, region, ErrNoAccountCapacity)
+	}
+
+	sort.SliceStable(candidates, func(i, j int) bool {
+		a, b := candidates[i], candidates[j]
+		if a.score != b.score {
+			return a.score < b.score
+		}
+		if a.usage.Shares != b.usage.Shares {
+			return a.usage.Shares < b.usage.Shares
+		}
+		return a.account.Name < b.account.Name
+	})
+	return candidates[0].account, nil
+}
+
+func (a PoolAccount) fits(usage AccountUsage) bool {
+	if a.MaxCapacityGiB > 0 && usage.CapacityGiB > a.MaxCapacityGiB {
+		return false
+	}
+	if a.MaxShares > 0 && usage.Shares > a.MaxShares {
+		return false
+	}
+	if a.MaxIOPS > 0 && usage.IOPS > a.MaxIOPS {
+		return false
+	}
+	return true
+}
+
+func (a PoolAccount) utilization(usage AccountUsage) float64 {
+	limited := false
+	score := 0.0
+	ratio := func(used, limit int64) {
+		if limit <= 0 {
+			return
+		}
+		limited = true
+		if r := float64(used) / float64(limit); r > score {
+			score = r
+		}
+	}
+	ratio(usage.CapacityGiB, a.MaxCapacityGiB)
+	ratio(int64(usage.Shares), int64(a.MaxShares))
+	ratio(usage.IOPS, a.MaxIOPS)
+	if !limited {
+		return float64(usage.CapacityGiB)
+	}
+	return score
+}
+
+// ParsePoolAccounts parses a comma-separated pool spec of "[resourceGroup/]account[@region]" entries.
+// Entries without a resource group use defaultResourceGroup.
+func ParsePoolAccounts(spec, defaultResourceGroup string) ([]PoolAccount, error) {
+	var accounts []PoolAccount
+	seen := map[string]bool{}
+	for _, raw := range strings.Split(spec, ",") {
+		entry := strings.TrimSpace(raw)
+		if entry == "" {
+			continue
+		}
+
+		account := PoolAccount{ResourceGroup: defaultResourceGroup}
+		if name, region, ok := strings.Cut(entry, "@"); ok {
+			entry, account.Region = name, strings.TrimSpace(region)
+		}
+		if rg, name, ok := strings.Cut(entry, "/"); ok {
+			account.ResourceGroup, entry = strings.TrimSpace(rg), name
+		}
+		account.Name = strings.TrimSpace(entry)
+
+		if account.Name == "" || account.ResourceGroup == "" {
+			return nil, fmt.Errorf("pool entry %q needs an account and resource group: %w", strings.TrimSpace(raw), ErrInvalidShareInput)
+		}
+		if seen[account.Name] {
+			return nil, fmt.Errorf("pool account %q listed twice: %w", account.Name, ErrInvalidShareInput)
+		}
+		seen[account.Name] = true
+		accounts = append(accounts, account)
+	}
+	return accounts, nil
+}
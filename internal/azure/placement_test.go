@@ -0,0 +1,96 @@
+package azure
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func TestPlacerSelectLeastLoaded(t *testing.T) {
+	placer := NewPlacer([]PoolAccount{
+		{Name: "acct1", ResourceGroup: "rg", MaxCapacityGiB: 1000},
+		{Name: "acct2", ResourceGroup: "rg", MaxCapacityGiB: 1000},
+	})
+
+	usage := map[string]AccountUsage{
+		"acct1": {CapacityGiB: 500, Shares: 5},
+		"acct2": {CapacityGiB: 100, Shares: 1},
+	}
+	got, err := placer.Select(usage, 10, "")
+	if err != nil {
+		t.Fatalf("Select error = %v", err)
+	}
+	if got.Name != "acct2" {
+		t.Fatalf("Select = %q, want acct2", got.Name)
+	}
+}
+
+func TestPlacerSelectDeterministicTieBreak(t *testing.T) {
+	placer := NewPlacer([]PoolAccount{
+		{Name: "bbb", ResourceGroup: "rg"},
+		{Name: "aaa", ResourceGroup: "rg"},
+	})
+
+	got, err := placer.Select(nil, 10, "")
+	if err != nil {
+		t.Fatalf("Select error = %v", err)
+	}
+	if got.Name != "aaa" {
+		t.Fatalf("Select = %q, want aaa", got.Name)
+	}
+}
+
+func TestPlacerSelectRespectsLimits(t *testing.T) {
+	placer := NewPlacer([]PoolAccount{
+		{Name: "full", ResourceGroup: "rg", MaxShares: 2},
+		{Name: "hot", ResourceGroup: "rg", MaxIOPS: 5000},
+		{Name: "far", ResourceGroup: "rg", Region: "eastus"},
+	})
+	usage := map[string]AccountUsage{
+		"full": {Shares: 2},
+		"hot":  {IOPS: 3000},
+	}
+
+	if _, err := placer.Select(usage, 100, "westeurope"); !errors.Is(err, ErrNoAccountCapacity) {
+		t.Fatalf("Select error = %v, want %v", err, ErrNoAccountCapacity)
+	}
+
+	got, err := placer.Select(usage, 100, "EastUS")
+	if err != nil {
+		t.Fatalf("Select error = %v", err)
+	}
+	if got.Name != "far" {
+		t.Fatalf("Select = %q, want far", got.Name)
+	}
+}
+
+func TestEstimateIOPS(t *testing.T) {
+	if got := EstimateIOPS(100); got != 3100 {
+		t.Fatalf("EstimateIOPS(100) = %d, want 3100", got)
+	}
+	if got := EstimateIOPS(200000); got != premiumMaxIOPS {
+		t.Fatalf("EstimateIOPS(200000) = %d, want %d", got, premiumMaxIOPS)
+	}
+}
+
+func TestParsePoolAccounts(t *testing.T) {
+	got, err := ParsePoolAccounts(" acct1, other-rg/acct2@westeurope ,acct3@eastus,", "rg")
+	if err != nil {
+		t.Fatalf("ParsePoolAccounts error = %v", err)
+	}
+	want := []PoolAccount{
+		{Name: "acct1", ResourceGroup: "rg"},
+		{Name: "acct2", ResourceGroup: "other-rg", Region: "westeurope"},
+		{Name: "acct3", ResourceGroup: "rg", Region: "eastus"},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("ParsePoolAccounts = %#v, want %#v", got, want)
+	}
+
+	if _, err := ParsePoolAccounts("acct1", ""); !errors.Is(err, ErrInvalidShareInput) {
+		t.Fatalf("ParsePoolAccounts error = %v, want %v", err, ErrInvalidShareInput)
+	}
+	if _, err := ParsePoolAccounts("acct1,rg/acct1", "rg"); !errors.Is(err, ErrInvalidShareInput) {
+		t.Fatalf("ParsePoolAccounts error = %v, want %v", err, ErrInvalidShareInput)
+	}
+}
@@ -0,0 +1,65 @@
+package azure
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+)
+
+var ErrUnknownAccount = errors.New("no share client for storage account")
+
+// ShareClientFactory builds a ShareClient for a storage account in a resource group.
+type ShareClientFactory func(resourceGroup, accountName string) (ShareClient, error)
+
+// Registry hands out ShareClients keyed by storage account name.
+// Clients are built lazily by the factory and cached for the lifetime of the process.
+type Registry struct {
+	mu      sync.Mutex
+	factory ShareClientFactory
+	clients map[string]ShareClient
+}
+
+// NewRegistry creates a Registry. A nil factory limits the registry to explicitly registered clients.
+func NewRegistry(factory ShareClientFactory) *Registry {
+	return &Registry{
+		factory: factory,
+		clients: map[string]ShareClient{},
+	}
+}
+
+// Register adds a pre-built client for an account, replacing any cached one.
+func (r *Registry) Register(accountName string, client ShareClient) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.clients[accountName] = client
+}
+
+// ForAccount returns the client for the account, building it on first use.
+// Clients are cached by account name, which is unique across Azure; the resource group is only passed to the factory.
+func (r *Registry) ForAccount(resourceGroup, accountName string) (ShareClient, error) {
+	if accountName == "" {
+		return nil, fmt.Errorf("account name required: %w", ErrInvalidShareInput)
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if client, ok := r.clients[accountName]; ok {
+		return client, nil
+	}
+	if r.factory == nil {
+		return nil, fmt.Errorf("account %q: %w", accountName, ErrUnknownAccount)
+	}
+
+	client, err := r.factory(resourceGroup, accountName)
+	if err != nil {
+		return nil, fmt.Errorf("build share client for account %q: %w", accountName, err)
+	}
+	r.clients[accountName] = client
+	return client, nil
+}
+
+// FileServiceHost returns the public Azure Files endpoint host for an account.
+func FileServiceHost(accountName string) string {
+	return fmt.Sprintf("%s.file.core.windows.net", accountName)
+}
@@ -0,0 +1,51 @@
+package azure
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestRegistryForAccount(t *testing.T) {
+	built := 0
+	registry := NewRegistry(func(resourceGroup, accountName string) (ShareClient, error) {
+		built++
+		return &FakeShareClient{}, nil
+	})
+
+	first, err := registry.ForAccount("rg", "acct")
+	if err != nil {
+		t.Fatalf("ForAccount error = %v", err)
+	}
+	second, err := registry.ForAccount("rg", "acct")
+	if err != nil {
+		t.Fatalf("ForAccount error = %v", err)
+	}
+	if first != second {
+		t.Fatalf("ForAccount returned different clients for the same account")
+	}
+	if built != 1 {
+		t.Fatalf("factory calls = %d, want 1", built)
+	}
+
+	if _, err := registry.ForAccount("rg", ""); !errors.Is(err, ErrInvalidShareInput) {
+		t.Fatalf("ForAccount(\"\") error = %v, want %v", err, ErrInvalidShareInput)
+	}
+}
+
+func TestRegistryWithoutFactory(t *testing.T) {
+	registry := NewRegistry(nil)
+	fake := &FakeShareClient{}
+	registry.Register("known", fake)
+
+	got, err := registry.ForAccount("rg", "known")
+	if err != nil {
+		t.Fatalf("ForAccount error = %v", err)
+	}
+	if got != fake {
+		t.Fatalf("ForAccount returned unexpected client")
+	}
+
+	if _, err := registry.ForAccount("rg", "unknown"); !errors.Is(err, ErrUnknownAccount) {
+		t.Fatalf("ForAccount error = %v, want %v", err, ErrUnknownAccount)
+	}
+}
@@ -2,10 +2,154 @@ package azure
 
 import (
 	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
 )
 
+// Share metadata keys recording who created and owns a share. Azure metadata names must be C#
+// identifiers and are returned lower-cased, so keys use lower-case snake case.
+const (
+	MetadataOwnerNamespace = "owner_namespace"
+	MetadataPVCUID         = "pvc_uid"
+	MetadataProvisionedBy  = "provisioned_by"
+	MetadataClusterID      = "cluster_id"
+	// MetadataDeleteAfter holds the RFC 3339 deadline after which a share released by its claim is deleted.
+	MetadataDeleteAfter = "delete_after"
+	// MetadataDeletionSnapshot holds the ID of the snapshot taken when the share's claim was deleted.
+	MetadataDeletionSnapshot = "deletion_snapshot"
+	// MetadataBackingShareClass marks the backing share of a subdirectory StorageClass with the class name.
+	MetadataBackingShareClass = "backing_share_class"
+)
+
+// snapshotTimeFormat is the layout of share snapshot IDs, e.g. 2024-05-01T10:00:00.0000000Z.
+const snapshotTimeFormat = "2006-01-02T15:04:05.0000000Z07:00"
+
+var ErrShareProvenanceMismatch = errors.New("share provenance does not match")
+var ErrShareCopyFailed = errors.New("share copy failed")
+
+// ShareProperties describes the current state of an Azure File share.
+// Metadata keys are lower-cased.
+type ShareProperties struct {
+	Name            string
+	QuotaGiB        int32
+	AccessTier      string
+	EnabledProtocol string
+	Metadata        map[string]string
+}
+
+// EnsureShareOptions carries optional share properties applied when a share is created.
+// Empty fields leave the Azure default in place.
+type EnsureShareOptions struct {
+	AccessTier      string
+	EnabledProtocol string
+	RootSquash      string
+	Metadata        map[string]string
+}
+
+// DeleteShareOptions guards share deletion. Shares are deleted together with their snapshots.
+// When RequireMetadata is set, the share is only deleted if its metadata carries every listed key
+// with the listed value; otherwise DeleteShare returns ErrShareProvenanceMismatch and leaves the
+// share in place.
+type DeleteShareOptions struct {
+	RequireMetadata map[string]string
+}
+
+// DeletedShare describes a soft-deleted share that the account still retains.
+// Version identifies the deletion when a share name was deleted more than once. Metadata keys are lower-cased.
+type DeletedShare struct {
+	Name                   string
+	Version                string
+	DeletedTime            time.Time
+	RemainingRetentionDays int32
+	Metadata               map[string]string
+}
+
+// CopyProgress counts the files of a share copy and how many of them, in listing order, have been copied
+// without a gap. A later pass can skip that many files.
+type CopyProgress struct {
+	Files     int
+	Completed int
+}
+
+// CopyShareOptions bounds the per-file requests of a CopyShare pass.
+// Skip is the Completed count of an earlier pass: those files are counted as copied without being checked.
+// MaxFiles caps the files checked or started after them; zero checks every file.
+type CopyShareOptions struct {
+	Skip     int
+	MaxFiles int
+}
+
+// Done reports whether every file has been copied.
+func (p CopyProgress) Done() bool {
+	return p.Completed == p.Files
+}
+
 // ShareClient manages Azure File shares.
+// CopyShare copies the files of a share, or of one of its snapshots, into another share of the same
+// account with server-side copies. It is incremental: every call creates missing directories, starts
+// the copies the target lacks and reports how many have completed, so callers repeat it until the
+// progress is Done, passing the previous progress in opts to only check the files after it. A failed or
+// aborted copy returns ErrShareCopyFailed. EnsureDirectory and
+// DeleteDirectory manage the top-level directories claims of subdirectory classes are given.
 type ShareClient interface {
-	EnsureShare(ctx context.Context, shareName string, quotaGiB int32) error
-	DeleteShare(ctx context.Context, shareName string) error
+	EnsureShare(ctx context.Context, shareName string, quotaGiB int32, opts *EnsureShareOptions) error
+	DeleteShare(ctx context.Context, shareName string, opts *DeleteShareOptions) error
+	GetShare(ctx context.Context, shareName string) (*ShareProperties, error)
+	SetShareQuota(ctx context.Context, shareName string, quotaGiB int32) error
+	ListShares(ctx context.Context) ([]ShareProperties, error)
+	SetShareMetadata(ctx context.Context, shareName string, metadata map[string]string) error
+	ListDeletedShares(ctx context.Context) ([]DeletedShare, error)
+	RestoreShare(ctx context.Context, shareName, version string) error
+	CreateSnapshot(ctx context.Context, shareName string) (string, error)
+	DeleteSnapshot(ctx context.Context, shareName, snapshot string) error
+	CopyShare(ctx context.Context, sourceShare, sourceSnapshot, targetShare string, opts *CopyShareOptions) (*CopyProgress, error)
+	EnsureDirectory(ctx context.Context, shareName, dir string) error
+	DeleteDirectory(ctx context.Context, shareName, dir string) error
+}
+
+// toMetadata converts metadata to the SDK's pointer form.
+func toMetadata(metadata map[string]string) map[string]*string {
+	if len(metadata) == 0 {
+		return nil
+	}
+	converted := make(map[string]*string, len(metadata))
+	for key, value := range metadata {
+		converted[key] = &value
+	}
+	return converted
+}
+
+// fromMetadata converts SDK metadata, lower-casing keys as Azure treats them case-insensitively.
+func fromMetadata(metadata map[string]*string) map[string]string {
+	if len(metadata) == 0 {
+		return nil
+	}
+	converted := make(map[string]string, len(metadata))
+	for key, value := range metadata {
+		if value != nil {
+			converted[strings.ToLower(key)] = *value
+		}
+	}
+	return converted
+}
+
+// CheckProvenance compares a share's metadata against the values required for its deletion.
+func CheckProvenance(shareName string, metadata map[string]string, opts *DeleteShareOptions) error {
+	if opts == nil {
+		return nil
+	}
+	var mismatched []string
+	for key, want := range opts.RequireMetadata {
+		if metadata[strings.ToLower(key)] != want {
+			mismatched = append(mismatched, key)
+		}
+	}
+	if len(mismatched) == 0 {
+		return nil
+	}
+	sort.Strings(mismatched)
+	return fmt.Errorf("share %q metadata %s: %w", shareName, strings.Join(mismatched, ", "), ErrShareProvenanceMismatch)
 }
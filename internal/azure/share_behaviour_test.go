@@ -0,0 +1,529 @@
+package azure
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore"
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore/arm"
+	azfake "github.com/Azure/azure-sdk-for-go/sdk/azcore/fake"
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore/policy"
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore/to"
+	"github.com/Azure/azure-sdk-for-go/sdk/resourcemanager/storage/armstorage"
+	"github.com/Azure/azure-sdk-for-go/sdk/resourcemanager/storage/armstorage/fake"
+	"github.com/Azure/azure-sdk-for-go/sdk/storage/azfile/share"
+)
+
+// testShareClientBehaviour is the contract every ShareClient implementation must satisfy.
+func testShareClientBehaviour(t *testing.T, client ShareClient) {
+	t.Helper()
+	ctx := context.Background()
+
+	if _, err := client.GetShare(ctx, "missing"); !errors.Is(err, ErrShareNotFound) {
+		t.Fatalf("GetShare missing error = %v, want %v", err, ErrShareNotFound)
+	}
+	if err := client.SetShareQuota(ctx, "missing", 10); !errors.Is(err, ErrShareNotFound) {
+		t.Fatalf("SetShareQuota missing error = %v, want %v", err, ErrShareNotFound)
+	}
+	if err := client.EnsureShare(ctx, "", 1, nil); !errors.Is(err, ErrInvalidShareInput) {
+		t.Fatalf("EnsureShare empty name error = %v, want %v", err, ErrInvalidShareInput)
+	}
+
+	opts := &EnsureShareOptions{AccessTier: "Cool", EnabledProtocol: "SMB", Metadata: map[string]string{MetadataOwnerNamespace: "team"}}
+	if err := client.EnsureShare(ctx, "share", 10, opts); err != nil {
+		t.Fatalf("EnsureShare error = %v", err)
+	}
+	props, err := client.GetShare(ctx, "share")
+	if err != nil {
+		t.Fatalf("GetShare error = %v", err)
+	}
+	want := ShareProperties{Name: "share", QuotaGiB: 10, AccessTier: "Cool", EnabledProtocol: "SMB", Metadata: map[string]string{MetadataOwnerNamespace: "team"}}
+	if !reflect.DeepEqual(*props, want) {
+		t.Fatalf("GetShare = %#v, want %#v", *props, want)
+	}
+
+	// Ensuring an existing share is a no-op; growth goes through SetShareQuota.
+	if err := client.EnsureShare(ctx, "share", 20, &EnsureShareOptions{AccessTier: "Hot"}); err != nil {
+		t.Fatalf("EnsureShare existing error = %v", err)
+	}
+	if props, _ := client.GetShare(ctx, "share"); props.QuotaGiB != 10 || props.AccessTier != "Cool" {
+		t.Fatalf("GetShare after re-ensure = %#v, want quota 10 and tier Cool", *props)
+	}
+	if err := client.SetShareQuota(ctx, "share", 20); err != nil {
+		t.Fatalf("SetShareQuota error = %v", err)
+	}
+	if props, _ := client.GetShare(ctx, "share"); props.QuotaGiB != 20 {
+		t.Fatalf("QuotaGiB = %d, want 20", props.QuotaGiB)
+	}
+
+	if err := client.SetShareMetadata(ctx, "missing", nil); !errors.Is(err, ErrShareNotFound) {
+		t.Fatalf("SetShareMetadata missing error = %v, want %v", err, ErrShareNotFound)
+	}
+	tagged := map[string]string{MetadataOwnerNamespace: "team", MetadataDeleteAfter: "2030-01-01T00:00:00Z"}
+	if err := client.SetShareMetadata(ctx, "share", tagged); err != nil {
+		t.Fatalf("SetShareMetadata error = %v", err)
+	}
+	listed, err := client.ListShares(ctx)
+	if err != nil {
+		t.Fatalf("ListShares error = %v", err)
+	}
+	if len(listed) != 1 || listed[0].Name != "share" || listed[0].QuotaGiB != 20 || !reflect.DeepEqual(listed[0].Metadata, tagged) {
+		t.Fatalf("ListShares = %#v, want the share with its metadata", listed)
+	}
+	if err := client.SetShareMetadata(ctx, "share", map[string]string{MetadataOwnerNamespace: "team"}); err != nil {
+		t.Fatalf("SetShareMetadata error = %v", err)
+	}
+	if props, _ := client.GetShare(ctx, "share"); props.Metadata[MetadataDeleteAfter] != "" {
+		t.Fatalf("Metadata = %v, want %s removed", props.Metadata, MetadataDeleteAfter)
+	}
+
+	// Snapshots do not keep the share from being deleted.
+	if _, err := client.CreateSnapshot(ctx, "missing"); !errors.Is(err, ErrShareNotFound) {
+		t.Fatalf("CreateSnapshot missing error = %v, want %v", err, ErrShareNotFound)
+	}
+	snapshot, err := client.CreateSnapshot(ctx, "share")
+	if err != nil {
+		t.Fatalf("CreateSnapshot error = %v", err)
+	}
+	if _, err := time.Parse(snapshotTimeFormat, snapshot); err != nil {
+		t.Fatalf("snapshot id %q: %v", snapshot, err)
+	}
+	for range 2 {
+		if err := client.DeleteSnapshot(ctx, "share", snapshot); err != nil {
+			t.Fatalf("DeleteSnapshot error = %v", err)
+		}
+	}
+	if _, err := client.GetShare(ctx, "share"); err != nil {
+		t.Fatalf("GetShare after DeleteSnapshot error = %v", err)
+	}
+	if _, err := client.CreateSnapshot(ctx, "share"); err != nil {
+		t.Fatalf("CreateSnapshot error = %v", err)
+	}
+
+	foreign := &DeleteShareOptions{RequireMetadata: map[string]string{MetadataOwnerNamespace: "other"}}
+	if err := client.DeleteShare(ctx, "share", foreign); !errors.Is(err, ErrShareProvenanceMismatch) {
+		t.Fatalf("DeleteShare foreign error = %v, want %v", err, ErrShareProvenanceMismatch)
+	}
+	if _, err := client.GetShare(ctx, "share"); err != nil {
+		t.Fatalf("GetShare after refused delete error = %v", err)
+	}
+
+	owned := &DeleteShareOptions{RequireMetadata: map[string]string{MetadataOwnerNamespace: "team"}}
+	if err := client.DeleteShare(ctx, "share", owned); err != nil {
+		t.Fatalf("DeleteShare error = %v", err)
+	}
+	if _, err := client.GetShare(ctx, "share"); !errors.Is(err, ErrShareNotFound) {
+		t.Fatalf("GetShare deleted error = %v, want %v", err, ErrShareNotFound)
+	}
+	if err := client.DeleteShare(ctx, "share", owned); err != nil {
+		t.Fatalf("DeleteShare missing error = %v", err)
+	}
+
+	// Soft-deleted shares are listed with their metadata and can be restored by version.
+	deleted, err := client.ListDeletedShares(ctx)
+	if err != nil {
+		t.Fatalf("ListDeletedShares error = %v", err)
+	}
+	if len(deleted) != 1 || deleted[0].Name != "share" || deleted[0].Version == "" || deleted[0].Metadata[MetadataOwnerNamespace] != "team" {
+		t.Fatalf("ListDeletedShares = %#v, want the deleted share with its metadata", deleted)
+	}
+	if err := client.RestoreShare(ctx, "share", "unknown"); !errors.Is(err, ErrShareNotFound) {
+		t.Fatalf("RestoreShare unknown version error = %v, want %v", err, ErrShareNotFound)
+	}
+	if err := client.RestoreShare(ctx, "share", deleted[0].Version); err != nil {
+		t.Fatalf("RestoreShare error = %v", err)
+	}
+	props, err = client.GetShare(ctx, "share")
+	if err != nil {
+		t.Fatalf("GetShare restored error = %v", err)
+	}
+	if props.QuotaGiB != 20 || props.Metadata[MetadataOwnerNamespace] != "team" {
+		t.Fatalf("GetShare restored = %#v, want quota 20 and the original metadata", *props)
+	}
+	if deleted, err := client.ListDeletedShares(ctx); err != nil || len(deleted) != 0 {
+		t.Fatalf("ListDeletedShares after restore = %#v, %v, want none", deleted, err)
+	}
+}
+
+func TestFakeShareClientBehaviour(t *testing.T) {
+	testShareClientBehaviour(t, &FakeShareClient{})
+}
+
+func TestClientBehaviour(t *testing.T) {
+	server := httptest.NewServer(newFileServiceStub())
+	defer server.Close()
+
+	client, err := NewClientWithEndpoint(server.URL, &azfake.TokenCredential{}, &share.ClientOptions{
+		ClientOptions: azcore.ClientOptions{
+			InsecureAllowCredentialWithHTTP: true,
+			Retry:                           policy.RetryOptions{MaxRetries: -1},
+		},
+	})
+	if err != nil {
+		t.Fatalf("NewClientWithEndpoint error = %v", err)
+	}
+	testShareClientBehaviour(t, client)
+}
+
+func TestARMShareClientBehaviour(t *testing.T) {
+	server := newFileSharesServerStub()
+	client, err := NewARMShareClient("sub", "rg", "acct", &azfake.TokenCredential{}, &arm.ClientOptions{
+		ClientOptions: azcore.ClientOptions{Transport: fake.NewFileSharesServerTransport(server)},
+	})
+	if err != nil {
+		t.Fatalf("NewARMShareClient error = %v", err)
+	}
+	testShareClientBehaviour(t, client)
+}
+
+// stubShare is the state kept by the service stubs.
+type stubShare struct {
+	quota     int32
+	tier      string
+	protocol  string
+	metadata  map[string]*string
+	version   string
+	snapshots []string
+}
+
+// newFileServiceStub emulates the share-level operations of the Azure Files REST API.
+func newFileServiceStub() http.Handler {
+	var mu sync.Mutex
+	shares := map[string]*stubShare{}
+	deleted := map[string]*stubShare{}
+	versions := 0
+
+	fail := func(w http.ResponseWriter, status int, code string) {
+		w.Header().Set("x-ms-error-code", code)
+		w.WriteHeader(status)
+	}
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+
+		name := strings.Trim(r.URL.Path, "/")
+		existing, ok := shares[name]
+		switch {
+		case r.Method == http.MethodGet && r.URL.Query().Get("comp") == "list":
+			var body strings.Builder
+			body.WriteString(`<?xml version="1.0" encoding="utf-8"?><EnumerationResults ServiceEndpoint="stub"><Shares>`)
+			for liveName, share := range shares {
+				body.WriteString("<Share><Name>" + liveName + "</Name><Properties><Quota>" + strconv.Itoa(int(share.quota)) + "</Quota></Properties><Metadata>")
+				for key, value := range share.metadata {
+					body.WriteString("<" + key + ">" + *value + "</" + key + ">")
+				}
+				body.WriteString("</Metadata></Share>")
+			}
+			listed := deleted
+			if !strings.Contains(r.URL.Query().Get("include"), "deleted") {
+				listed = nil
+			}
+			for deletedName, share := range listed {
+				body.WriteString("<Share><Name>" + deletedName + "</Name><Deleted>true</Deleted><Version>" + share.version + "</Version>")
+				body.WriteString("<Properties><Quota>" + strconv.Itoa(int(share.quota)) + "</Quota><RemainingRetentionDays>7</RemainingRetentionDays></Properties><Metadata>")
+				for key, value := range share.metadata {
+					body.WriteString("<" + key + ">" + *value + "</" + key + ">")
+				}
+				body.WriteString("</Metadata></Share>")
+			}
+			body.WriteString("</Shares><NextMarker /></EnumerationResults>")
+			w.Header().Set("Content-Type", "application/xml")
+			w.WriteHeader(http.StatusOK)
+			_, _ = w.Write([]byte(body.String()))
+		case r.Method == http.MethodPut && r.URL.Query().Get("comp") == "undelete":
+			// The SDK derives x-ms-deleted-share-name from the URL, which is path-style (and empty) for the stub's IP host.
+			restored, found := deleted[name]
+			if !found || restored.version != r.Header.Get("x-ms-deleted-share-version") {
+				fail(w, http.StatusNotFound, "ShareNotFound")
+				return
+			}
+			if ok {
+				fail(w, http.StatusConflict, "ShareAlreadyExists")
+				return
+			}
+			delete(deleted, name)
+			shares[name] = restored
+			w.WriteHeader(http.StatusCreated)
+		case r.Method == http.MethodPut && r.URL.Query().Get("comp") == "snapshot":
+			if !ok {
+				fail(w, http.StatusNotFound, "ShareNotFound")
+				return
+			}
+			snapshot := time.Now().UTC().Format(snapshotTimeFormat)
+			existing.snapshots = append(existing.snapshots, snapshot)
+			w.Header().Set("x-ms-snapshot", snapshot)
+			w.WriteHeader(http.StatusCreated)
+		case r.Method == http.MethodPut && r.URL.Query().Get("comp") == "metadata":
+			if !ok {
+				fail(w, http.StatusNotFound, "ShareNotFound")
+				return
+			}
+			existing.metadata = map[string]*string{}
+			for header, values := range r.Header {
+				if key, ok := strings.CutPrefix(strings.ToLower(header), "x-ms-meta-"); ok {
+					existing.metadata[key] = &values[0]
+				}
+			}
+			w.WriteHeader(http.StatusOK)
+		case r.Method == http.MethodPut && r.URL.Query().Get("comp") == "properties":
+			if !ok {
+				fail(w, http.StatusNotFound, "ShareNotFound")
+				return
+			}
+			if quota, err := strconv.Atoi(r.Header.Get("x-ms-share-quota")); err == nil {
+				existing.quota = int32(quota)
+			}
+			w.WriteHeader(http.StatusOK)
+		case r.Method == http.MethodPut:
+			if ok {
+				fail(w, http.StatusConflict, "ShareAlreadyExists")
+				return
+			}
+			quota, _ := strconv.Atoi(r.Header.Get("x-ms-share-quota"))
+			created := &stubShare{quota: int32(quota), tier: r.Header.Get("x-ms-access-tier"), protocol: r.Header.Get("x-ms-enabled-protocols"), metadata: map[string]*string{}}
+			for header, values := range r.Header {
+				if key, ok := strings.CutPrefix(strings.ToLower(header), "x-ms-meta-"); ok {
+					created.metadata[key] = &values[0]
+				}
+			}
+			shares[name] = created
+			w.WriteHeader(http.StatusCreated)
+		case r.Method == http.MethodGet || r.Method == http.MethodHead:
+			if !ok {
+				fail(w, http.StatusNotFound, "ShareNotFound")
+				return
+			}
+			w.Header().Set("x-ms-share-quota", strconv.Itoa(int(existing.quota)))
+			w.Header().Set("x-ms-access-tier", existing.tier)
+			w.Header().Set("x-ms-enabled-protocols", existing.protocol)
+			for key, value := range existing.metadata {
+				w.Header().Set("x-ms-meta-"+key, *value)
+			}
+			w.WriteHeader(http.StatusOK)
+		case r.Method == http.MethodDelete && r.URL.Query().Get("sharesnapshot") != "":
+			snapshot := r.URL.Query().Get("sharesnapshot")
+			if !ok || !slices.Contains(existing.snapshots, snapshot) {
+				fail(w, http.StatusNotFound, "ShareNotFound")
+				return
+			}
+			existing.snapshots = slices.DeleteFunc(existing.snapshots, func(id string) bool { return id == snapshot })
+			w.WriteHeader(http.StatusAccepted)
+		case r.Method == http.MethodDelete:
+			if !ok {
+				fail(w, http.StatusNotFound, "ShareNotFound")
+				return
+			}
+			if len(existing.snapshots) > 0 && r.Header.Get("x-ms-delete-snapshots") != "include" {
+				fail(w, http.StatusConflict, "ShareHasSnapshots")
+				return
+			}
+			versions++
+			existing.version = strconv.Itoa(versions)
+			deleted[name] = existing
+			delete(shares, name)
+			w.WriteHeader(http.StatusAccepted)
+		default:
+			w.WriteHeader(http.StatusMethodNotAllowed)
+		}
+	})
+}
+
+// newFileSharesServerStub backs the armstorage fake FileShares server with an in-memory map.
+func newFileSharesServerStub() *fake.FileSharesServer {
+	var mu sync.Mutex
+	shares := map[string]*stubShare{}
+	deleted := map[string]*stubShare{}
+	versions := 0
+
+	toFileShare := func(name string, s *stubShare) armstorage.FileShare {
+		return armstorage.FileShare{
+			Name: to.Ptr(name),
+			FileShareProperties: &armstorage.FileShareProperties{
+				ShareQuota:       to.Ptr(s.quota),
+				AccessTier:       to.Ptr(armstorage.ShareAccessTier(s.tier)),
+				EnabledProtocols: to.Ptr(armstorage.EnabledProtocols(s.protocol)),
+				Metadata:         s.metadata,
+			},
+		}
+	}
+
+	return &fake.FileSharesServer{
+		Create: func(_ context.Context, _, _, shareName string, fileShare armstorage.FileShare, options *armstorage.FileSharesClientCreateOptions) (resp azfake.Responder[armstorage.FileSharesClientCreateResponse], errResp azfake.ErrorResponder) {
+			mu.Lock()
+			defer mu.Unlock()
+			if options != nil && options.Expand != nil && strings.Contains(*options.Expand, "snapshots") {
+				existing, ok := shares[shareName]
+				if !ok {
+					errResp.SetResponseError(http.StatusNotFound, "ShareNotFound")
+					return
+				}
+				taken := time.Now().UTC()
+				existing.snapshots = append(existing.snapshots, taken.Format(snapshotTimeFormat))
+				snapshot := toFileShare(shareName, existing)
+				snapshot.FileShareProperties.SnapshotTime = to.Ptr(taken)
+				resp.SetResponse(http.StatusCreated, armstorage.FileSharesClientCreateResponse{FileShare: snapshot}, nil)
+				return
+			}
+			if _, ok := shares[shareName]; ok {
+				errResp.SetResponseError(http.StatusConflict, "ShareAlreadyExists")
+				return
+			}
+			created := &stubShare{}
+			if p := fileShare.FileShareProperties; p != nil {
+				if p.ShareQuota != nil {
+					created.quota = *p.ShareQuota
+				}
+				if p.AccessTier != nil {
+					created.tier = string(*p.AccessTier)
+				}
+				if p.EnabledProtocols != nil {
+					created.protocol = string(*p.EnabledProtocols)
+				}
+				created.metadata = p.Metadata
+			}
+			shares[shareName] = created
+			resp.SetResponse(http.StatusCreated, armstorage.FileSharesClientCreateResponse{FileShare: toFileShare(shareName, created)}, nil)
+			return
+		},
+		Get: func(_ context.Context, _, _, shareName string, _ *armstorage.FileSharesClientGetOptions) (resp azfake.Responder[armstorage.FileSharesClientGetResponse], errResp azfake.ErrorResponder) {
+			mu.Lock()
+			defer mu.Unlock()
+			existing, ok := shares[shareName]
+			if !ok {
+				errResp.SetResponseError(http.StatusNotFound, "ShareNotFound")
+				return
+			}
+			resp.SetResponse(http.StatusOK, armstorage.FileSharesClientGetResponse{FileShare: toFileShare(shareName, existing)}, nil)
+			return
+		},
+		Update: func(_ context.Context, _, _, shareName string, fileShare armstorage.FileShare, _ *armstorage.FileSharesClientUpdateOptions) (resp azfake.Responder[armstorage.FileSharesClientUpdateResponse], errResp azfake.ErrorResponder) {
+			mu.Lock()
+			defer mu.Unlock()
+			existing, ok := shares[shareName]
+			if !ok {
+				errResp.SetResponseError(http.StatusNotFound, "ShareNotFound")
+				return
+			}
+			if p := fileShare.FileShareProperties; p != nil && p.ShareQuota != nil {
+				existing.quota = *p.ShareQuota
+			}
+			if p := fileShare.FileShareProperties; p != nil && p.Metadata != nil {
+				existing.metadata = p.Metadata
+			}
+			resp.SetResponse(http.StatusOK, armstorage.FileSharesClientUpdateResponse{FileShare: toFileShare(shareName, existing)}, nil)
+			return
+		},
+		Delete: func(_ context.Context, _, _, shareName string, options *armstorage.FileSharesClientDeleteOptions) (resp azfake.Responder[armstorage.FileSharesClientDeleteResponse], errResp azfake.ErrorResponder) {
+			mu.Lock()
+			defer mu.Unlock()
+			existing, ok := shares[shareName]
+			if options != nil && options.XMSSnapshot != nil {
+				if !ok || !slices.Contains(existing.snapshots, *options.XMSSnapshot) {
+					errResp.SetResponseError(http.StatusNotFound, "ShareNotFound")
+					return
+				}
+				existing.snapshots = slices.DeleteFunc(existing.snapshots, func(id string) bool { return id == *options.XMSSnapshot })
+				resp.SetResponse(http.StatusOK, armstorage.FileSharesClientDeleteResponse{}, nil)
+				return
+			}
+			if !ok {
+				resp.SetResponse(http.StatusNoContent, armstorage.FileSharesClientDeleteResponse{}, nil)
+				return
+			}
+			versions++
+			existing.version = strconv.Itoa(versions)
+			deleted[shareName] = existing
+			delete(shares, shareName)
+			resp.SetResponse(http.StatusOK, armstorage.FileSharesClientDeleteResponse{}, nil)
+			return
+		},
+		NewListPager: func(_, _ string, options *armstorage.FileSharesClientListOptions) (resp azfake.PagerResponder[armstorage.FileSharesClientListResponse]) {
+			mu.Lock()
+			defer mu.Unlock()
+			var items []*armstorage.FileShareItem
+			for name, s := range shares {
+				items = append(items, &armstorage.FileShareItem{Name: to.Ptr(name), Properties: toFileShare(name, s).FileShareProperties})
+			}
+			if options != nil && options.Expand != nil && strings.Contains(*options.Expand, "deleted") {
+				for name, s := range deleted {
+					props := toFileShare(name, s).FileShareProperties
+					props.Deleted = to.Ptr(true)
+					props.Version = to.Ptr(s.version)
+					props.RemainingRetentionDays = to.Ptr[int32](7)
+					items = append(items, &armstorage.FileShareItem{Name: to.Ptr(name), Properties: props})
+				}
+			}
+			resp.AddPage(http.StatusOK, armstorage.FileSharesClientListResponse{FileShareItems: armstorage.FileShareItems{Value: items}}, nil)
+			return
+		},
+		Restore: func(_ context.Context, _, _, shareName string, deletedShare armstorage.DeletedShare, _ *armstorage.FileSharesClientRestoreOptions) (resp azfake.Responder[armstorage.FileSharesClientRestoreResponse], errResp azfake.ErrorResponder) {
+			mu.Lock()
+			defer mu.Unlock()
+			restored, ok := deleted[shareName]
+			if !ok || deletedShare.DeletedShareVersion == nil || restored.version != *deletedShare.DeletedShareVersion {
+				errResp.SetResponseError(http.StatusNotFound, "ShareNotFound")
+				return
+			}
+			if _, ok := shares[shareName]; ok {
+				errResp.SetResponseError(http.StatusConflict, "ShareAlreadyExists")
+				return
+			}
+			delete(deleted, shareName)
+			shares[shareName] = restored
+			resp.SetResponse(http.StatusOK, armstorage.FileSharesClientRestoreResponse{}, nil)
+			return
+		},
+	}
+}
+
+func TestEnsureShareBeingDeleted(t *testing.T) {
+	ctx := context.Background()
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.Header().Set("x-ms-error-code", "ShareBeingDeleted")
+		w.WriteHeader(http.StatusConflict)
+	}))
+	defer server.Close()
+	client, err := NewClientWithEndpoint(server.URL, &azfake.TokenCredential{}, &share.ClientOptions{
+		ClientOptions: azcore.ClientOptions{
+			InsecureAllowCredentialWithHTTP: true,
+			Retry:                           policy.RetryOptions{MaxRetries: -1},
+		},
+	})
+	if err != nil {
+		t.Fatalf("NewClientWithEndpoint error = %v", err)
+	}
+	if err := client.EnsureShare(ctx, "share", 1, nil); !errors.Is(err, ErrShareBeingDeleted) {
+		t.Fatalf("EnsureShare error = %v, want %v", err, ErrShareBeingDeleted)
+	}
+
+	armServer := &fake.FileSharesServer{
+		Get: func(context.Context, string, string, string, *armstorage.FileSharesClientGetOptions) (resp azfake.Responder[armstorage.FileSharesClientGetResponse], errResp azfake.ErrorResponder) {
+			errResp.SetResponseError(http.StatusNotFound, "ShareNotFound")
+			return
+		},
+		Create: func(context.Context, string, string, string, armstorage.FileShare, *armstorage.FileSharesClientCreateOptions) (resp azfake.Responder[armstorage.FileSharesClientCreateResponse], errResp azfake.ErrorResponder) {
+			errResp.SetResponseError(http.StatusConflict, "ShareBeingDeleted")
+			return
+		},
+	}
+	armClient, err := NewARMShareClient("sub", "rg", "acct", &azfake.TokenCredential{}, &arm.ClientOptions{
+		ClientOptions: azcore.ClientOptions{Transport: fake.NewFileSharesServerTransport(armServer)},
+	})
+	if err != nil {
+		t.Fatalf("NewARMShareClient error = %v", err)
+	}
+	if err := armClient.EnsureShare(ctx, "share", 1, nil); !errors.Is(err, ErrShareBeingDeleted) {
+		t.Fatalf("ARM EnsureShare error = %v, want %v", err, ErrShareBeingDeleted)
+	}
+}
@@ -10,7 +10,7 @@ func TestFakeShareClientEnsureDelete(t *testing.T) {
 	client := &FakeShareClient{}
 	ctx := context.Background()
 
-	if err := client.EnsureShare(ctx, "share", 10); err != nil {
+	if err := client.EnsureShare(ctx, "share", 10, nil); err != nil {
 		t.Fatalf("EnsureShare error = %v", err)
 	}
 
@@ -18,7 +18,7 @@ func TestFakeShareClientEnsureDelete(t *testing.T) {
 		t.Fatalf("Share quota = %d, want 10", client.Shares["share"])
 	}
 
-	if err := client.DeleteShare(ctx, "share"); err != nil {
+	if err := client.DeleteShare(ctx, "share", nil); err != nil {
 		t.Fatalf("DeleteShare error = %v", err)
 	}
 	if _, ok := client.Shares["share"]; ok {
@@ -36,11 +36,49 @@ func TestFakeShareClientErrors(t *testing.T) {
 	}
 	ctx := context.Background()
 
-	if err := client.EnsureShare(ctx, "share", 1); !errors.Is(err, ensureErr) {
+	if err := client.EnsureShare(ctx, "share", 1, nil); !errors.Is(err, ensureErr) {
 		t.Fatalf("EnsureShare error = %v, want %v", err, ensureErr)
 	}
 
-	if err := client.DeleteShare(ctx, "share"); !errors.Is(err, deleteErr) {
+	if err := client.DeleteShare(ctx, "share", nil); !errors.Is(err, deleteErr) {
 		t.Fatalf("DeleteShare error = %v, want %v", err, deleteErr)
 	}
 }
+
+func TestFakeShareClientQuota(t *testing.T) {
+	client := &FakeShareClient{}
+	ctx := context.Background()
+
+	if err := client.SetShareQuota(ctx, "share", 5); !errors.Is(err, ErrShareNotFound) {
+		t.Fatalf("SetShareQuota error = %v, want %v", err, ErrShareNotFound)
+	}
+
+	if err := client.EnsureShare(ctx, "share", 1, nil); err != nil {
+		t.Fatalf("EnsureShare error = %v", err)
+	}
+	if err := client.EnsureShare(ctx, "share", 3, nil); err != nil {
+		t.Fatalf("EnsureShare error = %v", err)
+	}
+	props, err := client.GetShare(ctx, "share")
+	if err != nil {
+		t.Fatalf("GetShare error = %v", err)
+	}
+	if props.QuotaGiB != 1 {
+		t.Fatalf("QuotaGiB = %d, want 1 (existing share keeps quota)", props.QuotaGiB)
+	}
+
+	if err := client.SetShareQuota(ctx, "share", 5); err != nil {
+		t.Fatalf("SetShareQuota error = %v", err)
+	}
+	props, err = client.GetShare(ctx, "share")
+	if err != nil {
+		t.Fatalf("GetShare error = %v", err)
+	}
+	if props.QuotaGiB != 5 {
+		t.Fatalf("QuotaGiB = %d, want 5", props.QuotaGiB)
+	}
+
+	if _, err := client.GetShare(ctx, "missing"); !errors.Is(err, ErrShareNotFound) {
+		t.Fatalf("GetShare error = %v, want %v", err, ErrShareNotFound)
+	}
+}
@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"os"
 	"strconv"
+	"time"
 )
 
 const (
@@ -11,6 +12,28 @@ const (
 	defaultMetricsAddr      = ":8080"
 	defaultHealthAddr       = ":8081"
 	defaultAuthMode         = "workload"
+
+	defaultNodeSecretRotation  = time.Hour
+	defaultSoftDeleteScanEvery = time.Hour
+	defaultDeletionScanEvery   = 10 * time.Minute
+
+	defaultWebhookPort        = 9443
+	defaultWebhookCertDir     = "/tmp/k8s-webhook-server/serving-certs"
+	defaultWebhookServiceName = "azurefile-provisioner-webhook"
+	defaultWebhookConfigName  = "azurefile-provisioner"
+)
+
+// Share API values select the ShareClient implementation.
+const (
+	ShareAPIDataPlane = "dataplane"
+	ShareAPIARM       = "arm"
+)
+
+// PV mismatch policy values select how PVs that do not match their claim are handled.
+const (
+	PVMismatchHalt     = "halt"
+	PVMismatchRecreate = "recreate"
+	PVMismatchAdopt    = "adopt"
 )
 
 // Config holds runtime configuration loaded from the environment.
@@ -26,6 +49,32 @@ type Config struct {
 	AuthMode              string
 	TenantID              string
 	ClientID              string
+	StorageAccountPool    string
+	PoolMaxCapacityGiB    int64
+	PoolMaxShares         int64
+	PoolMaxIOPS           int64
+	Location              string
+	AccountSku            string
+	AccountKind           string
+	AccountAutoCreate     bool
+	ShareAPI              string
+	NodeSecretsEnabled    bool
+	NodeSecretNamespace   string
+	NodeSecretRotation    time.Duration
+	SoftDeleteMetrics     bool
+	SoftDeleteScanEvery   time.Duration
+	ClusterID             string
+	PVMismatchPolicy      string
+	DeletionGracePeriod   time.Duration
+	DeletionScanEvery     time.Duration
+	VolumeSnapshots       bool
+	WebhookEnabled        bool
+	WebhookPort           int
+	WebhookCertDir        string
+	WebhookServiceName    string
+	WebhookConfigName     string
+	PodNamespace          string
+	ServiceAccountName    string
 }
 
 // Load reads configuration from environment variables.
@@ -35,18 +84,138 @@ func Load() (Config, error) {
 		return Config{}, fmt.Errorf("read leader election flag: %w", err)
 	}
 
+	accountAutoCreate, err := readBoolEnv("AZURE_STORAGE_ACCOUNT_AUTO_CREATE", false)
+	if err != nil {
+		return Config{}, fmt.Errorf("read account auto-create flag: %w", err)
+	}
+	subscriptionID := readEnv("AZURE_SUBSCRIPTION_ID", "")
+	location := readEnv("AZURE_LOCATION", "")
+	if accountAutoCreate && (location == "" || subscriptionID == "") {
+		return Config{}, fmt.Errorf("AZURE_STORAGE_ACCOUNT_AUTO_CREATE requires AZURE_SUBSCRIPTION_ID and AZURE_LOCATION")
+	}
+
+	shareAPI := readEnv("AZURE_SHARE_API", ShareAPIDataPlane)
+	switch shareAPI {
+	case ShareAPIDataPlane:
+	case ShareAPIARM:
+		if subscriptionID == "" || readEnv("AZURE_RESOURCE_GROUP", "") == "" {
+			return Config{}, fmt.Errorf("AZURE_SHARE_API=%s requires AZURE_SUBSCRIPTION_ID and AZURE_RESOURCE_GROUP", ShareAPIARM)
+		}
+	default:
+		return Config{}, fmt.Errorf("AZURE_SHARE_API %q not supported (supported: %s, %s)", shareAPI, ShareAPIDataPlane, ShareAPIARM)
+	}
+
+	pvMismatchPolicy := readEnv("PV_MISMATCH_POLICY", PVMismatchHalt)
+	switch pvMismatchPolicy {
+	case PVMismatchHalt, PVMismatchRecreate, PVMismatchAdopt:
+	default:
+		return Config{}, fmt.Errorf("PV_MISMATCH_POLICY %q not supported (supported: %s, %s, %s)", pvMismatchPolicy, PVMismatchHalt, PVMismatchRecreate, PVMismatchAdopt)
+	}
+
+	nodeSecrets, err := readBoolEnv("AZURE_NODE_SECRETS_ENABLED", false)
+	if err != nil {
+		return Config{}, fmt.Errorf("read node secrets flag: %w", err)
+	}
+	if nodeSecrets && subscriptionID == "" {
+		return Config{}, fmt.Errorf("AZURE_NODE_SECRETS_ENABLED requires AZURE_SUBSCRIPTION_ID")
+	}
+	nodeSecretRotation, err := readDurationEnv("AZURE_NODE_SECRET_ROTATION_INTERVAL", defaultNodeSecretRotation)
+	if err != nil {
+		return Config{}, fmt.Errorf("read node secret rotation interval: %w", err)
+	}
+
+	softDeleteMetrics, err := readBoolEnv("AZURE_SOFT_DELETE_METRICS_ENABLED", true)
+	if err != nil {
+		return Config{}, fmt.Errorf("read soft delete metrics flag: %w", err)
+	}
+	softDeleteScanEvery, err := readDurationEnv("AZURE_SOFT_DELETE_SCAN_INTERVAL", defaultSoftDeleteScanEvery)
+	if err != nil {
+		return Config{}, fmt.Errorf("read soft delete scan interval: %w", err)
+	}
+
+	deletionGracePeriod, err := readNonNegativeDurationEnv("SHARE_DELETION_GRACE_PERIOD", 0)
+	if err != nil {
+		return Config{}, fmt.Errorf("read share deletion grace period: %w", err)
+	}
+	deletionScanEvery, err := readDurationEnv("SHARE_DELETION_SCAN_INTERVAL", defaultDeletionScanEvery)
+	if err != nil {
+		return Config{}, fmt.Errorf("read share deletion scan interval: %w", err)
+	}
+
+	volumeSnapshots, err := readBoolEnv("VOLUME_SNAPSHOTS_ENABLED", false)
+	if err != nil {
+		return Config{}, fmt.Errorf("read volume snapshots flag: %w", err)
+	}
+
+	webhookEnabled, err := readBoolEnv("WEBHOOK_ENABLED", false)
+	if err != nil {
+		return Config{}, fmt.Errorf("read webhook flag: %w", err)
+	}
+	webhookPort, err := readIntEnv("WEBHOOK_PORT", defaultWebhookPort)
+	if err != nil {
+		return Config{}, fmt.Errorf("read webhook port: %w", err)
+	}
+	podNamespace := readEnv("POD_NAMESPACE", "")
+	serviceAccount := readEnv("POD_SERVICE_ACCOUNT", "")
+	if webhookEnabled && (podNamespace == "" || serviceAccount == "") {
+		return Config{}, fmt.Errorf("WEBHOOK_ENABLED requires POD_NAMESPACE and POD_SERVICE_ACCOUNT")
+	}
+	nodeSecretNamespace := readEnv("AZURE_NODE_SECRET_NAMESPACE", podNamespace)
+	if nodeSecrets && nodeSecretNamespace == "" {
+		return Config{}, fmt.Errorf("AZURE_NODE_SECRETS_ENABLED requires AZURE_NODE_SECRET_NAMESPACE or POD_NAMESPACE")
+	}
+
+	poolMaxCapacity, err := readIntEnv("AZURE_POOL_MAX_CAPACITY_GIB", 0)
+	if err != nil {
+		return Config{}, fmt.Errorf("read pool capacity limit: %w", err)
+	}
+	poolMaxShares, err := readIntEnv("AZURE_POOL_MAX_SHARES", 0)
+	if err != nil {
+		return Config{}, fmt.Errorf("read pool share limit: %w", err)
+	}
+	poolMaxIOPS, err := readIntEnv("AZURE_POOL_MAX_IOPS", 0)
+	if err != nil {
+		return Config{}, fmt.Errorf("read pool iops limit: %w", err)
+	}
+
 	return Config{
 		LeaderElectionEnabled: leaderElection,
 		LeaderElectionID:      readEnv("LEADER_ELECTION_ID", defaultLeaderElectionID),
 		MetricsAddr:           readEnv("METRICS_ADDR", defaultMetricsAddr),
 		HealthAddr:            readEnv("HEALTH_ADDR", defaultHealthAddr),
-		SubscriptionID:        readEnv("AZURE_SUBSCRIPTION_ID", ""),
+		SubscriptionID:        subscriptionID,
 		ResourceGroup:         readEnv("AZURE_RESOURCE_GROUP", ""),
 		StorageAccount:        readEnv("AZURE_STORAGE_ACCOUNT", ""),
 		Server:                readEnv("AZURE_FILE_SERVER", ""),
 		AuthMode:              readEnv("AZURE_AUTH_MODE", defaultAuthMode),
 		TenantID:              readEnv("AZURE_TENANT_ID", ""),
 		ClientID:              readEnv("AZURE_CLIENT_ID", ""),
+		StorageAccountPool:    readEnv("AZURE_STORAGE_ACCOUNT_POOL", ""),
+		PoolMaxCapacityGiB:    poolMaxCapacity,
+		PoolMaxShares:         poolMaxShares,
+		PoolMaxIOPS:           poolMaxIOPS,
+		Location:              location,
+		AccountSku:            readEnv("AZURE_STORAGE_ACCOUNT_SKU", ""),
+		AccountKind:           readEnv("AZURE_STORAGE_ACCOUNT_KIND", ""),
+		AccountAutoCreate:     accountAutoCreate,
+		ShareAPI:              shareAPI,
+		NodeSecretsEnabled:    nodeSecrets,
+		NodeSecretNamespace:   nodeSecretNamespace,
+		NodeSecretRotation:    nodeSecretRotation,
+		SoftDeleteMetrics:     softDeleteMetrics,
+		SoftDeleteScanEvery:   softDeleteScanEvery,
+		ClusterID:             readEnv("CLUSTER_ID", ""),
+		PVMismatchPolicy:      pvMismatchPolicy,
+		DeletionGracePeriod:   deletionGracePeriod,
+		DeletionScanEvery:     deletionScanEvery,
+		VolumeSnapshots:       volumeSnapshots,
+		WebhookEnabled:        webhookEnabled,
+		WebhookPort:           int(webhookPort),
+		WebhookCertDir:        readEnv("WEBHOOK_CERT_DIR", defaultWebhookCertDir),
+		WebhookServiceName:    readEnv("WEBHOOK_SERVICE_NAME", defaultWebhookServiceName),
+		WebhookConfigName:     readEnv("WEBHOOK_CONFIGURATION_NAME", defaultWebhookConfigName),
+		PodNamespace:          podNamespace,
+		ServiceAccountName:    serviceAccount,
 	}, nil
 }
 
@@ -69,3 +238,49 @@ func readBoolEnv(key string, fallback bool) (bool, error) {
 	}
 	return parsed, nil
 }
+
+func readIntEnv(key string, fallback int64) (int64, error) {
+	value := os.Getenv(key)
+	if value == "" {
+		return fallback, nil
+	}
+	parsed, err := strconv.ParseInt(value, 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("parse %s: %w", key, err)
+	}
+	if parsed < 0 {
+		return 0, fmt.Errorf("parse %s: must not be negative", key)
+	}
+	return parsed, nil
+}
+
+func readDurationEnv(key string, fallback time.Duration) (time.Duration, error) {
+	value := os.Getenv(key)
+	if value == "" {
+		return fallback, nil
+	}
+	parsed, err := time.ParseDuration(value)
+	if err != nil {
+		return 0, fmt.Errorf("parse %s: %w", key, err)
+	}
+	if parsed <= 0 {
+		return 0, fmt.Errorf("parse %s: must be positive", key)
+	}
+	return parsed, nil
+}
+
+// readNonNegativeDurationEnv reads a duration where zero switches the feature off.
+func readNonNegativeDurationEnv(key string, fallback time.Duration) (time.Duration, error) {
+	value := os.Getenv(key)
+	if value == "" {
+		return fallback, nil
+	}
+	parsed, err := time.ParseDuration(value)
+	if err != nil {
+		return 0, fmt.Errorf("parse %s: %w", key, err)
+	}
+	if parsed < 0 {
+		return 0, fmt.Errorf("parse %s: must not be negative", key)
+	}
+	return parsed, nil
+}
@@ -1,6 +1,9 @@
 package config
 
-import "testing"
+import (
+	"testing"
+	"time"
+)
 
 func TestLoadDefaults(t *testing.T) {
 	cfg, err := Load()
@@ -32,6 +35,27 @@ func TestLoadDefaults(t *testing.T) {
 	if cfg.Server != "" {
 		t.Fatalf("Server = %q, want empty", cfg.Server)
 	}
+	if cfg.ShareAPI != ShareAPIDataPlane {
+		t.Fatalf("ShareAPI = %q, want %q", cfg.ShareAPI, ShareAPIDataPlane)
+	}
+	if cfg.PVMismatchPolicy != PVMismatchHalt {
+		t.Fatalf("PVMismatchPolicy = %q, want %q", cfg.PVMismatchPolicy, PVMismatchHalt)
+	}
+	if cfg.NodeSecretsEnabled || cfg.NodeSecretRotation != defaultNodeSecretRotation {
+		t.Fatalf("node secrets = %v/%s, want disabled/%s", cfg.NodeSecretsEnabled, cfg.NodeSecretRotation, defaultNodeSecretRotation)
+	}
+	if cfg.DeletionGracePeriod != 0 || cfg.DeletionScanEvery != defaultDeletionScanEvery {
+		t.Fatalf("deletion = %s/%s, want immediate/%s", cfg.DeletionGracePeriod, cfg.DeletionScanEvery, defaultDeletionScanEvery)
+	}
+	if !cfg.SoftDeleteMetrics || cfg.SoftDeleteScanEvery != defaultSoftDeleteScanEvery {
+		t.Fatalf("soft delete metrics = %v/%s, want enabled/%s", cfg.SoftDeleteMetrics, cfg.SoftDeleteScanEvery, defaultSoftDeleteScanEvery)
+	}
+	if cfg.VolumeSnapshots {
+		t.Fatalf("VolumeSnapshots = true, want false")
+	}
+	if cfg.WebhookEnabled || cfg.WebhookPort != defaultWebhookPort || cfg.WebhookCertDir != defaultWebhookCertDir {
+		t.Fatalf("webhook = %v/%d/%q, want disabled/%d/%q", cfg.WebhookEnabled, cfg.WebhookPort, cfg.WebhookCertDir, defaultWebhookPort, defaultWebhookCertDir)
+	}
 }
 
 func TestLoadOverrides(t *testing.T) {
@@ -43,6 +67,30 @@ func TestLoadOverrides(t *testing.T) {
 	t.Setenv("AZURE_RESOURCE_GROUP", "rg")
 	t.Setenv("AZURE_STORAGE_ACCOUNT", "acct")
 	t.Setenv("AZURE_FILE_SERVER", "server")
+	t.Setenv("AZURE_STORAGE_ACCOUNT_POOL", "acct1,acct2")
+	t.Setenv("AZURE_POOL_MAX_CAPACITY_GIB", "1024")
+	t.Setenv("AZURE_POOL_MAX_SHARES", "50")
+	t.Setenv("AZURE_POOL_MAX_IOPS", "20000")
+	t.Setenv("AZURE_LOCATION", "westeurope")
+	t.Setenv("AZURE_STORAGE_ACCOUNT_SKU", "Premium_LRS")
+	t.Setenv("AZURE_STORAGE_ACCOUNT_KIND", "FileStorage")
+	t.Setenv("AZURE_STORAGE_ACCOUNT_AUTO_CREATE", "true")
+	t.Setenv("AZURE_SHARE_API", "arm")
+	t.Setenv("AZURE_NODE_SECRETS_ENABLED", "true")
+	t.Setenv("AZURE_NODE_SECRET_NAMESPACE", "azurefile-secrets")
+	t.Setenv("AZURE_NODE_SECRET_ROTATION_INTERVAL", "15m")
+	t.Setenv("AZURE_SOFT_DELETE_METRICS_ENABLED", "false")
+	t.Setenv("AZURE_SOFT_DELETE_SCAN_INTERVAL", "30m")
+	t.Setenv("CLUSTER_ID", "aks-prod")
+	t.Setenv("PV_MISMATCH_POLICY", "adopt")
+	t.Setenv("SHARE_DELETION_GRACE_PERIOD", "72h")
+	t.Setenv("SHARE_DELETION_SCAN_INTERVAL", "5m")
+	t.Setenv("VOLUME_SNAPSHOTS_ENABLED", "true")
+	t.Setenv("WEBHOOK_ENABLED", "true")
+	t.Setenv("WEBHOOK_PORT", "10250")
+	t.Setenv("WEBHOOK_SERVICE_NAME", "files-webhook")
+	t.Setenv("POD_NAMESPACE", "storage-system")
+	t.Setenv("POD_SERVICE_ACCOUNT", "provisioner")
 
 	cfg, err := Load()
 	if err != nil {
@@ -73,6 +121,42 @@ func TestLoadOverrides(t *testing.T) {
 	if cfg.Server != "server" {
 		t.Fatalf("Server = %q, want %q", cfg.Server, "server")
 	}
+	if cfg.StorageAccountPool != "acct1,acct2" {
+		t.Fatalf("StorageAccountPool = %q, want %q", cfg.StorageAccountPool, "acct1,acct2")
+	}
+	if cfg.PoolMaxCapacityGiB != 1024 || cfg.PoolMaxShares != 50 || cfg.PoolMaxIOPS != 20000 {
+		t.Fatalf("pool limits = %d/%d/%d, want 1024/50/20000", cfg.PoolMaxCapacityGiB, cfg.PoolMaxShares, cfg.PoolMaxIOPS)
+	}
+	if cfg.Location != "westeurope" || cfg.AccountSku != "Premium_LRS" || cfg.AccountKind != "FileStorage" || !cfg.AccountAutoCreate {
+		t.Fatalf("account settings = %q/%q/%q/%v, want westeurope/Premium_LRS/FileStorage/true", cfg.Location, cfg.AccountSku, cfg.AccountKind, cfg.AccountAutoCreate)
+	}
+	if cfg.ShareAPI != ShareAPIARM {
+		t.Fatalf("ShareAPI = %q, want %q", cfg.ShareAPI, ShareAPIARM)
+	}
+	if !cfg.NodeSecretsEnabled || cfg.NodeSecretNamespace != "azurefile-secrets" || cfg.NodeSecretRotation != 15*time.Minute {
+		t.Fatalf("node secrets = %v/%q/%s, want true/azurefile-secrets/15m", cfg.NodeSecretsEnabled, cfg.NodeSecretNamespace, cfg.NodeSecretRotation)
+	}
+	if cfg.SoftDeleteMetrics || cfg.SoftDeleteScanEvery != 30*time.Minute {
+		t.Fatalf("soft delete metrics = %v/%s, want disabled/30m", cfg.SoftDeleteMetrics, cfg.SoftDeleteScanEvery)
+	}
+	if cfg.DeletionGracePeriod != 72*time.Hour || cfg.DeletionScanEvery != 5*time.Minute {
+		t.Fatalf("deletion = %s/%s, want 72h/5m", cfg.DeletionGracePeriod, cfg.DeletionScanEvery)
+	}
+	if !cfg.VolumeSnapshots {
+		t.Fatalf("VolumeSnapshots = false, want true")
+	}
+	if cfg.ClusterID != "aks-prod" {
+		t.Fatalf("ClusterID = %q, want %q", cfg.ClusterID, "aks-prod")
+	}
+	if cfg.PVMismatchPolicy != PVMismatchAdopt {
+		t.Fatalf("PVMismatchPolicy = %q, want %q", cfg.PVMismatchPolicy, PVMismatchAdopt)
+	}
+	if !cfg.WebhookEnabled || cfg.WebhookPort != 10250 || cfg.WebhookServiceName != "files-webhook" {
+		t.Fatalf("webhook = %v/%d/%q, want true/10250/files-webhook", cfg.WebhookEnabled, cfg.WebhookPort, cfg.WebhookServiceName)
+	}
+	if cfg.PodNamespace != "storage-system" || cfg.ServiceAccountName != "provisioner" {
+		t.Fatalf("pod identity = %q/%q, want storage-system/provisioner", cfg.PodNamespace, cfg.ServiceAccountName)
+	}
 }
 
 func TestLoadInvalidBool(t *testing.T) {
@@ -83,3 +167,86 @@ func TestLoadInvalidBool(t *testing.T) {
 		t.Fatalf("Load() error = nil, want error")
 	}
 }
+
+func TestLoadInvalidPoolLimit(t *testing.T) {
+	t.Setenv("AZURE_POOL_MAX_SHARES", "-1")
+
+	_, err := Load()
+	if err == nil {
+		t.Fatalf("Load() error = nil, want error")
+	}
+}
+
+func TestLoadAutoCreateRequiresLocation(t *testing.T) {
+	t.Setenv("AZURE_SUBSCRIPTION_ID", "sub")
+	t.Setenv("AZURE_STORAGE_ACCOUNT_AUTO_CREATE", "true")
+
+	_, err := Load()
+	if err == nil {
+		t.Fatalf("Load() error = nil, want error")
+	}
+}
+
+func TestLoadInvalidShareAPI(t *testing.T) {
+	t.Setenv("AZURE_SHARE_API", "rest")
+
+	if _, err := Load(); err == nil {
+		t.Fatalf("Load() error = nil, want error")
+	}
+
+	t.Setenv("AZURE_SHARE_API", "arm")
+	if _, err := Load(); err == nil {
+		t.Fatalf("Load() without subscription error = nil, want error")
+	}
+}
+
+func TestLoadInvalidPVMismatchPolicy(t *testing.T) {
+	t.Setenv("PV_MISMATCH_POLICY", "ignore")
+
+	if _, err := Load(); err == nil {
+		t.Fatalf("Load() error = nil, want error")
+	}
+}
+
+func TestLoadInvalidRotationInterval(t *testing.T) {
+	t.Setenv("AZURE_NODE_SECRET_ROTATION_INTERVAL", "0s")
+
+	if _, err := Load(); err == nil {
+		t.Fatalf("Load() error = nil, want error")
+	}
+}
+
+func TestLoadNegativeDeletionGracePeriod(t *testing.T) {
+	t.Setenv("SHARE_DELETION_GRACE_PERIOD", "-1h")
+
+	if _, err := Load(); err == nil {
+		t.Fatalf("Load() error = nil, want error")
+	}
+}
+
+func TestLoadWebhookRequiresPodIdentity(t *testing.T) {
+	t.Setenv("WEBHOOK_ENABLED", "true")
+
+	if _, err := Load(); err == nil {
+		t.Fatalf("Load() error = nil, want error")
+	}
+}
+
+func TestLoadNodeSecretNamespaceDefaultsToPodNamespace(t *testing.T) {
+	t.Setenv("AZURE_SUBSCRIPTION_ID", "sub")
+	t.Setenv("AZURE_NODE_SECRETS_ENABLED", "true")
+	t.Setenv("POD_NAMESPACE", "azurefile-provisioner-system")
+
+	cfg, err := Load()
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+	if cfg.NodeSecretNamespace != "azurefile-provisioner-system" {
+		t.Fatalf("NodeSecretNamespace = %q, want the pod namespace", cfg.NodeSecretNamespace)
+	}
+
+	t.Setenv("POD_NAMESPACE", "")
+	if _, err := Load(); err == nil {
+		t.Fatalf("Load() without any namespace error = nil, want error")
+	}
+}
@@ -2,29 +2,88 @@ package constants
 
 const (
 	// Annotation Keys
-	ShareOverrideAnnotation = "kliggo.ch/share-override"
-	ShareNameAnnotation     = "kliggo.ch/share-name"
-	RetainShareAnnotation   = "kliggo.ch/retain-share"
+	ShareOverrideAnnotation        = "kliggo.ch/share-override"
+	ShareNameAnnotation            = "kliggo.ch/share-name"
+	RetainShareAnnotation          = "kliggo.ch/retain-share"
+	SelectedNodeAnnotation         = "volume.kubernetes.io/selected-node"
+	StorageAccountAnnotation       = "kliggo.ch/storage-account"
+	VolumeHandleAnnotation         = "kliggo.ch/volume-handle"
+	ObservedGenerationAnnotation   = "kliggo.ch/observed-generation"
+	RestoreFromAnnotation          = "kliggo.ch/restore-from-pvc-uid"
+	SnapshotBeforeDeleteAnnotation = "kliggo.ch/snapshot-before-delete"
+	DeletionSnapshotAnnotation     = "kliggo.ch/deletion-snapshot"
+	CloneProgressAnnotation        = "kliggo.ch/clone-progress"
+	CloneSnapshotAnnotation        = "kliggo.ch/clone-snapshot"
+	AdoptShareAnnotation           = "kliggo.ch/adopt-share"
+	TransferOwnershipAnnotation    = "kliggo.ch/transfer-share-ownership"
+	// ContentSnapshotAnnotation records on a VolumeSnapshotContent the Azure snapshot taken for it.
+	ContentSnapshotAnnotation = "kliggo.ch/snapshot-id"
 
 	// Finalizers
 	FinalizerName = "kliggo.ch/azurefile-provisioner"
+	// SnapshotContentFinalizer is the external-snapshotter finalizer held until a content's snapshot is deleted.
+	SnapshotContentFinalizer = "snapshot.storage.kubernetes.io/volumesnapshotcontent-bound-protection"
 
 	// Event Reasons
-	EventShareEnsuring      = "ShareEnsuring"
-	EventShareReady         = "ShareReady"
-	EventShareError         = "ShareError"
-	EventShareValidation    = "ShareValidationError"
-	EventShareNameInvalid   = "ShareNameInvalid"
-	EventPVCInvalid         = "PVCInvalid"
-	EventShareClientMissing = "ShareClientMissing"
-	EventPVBuildError       = "PVBuildError"
-	EventCleanupStarted     = "CleanupStarted"
-	EventShareDeleted       = "ShareDeleted"
-	EventShareRetained      = "ShareRetained"
-	EventCleanupComplete    = "CleanupComplete"
-	EventPVCreated          = "PVCreated"
-	EventPVMismatch         = "PVMismatch"
-	EventPVAlreadyExists    = "PVAlreadyExists"
+	EventShareEnsuring             = "ShareEnsuring"
+	EventShareReady                = "ShareReady"
+	EventShareError                = "ShareError"
+	EventShareValidation           = "ShareValidationError"
+	EventShareNameInvalid          = "ShareNameInvalid"
+	EventPVCInvalid                = "PVCInvalid"
+	EventShareClientMissing        = "ShareClientMissing"
+	EventPVBuildError              = "PVBuildError"
+	EventCleanupStarted            = "CleanupStarted"
+	EventShareDeleted              = "ShareDeleted"
+	EventShareRetained             = "ShareRetained"
+	EventCleanupComplete           = "CleanupComplete"
+	EventPVCreated                 = "PVCreated"
+	EventPVMismatch                = "PVMismatch"
+	EventPVAlreadyExists           = "PVAlreadyExists"
+	EventResizing                  = "Resizing"
+	EventResizeSuccessful          = "VolumeResizeSuccessful"
+	EventResizeFailed              = "VolumeResizeFailed"
+	EventShrinkRefused             = "ShareShrinkRefused"
+	EventWaitingForConsumer        = "WaitForFirstConsumer"
+	EventSelectedNodeGone          = "SelectedNodeNotFound"
+	EventAccountNotAllowed         = "StorageAccountNotAllowed"
+	EventAccountSelected           = "StorageAccountSelected"
+	EventAccountPoolFull           = "StorageAccountPoolExhausted"
+	EventAccountInvalid            = "StorageAccountInvalid"
+	EventNodeSecretError           = "NodeSecretError"
+	EventOwnershipConflict         = "ShareOwnershipConflict"
+	EventPVBound                   = "PVBound"
+	EventPVMismatchHalted          = "PVMismatchHalted"
+	EventPVRecreated               = "PVRecreated"
+	EventPVAdopted                 = "PVAdopted"
+	EventAzureThrottled            = "AzureThrottled"
+	EventAzureUnauthorized         = "AzureAuthorizationFailed"
+	EventShareBeingDeleted         = "ShareBeingDeleted"
+	EventShareQuotaExceeded        = "ShareQuotaExceeded"
+	EventShareRestored             = "ShareRestored"
+	EventShareRestoreFailed        = "ShareRestoreFailed"
+	EventShareScheduledForDeletion = "ShareScheduledForDeletion"
+	EventShareReclaimed            = "ShareReclaimed"
+	EventShareSnapshotCreated      = "ShareSnapshotCreated"
+	EventSnapshotCreated           = "SnapshotCreated"
+	EventSnapshotFailed            = "SnapshotFailed"
+	EventSnapshotDeleted           = "SnapshotDeleted"
+	EventCloneStarted              = "CloneStarted"
+	EventCloneInProgress           = "CloneInProgress"
+	EventCloneCompleted            = "CloneCompleted"
+	EventCloneFailed               = "CloneFailed"
+	EventCloneSourceNotReady       = "CloneSourceNotReady"
+	EventShareAdopted              = "ShareAdopted"
+	EventShareAdoptionFailed       = "ShareAdoptionFailed"
+	EventDirectoryDeleted          = "DirectoryDeleted"
+	EventShareMetadataBackfilled   = "ShareMetadataBackfilled"
+	EventSnapshotPolicyInvalid     = "SnapshotPolicyInvalid"
+
+	// PVC condition types owned by the provisioner; their reasons are Event reasons.
+	ConditionShareReady         = "ShareReady"
+	ConditionPVBound            = "PVBound"
+	ConditionProvisioningFailed = "ProvisioningFailed"
+	ConditionShrinkRefused      = "ShrinkRefused"
 
 	// Drivers
 	AzureFileCSIDriver = "file.csi.azure.com"
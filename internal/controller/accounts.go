@@ -0,0 +1,219 @@
+package controller
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"aks-azureFiles-controller/internal/azure"
+	"aks-azureFiles-controller/internal/constants"
+	"aks-azureFiles-controller/internal/k8s"
+)
+
+var ErrStorageAccountNotAllowed = errors.New("storage account not allowed")
+
+// shareLocation identifies the storage account that holds a claim's share.
+// Region is only known for pool accounts placed with a region.
+type shareLocation struct {
+	ResourceGroup  string
+	StorageAccount string
+	Server         string
+	Region         string
+}
+
+// resolveLocation picks the storage account for a claim being provisioned.
+// An account already recorded for the claim wins so later reconciles stay deterministic; otherwise the
+// account of an adopted share or the 'storage-account' annotation (when the class allows it), then the
+// class, then the account pool, then the controller default. Adopted shares and the backing shares of
+// subdirectory classes are never placed from the pool. pooled is true when the account still has to be placed from the pool.
+func (r *PVCReconciler) resolveLocation(ctx context.Context, pvc *corev1.PersistentVolumeClaim, params k8s.ShareParameters) (location shareLocation, pooled bool, err error) {
+	location, ok, err := r.recordedLocation(ctx, pvc, params)
+	if err != nil || ok {
+		return location, false, err
+	}
+
+	account := params.StorageAccount
+	requested := pvc.Annotations[constants.StorageAccountAnnotation]
+	if adoptedAccount, _, err := k8s.ParseAdoptedShare(pvc.Annotations[constants.AdoptShareAnnotation]); err == nil && adoptedAccount != "" {
+		if requested != "" && requested != adoptedAccount {
+			return shareLocation{}, false, fmt.Errorf("annotations %s and %s name different accounts: %w", constants.AdoptShareAnnotation, constants.StorageAccountAnnotation, ErrStorageAccountNotAllowed)
+		}
+		requested = adoptedAccount
+	}
+	if requested != "" && requested != account {
+		if !params.AllowsStorageAccount(requested) {
+			return shareLocation{}, false, fmt.Errorf("account %q not in the class allow-list: %w", requested, ErrStorageAccountNotAllowed)
+		}
+		account = requested
+	}
+	if account == "" && r.Placer != nil && !adoptsShare(pvc) && !params.UsesSubdirectories() {
+		return shareLocation{}, true, nil
+	}
+	if account == "" {
+		account = r.Config.StorageAccount
+	}
+
+	resourceGroup := params.ResourceGroup
+	if resourceGroup == "" {
+		resourceGroup = r.Config.ResourceGroup
+	}
+	return r.locationFor(resourceGroup, account), false, nil
+}
+
+// deletionLocation resolves the account of a claim being deleted from its PV, then from the location recorded
+// for it (when its class allows it), then from config. Without a class the recorded location cannot be
+// checked and is used as is: shares are only deleted when their metadata names the claim.
+func (r *PVCReconciler) deletionLocation(ctx context.Context, pvc *corev1.PersistentVolumeClaim, pv *corev1.PersistentVolume) shareLocation {
+	if location, ok := pvLocation(pv); ok {
+		return r.locationFor(location.ResourceGroup, location.StorageAccount)
+	}
+	storageClass, err := k8s.GetStorageClass(ctx, r.Client, pvc)
+	if err == nil && storageClass != nil {
+		params, err := k8s.ParseShareParameters(storageClass.Parameters)
+		if err == nil {
+			if location, ok, err := r.recordedLocation(ctx, pvc, params); err == nil && ok {
+				return location
+			}
+			return r.locationFor(r.Config.ResourceGroup, r.Config.StorageAccount)
+		}
+	}
+	if resourceGroup, account, _, _, err := k8s.ParseDirectoryVolumeHandle(pvc.Annotations[constants.VolumeHandleAnnotation]); err == nil {
+		return r.locationFor(resourceGroup, account)
+	}
+	return r.locationFor(r.Config.ResourceGroup, r.Config.StorageAccount)
+}
+
+// recordedLocation returns the location already chosen for the claim: the volume handle of the PV bound to it,
+// else the volume handle recorded on the PVC by pool placement or an earlier reconcile. Users can edit the
+// annotation, so the account it names must still be one the claim could have been given.
+func (r *PVCReconciler) recordedLocation(ctx context.Context, pvc *corev1.PersistentVolumeClaim, params k8s.ShareParameters) (shareLocation, bool, error) {
+	if pvc == nil {
+		return shareLocation{}, false, nil
+	}
+	pv, err := r.boundPV(ctx, pvc)
+	if err != nil {
+		return shareLocation{}, false, err
+	}
+	if location, ok := pvLocation(pv); ok {
+		return r.locationFor(location.ResourceGroup, location.StorageAccount), true, nil
+	}
+
+	resourceGroup, account, _, _, err := k8s.ParseDirectoryVolumeHandle(pvc.Annotations[constants.VolumeHandleAnnotation])
+	if err != nil {
+		return shareLocation{}, false, nil
+	}
+	if !r.locationAllowed(resourceGroup, account, params) {
+		return shareLocation{}, false, fmt.Errorf("account %q in resource group %q recorded in %s is not allowed for the class: %w",
+			account, resourceGroup, constants.VolumeHandleAnnotation, ErrStorageAccountNotAllowed)
+	}
+	return r.locationFor(resourceGroup, account), true, nil
+}
+
+// boundPV returns the PV the claim is bound to when it is a volume of this driver whose claimRef names the claim.
+func (r *PVCReconciler) boundPV(ctx context.Context, pvc *corev1.PersistentVolumeClaim) (*corev1.PersistentVolume, error) {
+	if pvc.Spec.VolumeName == "" {
+		return nil, nil
+	}
+	pv := &corev1.PersistentVolume{}
+	if err := r.Client.Get(ctx, client.ObjectKey{Name: pvc.Spec.VolumeName}, pv); err != nil {
+		if apierrors.IsNotFound(err) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("get bound pv: %w", err)
+	}
+	if pv.Spec.ClaimRef == nil || pv.Spec.ClaimRef.UID != pvc.UID || pv.Spec.CSI == nil || pv.Spec.CSI.Driver != constants.AzureFileCSIDriver {
+		return nil, nil
+	}
+	return pv, nil
+}
+
+// locationAllowed reports whether resolveLocation could have picked the account: the class's account or one of
+// its allow-list in the class's resource group, or, for classes without an account, the controller default or
+// a pool account.
+func (r *PVCReconciler) locationAllowed(resourceGroup, account string, params k8s.ShareParameters) bool {
+	classResourceGroup := params.ResourceGroup
+	if classResourceGroup == "" {
+		classResourceGroup = r.Config.ResourceGroup
+	}
+	if resourceGroup == classResourceGroup && params.AllowsStorageAccount(account) {
+		return true
+	}
+	if params.StorageAccount != "" {
+		return false
+	}
+	if resourceGroup == classResourceGroup && account == r.Config.StorageAccount {
+		return true
+	}
+	if r.Placer == nil {
+		return false
+	}
+	for _, pooled := range r.Placer.Accounts() {
+		if pooled.Name == account && pooled.ResourceGroup == resourceGroup {
+			return true
+		}
+	}
+	return false
+}
+
+// pvLocation returns the resource group and account of a PV's volume handle.
+func pvLocation(pv *corev1.PersistentVolume) (shareLocation, bool) {
+	if pv == nil || pv.Spec.CSI == nil {
+		return shareLocation{}, false
+	}
+	resourceGroup, account, _, _, err := k8s.ParseDirectoryVolumeHandle(pv.Spec.CSI.VolumeHandle)
+	if err != nil {
+		return shareLocation{}, false
+	}
+	return shareLocation{ResourceGroup: resourceGroup, StorageAccount: account}, true
+}
+
+func (r *PVCReconciler) locationFor(resourceGroup, account string) shareLocation {
+	server := azure.FileServiceHost(account)
+	if account == r.Config.StorageAccount && r.Config.Server != "" {
+		server = r.Config.Server
+	}
+	return shareLocation{
+		ResourceGroup:  resourceGroup,
+		StorageAccount: account,
+		Server:         server,
+	}
+}
+
+// verifyAccount checks the storage account through the management plane, creating it when enabled.
+// The class SKU, when set, must match the account. Without a Verifier accounts are trusted as configured.
+func (r *PVCReconciler) verifyAccount(ctx context.Context, location shareLocation, params k8s.ShareParameters, region string) error {
+	if r.Verifier == nil {
+		return nil
+	}
+	spec := azure.AccountSpec{
+		Name:          location.StorageAccount,
+		ResourceGroup: location.ResourceGroup,
+		Location:      location.Region,
+		SkuName:       params.SkuName,
+		NFS:           params.IsNFS(),
+	}
+	if spec.Location == "" {
+		spec.Location = region
+	}
+	if params.SkuName != "" {
+		spec.Kind = azure.DefaultAccountKind(params.SkuName)
+	}
+	return r.Verifier.Verify(ctx, spec)
+}
+
+// shareClientFor returns the ShareClient for a location: the default client for the configured account,
+// otherwise one from the account registry.
+func (r *PVCReconciler) shareClientFor(location shareLocation) (azure.ShareClient, error) {
+	account := location.StorageAccount
+	if r.Shares != nil && (account == "" || account == r.Config.StorageAccount) {
+		return r.Shares, nil
+	}
+	if r.Accounts == nil {
+		return nil, fmt.Errorf("account %q: %w", account, azure.ErrUnknownAccount)
+	}
+	return r.Accounts.ForAccount(location.ResourceGroup, account)
+}
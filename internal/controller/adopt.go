@@ -0,0 +1,93 @@
+package controller
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"maps"
+
+	"github.com/go-logr/logr"
+	corev1 "k8s.io/api/core/v1"
+
+	"aks-azureFiles-controller/internal/azure"
+	"aks-azureFiles-controller/internal/constants"
+	"aks-azureFiles-controller/internal/k8s"
+)
+
+var ErrShareAdoptionFailed = errors.New("share adoption failed")
+
+// adoptShare attaches the claim to the existing share named by the adopt-share annotation instead of creating one.
+// The share must exist, must not belong to another namespace or be a backing share and, until the claim is bound,
+// must hold the request. Adoption only mounts the share: it is kept when the claim is deleted, and only an
+// unowned share is changed, to record the claim's namespace as its owner.
+// Only a class that allows ownership transfer lets the claim take the share over with the transfer-share-ownership
+// annotation; the share is then stamped with the claim's provenance, which deletion requires.
+func (r *PVCReconciler) adoptShare(ctx context.Context, logger logr.Logger, pvc *corev1.PersistentVolumeClaim, shares azure.ShareClient, shareName string, params k8s.ShareParameters, requestedGiB int32) (*azure.ShareProperties, error) {
+	props, err := shares.GetShare(ctx, shareName)
+	if errors.Is(err, azure.ErrShareNotFound) {
+		return nil, fmt.Errorf("share %q does not exist: %w", shareName, ErrShareAdoptionFailed)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("get share: %w", err)
+	}
+	if err := checkShareOwnership(pvc, props, params); err != nil {
+		return nil, err
+	}
+	if pvc.Spec.VolumeName == "" && props.QuotaGiB < requestedGiB {
+		return nil, fmt.Errorf("share %q has a quota of %d GiB, less than the %d GiB requested: %w", shareName, props.QuotaGiB, requestedGiB, ErrShareAdoptionFailed)
+	}
+	if pvc.Annotations[constants.TransferOwnershipAnnotation] == "true" && !params.AllowOwnershipTransfer {
+		return nil, fmt.Errorf("annotation %s requires the class parameter %s: %w", constants.TransferOwnershipAnnotation, k8s.ParamAllowOwnershipTransfer, ErrShareAdoptionFailed)
+	}
+
+	if !transfersOwnership(pvc, params) {
+		// The first namespace to adopt an unowned share claims it, so claims of other namespaces cannot
+		// adopt it as well. No pvc_uid is recorded, which keeps deletion off.
+		if props.Metadata[azure.MetadataOwnerNamespace] == "" {
+			metadata := mergedMetadata(props.Metadata, map[string]string{azure.MetadataOwnerNamespace: pvc.Namespace})
+			if err := shares.SetShareMetadata(ctx, shareName, metadata); err != nil {
+				return nil, fmt.Errorf("set share metadata: %w", err)
+			}
+			props.Metadata = metadata
+		}
+		if pvc.Annotations[constants.ShareNameAnnotation] == "" {
+			logger.Info("adopted share", "quotaGiB", props.QuotaGiB, "transferOwnership", false)
+			r.Recorder.Eventf(pvc, corev1.EventTypeNormal, constants.EventShareAdopted, "Adopted existing Azure File share %s; it is kept when the claim is deleted", shareName)
+		}
+		return props, nil
+	}
+
+	metadata := mergedMetadata(props.Metadata, shareMetadata(pvc, r.Config.ClusterID))
+	if maps.Equal(metadata, props.Metadata) {
+		return props, nil
+	}
+	if err := shares.SetShareMetadata(ctx, shareName, metadata); err != nil {
+		return nil, fmt.Errorf("set share metadata: %w", err)
+	}
+	props.Metadata = metadata
+
+	logger.Info("adopted share", "quotaGiB", props.QuotaGiB, "transferOwnership", true)
+	r.Recorder.Eventf(pvc, corev1.EventTypeNormal, constants.EventShareAdopted, "Adopted existing Azure File share %s; it is now reclaimed with the claim", shareName)
+	return props, nil
+}
+
+// mergedMetadata returns a copy of existing with metadata laid over it.
+func mergedMetadata(existing, metadata map[string]string) map[string]string {
+	merged := maps.Clone(existing)
+	if merged == nil {
+		merged = map[string]string{}
+	}
+	maps.Copy(merged, metadata)
+	return merged
+}
+
+// adoptsShare reports whether the claim names an existing share to adopt.
+func adoptsShare(pvc *corev1.PersistentVolumeClaim) bool {
+	return pvc.Annotations[constants.AdoptShareAnnotation] != ""
+}
+
+// transfersOwnership reports whether an adopted share is handed over to the claim, so it is deleted with it.
+// The claim asks for it and its class must allow it.
+func transfersOwnership(pvc *corev1.PersistentVolumeClaim, params k8s.ShareParameters) bool {
+	return params.AllowOwnershipTransfer && pvc.Annotations[constants.TransferOwnershipAnnotation] == "true"
+}
@@ -0,0 +1,47 @@
+package controller
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/go-logr/logr"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"aks-azureFiles-controller/internal/constants"
+	"aks-azureFiles-controller/internal/k8s"
+)
+
+// selectedNodeTopology resolves the topology of the node the scheduler picked for a WaitForFirstConsumer claim.
+// It returns ready=false while provisioning has to wait for the scheduler; the annotation update requeues the PVC.
+func (r *PVCReconciler) selectedNodeTopology(ctx context.Context, logger logr.Logger, pvc *corev1.PersistentVolumeClaim) (k8s.Topology, bool, error) {
+	nodeName := ""
+	if pvc.Annotations != nil {
+		nodeName = pvc.Annotations[constants.SelectedNodeAnnotation]
+	}
+	if nodeName == "" {
+		logger.Info("waiting for first consumer")
+		r.Recorder.Event(pvc, corev1.EventTypeNormal, constants.EventWaitingForConsumer, "Waiting for first consumer to be scheduled before provisioning")
+		return k8s.Topology{}, false, nil
+	}
+
+	node := &corev1.Node{}
+	if err := r.Client.Get(ctx, client.ObjectKey{Name: nodeName}, node); err != nil {
+		if !apierrors.IsNotFound(err) {
+			return k8s.Topology{}, false, fmt.Errorf("get selected node: %w", err)
+		}
+
+		// Dropping the annotation hands the claim back to the scheduler, which will pick another node.
+		logger.Info("selected node not found", "node", nodeName)
+		r.Recorder.Eventf(pvc, corev1.EventTypeWarning, constants.EventSelectedNodeGone, "Selected node %q not found; asking the scheduler to reschedule", nodeName)
+		patch := client.MergeFrom(pvc.DeepCopy())
+		delete(pvc.Annotations, constants.SelectedNodeAnnotation)
+		if err := r.Client.Patch(ctx, pvc, patch); err != nil {
+			return k8s.Topology{}, false, fmt.Errorf("remove selected node annotation: %w", err)
+		}
+		return k8s.Topology{}, false, nil
+	}
+
+	return k8s.TopologyFromNode(node), true, nil
+}
@@ -0,0 +1,289 @@
+package controller
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/go-logr/logr"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"aks-azureFiles-controller/internal/azure"
+	"aks-azureFiles-controller/internal/constants"
+	"aks-azureFiles-controller/internal/k8s"
+	"aks-azureFiles-controller/internal/snapshotv1"
+)
+
+var (
+	ErrInvalidDataSource  = errors.New("invalid data source")
+	ErrDataSourceNotReady = errors.New("data source not ready")
+)
+
+// cloneRequeue is how often a claim is requeued while its share is being copied or its source is not ready.
+const cloneRequeue = 15 * time.Second
+
+// cloneComplete is the CloneProgressAnnotation value once every file has been copied.
+const cloneComplete = "complete"
+
+// cloneBatchFiles caps the files whose copy is checked or started per reconcile, so a large share costs a
+// bounded number of requests every cloneRequeue rather than one per file.
+const cloneBatchFiles = 500
+
+// cloneSource is the share, or share snapshot, a claim's data source resolves to.
+type cloneSource struct {
+	description   string
+	resourceGroup string
+	account       string
+	share         string
+	snapshot      string
+}
+
+// populateShare copies the claim's data source into its share and reports whether the copy has completed.
+// Claims without a data source, and claims already bound, are complete. Progress is recorded in the
+// CloneProgressAnnotation and reported in events and the ShareReady condition; each pass resumes after the
+// files the recorded progress counts as copied and checks at most cloneBatchFiles more. A claim source is
+// copied from a snapshot taken on the first pass, recorded in the CloneSnapshotAnnotation and deleted once
+// the copy completes, so the files a pass skips do not shift while the source is written to.
+func (r *PVCReconciler) populateShare(ctx context.Context, logger logr.Logger, pvc *corev1.PersistentVolumeClaim, location shareLocation, shares azure.ShareClient, params k8s.ShareParameters, shareName string, quotaGiB int32) (bool, error) {
+	ref := claimDataSource(pvc)
+	previous := pvc.Annotations[constants.CloneProgressAnnotation]
+	if ref == nil || previous == cloneComplete || pvc.Spec.VolumeName != "" {
+		return true, nil
+	}
+	if pvc.Annotations[constants.ShareOverrideAnnotation] != "" || pvc.Annotations[constants.RestoreFromAnnotation] != "" || adoptsShare(pvc) {
+		return false, fmt.Errorf("a data source cannot be combined with %s, %s or %s: %w",
+			constants.ShareOverrideAnnotation, constants.RestoreFromAnnotation, constants.AdoptShareAnnotation, ErrInvalidDataSource)
+	}
+
+	source, err := r.resolveDataSource(ctx, pvc, ref)
+	if err != nil {
+		return false, err
+	}
+	if source.resourceGroup != location.ResourceGroup || source.account != location.StorageAccount {
+		return false, fmt.Errorf("%s is in storage account %s/%s but the claim resolves to %s/%s; shares are only copied within an account: %w",
+			source.description, source.resourceGroup, source.account, location.ResourceGroup, location.StorageAccount, ErrInvalidDataSource)
+	}
+	props, err := shares.GetShare(ctx, source.share)
+	if err != nil {
+		return false, fmt.Errorf("get source share: %w", err)
+	}
+	if err := checkShareOwnership(pvc, props, params); err != nil {
+		return false, fmt.Errorf("%s: %w: %w", source.description, ErrInvalidDataSource, err)
+	}
+	if quotaGiB < props.QuotaGiB {
+		return false, fmt.Errorf("requested %d GiB is smaller than the %d GiB of %s: %w", quotaGiB, props.QuotaGiB, source.description, ErrInvalidDataSource)
+	}
+
+	snapshot := source.snapshot
+	if snapshot == "" {
+		if snapshot, err = r.cloneSnapshot(ctx, logger, pvc, shares, source.share); err != nil {
+			return false, err
+		}
+	}
+	opts := &azure.CopyShareOptions{MaxFiles: cloneBatchFiles}
+	var files int
+	if _, err := fmt.Sscanf(previous, "%d/%d", &opts.Skip, &files); err != nil {
+		opts.Skip = 0
+	}
+	progress, err := shares.CopyShare(ctx, source.share, snapshot, shareName, opts)
+	if errors.Is(err, azure.ErrShareNotFound) && source.snapshot == "" {
+		// The recorded snapshot is gone, so the recorded progress counts files of a listing that no longer
+		// exists: start over from a new snapshot. Files already copied are not copied again.
+		logger.Info("clone snapshot gone; restarting the copy", "snapshot", snapshot)
+		patch := client.MergeFrom(pvc.DeepCopy())
+		delete(pvc.Annotations, constants.CloneSnapshotAnnotation)
+		delete(pvc.Annotations, constants.CloneProgressAnnotation)
+		if err := r.Client.Patch(ctx, pvc, patch); err != nil {
+			return false, fmt.Errorf("patch pvc annotations: %w", err)
+		}
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	value := fmt.Sprintf("%d/%d", progress.Completed, progress.Files)
+	if progress.Done() {
+		value = cloneComplete
+		if source.snapshot == "" {
+			if err := shares.DeleteSnapshot(ctx, source.share, snapshot); err != nil {
+				return false, fmt.Errorf("delete clone snapshot: %w", err)
+			}
+		}
+	}
+	if value == previous {
+		return false, nil
+	}
+
+	logger.Info("copying data source", "source", source.description, "completed", progress.Completed, "files", progress.Files)
+	switch {
+	case progress.Done():
+		r.Recorder.Eventf(pvc, corev1.EventTypeNormal, constants.EventCloneCompleted, "Copied %d files from %s", progress.Files, source.description)
+	case previous == "":
+		r.Recorder.Eventf(pvc, corev1.EventTypeNormal, constants.EventCloneStarted, "Copying %d files from %s into Azure File share %s", progress.Files, source.description, shareName)
+	default:
+		r.Recorder.Eventf(pvc, corev1.EventTypeNormal, constants.EventCloneInProgress, "Copied %d of %d files from %s", progress.Completed, progress.Files, source.description)
+	}
+
+	patch := client.MergeFrom(pvc.DeepCopy())
+	if pvc.Annotations == nil {
+		pvc.Annotations = map[string]string{}
+	}
+	pvc.Annotations[constants.CloneProgressAnnotation] = value
+	if progress.Done() {
+		delete(pvc.Annotations, constants.CloneSnapshotAnnotation)
+	}
+	if err := r.Client.Patch(ctx, pvc, patch); err != nil {
+		return false, fmt.Errorf("patch pvc annotations: %w", err)
+	}
+	if progress.Done() {
+		return true, nil
+	}
+	copying := claimCondition(constants.ConditionShareReady, corev1.ConditionFalse, constants.EventCloneInProgress,
+		fmt.Sprintf("Copied %d of %d files from %s", progress.Completed, progress.Files, source.description))
+	return false, r.setConditions(ctx, pvc, copying)
+}
+
+// cloneSnapshot returns the snapshot of the source share recorded on the claim, taking and recording one on the
+// first pass.
+func (r *PVCReconciler) cloneSnapshot(ctx context.Context, logger logr.Logger, pvc *corev1.PersistentVolumeClaim, shares azure.ShareClient, sourceShare string) (string, error) {
+	if snapshot := pvc.Annotations[constants.CloneSnapshotAnnotation]; snapshot != "" {
+		return snapshot, nil
+	}
+	snapshot, err := shares.CreateSnapshot(ctx, sourceShare)
+	if err != nil {
+		return "", fmt.Errorf("snapshot source share: %w", err)
+	}
+	patch := client.MergeFrom(pvc.DeepCopy())
+	if pvc.Annotations == nil {
+		pvc.Annotations = map[string]string{}
+	}
+	pvc.Annotations[constants.CloneSnapshotAnnotation] = snapshot
+	if err := r.Client.Patch(ctx, pvc, patch); err != nil {
+		// Without the record a retry would take another snapshot, so this one would be orphaned.
+		if deleteErr := shares.DeleteSnapshot(ctx, sourceShare, snapshot); deleteErr != nil {
+			logger.Error(deleteErr, "delete unrecorded snapshot", "share", sourceShare, "snapshot", snapshot)
+		}
+		return "", fmt.Errorf("patch pvc annotations: %w", err)
+	}
+	logger.Info("clone snapshot created", "share", sourceShare, "snapshot", snapshot)
+	return snapshot, nil
+}
+
+// releaseCloneSnapshot deletes the snapshot an unfinished copy was taken from. Sources that no longer resolve
+// are skipped: deleting their share deletes its snapshots too.
+func (r *PVCReconciler) releaseCloneSnapshot(ctx context.Context, logger logr.Logger, pvc *corev1.PersistentVolumeClaim, location shareLocation) error {
+	snapshot := pvc.Annotations[constants.CloneSnapshotAnnotation]
+	ref := claimDataSource(pvc)
+	if snapshot == "" || ref == nil {
+		return nil
+	}
+	source, err := r.resolveDataSource(ctx, pvc, ref)
+	if errors.Is(err, ErrDataSourceNotReady) || errors.Is(err, ErrInvalidDataSource) {
+		logger.Info("clone source gone; leaving its snapshot", "snapshot", snapshot, "reason", err.Error())
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if source.snapshot != "" {
+		return nil
+	}
+	shares, err := r.shareClientFor(location)
+	if err != nil {
+		return fmt.Errorf("resolve share client: %w", err)
+	}
+	if err := shares.DeleteSnapshot(ctx, source.share, snapshot); err != nil {
+		return fmt.Errorf("delete clone snapshot: %w", err)
+	}
+	logger.Info("clone snapshot deleted", "share", source.share, "snapshot", snapshot)
+	return nil
+}
+
+// resolveDataSource finds the share behind a claim of this provisioner or a VolumeSnapshot of its driver
+// in the claim's namespace. Claims resolve through the PV bound to them and snapshots through a content
+// bound back to them, never through annotations their users can edit. Sources that are not provisioned or
+// ready yet return ErrDataSourceNotReady.
+func (r *PVCReconciler) resolveDataSource(ctx context.Context, pvc *corev1.PersistentVolumeClaim, ref *corev1.TypedObjectReference) (*cloneSource, error) {
+	if ref.Namespace != nil && *ref.Namespace != "" && *ref.Namespace != pvc.Namespace {
+		return nil, fmt.Errorf("data source in namespace %q: cross-namespace sources are not supported: %w", *ref.Namespace, ErrInvalidDataSource)
+	}
+	group := ""
+	if ref.APIGroup != nil {
+		group = *ref.APIGroup
+	}
+	key := client.ObjectKey{Namespace: pvc.Namespace, Name: ref.Name}
+
+	switch {
+	case group == "" && ref.Kind == "PersistentVolumeClaim":
+		description := fmt.Sprintf("PersistentVolumeClaim %s", ref.Name)
+		source := &corev1.PersistentVolumeClaim{}
+		if err := r.Client.Get(ctx, key, source); err != nil {
+			if apierrors.IsNotFound(err) {
+				return nil, fmt.Errorf("%s not found: %w", description, ErrDataSourceNotReady)
+			}
+			return nil, fmt.Errorf("get source pvc: %w", err)
+		}
+		if source.Spec.VolumeName == "" {
+			return nil, fmt.Errorf("%s is not bound yet: %w", description, ErrDataSourceNotReady)
+		}
+		pv, err := r.boundPV(ctx, source)
+		if err != nil {
+			return nil, fmt.Errorf("get source pv: %w", err)
+		}
+		if pv == nil {
+			return nil, fmt.Errorf("%s is not bound to a volume of this provisioner: %w", description, ErrInvalidDataSource)
+		}
+		resourceGroup, account, shareName, err := k8s.ParseVolumeHandle(pv.Spec.CSI.VolumeHandle)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w: %w", description, ErrInvalidDataSource, err)
+		}
+		return &cloneSource{description: description, resourceGroup: resourceGroup, account: account, share: shareName}, nil
+
+	case group == snapshotv1.GroupVersion.Group && ref.Kind == "VolumeSnapshot":
+		description := fmt.Sprintf("VolumeSnapshot %s", ref.Name)
+		snapshot := &snapshotv1.VolumeSnapshot{}
+		if err := r.Client.Get(ctx, key, snapshot); err != nil {
+			if apierrors.IsNotFound(err) {
+				return nil, fmt.Errorf("%s not found: %w", description, ErrDataSourceNotReady)
+			}
+			return nil, fmt.Errorf("get source volumesnapshot: %w", err)
+		}
+		status := snapshot.Status
+		if status == nil || status.BoundVolumeSnapshotContentName == nil || status.ReadyToUse == nil || !*status.ReadyToUse {
+			return nil, fmt.Errorf("%s is not ready to use: %w", description, ErrDataSourceNotReady)
+		}
+		content := &snapshotv1.VolumeSnapshotContent{}
+		if err := r.Client.Get(ctx, client.ObjectKey{Name: *status.BoundVolumeSnapshotContentName}, content); err != nil {
+			return nil, fmt.Errorf("get source volumesnapshotcontent: %w", err)
+		}
+		if ref := content.Spec.VolumeSnapshotRef; ref.Name != snapshot.Name || ref.Namespace != snapshot.Namespace || (ref.UID != "" && ref.UID != snapshot.UID) {
+			return nil, fmt.Errorf("%s names content %s bound to VolumeSnapshot %s/%s: %w", description, content.Name, ref.Namespace, ref.Name, ErrInvalidDataSource)
+		}
+		if content.Spec.Driver != k8s.ManagedProvisioner {
+			return nil, fmt.Errorf("%s was taken by driver %q: %w", description, content.Spec.Driver, ErrInvalidDataSource)
+		}
+		if content.Status == nil || content.Status.SnapshotHandle == nil {
+			return nil, fmt.Errorf("%s has no snapshot handle yet: %w", description, ErrDataSourceNotReady)
+		}
+		resourceGroup, account, shareName, snapshotID, err := k8s.ParseSnapshotHandle(*content.Status.SnapshotHandle)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w: %w", description, ErrInvalidDataSource, err)
+		}
+		return &cloneSource{description: description, resourceGroup: resourceGroup, account: account, share: shareName, snapshot: snapshotID}, nil
+	}
+	return nil, fmt.Errorf("data source kind %q of group %q is not supported: %w", ref.Kind, group, ErrInvalidDataSource)
+}
+
+// claimDataSource returns the claim's dataSourceRef, falling back to its dataSource.
+func claimDataSource(pvc *corev1.PersistentVolumeClaim) *corev1.TypedObjectReference {
+	if ref := pvc.Spec.DataSourceRef; ref != nil {
+		return ref
+	}
+	if ref := pvc.Spec.DataSource; ref != nil {
+		return &corev1.TypedObjectReference{APIGroup: ref.APIGroup, Kind: ref.Kind, Name: ref.Name}
+	}
+	return nil
+}
@@ -0,0 +1,102 @@
+package controller
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/util/retry"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"aks-azureFiles-controller/internal/constants"
+)
+
+// claimCondition builds a provisioner-owned PVC condition; reason is one of the constants.Event* reasons.
+func claimCondition(conditionType string, status corev1.ConditionStatus, reason, message string) corev1.PersistentVolumeClaimCondition {
+	return corev1.PersistentVolumeClaimCondition{
+		Type:    corev1.PersistentVolumeClaimConditionType(conditionType),
+		Status:  status,
+		Reason:  reason,
+		Message: message,
+	}
+}
+
+// setConditions records conditions on the PVC status and the generation they describe in the
+// observed-generation annotation. Unchanged conditions are not written, and LastTransitionTime
+// only moves when a condition's status changes, so steady-state reconciles do not patch the claim.
+// A merge patch replaces the whole conditions list, so it goes through patchClaimStatus.
+func (r *PVCReconciler) setConditions(ctx context.Context, pvc *corev1.PersistentVolumeClaim, conditions ...corev1.PersistentVolumeClaimCondition) error {
+	now := metav1.Now()
+	if err := r.patchClaimStatus(ctx, pvc, func() bool { return applyConditions(pvc, now, conditions) }); err != nil {
+		return fmt.Errorf("patch pvc conditions: %w", err)
+	}
+
+	generation := strconv.FormatInt(pvc.Generation, 10)
+	if pvc.Annotations[constants.ObservedGenerationAnnotation] == generation {
+		return nil
+	}
+	patch := client.MergeFrom(pvc.DeepCopy())
+	if pvc.Annotations == nil {
+		pvc.Annotations = map[string]string{}
+	}
+	pvc.Annotations[constants.ObservedGenerationAnnotation] = generation
+	if err := r.Client.Patch(ctx, pvc, patch); err != nil {
+		return fmt.Errorf("patch observed generation: %w", err)
+	}
+	return nil
+}
+
+// patchClaimStatus applies mutate to the PVC status and patches it when mutate reports a change. The patch is
+// sent with an optimistic lock and, on a conflict, mutate is reapplied to the refetched claim, so writes of the
+// resizer or the PV controller to the same lists are not overwritten.
+func (r *PVCReconciler) patchClaimStatus(ctx context.Context, pvc *corev1.PersistentVolumeClaim, mutate func() bool) error {
+	refresh := false
+	return retry.RetryOnConflict(retry.DefaultRetry, func() error {
+		if refresh {
+			if err := r.Client.Get(ctx, client.ObjectKeyFromObject(pvc), pvc); err != nil {
+				return err
+			}
+		}
+		refresh = true
+		patch := client.MergeFromWithOptions(pvc.DeepCopy(), client.MergeFromWithOptimisticLock{})
+		if !mutate() {
+			return nil
+		}
+		return r.Client.Status().Patch(ctx, pvc, patch)
+	})
+}
+
+// applyConditions merges conditions into the PVC status and reports whether anything changed.
+func applyConditions(pvc *corev1.PersistentVolumeClaim, now metav1.Time, conditions []corev1.PersistentVolumeClaimCondition) bool {
+	changed := false
+	for _, condition := range conditions {
+		existing := findPVCCondition(pvc, condition.Type)
+		if existing == nil {
+			condition.LastProbeTime = now
+			condition.LastTransitionTime = now
+			pvc.Status.Conditions = append(pvc.Status.Conditions, condition)
+			changed = true
+			continue
+		}
+		if existing.Status == condition.Status && existing.Reason == condition.Reason && existing.Message == condition.Message {
+			continue
+		}
+		if existing.Status != condition.Status {
+			existing.LastTransitionTime = now
+		}
+		existing.Status = condition.Status
+		existing.Reason = condition.Reason
+		existing.Message = condition.Message
+		existing.LastProbeTime = now
+		changed = true
+	}
+	return changed
+}
+
+// conditionTrue reports whether the provisioner condition is currently True on the PVC.
+func conditionTrue(pvc *corev1.PersistentVolumeClaim, conditionType string) bool {
+	condition := findPVCCondition(pvc, corev1.PersistentVolumeClaimConditionType(conditionType))
+	return condition != nil && condition.Status == corev1.ConditionTrue
+}
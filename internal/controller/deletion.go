@@ -2,25 +2,34 @@ package controller
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"maps"
+	"strings"
+	"time"
 
 	"github.com/go-logr/logr"
 	corev1 "k8s.io/api/core/v1"
+	storagev1 "k8s.io/api/storage/v1"
 	apierrors "k8s.io/apimachinery/pkg/api/errors"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/reconcile"
 
+	"aks-azureFiles-controller/internal/azure"
 	"aks-azureFiles-controller/internal/constants"
 	"aks-azureFiles-controller/internal/k8s"
 	"aks-azureFiles-controller/internal/naming"
+	"aks-azureFiles-controller/internal/snapshotv1"
 )
 
+var ErrShareHasSnapshots = errors.New("share has volume snapshots")
+
 // handleDeletion cleans up Azure resources and Kubernetes PVs when a PVC is deleted.
 // Flow:
 // 1. Check if we manage this PVC (if not, just remove finalizer).
-// 2. Identify the share name (from annotation or computed).
-// 3. Delete the Azure Share (unless 'retain-share' annotation is present).
-// 4. Delete the PV.
+// 2. Identify the share name (from annotation or computed) and the PV.
+// 3. Resolve the reclaim policy.
+// 4. Retain the share, or delete it (or the claim's directory) and the PV.
 // 5. Remove the Finalizer to allow PVC deletion to complete.
 func (r *PVCReconciler) handleDeletion(ctx context.Context, logger logr.Logger, pvc *corev1.PersistentVolumeClaim) (reconcile.Result, error) {
 	// 1. Check management
@@ -29,33 +38,130 @@ func (r *PVCReconciler) handleDeletion(ctx context.Context, logger logr.Logger,
 		return r.removeFinalizer(ctx, pvc)
 	}
 
-	// 2. Identify Share Name
+	// 2. Identify Share Name and PV
 	shareName := ""
 	if pvc.Annotations != nil {
 		shareName = pvc.Annotations[constants.ShareNameAnnotation]
 	}
 	if shareName == "" {
-		shareName, _ = naming.ComputeShareName(pvc.Namespace, pvc.Name, "")
+		shareName = r.fallbackShareName(ctx, pvc)
 	}
 
-	logger = logger.WithValues("share", shareName)
+	pv, err := r.findPV(ctx, pvc)
+	if err != nil {
+		return reconcile.Result{}, fmt.Errorf("find pv: %w", err)
+	}
+	location := r.deletionLocation(ctx, pvc, pv)
+	backingShare, directory := r.claimDirectory(ctx, pvc, pv)
+
+	logger = logger.WithValues("share", shareName, "storageAccount", location.StorageAccount)
 	logger.Info("cleanup started")
 	r.Recorder.Event(pvc, corev1.EventTypeNormal, constants.EventCleanupStarted, "Cleanup started for Azure File share")
+	if err := r.releaseCloneSnapshot(ctx, logger, pvc, location); err != nil {
+		return reconcile.Result{}, fmt.Errorf("release clone snapshot: %w", err)
+	}
 
-	// 3. Delete Azure Share
-	if shouldRetainShare(pvc) {
-		r.Recorder.Event(pvc, corev1.EventTypeNormal, constants.EventShareRetained, "Azure File share retained")
-	} else if r.Shares != nil && shareName != "" {
-		if err := r.Shares.DeleteShare(ctx, shareName); err != nil {
-			r.Recorder.Event(pvc, corev1.EventTypeWarning, constants.EventShareError, "Failed to delete Azure File share")
-			return reconcile.Result{}, fmt.Errorf("delete share: %w", err)
-		}
-		r.Recorder.Event(pvc, corev1.EventTypeNormal, constants.EventShareDeleted, "Azure File share deleted")
+	// 3. Resolve Reclaim Policy
+	policy, err := r.deletionReclaimPolicy(ctx, pvc, pv)
+	if err != nil {
+		return reconcile.Result{}, fmt.Errorf("resolve reclaim policy: %w", err)
 	}
+	logger.Info("reclaim policy resolved", "policy", policy)
 
-	// 4. Delete PV
-	if err := r.deletePV(ctx, pvc); err != nil {
-		return reconcile.Result{}, fmt.Errorf("delete pv: %w", err)
+	// 4. Retain or Delete
+	if policy == corev1.PersistentVolumeReclaimRetain {
+		if err := r.retainPV(ctx, pv); err != nil {
+			return reconcile.Result{}, fmt.Errorf("retain pv: %w", err)
+		}
+		r.Recorder.Event(pvc, corev1.EventTypeNormal, constants.EventShareRetained, "Azure File share retained; PersistentVolume will be Released")
+	} else if directory != "" {
+		if result, err := r.deleteClaimDirectory(ctx, logger, pvc, location, backingShare, directory); err != nil || !result.IsZero() {
+			return result, err
+		}
+		if err := r.deletePV(ctx, pv); err != nil {
+			return reconcile.Result{}, fmt.Errorf("delete pv: %w", err)
+		}
+	} else {
+		if shareName != "" {
+			shares, err := r.shareClientFor(location)
+			if err != nil {
+				r.Recorder.Event(pvc, corev1.EventTypeWarning, constants.EventShareClientMissing, "No share client for the claim's storage account")
+				return reconcile.Result{}, fmt.Errorf("resolve share client: %w", err)
+			}
+			// Only a share whose metadata shows it was created for this claim is deleted.
+			opts := &azure.DeleteShareOptions{RequireMetadata: shareMetadata(pvc, r.Config.ClusterID)}
+			key, snapshotPolicy := r.deletionSnapshotPolicy(ctx, pvc)
+			if err := k8s.CheckSnapshotPolicy(key, snapshotPolicy, r.Config.DeletionGracePeriod); err != nil {
+				// Deleting the share would take the snapshot with it; the claim waits for a grace period or another policy.
+				r.Recorder.Eventf(pvc, corev1.EventTypeWarning, constants.EventSnapshotPolicyInvalid, "Azure File share not deleted: %v", err)
+				return reconcile.Result{}, fmt.Errorf("check snapshot policy: %w", err)
+			}
+			var deadline time.Time
+			var snapshot string
+			contents, err := r.shareSnapshotContents(ctx, location, shareName)
+			switch {
+			case err != nil:
+				err = fmt.Errorf("list volumesnapshotcontents: %w", err)
+			case len(contents) > 0:
+				err = fmt.Errorf("VolumeSnapshotContents %s hold snapshots of share %q: %w", strings.Join(contents, ", "), shareName, ErrShareHasSnapshots)
+			case snapshotPolicy != k8s.SnapshotNone:
+				snapshot, err = r.snapshotShare(ctx, logger, pvc, shares, shareName, opts)
+			}
+			switch {
+			case err != nil, snapshotPolicy == k8s.SnapshotRetain:
+				// The snapshot failed, or it replaces the deletion.
+			case r.Config.DeletionGracePeriod > 0:
+				deadline, err = r.scheduleShareDeletion(ctx, shares, shareName, opts)
+			default:
+				err = shares.DeleteShare(ctx, shareName, opts)
+			}
+			if errors.Is(err, azure.ErrShareBeingDeleted) {
+				// An earlier attempt already started the deletion.
+				err = nil
+			}
+			switch {
+			case errors.Is(err, azure.ErrShareProvenanceMismatch):
+				// The share was not created for this claim (override, edited annotation or another cluster).
+				logger.Info("share not created for this claim; retaining", "reason", err.Error())
+				if err := r.retainPV(ctx, pv); err != nil {
+					return reconcile.Result{}, fmt.Errorf("retain pv: %w", err)
+				}
+				r.Recorder.Eventf(pvc, corev1.EventTypeWarning, constants.EventShareRetained, "Azure File share retained because it was not provisioned for this claim: %v", err)
+			case errors.Is(err, ErrShareHasSnapshots):
+				// Deleting the share would delete the snapshots under the VolumeSnapshots.
+				logger.Info("share has volume snapshots; retaining", "volumeSnapshotContents", contents)
+				if err := r.retainPV(ctx, pv); err != nil {
+					return reconcile.Result{}, fmt.Errorf("retain pv: %w", err)
+				}
+				r.Recorder.Eventf(pvc, corev1.EventTypeWarning, constants.EventShareRetained, "Azure File share retained because VolumeSnapshotContents %s hold snapshots of it", strings.Join(contents, ", "))
+			case err != nil:
+				reason := azureErrorReason(err)
+				r.Recorder.Eventf(pvc, corev1.EventTypeWarning, reason, "Failed to delete Azure File share: %v", err)
+				if delay, ok := azureRetryDelay(err); ok {
+					logger.Info("share deletion deferred", "reason", reason, "retryAfter", delay)
+					return reconcile.Result{RequeueAfter: delay}, nil
+				}
+				return reconcile.Result{}, fmt.Errorf("delete share: %w", err)
+			case snapshotPolicy == k8s.SnapshotRetain:
+				if err := r.retainPV(ctx, pv); err != nil {
+					return reconcile.Result{}, fmt.Errorf("retain pv: %w", err)
+				}
+				r.Recorder.Eventf(pvc, corev1.EventTypeNormal, constants.EventShareRetained, "Azure File share retained with snapshot %s; PersistentVolume will be Released", snapshot)
+			default:
+				if deadline.IsZero() {
+					r.Recorder.Event(pvc, corev1.EventTypeNormal, constants.EventShareDeleted, "Azure File share deleted")
+				} else {
+					logger.Info("share deletion scheduled", "deleteAfter", deadline)
+					r.Recorder.Eventf(pvc, corev1.EventTypeNormal, constants.EventShareScheduledForDeletion,
+						"Azure File share will be deleted after %s unless a claim re-claims it", deadline.Format(time.RFC3339))
+				}
+				if err := r.deletePV(ctx, pv); err != nil {
+					return reconcile.Result{}, fmt.Errorf("delete pv: %w", err)
+				}
+			}
+		} else if err := r.deletePV(ctx, pv); err != nil {
+			return reconcile.Result{}, fmt.Errorf("delete pv: %w", err)
+		}
 	}
 
 	// 5. Remove Finalizer
@@ -63,38 +169,192 @@ func (r *PVCReconciler) handleDeletion(ctx context.Context, logger logr.Logger,
 	return r.removeFinalizer(ctx, pvc)
 }
 
-func (r *PVCReconciler) deletePV(ctx context.Context, pvc *corev1.PersistentVolumeClaim) error {
+// fallbackShareName derives the generated share name, or the backing share of subdirectory classes, of a claim
+// deleted before its annotations were written.
+// Override annotations are ignored: a share the claim did not create is never looked up by guesswork.
+func (r *PVCReconciler) fallbackShareName(ctx context.Context, pvc *corev1.PersistentVolumeClaim) string {
+	var params k8s.ShareParameters
+	storageClass, err := k8s.GetStorageClass(ctx, r.Client, pvc)
+	if err == nil {
+		params, _ = k8s.ParseShareParameters(storageClass.Parameters)
+	}
+	if params.UsesSubdirectories() {
+		name, _ := backingShareName(pvc, storageClass.Name, params)
+		return name
+	}
+	if params.UniqueShareNames() {
+		name, _ := naming.ComputeUniqueShareName(pvc.Namespace, pvc.Name, string(pvc.UID))
+		return name
+	}
+	name, _ := naming.ComputeShareName(pvc.Namespace, pvc.Name, "")
+	return name
+}
+
+// deletionSnapshotPolicy resolves whether the share is snapshotted before it is deleted, and the key that set it.
+func (r *PVCReconciler) deletionSnapshotPolicy(ctx context.Context, pvc *corev1.PersistentVolumeClaim) (key, policy string) {
+	var params k8s.ShareParameters
+	if storageClass, err := k8s.GetStorageClass(ctx, r.Client, pvc); err == nil {
+		params, _ = k8s.ParseShareParameters(storageClass.Parameters)
+	}
+	return k8s.ClaimSnapshotPolicy(pvc, params)
+}
+
+// snapshotShare takes the deletion snapshot of a share created for the claim and returns its ID.
+// The ID is recorded on the claim first, so a retried deletion does not snapshot again, then in the
+// share's metadata. A missing share returns an empty ID. With the 'retain' policy the share is then kept;
+// with 'true' its deletion is scheduled, so the snapshot lives until the ShareReaper deletes the share.
+func (r *PVCReconciler) snapshotShare(ctx context.Context, logger logr.Logger, pvc *corev1.PersistentVolumeClaim, shares azure.ShareClient, shareName string, opts *azure.DeleteShareOptions) (string, error) {
+	props, err := shares.GetShare(ctx, shareName)
+	if errors.Is(err, azure.ErrShareNotFound) {
+		return "", nil
+	}
+	if err != nil {
+		return "", err
+	}
+	if err := azure.CheckProvenance(shareName, props.Metadata, opts); err != nil {
+		return "", err
+	}
+
+	snapshot := pvc.Annotations[constants.DeletionSnapshotAnnotation]
+	if snapshot == "" {
+		snapshot, err = shares.CreateSnapshot(ctx, shareName)
+		if err != nil {
+			return "", fmt.Errorf("snapshot share: %w", err)
+		}
+		logger.Info("share snapshot created", "snapshot", snapshot)
+		r.Recorder.Eventf(pvc, corev1.EventTypeNormal, constants.EventShareSnapshotCreated, "Created snapshot %s of Azure File share %s before its deletion", snapshot, shareName)
+
+		patch := client.MergeFrom(pvc.DeepCopy())
+		if pvc.Annotations == nil {
+			pvc.Annotations = map[string]string{}
+		}
+		pvc.Annotations[constants.DeletionSnapshotAnnotation] = snapshot
+		if err := r.Client.Patch(ctx, pvc, patch); err != nil {
+			return "", fmt.Errorf("patch pvc annotations: %w", err)
+		}
+	}
+
+	if props.Metadata[azure.MetadataDeletionSnapshot] != snapshot {
+		metadata := maps.Clone(props.Metadata)
+		if metadata == nil {
+			metadata = map[string]string{}
+		}
+		metadata[azure.MetadataDeletionSnapshot] = snapshot
+		if err := shares.SetShareMetadata(ctx, shareName, metadata); err != nil {
+			return "", fmt.Errorf("record snapshot in share metadata: %w", err)
+		}
+	}
+	return snapshot, nil
+}
+
+// shareSnapshotContents returns the names of the VolumeSnapshotContents of the driver whose snapshot, or
+// source volume, is the share in the account. Contents are only listed when volume snapshots are enabled.
+func (r *PVCReconciler) shareSnapshotContents(ctx context.Context, location shareLocation, shareName string) ([]string, error) {
+	if !r.Config.VolumeSnapshots {
+		return nil, nil
+	}
+	contentList := &snapshotv1.VolumeSnapshotContentList{}
+	if err := r.Client.List(ctx, contentList); err != nil {
+		return nil, err
+	}
+
+	var names []string
+	for _, content := range contentList.Items {
+		if content.Spec.Driver != k8s.ManagedProvisioner {
+			continue
+		}
+		var resourceGroup, account, contentShare string
+		var err error
+		switch source := content.Spec.Source; {
+		case content.Status != nil && content.Status.SnapshotHandle != nil:
+			resourceGroup, account, contentShare, _, err = k8s.ParseSnapshotHandle(*content.Status.SnapshotHandle)
+		case source.SnapshotHandle != nil:
+			resourceGroup, account, contentShare, _, err = k8s.ParseSnapshotHandle(*source.SnapshotHandle)
+		case source.VolumeHandle != nil:
+			resourceGroup, account, contentShare, err = k8s.ParseVolumeHandle(*source.VolumeHandle)
+		default:
+			continue
+		}
+		if err == nil && resourceGroup == location.ResourceGroup && account == location.StorageAccount && contentShare == shareName {
+			names = append(names, content.Name)
+		}
+	}
+	return names, nil
+}
+
+// findPV returns the PV provisioned for the PVC, or nil when it does not exist or belongs to another claim.
+// PVs are looked up by their claim labels because the PV name depends on the storage account.
+func (r *PVCReconciler) findPV(ctx context.Context, pvc *corev1.PersistentVolumeClaim) (*corev1.PersistentVolume, error) {
 	if pvc == nil {
-		return nil
+		return nil, nil
 	}
 	shareName := ""
 	if pvc.Annotations != nil {
 		shareName = pvc.Annotations[constants.ShareNameAnnotation]
 	}
 	if shareName == "" {
-		return nil
+		return nil, nil
 	}
-	pv, err := k8s.BuildPV(pvc, shareName, r.Config.ResourceGroup, r.Config.StorageAccount, r.Config.Server, corev1.PersistentVolumeReclaimDelete)
-	if err != nil {
-		return fmt.Errorf("build pv: %w", err)
+
+	pvList := &corev1.PersistentVolumeList{}
+	if err := r.Client.List(ctx, pvList, client.MatchingLabels{
+		k8s.LabelPVCNamespace: pvc.Namespace,
+		k8s.LabelPVCName:      pvc.Name,
+	}); err != nil {
+		return nil, fmt.Errorf("list pvs: %w", err)
+	}
+	for i := range pvList.Items {
+		if pvMatches(&pvList.Items[i], pvc, shareName) {
+			return &pvList.Items[i], nil
+		}
+	}
+	return nil, nil
+}
+
+// deletionReclaimPolicy resolves how a deleted claim's share is reclaimed.
+// The 'retain-share' annotation wins, then the policy recorded on the PV, then the StorageClass.
+func (r *PVCReconciler) deletionReclaimPolicy(ctx context.Context, pvc *corev1.PersistentVolumeClaim, pv *corev1.PersistentVolume) (corev1.PersistentVolumeReclaimPolicy, error) {
+	if policy, ok := reclaimPolicyOverride(pvc); ok {
+		return policy, nil
+	}
+	if pv != nil && pv.Spec.PersistentVolumeReclaimPolicy != "" {
+		return pv.Spec.PersistentVolumeReclaimPolicy, nil
 	}
 
-	existing := &corev1.PersistentVolume{}
-	if err := r.Client.Get(ctx, client.ObjectKey{Name: pv.Name}, existing); err != nil {
+	storageClass, err := k8s.GetStorageClass(ctx, r.Client, pvc)
+	if err != nil {
 		if apierrors.IsNotFound(err) {
-			return nil
+			return corev1.PersistentVolumeReclaimDelete, nil
 		}
-		return fmt.Errorf("get pv: %w", err)
+		return "", fmt.Errorf("get storageclass: %w", err)
 	}
-	if !pvMatches(existing, pvc, shareName) {
+	return k8s.ReclaimPolicy(storageClass), nil
+}
+
+func (r *PVCReconciler) deletePV(ctx context.Context, pv *corev1.PersistentVolume) error {
+	if pv == nil {
 		return nil
 	}
-	if err := r.Client.Delete(ctx, existing); err != nil {
+	if err := r.Client.Delete(ctx, pv); err != nil && !apierrors.IsNotFound(err) {
 		return fmt.Errorf("delete pv: %w", err)
 	}
 	return nil
 }
 
+// retainPV makes sure a PV left behind for a retained share carries the Retain policy,
+// so that it stays Released instead of waiting for a deleter.
+func (r *PVCReconciler) retainPV(ctx context.Context, pv *corev1.PersistentVolume) error {
+	if pv == nil || pv.Spec.PersistentVolumeReclaimPolicy == corev1.PersistentVolumeReclaimRetain {
+		return nil
+	}
+	patch := client.MergeFrom(pv.DeepCopy())
+	pv.Spec.PersistentVolumeReclaimPolicy = corev1.PersistentVolumeReclaimRetain
+	if err := r.Client.Patch(ctx, pv, patch); err != nil {
+		return fmt.Errorf("patch pv reclaim policy: %w", err)
+	}
+	return nil
+}
+
 func (r *PVCReconciler) removeFinalizer(ctx context.Context, pvc *corev1.PersistentVolumeClaim) (reconcile.Result, error) {
 	kept := pvc.Finalizers[:0]
 	for _, f := range pvc.Finalizers {
@@ -114,9 +374,30 @@ func (r *PVCReconciler) removeFinalizer(ctx context.Context, pvc *corev1.Persist
 	return reconcile.Result{}, nil
 }
 
-func shouldRetainShare(pvc *corev1.PersistentVolumeClaim) bool {
+// reclaimPolicyOverride reads the per-PVC 'retain-share' annotation.
+// "true" forces Retain, "false" forces Delete; anything else defers to the StorageClass.
+func reclaimPolicyOverride(pvc *corev1.PersistentVolumeClaim) (corev1.PersistentVolumeReclaimPolicy, bool) {
 	if pvc == nil || pvc.Annotations == nil {
-		return false
+		return "", false
+	}
+	switch pvc.Annotations[constants.RetainShareAnnotation] {
+	case "true":
+		return corev1.PersistentVolumeReclaimRetain, true
+	case "false":
+		return corev1.PersistentVolumeReclaimDelete, true
+	default:
+		return "", false
+	}
+}
+
+// reclaimPolicyFor returns the reclaim policy for a new PV: Retain for adopted shares whose ownership was not
+// transferred, then the annotation override, else the StorageClass policy.
+func reclaimPolicyFor(pvc *corev1.PersistentVolumeClaim, sc *storagev1.StorageClass, params k8s.ShareParameters) corev1.PersistentVolumeReclaimPolicy {
+	if adoptsShare(pvc) && !transfersOwnership(pvc, params) {
+		return corev1.PersistentVolumeReclaimRetain
+	}
+	if policy, ok := reclaimPolicyOverride(pvc); ok {
+		return policy
 	}
-	return pvc.Annotations[constants.RetainShareAnnotation] == "true"
+	return k8s.ReclaimPolicy(sc)
 }
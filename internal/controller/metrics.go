@@ -4,12 +4,16 @@ import (
 	"errors"
 
 	"github.com/prometheus/client_golang/prometheus"
+
+	"aks-azureFiles-controller/internal/azure"
 )
 
 // ReconcileMetrics captures controller reconcile metrics.
 type ReconcileMetrics struct {
-	total    *prometheus.CounterVec
-	duration *prometheus.HistogramVec
+	total       *prometheus.CounterVec
+	duration    *prometheus.HistogramVec
+	pvMismatch  *prometheus.CounterVec
+	softDeleted *prometheus.GaugeVec
 }
 
 // NewReconcileMetrics builds the metrics definitions.
@@ -30,6 +34,20 @@ func NewReconcileMetrics() *ReconcileMetrics {
 			},
 			[]string{"result"},
 		),
+		pvMismatch: prometheus.NewCounterVec(
+			prometheus.CounterOpts{
+				Name: "pv_mismatch_total",
+				Help: "Total number of mismatched PVs by remediation action.",
+			},
+			[]string{"action"},
+		),
+		softDeleted: prometheus.NewGaugeVec(
+			prometheus.GaugeOpts{
+				Name: "soft_deleted_share_retention_days",
+				Help: "Remaining retention days of soft-deleted shares, by the claim that created them.",
+			},
+			[]string{"storage_account", "share", "version", "owner_namespace", "pvc_uid"},
+		),
 	}
 }
 
@@ -42,16 +60,12 @@ func (m *ReconcileMetrics) Register(registerer prometheus.Registerer) error {
 		return errors.New("metrics registerer is nil")
 	}
 
-	if err := registerer.Register(m.total); err != nil {
-		var already prometheus.AlreadyRegisteredError
-		if !errors.As(err, &already) {
-			return err
-		}
-	}
-	if err := registerer.Register(m.duration); err != nil {
-		var already prometheus.AlreadyRegisteredError
-		if !errors.As(err, &already) {
-			return err
+	for _, collector := range []prometheus.Collector{m.total, m.duration, m.pvMismatch, m.softDeleted} {
+		if err := registerer.Register(collector); err != nil {
+			var already prometheus.AlreadyRegisteredError
+			if !errors.As(err, &already) {
+				return err
+			}
 		}
 	}
 	return nil
@@ -65,3 +79,23 @@ func (m *ReconcileMetrics) Observe(result string, seconds float64) {
 	m.total.WithLabelValues(result).Inc()
 	m.duration.WithLabelValues(result).Observe(seconds)
 }
+
+// ObservePVMismatch records the remediation action taken for a mismatched PV.
+func (m *ReconcileMetrics) ObservePVMismatch(action string) {
+	if m == nil {
+		return
+	}
+	m.pvMismatch.WithLabelValues(action).Inc()
+}
+
+// ObserveSoftDeletedShares replaces the soft-deleted shares reported for a storage account.
+func (m *ReconcileMetrics) ObserveSoftDeletedShares(account string, shares []azure.DeletedShare) {
+	if m == nil {
+		return
+	}
+	m.softDeleted.DeletePartialMatch(prometheus.Labels{"storage_account": account})
+	for _, share := range shares {
+		m.softDeleted.WithLabelValues(account, share.Name, share.Version,
+			share.Metadata[azure.MetadataOwnerNamespace], share.Metadata[azure.MetadataPVCUID]).Set(float64(share.RemainingRetentionDays))
+	}
+}
@@ -0,0 +1,126 @@
+package controller
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/go-logr/logr"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	"aks-azureFiles-controller/internal/constants"
+)
+
+// PVMismatchPolicy decides what happens when the PV named for a claim does not match it.
+type PVMismatchPolicy string
+
+const (
+	// PVMismatchHalt stops provisioning with a terminal condition until the PV is fixed by hand.
+	PVMismatchHalt PVMismatchPolicy = "halt"
+	// PVMismatchRecreate deletes the PV and builds it again when no claim is bound to it.
+	PVMismatchRecreate PVMismatchPolicy = "recreate"
+	// PVMismatchAdopt points the PV's ClaimRef at the claim when its volume source already matches.
+	PVMismatchAdopt PVMismatchPolicy = "adopt"
+)
+
+// pvRemediationRequeue is how long to wait before checking a recreated or adopted PV again.
+const pvRemediationRequeue = 5 * time.Second
+
+// remediatePVMismatch applies the configured PVMismatchPolicy to an existing PV that failed pvMatches.
+// Recreate and adopt fall back to halt when they are not safe for the PV.
+func (r *PVCReconciler) remediatePVMismatch(ctx context.Context, logger logr.Logger, pvc *corev1.PersistentVolumeClaim, existing, expected *corev1.PersistentVolume, outcome *string) (reconcile.Result, error) {
+	mismatch := claimCondition(constants.ConditionPVBound, corev1.ConditionFalse, constants.EventPVMismatch, fmt.Sprintf("PersistentVolume %s does not match the expected share or claim", existing.Name))
+	if err := r.setConditions(ctx, pvc, mismatch); err != nil {
+		return reconcile.Result{}, err
+	}
+	if existing.DeletionTimestamp != nil {
+		// A PV deleted by an earlier recreate is still held by its protection finalizer.
+		*outcome = "wait"
+		return reconcile.Result{RequeueAfter: pvRemediationRequeue}, nil
+	}
+
+	policy := r.Config.PVMismatchPolicy
+	var refused string
+	switch policy {
+	case PVMismatchRecreate:
+		if refused = recreateBlocker(existing); refused == "" {
+			return r.recreatePV(ctx, logger, pvc, existing)
+		}
+	case PVMismatchAdopt:
+		if refused = adoptBlocker(existing, expected); refused == "" {
+			return r.adoptPV(ctx, logger, pvc, existing, expected)
+		}
+	}
+
+	r.Metrics.ObservePVMismatch(string(PVMismatchHalt))
+	*outcome = "terminal"
+	err := fmt.Errorf("pv %s does not match claim %s/%s: %w", existing.Name, pvc.Namespace, pvc.Name, ErrPVMismatch)
+	if refused != "" {
+		err = fmt.Errorf("%w; %s refused: %s", err, policy, refused)
+	}
+	return r.terminalError(ctx, logger, pvc, constants.EventPVMismatchHalted, err)
+}
+
+// recreateBlocker returns why the PV must not be deleted, or "" when no claim is bound to it. Bound PVs may be
+// mounted, and Released PVs with reclaim policy Retain are kept for their data.
+func recreateBlocker(pv *corev1.PersistentVolume) string {
+	if pv.Spec.CSI == nil || pv.Spec.CSI.Driver != constants.AzureFileCSIDriver {
+		return "not an Azure File CSI volume"
+	}
+	if pv.Status.Phase == corev1.VolumeReleased && pv.Spec.PersistentVolumeReclaimPolicy == corev1.PersistentVolumeReclaimRetain {
+		return "released with reclaim policy Retain"
+	}
+	if pv.Spec.ClaimRef == nil || pv.Status.Phase == corev1.VolumeAvailable || pv.Status.Phase == corev1.VolumeReleased {
+		return ""
+	}
+	return fmt.Sprintf("bound to claim %s/%s", pv.Spec.ClaimRef.Namespace, pv.Spec.ClaimRef.Name)
+}
+
+// adoptBlocker returns why the PV cannot be rebound to the claim, or "" when only its ClaimRef is stale.
+// The volume source of a PV is immutable, so a PV pointing at another share cannot be adopted.
+func adoptBlocker(pv, expected *corev1.PersistentVolume) string {
+	if pv.Spec.CSI == nil || pv.Spec.CSI.Driver != constants.AzureFileCSIDriver {
+		return "not an Azure File CSI volume"
+	}
+	if pv.Spec.CSI.VolumeHandle != expected.Spec.CSI.VolumeHandle || pv.Spec.CSI.VolumeAttributes["shareName"] != expected.Spec.CSI.VolumeAttributes["shareName"] {
+		return "volume source points at another share"
+	}
+	ref := pv.Spec.ClaimRef
+	if ref != nil && (ref.Namespace != expected.Spec.ClaimRef.Namespace || ref.Name != expected.Spec.ClaimRef.Name) {
+		return fmt.Sprintf("bound to claim %s/%s", ref.Namespace, ref.Name)
+	}
+	return ""
+}
+
+// recreatePV deletes the mismatched PV; a later reconcile builds it again.
+func (r *PVCReconciler) recreatePV(ctx context.Context, logger logr.Logger, pvc *corev1.PersistentVolumeClaim, existing *corev1.PersistentVolume) (reconcile.Result, error) {
+	if err := r.Client.Delete(ctx, existing, client.Preconditions{UID: &existing.UID}); err != nil && !apierrors.IsNotFound(err) {
+		return reconcile.Result{}, fmt.Errorf("delete mismatched pv: %w", err)
+	}
+	r.Metrics.ObservePVMismatch(string(PVMismatchRecreate))
+	r.Recorder.Eventf(pvc, corev1.EventTypeNormal, constants.EventPVRecreated, "Deleted mismatched PersistentVolume %s to build it again", existing.Name)
+	logger.Info("deleted mismatched pv")
+	return reconcile.Result{RequeueAfter: pvRemediationRequeue}, nil
+}
+
+// adoptPV binds the PV to the claim by rewriting its ClaimRef and PVC labels.
+func (r *PVCReconciler) adoptPV(ctx context.Context, logger logr.Logger, pvc *corev1.PersistentVolumeClaim, existing, expected *corev1.PersistentVolume) (reconcile.Result, error) {
+	patch := client.MergeFromWithOptions(existing.DeepCopy(), client.MergeFromWithOptimisticLock{})
+	existing.Spec.ClaimRef = expected.Spec.ClaimRef.DeepCopy()
+	if existing.Labels == nil {
+		existing.Labels = map[string]string{}
+	}
+	for key, value := range expected.Labels {
+		existing.Labels[key] = value
+	}
+	if err := r.Client.Patch(ctx, existing, patch); err != nil {
+		return reconcile.Result{}, fmt.Errorf("adopt pv: %w", err)
+	}
+	r.Metrics.ObservePVMismatch(string(PVMismatchAdopt))
+	r.Recorder.Eventf(pvc, corev1.EventTypeNormal, constants.EventPVAdopted, "Adopted PersistentVolume %s for the claim", existing.Name)
+	logger.Info("adopted mismatched pv")
+	return reconcile.Result{RequeueAfter: pvRemediationRequeue}, nil
+}
@@ -0,0 +1,87 @@
+package controller
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"maps"
+
+	"github.com/go-logr/logr"
+	corev1 "k8s.io/api/core/v1"
+
+	"aks-azureFiles-controller/internal/azure"
+	"aks-azureFiles-controller/internal/constants"
+	"aks-azureFiles-controller/internal/k8s"
+)
+
+var ErrShareOwnershipConflict = errors.New("share owned by another namespace")
+
+// shareMetadata tags a share with the provisioner, cluster and claim that created it, or restored it.
+// Deletion requires the same metadata, so only shares created for the claim are removed.
+func shareMetadata(pvc *corev1.PersistentVolumeClaim, clusterID string) map[string]string {
+	metadata := map[string]string{
+		azure.MetadataProvisionedBy:  k8s.ManagedProvisioner,
+		azure.MetadataOwnerNamespace: pvc.Namespace,
+		azure.MetadataPVCUID:         string(pvc.UID),
+	}
+	if clusterID != "" {
+		metadata[azure.MetadataClusterID] = clusterID
+	}
+	return metadata
+}
+
+// checkShareOwnership refuses shares tagged by another namespace, so that a share-override annotation
+// cannot expose (or later delete) another team's data. Untagged shares, shares of the claim's own
+// namespace and owners allow-listed by the class are accepted. Backing shares of subdirectory classes
+// hold the directories of many claims and are only accepted for claims of their own class, which in
+// turn only accept their own backing share.
+func checkShareOwnership(pvc *corev1.PersistentVolumeClaim, props *azure.ShareProperties, params k8s.ShareParameters) error {
+	backingClass := props.Metadata[azure.MetadataBackingShareClass]
+	switch {
+	case params.UsesSubdirectories() && (backingClass == "" || backingClass != k8s.StorageClassName(pvc)):
+		return fmt.Errorf("share %q is not the backing share of the claim's class: %w", props.Name, ErrShareOwnershipConflict)
+	case !params.UsesSubdirectories() && backingClass != "":
+		return fmt.Errorf("share %q is the backing share of class %q: %w", props.Name, backingClass, ErrShareOwnershipConflict)
+	}
+	owner := props.Metadata[azure.MetadataOwnerNamespace]
+	if owner == "" || owner == pvc.Namespace || params.AllowsOwnerNamespace(owner) {
+		return nil
+	}
+	return fmt.Errorf("share %q belongs to namespace %q: %w", props.Name, owner, ErrShareOwnershipConflict)
+}
+
+// backfillShareMetadata tags a share created before provenance metadata existed with the claim, so the
+// claim's reclaim policy can delete it again. Only a share the claim is bound to under its generated name
+// is tagged: an untagged share reached through an override may hold someone else's data.
+func (r *PVCReconciler) backfillShareMetadata(ctx context.Context, logger logr.Logger, pvc *corev1.PersistentVolumeClaim, shares azure.ShareClient, props *azure.ShareProperties, params k8s.ShareParameters) error {
+	if props.Metadata[azure.MetadataProvisionedBy] != "" || pvc.Annotations[constants.ShareOverrideAnnotation] != "" {
+		return nil
+	}
+	generated, err := shareNameFor(pvc, params)
+	if err != nil || generated != props.Name {
+		return nil
+	}
+	pv, err := r.boundPV(ctx, pvc)
+	if err != nil {
+		return err
+	}
+	if pv == nil {
+		return nil
+	}
+	if _, _, shareName, directory, err := k8s.ParseDirectoryVolumeHandle(pv.Spec.CSI.VolumeHandle); err != nil || shareName != props.Name || directory != "" {
+		return nil
+	}
+
+	metadata := maps.Clone(props.Metadata)
+	if metadata == nil {
+		metadata = map[string]string{}
+	}
+	maps.Copy(metadata, shareMetadata(pvc, r.Config.ClusterID))
+	if err := shares.SetShareMetadata(ctx, props.Name, metadata); err != nil {
+		return fmt.Errorf("backfill share metadata: %w", err)
+	}
+	props.Metadata = metadata
+	logger.Info("backfilled share metadata")
+	r.Recorder.Eventf(pvc, corev1.EventTypeNormal, constants.EventShareMetadataBackfilled, "Tagged Azure File share %s, created before provenance metadata, with the claim", props.Name)
+	return nil
+}
@@ -0,0 +1,158 @@
+package controller
+
+import (
+	"context"
+	"fmt"
+	"math"
+
+	"github.com/go-logr/logr"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"aks-azureFiles-controller/internal/azure"
+	"aks-azureFiles-controller/internal/constants"
+	"aks-azureFiles-controller/internal/k8s"
+)
+
+// placeFromPool picks the least-loaded pool account for a new claim and records the choice on the PVC
+// before any Azure call, so retries and deletion resolve the same account.
+func (r *PVCReconciler) placeFromPool(ctx context.Context, logger logr.Logger, pvc *corev1.PersistentVolumeClaim, shareName string, quotaGiB int32, region string) (shareLocation, error) {
+	usage, err := r.accountUsage(ctx)
+	if err != nil {
+		return shareLocation{}, fmt.Errorf("compute account usage: %w", err)
+	}
+
+	account, err := r.Placer.Select(usage, quotaGiB, region)
+	if err != nil {
+		r.Recorder.Event(pvc, corev1.EventTypeWarning, constants.EventAccountPoolFull, "No storage account in the pool has capacity for this claim")
+		return shareLocation{}, fmt.Errorf("select storage account: %w", err)
+	}
+
+	location := r.locationFor(account.ResourceGroup, account.Name)
+	location.Region = account.Region
+	patch := client.MergeFrom(pvc.DeepCopy())
+	if pvc.Annotations == nil {
+		pvc.Annotations = map[string]string{}
+	}
+	pvc.Annotations[constants.VolumeHandleAnnotation] = k8s.VolumeHandle(location.ResourceGroup, location.StorageAccount, shareName)
+	if err := r.Client.Patch(ctx, pvc, patch); err != nil {
+		return shareLocation{}, fmt.Errorf("record storage account: %w", err)
+	}
+
+	logger.Info("placed claim", "storageAccount", account.Name, "current", usage[account.Name])
+	r.Recorder.Eventf(pvc, corev1.EventTypeNormal, constants.EventAccountSelected, "Selected storage account %q from the pool", account.Name)
+	return location, nil
+}
+
+// shareDemand collects the claims on one share to derive the quota provisioning gave it.
+type shareDemand struct {
+	params       k8s.ShareParameters
+	requestedGiB int64
+	directories  bool
+	adopted      bool
+}
+
+// quotaGiB mirrors the quota provisioning sets: the request, summed over the directories of a backing share,
+// raised to the class floors. Adopted shares keep the quota they came with, approximated by the request.
+func (d *shareDemand) quotaGiB() int64 {
+	if d.adopted || d.requestedGiB > math.MaxInt32 {
+		return d.requestedGiB
+	}
+	return int64(d.params.EffectiveQuotaGiB(int32(d.requestedGiB)))
+}
+
+// accountUsage sums provisioned quota, share count and estimated IOPS per storage account over the shares
+// this controller provisioned. Each share counts once with the quota derived from its claims and their class;
+// claims placed on an account but without a PV yet count as well, so concurrent placements see each other.
+// PVs whose claim is gone count with their capacity, as their class floors can no longer be told.
+func (r *PVCReconciler) accountUsage(ctx context.Context) (map[string]azure.AccountUsage, error) {
+	pvList := &corev1.PersistentVolumeList{}
+	if err := r.Client.List(ctx, pvList, client.HasLabels{k8s.LabelShareName}); err != nil {
+		return nil, fmt.Errorf("list pvs: %w", err)
+	}
+	pvcList := &corev1.PersistentVolumeClaimList{}
+	if err := r.Client.List(ctx, pvcList); err != nil {
+		return nil, fmt.Errorf("list pvcs: %w", err)
+	}
+	claims := make(map[types.UID]*corev1.PersistentVolumeClaim, len(pvcList.Items))
+	for i := range pvcList.Items {
+		claims[pvcList.Items[i].UID] = &pvcList.Items[i]
+	}
+
+	classes := map[string]k8s.ShareParameters{}
+	paramsFor := func(pvc *corev1.PersistentVolumeClaim) k8s.ShareParameters {
+		name := k8s.StorageClassName(pvc)
+		if params, ok := classes[name]; ok {
+			return params
+		}
+		var params k8s.ShareParameters
+		if storageClass, err := k8s.GetStorageClass(ctx, r.Client, pvc); err == nil && storageClass != nil {
+			params, _ = k8s.ParseShareParameters(storageClass.Parameters)
+		}
+		classes[name] = params
+		return params
+	}
+
+	demands := map[string]map[string]*shareDemand{}
+	add := func(account, shareName, directory string, pvc *corev1.PersistentVolumeClaim, requestedGiB int64) {
+		if demands[account] == nil {
+			demands[account] = map[string]*shareDemand{}
+		}
+		demand, ok := demands[account][shareName]
+		if !ok {
+			demand = &shareDemand{}
+			if pvc != nil {
+				demand.params = paramsFor(pvc)
+				demand.adopted = adoptsShare(pvc)
+			}
+			demands[account][shareName] = demand
+		}
+		switch {
+		case directory != "":
+			demand.directories = true
+			demand.requestedGiB += requestedGiB
+		case !demand.directories && requestedGiB > demand.requestedGiB:
+			demand.requestedGiB = requestedGiB
+		}
+	}
+
+	placed := map[types.UID]bool{}
+	for _, pv := range pvList.Items {
+		if pv.Spec.CSI == nil || pv.Spec.CSI.Driver != constants.AzureFileCSIDriver {
+			continue
+		}
+		_, account, shareName, directory, err := k8s.ParseDirectoryVolumeHandle(pv.Spec.CSI.VolumeHandle)
+		if err != nil {
+			continue
+		}
+		var pvc *corev1.PersistentVolumeClaim
+		if pv.Spec.ClaimRef != nil {
+			pvc = claims[pv.Spec.ClaimRef.UID]
+			placed[pv.Spec.ClaimRef.UID] = true
+		}
+		add(account, shareName, directory, pvc, k8s.CeilGiB(pv.Spec.Capacity[corev1.ResourceStorage]))
+	}
+	for _, pvc := range claims {
+		if placed[pvc.UID] {
+			continue
+		}
+		_, account, shareName, directory, err := k8s.ParseDirectoryVolumeHandle(pvc.Annotations[constants.VolumeHandleAnnotation])
+		if err != nil {
+			continue
+		}
+		requestedGiB, err := k8s.QuotaGiBFromPVC(pvc)
+		if err != nil {
+			continue
+		}
+		add(account, shareName, directory, pvc, int64(requestedGiB))
+	}
+
+	usage := map[string]azure.AccountUsage{}
+	for account, shares := range demands {
+		for _, demand := range shares {
+			usage[account] = usage[account].Add(demand.quotaGiB())
+		}
+	}
+	return usage, nil
+}
@@ -19,12 +19,12 @@ import (
 
 // handleProvisioning manages the creation lifecycle of an Azure File share and its corresponding Kubernetes PV.
 // Flow:
-// 1. Validate StorageClass and Provisioner.
+// 1. Validate StorageClass, Provisioner, parameters and the claim.
 // 2. Ensure Finalizer exists on PVC.
-// 3. Compute Share Name (honoring overrides).
-// 4. Ensure Azure File Share exists (idempotent).
+// 3. Resolve the storage account and compute the Share Name (honoring overrides).
+// 4. Ensure Azure File Share exists (idempotent) and holds the claim's data.
 // 5. Ensure Kubernetes PersistentVolume exists and is bound to the share.
-// 6. Annotate PVC with the final share name.
+// 6. Annotate PVC with the final share name and report binding.
 func (r *PVCReconciler) handleProvisioning(ctx context.Context, logger logr.Logger, pvc *corev1.PersistentVolumeClaim, outcome *string) (reconcile.Result, error) {
 	// 1. Validate StorageClass and Provisioner
 	if !k8s.IsManagedPVC(pvc) {
@@ -36,7 +36,9 @@ func (r *PVCReconciler) handleProvisioning(ctx context.Context, logger logr.Logg
 	storageClass, err := k8s.GetStorageClass(ctx, r.Client, pvc)
 	if err != nil {
 		if apierrors.IsNotFound(err) {
+			// The StorageClass watch requeues the claim once the class is created.
 			logger.WithValues("reason", "storageclass not found").Info("skip pvc")
+			*outcome = "wait"
 			return reconcile.Result{}, nil
 		}
 		return reconcile.Result{}, fmt.Errorf("get storageclass: %w", err)
@@ -49,53 +51,213 @@ func (r *PVCReconciler) handleProvisioning(ctx context.Context, logger logr.Logg
 		return reconcile.Result{}, nil
 	}
 
+	params, err := k8s.ParseShareParameters(storageClass.Parameters)
+	if err != nil {
+		*outcome = "terminal"
+		return r.terminalError(ctx, logger, pvc, constants.EventShareValidation, fmt.Errorf("parse storageclass %q parameters: %w", storageClass.Name, err))
+	}
+
+	if err := k8s.ValidateClaim(pvc); err != nil {
+		*outcome = "terminal"
+		return r.terminalError(ctx, logger, pvc, constants.EventPVCInvalid, fmt.Errorf("validate pvc: %w", err))
+	}
+	if params.UsesSubdirectories() {
+		if err := checkSubdirectoryClaim(pvc); err != nil {
+			*outcome = "terminal"
+			return r.terminalError(ctx, logger, pvc, constants.EventPVCInvalid, fmt.Errorf("validate pvc: %w", err))
+		}
+	}
+	key, snapshotPolicy := k8s.ClaimSnapshotPolicy(pvc, params)
+	if err := k8s.CheckSnapshotPolicy(key, snapshotPolicy, r.Config.DeletionGracePeriod); err != nil {
+		*outcome = "terminal"
+		return r.terminalError(ctx, logger, pvc, constants.EventSnapshotPolicyInvalid, fmt.Errorf("validate snapshot policy: %w", err))
+	}
+
+	pvOpts := []k8s.PVOption{k8s.WithProtocol(params.Protocol)}
+	var topology k8s.Topology
+	if k8s.IsWaitForFirstConsumer(storageClass) && pvc.Spec.VolumeName == "" {
+		var ready bool
+		topology, ready, err = r.selectedNodeTopology(ctx, logger, pvc)
+		if err != nil {
+			return reconcile.Result{}, err
+		}
+		if !ready {
+			*outcome = "wait"
+			waiting := claimCondition(constants.ConditionShareReady, corev1.ConditionFalse, constants.EventWaitingForConsumer, "Waiting for the scheduler to select a node")
+			return reconcile.Result{}, r.setConditions(ctx, pvc, waiting)
+		}
+		pvOpts = append(pvOpts, k8s.WithTopology(topology))
+	}
+
 	// 2. Ensure Finalizer exists
 	if err := r.ensureFinalizer(ctx, pvc); err != nil {
 		return reconcile.Result{}, fmt.Errorf("ensure finalizer: %w", err)
 	}
 
-	// 3. Compute Share Name
-	shareOverride := ""
-	if pvc.Annotations != nil {
-		shareOverride = pvc.Annotations[constants.ShareOverrideAnnotation]
+	// 3. Resolve Storage Account and Share Name
+	location, pooled, err := r.resolveLocation(ctx, pvc, params)
+	if err != nil && !errors.Is(err, ErrStorageAccountNotAllowed) {
+		return reconcile.Result{}, fmt.Errorf("resolve storage account: %w", err)
 	}
-
-	shareName, err := naming.ComputeShareName(pvc.Namespace, pvc.Name, shareOverride)
 	if err != nil {
 		*outcome = "terminal"
-		return r.terminalError(logger, pvc, constants.EventShareNameInvalid, fmt.Errorf("compute share name: %w", err))
+		return r.terminalError(ctx, logger, pvc, constants.EventAccountNotAllowed, fmt.Errorf("resolve storage account: %w", err))
+	}
+	shareName, err := shareNameFor(pvc, params)
+	if err != nil {
+		*outcome = "terminal"
+		return r.terminalError(ctx, logger, pvc, constants.EventShareNameInvalid, fmt.Errorf("compute share name: %w", err))
+	}
+	var directory string
+	if params.UsesSubdirectories() {
+		if directory, err = claimDirectoryName(pvc); err != nil {
+			*outcome = "terminal"
+			return r.terminalError(ctx, logger, pvc, constants.EventShareNameInvalid, fmt.Errorf("compute claim directory: %w", err))
+		}
+		shareName, err = backingShareName(pvc, storageClass.Name, params)
+		if err != nil {
+			*outcome = "terminal"
+			return r.terminalError(ctx, logger, pvc, constants.EventShareNameInvalid, fmt.Errorf("compute backing share name: %w", err))
+		}
+		pvOpts = append(pvOpts, k8s.WithSubdirectory(directory))
 	}
 
-	quotaGiB, err := k8s.QuotaGiBFromPVC(pvc)
+	requestedGiB, err := k8s.QuotaGiBFromPVC(pvc)
 	if err != nil {
 		*outcome = "terminal"
-		return r.terminalError(logger, pvc, constants.EventPVCInvalid, fmt.Errorf("derive quota: %w", err))
+		return r.terminalError(ctx, logger, pvc, constants.EventPVCInvalid, fmt.Errorf("derive quota: %w", err))
+	}
+	quotaGiB := params.EffectiveQuotaGiB(requestedGiB)
+	if adoptsShare(pvc) || params.UsesSubdirectories() {
+		// Class quota floors apply to shares the controller creates, not to adopted ones; backing shares
+		// apply them to the sum of their directories.
+		quotaGiB = requestedGiB
+	}
+
+	if pooled {
+		location, err = r.placeFromPool(ctx, logger, pvc, shareName, quotaGiB, topology.Region)
+		if errors.Is(err, azure.ErrNoAccountCapacity) {
+			return reconcile.Result{}, r.shareNotReady(ctx, pvc, constants.EventAccountPoolFull, err)
+		}
+		if err != nil {
+			return reconcile.Result{}, err
+		}
 	}
+	logger = logger.WithValues("storageAccount", location.StorageAccount)
 
-	if r.Shares == nil {
+	if err := r.verifyAccount(ctx, location, params, topology.Region); err != nil {
+		if errors.Is(err, azure.ErrAccountMisconfigured) {
+			*outcome = "terminal"
+			return r.terminalError(ctx, logger, pvc, constants.EventAccountInvalid, fmt.Errorf("verify storage account: %w", err))
+		}
+		r.Recorder.Event(pvc, corev1.EventTypeWarning, constants.EventAccountInvalid, "Storage account could not be verified")
+		return reconcile.Result{}, r.shareNotReady(ctx, pvc, constants.EventAccountInvalid, fmt.Errorf("verify storage account: %w", err))
+	}
+
+	shares, err := r.shareClientFor(location)
+	if err != nil {
 		*outcome = "terminal"
-		return r.terminalError(logger, pvc, constants.EventShareClientMissing, fmt.Errorf("share client not configured: %w", ErrInvalidPVCRequest))
+		return r.terminalError(ctx, logger, pvc, constants.EventShareClientMissing, fmt.Errorf("share client not configured: %w", err))
 	}
 
 	// 4. Ensure Azure File Share
-	pvLogger := logger.WithValues("pv", "", "share", shareName)
-	r.Recorder.Event(pvc, corev1.EventTypeNormal, constants.EventShareEnsuring, "Ensuring Azure File share exists")
-	pvLogger.Info("ensuring share", "quotaGiB", quotaGiB)
-	if err := r.Shares.EnsureShare(ctx, shareName, quotaGiB); err != nil {
-		r.Recorder.Event(pvc, corev1.EventTypeWarning, constants.EventShareError, "Failed to ensure Azure File share")
-		if errors.Is(err, azure.ErrInvalidShareInput) || errors.Is(err, ErrInvalidPVCRequest) {
+	if sourceUID := pvc.Annotations[constants.RestoreFromAnnotation]; sourceUID != "" {
+		if pvc.Annotations[constants.ShareOverrideAnnotation] != "" || adoptsShare(pvc) {
+			*outcome = "terminal"
+			return r.terminalError(ctx, logger, pvc, constants.EventShareRestoreFailed,
+				fmt.Errorf("annotation %s cannot be combined with %s or %s", constants.RestoreFromAnnotation, constants.ShareOverrideAnnotation, constants.AdoptShareAnnotation))
+		}
+		shareName, err = r.restoreShare(ctx, logger, pvc, shares, sourceUID)
+		if errors.Is(err, ErrNoDeletedShare) || errors.Is(err, ErrRestoreSourceInUse) {
 			*outcome = "terminal"
-			return r.terminalError(logger, pvc, constants.EventShareValidation, fmt.Errorf("ensure share: %w", err))
+			return r.terminalError(ctx, logger, pvc, constants.EventShareRestoreFailed, err)
+		}
+		if err != nil {
+			return r.handleShareError(ctx, logger, pvc, fmt.Errorf("restore share: %w", err), outcome)
+		}
+	}
+	pvLogger := logger.WithValues("pv", "", "share", shareName)
+	var props *azure.ShareProperties
+	switch {
+	case adoptsShare(pvc):
+		if props, err = r.adoptShare(ctx, pvLogger, pvc, shares, shareName, params, requestedGiB); err != nil {
+			err = fmt.Errorf("adopt share: %w", err)
+		}
+	case params.UsesSubdirectories():
+		props, quotaGiB, err = r.ensureClaimDirectory(ctx, pvLogger, pvc, location, shares, params, shareName, directory, requestedGiB)
+		if err != nil && !errors.Is(err, ErrShareOwnershipConflict) {
+			return r.handleShareError(ctx, pvLogger, pvc, fmt.Errorf("ensure claim directory: %w", err), outcome)
+		}
+	default:
+		r.Recorder.Event(pvc, corev1.EventTypeNormal, constants.EventShareEnsuring, "Ensuring Azure File share exists")
+		pvLogger.Info("ensuring share", "quotaGiB", quotaGiB)
+		if err := shares.EnsureShare(ctx, shareName, quotaGiB, r.shareOptions(params, pvc)); err != nil {
+			return r.handleShareError(ctx, pvLogger, pvc, fmt.Errorf("ensure share: %w", err), outcome)
+		}
+		props, err = shares.GetShare(ctx, shareName)
+		if err != nil {
+			return r.handleShareError(ctx, pvLogger, pvc, fmt.Errorf("get share: %w", err), outcome)
+		}
+		err = checkShareOwnership(pvc, props, params)
+		if err == nil {
+			if err = r.backfillShareMetadata(ctx, pvLogger, pvc, shares, props, params); err != nil {
+				err = fmt.Errorf("backfill share metadata: %w", err)
+			}
 		}
-		return reconcile.Result{}, fmt.Errorf("ensure share: %w", err)
+	}
+	switch {
+	case errors.Is(err, ErrShareOwnershipConflict):
+		*outcome = "terminal"
+		return r.terminalError(ctx, logger, pvc, constants.EventOwnershipConflict, err)
+	case errors.Is(err, ErrShareAdoptionFailed):
+		*outcome = "terminal"
+		return r.terminalError(ctx, logger, pvc, constants.EventShareAdoptionFailed, err)
+	case err != nil:
+		return r.handleShareError(ctx, pvLogger, pvc, err, outcome)
+	}
+	if err := r.reclaimShare(ctx, pvLogger, pvc, shares, props, params); err != nil {
+		return r.handleShareError(ctx, pvLogger, pvc, fmt.Errorf("reclaim share: %w", err), outcome)
+	}
+	if err := r.reconcileShareQuota(ctx, pvLogger, pvc, shares, props, quotaGiB); err != nil {
+		return r.handleShareError(ctx, pvLogger, pvc, fmt.Errorf("reconcile share quota: %w", err), outcome)
+	}
+	populated, err := r.populateShare(ctx, pvLogger, pvc, location, shares, params, shareName, quotaGiB)
+	switch {
+	case errors.Is(err, ErrInvalidDataSource), errors.Is(err, azure.ErrShareCopyFailed):
+		*outcome = "terminal"
+		return r.terminalError(ctx, logger, pvc, constants.EventCloneFailed, fmt.Errorf("copy data source: %w", err))
+	case errors.Is(err, ErrDataSourceNotReady):
+		*outcome = "wait"
+		waiting := claimCondition(constants.ConditionShareReady, corev1.ConditionFalse, constants.EventCloneSourceNotReady, err.Error())
+		return reconcile.Result{RequeueAfter: cloneRequeue}, r.setConditions(ctx, pvc, waiting)
+	case err != nil:
+		return r.handleShareError(ctx, pvLogger, pvc, fmt.Errorf("copy data source: %w", err), outcome)
+	case !populated:
+		*outcome = "wait"
+		return reconcile.Result{RequeueAfter: cloneRequeue}, nil
 	}
 	r.Recorder.Event(pvc, corev1.EventTypeNormal, constants.EventShareReady, "Azure File share is ready")
+	if err := r.setConditions(ctx, pvc,
+		claimCondition(constants.ConditionShareReady, corev1.ConditionTrue, constants.EventShareReady, fmt.Sprintf("Azure File share %s is ready", shareName)),
+		claimCondition(constants.ConditionProvisioningFailed, corev1.ConditionFalse, constants.EventShareReady, ""),
+	); err != nil {
+		return reconcile.Result{}, err
+	}
+
+	if r.NodeSecrets != nil && !params.IsNFS() {
+		ref, err := r.ensureKeySecret(ctx, location)
+		if err != nil {
+			r.Recorder.Event(pvc, corev1.EventTypeWarning, constants.EventNodeSecretError, "Failed to write the storage account key Secret")
+			return reconcile.Result{}, fmt.Errorf("ensure key secret: %w", err)
+		}
+		pvOpts = append(pvOpts, k8s.WithNodeStageSecret(ref.Namespace, ref.Name))
+	}
 
 	// 5. Ensure Kubernetes PersistentVolume
-	pv, err := k8s.BuildPV(pvc, shareName, r.Config.ResourceGroup, r.Config.StorageAccount, r.Config.Server, corev1.PersistentVolumeReclaimDelete)
+	pv, err := k8s.BuildPV(pvc, shareName, location.ResourceGroup, location.StorageAccount, location.Server, reclaimPolicyFor(pvc, storageClass, params), pvOpts...)
 	if err != nil {
 		*outcome = "terminal"
-		return r.terminalError(logger, pvc, constants.EventPVBuildError, fmt.Errorf("build pv: %w", err))
+		return r.terminalError(ctx, logger, pvc, constants.EventPVBuildError, fmt.Errorf("build pv: %w", err))
 	}
 
 	pvLogger = logger.WithValues("pv", pv.Name, "share", shareName)
@@ -112,23 +274,78 @@ func (r *PVCReconciler) handleProvisioning(ctx context.Context, logger logr.Logg
 		pvLogger.Info("created pv")
 	} else {
 		if !pvMatches(existing, pvc, shareName) {
-			r.Recorder.Event(pvc, corev1.EventTypeWarning, constants.EventPVMismatch, "Existing PV does not match expected share or claim")
-			return reconcile.Result{}, fmt.Errorf("pv mismatch for %s/%s: %w", pvc.Namespace, pvc.Name, ErrPVMismatch)
+			return r.remediatePVMismatch(ctx, pvLogger, pvc, existing, pv, outcome)
 		}
 		r.Recorder.Event(pvc, corev1.EventTypeNormal, constants.EventPVAlreadyExists, "PersistentVolume already exists")
 		pvLogger.Info("pv already exists")
+		if err := r.reconcileCapacity(ctx, pvLogger, pvc, existing); err != nil {
+			return reconcile.Result{}, fmt.Errorf("reconcile capacity: %w", err)
+		}
 	}
 
-	// 6. Annotate PVC
-	if err := r.ensureShareAnnotation(ctx, pvc, shareName); err != nil {
+	// 6. Annotate PVC and report binding
+	if err := r.ensureShareAnnotations(ctx, pvc, shareName, pv.Spec.CSI.VolumeHandle); err != nil {
 		return reconcile.Result{}, fmt.Errorf("annotate pvc: %w", err)
 	}
+	if err := r.reportBinding(ctx, pvc, pv.Name); err != nil {
+		return reconcile.Result{}, err
+	}
 
 	return reconcile.Result{}, nil
 }
 
-func (r *PVCReconciler) ensureShareAnnotation(ctx context.Context, pvc *corev1.PersistentVolumeClaim, shareName string) error {
-	if pvc.Annotations != nil && pvc.Annotations[constants.ShareNameAnnotation] == shareName {
+// shareNotReady records why the share is not ready yet and returns cause so the claim is retried.
+func (r *PVCReconciler) shareNotReady(ctx context.Context, pvc *corev1.PersistentVolumeClaim, reason string, cause error) error {
+	if err := r.setConditions(ctx, pvc, claimCondition(constants.ConditionShareReady, corev1.ConditionFalse, reason, cause.Error())); err != nil {
+		return errors.Join(cause, err)
+	}
+	return cause
+}
+
+// reportBinding sets PVBound once the claim is bound to the provisioned PV, emitting an event on the transition.
+func (r *PVCReconciler) reportBinding(ctx context.Context, pvc *corev1.PersistentVolumeClaim, pvName string) error {
+	if pvc.Spec.VolumeName != pvName || pvc.Status.Phase != corev1.ClaimBound {
+		pending := claimCondition(constants.ConditionPVBound, corev1.ConditionFalse, constants.EventPVCreated, fmt.Sprintf("Waiting for the claim to bind to PersistentVolume %s", pvName))
+		return r.setConditions(ctx, pvc, pending)
+	}
+	if !conditionTrue(pvc, constants.ConditionPVBound) {
+		r.Recorder.Eventf(pvc, corev1.EventTypeNormal, constants.EventPVBound, "Bound to PersistentVolume %s", pvName)
+	}
+	bound := claimCondition(constants.ConditionPVBound, corev1.ConditionTrue, constants.EventPVBound, fmt.Sprintf("Bound to PersistentVolume %s", pvName))
+	return r.setConditions(ctx, pvc, bound)
+}
+
+// shareNameFor derives the claim's share name from the adopt-share or override annotation or, without one,
+// from its namespace and name, suffixed with a hash of its UID when the class asks for unique names.
+func shareNameFor(pvc *corev1.PersistentVolumeClaim, params k8s.ShareParameters) (string, error) {
+	override := pvc.Annotations[constants.ShareOverrideAnnotation]
+	if adopted := pvc.Annotations[constants.AdoptShareAnnotation]; adopted != "" {
+		if override != "" {
+			return "", fmt.Errorf("annotations %s and %s are mutually exclusive", constants.AdoptShareAnnotation, constants.ShareOverrideAnnotation)
+		}
+		_, shareName, err := k8s.ParseAdoptedShare(adopted)
+		return shareName, err
+	}
+	if override == "" && params.UniqueShareNames() {
+		return naming.ComputeUniqueShareName(pvc.Namespace, pvc.Name, string(pvc.UID))
+	}
+	return naming.ComputeShareName(pvc.Namespace, pvc.Name, override)
+}
+
+// shareOptions maps StorageClass parameters and the claim's provenance onto share creation options.
+func (r *PVCReconciler) shareOptions(params k8s.ShareParameters, pvc *corev1.PersistentVolumeClaim) *azure.EnsureShareOptions {
+	return &azure.EnsureShareOptions{
+		AccessTier:      params.AccessTier,
+		EnabledProtocol: params.Protocol,
+		RootSquash:      params.RootSquash,
+		Metadata:        shareMetadata(pvc, r.Config.ClusterID),
+	}
+}
+
+// ensureShareAnnotations records the share name and volume handle so deletion and later reconciles
+// resolve the same share and account.
+func (r *PVCReconciler) ensureShareAnnotations(ctx context.Context, pvc *corev1.PersistentVolumeClaim, shareName, volumeHandle string) error {
+	if pvc.Annotations != nil && pvc.Annotations[constants.ShareNameAnnotation] == shareName && pvc.Annotations[constants.VolumeHandleAnnotation] == volumeHandle {
 		return nil
 	}
 
@@ -137,6 +354,7 @@ func (r *PVCReconciler) ensureShareAnnotation(ctx context.Context, pvc *corev1.P
 		pvc.Annotations = map[string]string{}
 	}
 	pvc.Annotations[constants.ShareNameAnnotation] = shareName
+	pvc.Annotations[constants.VolumeHandleAnnotation] = volumeHandle
 
 	if err := r.Client.Patch(ctx, pvc, patch); err != nil {
 		return fmt.Errorf("patch pvc annotations: %w", err)
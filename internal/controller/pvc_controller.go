@@ -6,23 +6,40 @@ import (
 	"time"
 
 	corev1 "k8s.io/api/core/v1"
+	storagev1 "k8s.io/api/storage/v1"
 	apierrors "k8s.io/apimachinery/pkg/api/errors"
 	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/client-go/tools/record"
 	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/builder"
 	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
 	"sigs.k8s.io/controller-runtime/pkg/log"
 	"sigs.k8s.io/controller-runtime/pkg/manager"
+	"sigs.k8s.io/controller-runtime/pkg/predicate"
 	"sigs.k8s.io/controller-runtime/pkg/reconcile"
 
 	"aks-azureFiles-controller/internal/azure"
+	"aks-azureFiles-controller/internal/k8s"
 )
 
+// storageClassIndex indexes PVCs by spec.storageClassName so a StorageClass event finds its claims.
+const storageClassIndex = "spec.storageClassName"
+
 // ReconcilerConfig holds Azure config for the PVC reconciler.
+// The account fields describe the default account used when a StorageClass does not name one.
+// ClusterID is recorded on created shares, and deletion only removes shares carrying the same ID.
+// PVMismatchPolicy handles PVs that do not match their claim; empty means PVMismatchHalt.
+// A positive DeletionGracePeriod tags released shares with a deadline instead of deleting them (see ShareReaper).
+// With VolumeSnapshots, shares VolumeSnapshotContents hold snapshots of are retained instead of deleted.
 type ReconcilerConfig struct {
-	ResourceGroup  string
-	StorageAccount string
-	Server         string
+	ResourceGroup       string
+	StorageAccount      string
+	Server              string
+	ClusterID           string
+	PVMismatchPolicy    PVMismatchPolicy
+	DeletionGracePeriod time.Duration
+	VolumeSnapshots     bool
 }
 
 // PVCReconciler reconciles PersistentVolumeClaims for Azure File shares.
@@ -31,12 +48,16 @@ type ReconcilerConfig struct {
 // 2. Creates a corresponding PersistentVolume (PV) pointing to that share.
 // 3. Manages the lifecycle (creation, deletion) of these resources.
 type PVCReconciler struct {
-	Client   client.Client
-	Scheme   *runtime.Scheme
-	Recorder record.EventRecorder
-	Config   ReconcilerConfig
-	Shares   azure.ShareClient
-	Metrics  *ReconcileMetrics
+	Client      client.Client
+	Scheme      *runtime.Scheme
+	Recorder    record.EventRecorder
+	Config      ReconcilerConfig
+	Shares      azure.ShareClient
+	Accounts    *azure.Registry
+	Placer      *azure.Placer
+	Verifier    *azure.AccountVerifier
+	NodeSecrets *NodeSecretConfig
+	Metrics     *ReconcileMetrics
 }
 
 // Reconcile is idempotent and safe to retry.
@@ -77,8 +98,64 @@ func (r *PVCReconciler) Reconcile(ctx context.Context, req reconcile.Request) (r
 }
 
 // SetupWithManager wires the controller into the manager.
+// Besides PVCs it watches the PVs it created, so deleted or edited PVs are repaired, and managed
+// StorageClasses, so claims created before their class are provisioned once it appears.
 func (r *PVCReconciler) SetupWithManager(mgr manager.Manager) error {
+	if err := mgr.GetFieldIndexer().IndexField(context.Background(), &corev1.PersistentVolumeClaim{}, storageClassIndex, indexStorageClass); err != nil {
+		return fmt.Errorf("index pvc storage class: %w", err)
+	}
 	return ctrl.NewControllerManagedBy(mgr).
 		For(&corev1.PersistentVolumeClaim{}).
+		Watches(&corev1.PersistentVolume{},
+			handler.EnqueueRequestsFromMapFunc(r.pvcForPV),
+			builder.WithPredicates(predicate.NewPredicateFuncs(func(obj client.Object) bool {
+				_, ok := obj.GetLabels()[k8s.LabelPVCName]
+				return ok
+			})),
+		).
+		Watches(&storagev1.StorageClass{},
+			handler.EnqueueRequestsFromMapFunc(r.pendingPVCsForStorageClass),
+			builder.WithPredicates(predicate.NewPredicateFuncs(func(obj client.Object) bool {
+				sc, ok := obj.(*storagev1.StorageClass)
+				return ok && k8s.GetProvisioner(sc) == k8s.ManagedProvisioner
+			})),
+		).
 		Complete(r)
 }
+
+// pvcForPV maps a PV built by BuildPV back to its claim through the PVC labels.
+func (r *PVCReconciler) pvcForPV(_ context.Context, obj client.Object) []reconcile.Request {
+	labels := obj.GetLabels()
+	namespace, name := labels[k8s.LabelPVCNamespace], labels[k8s.LabelPVCName]
+	if namespace == "" || name == "" {
+		return nil
+	}
+	return []reconcile.Request{{NamespacedName: client.ObjectKey{Namespace: namespace, Name: name}}}
+}
+
+// pendingPVCsForStorageClass maps a managed StorageClass to its claims that have no volume yet.
+func (r *PVCReconciler) pendingPVCsForStorageClass(ctx context.Context, obj client.Object) []reconcile.Request {
+	pvcList := &corev1.PersistentVolumeClaimList{}
+	if err := r.Client.List(ctx, pvcList, client.MatchingFields{storageClassIndex: obj.GetName()}); err != nil {
+		log.FromContext(ctx).Error(err, "list pvcs for storageclass", "storageClass", obj.GetName())
+		return nil
+	}
+	var requests []reconcile.Request
+	for i := range pvcList.Items {
+		pvc := &pvcList.Items[i]
+		if pvc.Spec.VolumeName != "" {
+			continue
+		}
+		requests = append(requests, reconcile.Request{NamespacedName: client.ObjectKeyFromObject(pvc)})
+	}
+	return requests
+}
+
+// indexStorageClass is the storageClassIndex extractor.
+func indexStorageClass(obj client.Object) []string {
+	pvc, ok := obj.(*corev1.PersistentVolumeClaim)
+	if !ok || pvc.Spec.StorageClassName == nil || *pvc.Spec.StorageClassName == "" {
+		return nil
+	}
+	return []string{*pvc.Spec.StorageClassName}
+}
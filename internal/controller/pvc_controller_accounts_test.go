@@ -0,0 +1,534 @@
+package controller
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	storagev1 "k8s.io/api/storage/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/tools/record"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	"aks-azureFiles-controller/internal/azure"
+	"aks-azureFiles-controller/internal/constants"
+	"aks-azureFiles-controller/internal/k8s"
+	"aks-azureFiles-controller/internal/logging"
+)
+
+func TestReconcileUsesStorageClassAccount(t *testing.T) {
+	scheme := runtime.NewScheme()
+	if err := corev1.AddToScheme(scheme); err != nil {
+		t.Fatalf("AddToScheme corev1: %v", err)
+	}
+	if err := storagev1.AddToScheme(scheme); err != nil {
+		t.Fatalf("AddToScheme storagev1: %v", err)
+	}
+
+	sc := &storagev1.StorageClass{
+		ObjectMeta:  metav1.ObjectMeta{Name: "azurefile"},
+		Provisioner: k8s.ManagedProvisioner,
+		Parameters: map[string]string{
+			k8s.ParamStorageAccount:  "classacct",
+			k8s.ParamResourceGroup:   "class-rg",
+			k8s.ParamAllowedAccounts: "teamacct",
+		},
+	}
+
+	pvc := basePVC()
+	pvc.Spec.StorageClassName = stringPtr("azurefile")
+	pvc.Annotations = map[string]string{constants.StorageAccountAnnotation: "teamacct"}
+
+	k8sClient := fake.NewClientBuilder().WithScheme(scheme).WithObjects(sc, pvc).Build()
+	defaultShares := &azure.FakeShareClient{}
+	teamShares := &azure.FakeShareClient{}
+	accounts := azure.NewRegistry(nil)
+	accounts.Register("teamacct", teamShares)
+
+	reconciler := &PVCReconciler{
+		Client:   k8sClient,
+		Scheme:   scheme,
+		Recorder: record.NewFakeRecorder(20),
+		Config: ReconcilerConfig{
+			ResourceGroup:  "rg",
+			StorageAccount: "account",
+			Server:         "server",
+		},
+		Shares:   defaultShares,
+		Accounts: accounts,
+	}
+
+	ctx := ctrl.LoggerInto(context.Background(), logging.NewLogger())
+	request := reconcile.Request{NamespacedName: client.ObjectKeyFromObject(pvc)}
+	if _, err := reconciler.Reconcile(ctx, request); err != nil {
+		t.Fatalf("Reconcile error = %v", err)
+	}
+
+	shareName := shareNameForTest(pvc)
+	if _, ok := teamShares.Shares[shareName]; !ok {
+		t.Fatalf("share not created in annotated account")
+	}
+	if len(defaultShares.Shares) != 0 {
+		t.Fatalf("default account Shares = %#v, want none", defaultShares.Shares)
+	}
+
+	pvList := &corev1.PersistentVolumeList{}
+	if err := k8sClient.List(ctx, pvList); err != nil {
+		t.Fatalf("List PVs error = %v", err)
+	}
+	if len(pvList.Items) != 1 {
+		t.Fatalf("PV count = %d, want 1", len(pvList.Items))
+	}
+	pv := pvList.Items[0]
+	wantHandle := k8s.VolumeHandle("class-rg", "teamacct", shareName)
+	if pv.Spec.CSI.VolumeHandle != wantHandle {
+		t.Fatalf("VolumeHandle = %q, want %q", pv.Spec.CSI.VolumeHandle, wantHandle)
+	}
+	if pv.Spec.CSI.VolumeAttributes["server"] != azure.FileServiceHost("teamacct") {
+		t.Fatalf("server = %q, want %q", pv.Spec.CSI.VolumeAttributes["server"], azure.FileServiceHost("teamacct"))
+	}
+
+	provisioned := &corev1.PersistentVolumeClaim{}
+	if err := k8sClient.Get(ctx, request.NamespacedName, provisioned); err != nil {
+		t.Fatalf("Get PVC error = %v", err)
+	}
+	if provisioned.Annotations[constants.VolumeHandleAnnotation] != wantHandle {
+		t.Fatalf("volume-handle annotation = %q, want %q", provisioned.Annotations[constants.VolumeHandleAnnotation], wantHandle)
+	}
+
+	// Deletion resolves the account from the PV, not from the default config.
+	if err := k8sClient.Delete(ctx, provisioned); err != nil {
+		t.Fatalf("Delete PVC error = %v", err)
+	}
+	if _, err := reconciler.Reconcile(ctx, request); err != nil {
+		t.Fatalf("Reconcile error = %v", err)
+	}
+	if _, ok := teamShares.Shares[shareName]; ok {
+		t.Fatalf("share still present in annotated account")
+	}
+	if err := k8sClient.List(ctx, pvList); err != nil {
+		t.Fatalf("List PVs error = %v", err)
+	}
+	if len(pvList.Items) != 0 {
+		t.Fatalf("PV count = %d, want 0", len(pvList.Items))
+	}
+}
+
+func TestReconcileRejectsAccountOutsideAllowList(t *testing.T) {
+	scheme := runtime.NewScheme()
+	if err := corev1.AddToScheme(scheme); err != nil {
+		t.Fatalf("AddToScheme corev1: %v", err)
+	}
+	if err := storagev1.AddToScheme(scheme); err != nil {
+		t.Fatalf("AddToScheme storagev1: %v", err)
+	}
+
+	sc := &storagev1.StorageClass{
+		ObjectMeta:  metav1.ObjectMeta{Name: "azurefile"},
+		Provisioner: k8s.ManagedProvisioner,
+	}
+
+	pvc := basePVC()
+	pvc.Spec.StorageClassName = stringPtr("azurefile")
+	pvc.Annotations = map[string]string{constants.StorageAccountAnnotation: "otheracct"}
+
+	k8sClient := fake.NewClientBuilder().WithScheme(scheme).WithObjects(sc, pvc).Build()
+	shareClient := &azure.FakeShareClient{}
+	recorder := record.NewFakeRecorder(20)
+
+	reconciler := &PVCReconciler{
+		Client:   k8sClient,
+		Scheme:   scheme,
+		Recorder: recorder,
+		Config: ReconcilerConfig{
+			ResourceGroup:  "rg",
+			StorageAccount: "account",
+			Server:         "server",
+		},
+		Shares: shareClient,
+	}
+
+	ctx := ctrl.LoggerInto(context.Background(), logging.NewLogger())
+	if _, err := reconciler.Reconcile(ctx, reconcile.Request{NamespacedName: client.ObjectKeyFromObject(pvc)}); err != nil {
+		t.Fatalf("Reconcile error = %v", err)
+	}
+	if len(shareClient.Shares) != 0 {
+		t.Fatalf("Shares = %#v, want none", shareClient.Shares)
+	}
+	if !hasEvent(recorder, constants.EventAccountNotAllowed) {
+		t.Fatalf("event %q not recorded", constants.EventAccountNotAllowed)
+	}
+}
+
+func TestReconcileRejectsRecordedAccountOutsideAllowList(t *testing.T) {
+	scheme := runtime.NewScheme()
+	if err := corev1.AddToScheme(scheme); err != nil {
+		t.Fatalf("AddToScheme corev1: %v", err)
+	}
+	if err := storagev1.AddToScheme(scheme); err != nil {
+		t.Fatalf("AddToScheme storagev1: %v", err)
+	}
+
+	sc := &storagev1.StorageClass{
+		ObjectMeta:  metav1.ObjectMeta{Name: "azurefile"},
+		Provisioner: k8s.ManagedProvisioner,
+	}
+
+	pvc := basePVC()
+	pvc.Spec.StorageClassName = stringPtr("azurefile")
+	shareName := shareNameForTest(pvc)
+	pvc.Annotations = map[string]string{constants.VolumeHandleAnnotation: k8s.VolumeHandle("rg", "otheracct", shareName)}
+
+	k8sClient := fake.NewClientBuilder().WithScheme(scheme).WithObjects(sc, pvc).Build()
+	otherShares := &azure.FakeShareClient{}
+	accounts := azure.NewRegistry(nil)
+	accounts.Register("otheracct", otherShares)
+	recorder := record.NewFakeRecorder(20)
+
+	reconciler := &PVCReconciler{
+		Client:   k8sClient,
+		Scheme:   scheme,
+		Recorder: recorder,
+		Config: ReconcilerConfig{
+			ResourceGroup:  "rg",
+			StorageAccount: "account",
+			Server:         "server",
+		},
+		Shares:   &azure.FakeShareClient{},
+		Accounts: accounts,
+	}
+
+	ctx := ctrl.LoggerInto(context.Background(), logging.NewLogger())
+	if _, err := reconciler.Reconcile(ctx, reconcile.Request{NamespacedName: client.ObjectKeyFromObject(pvc)}); err != nil {
+		t.Fatalf("Reconcile error = %v", err)
+	}
+	if len(otherShares.Shares) != 0 {
+		t.Fatalf("Shares = %#v, want none in the recorded account", otherShares.Shares)
+	}
+	if !hasEvent(recorder, constants.EventAccountNotAllowed) {
+		t.Fatalf("event %q not recorded", constants.EventAccountNotAllowed)
+	}
+}
+
+func TestDeletionUsesRecordedAccountWithoutPV(t *testing.T) {
+	scheme := runtime.NewScheme()
+	if err := corev1.AddToScheme(scheme); err != nil {
+		t.Fatalf("AddToScheme corev1: %v", err)
+	}
+	if err := storagev1.AddToScheme(scheme); err != nil {
+		t.Fatalf("AddToScheme storagev1: %v", err)
+	}
+
+	pvc := basePVC()
+	pvc.Spec.StorageClassName = stringPtr("azurefile")
+	pvc.Finalizers = []string{constants.FinalizerName}
+	now := metav1.NewTime(time.Now())
+	pvc.DeletionTimestamp = &now
+	shareName := shareNameForTest(pvc)
+	pvc.Annotations = map[string]string{
+		constants.ShareNameAnnotation:    shareName,
+		constants.VolumeHandleAnnotation: k8s.VolumeHandle("rg", "teamacct", shareName),
+	}
+
+	k8sClient := fake.NewClientBuilder().WithScheme(scheme).WithObjects(pvc).Build()
+	teamShares := &azure.FakeShareClient{
+		Shares:  map[string]int32{shareName: 1},
+		Options: map[string]azure.EnsureShareOptions{shareName: {Metadata: shareMetadata(pvc, "")}},
+	}
+	accounts := azure.NewRegistry(nil)
+	accounts.Register("teamacct", teamShares)
+
+	reconciler := &PVCReconciler{
+		Client:   k8sClient,
+		Scheme:   scheme,
+		Recorder: record.NewFakeRecorder(20),
+		Config: ReconcilerConfig{
+			ResourceGroup:  "rg",
+			StorageAccount: "account",
+			Server:         "server",
+		},
+		Shares:   &azure.FakeShareClient{},
+		Accounts: accounts,
+	}
+
+	ctx := ctrl.LoggerInto(context.Background(), logging.NewLogger())
+	if _, err := reconciler.Reconcile(ctx, reconcile.Request{NamespacedName: client.ObjectKeyFromObject(pvc)}); err != nil {
+		t.Fatalf("Reconcile error = %v", err)
+	}
+	if _, ok := teamShares.Shares[shareName]; ok {
+		t.Fatalf("share still present in recorded account")
+	}
+}
+
+func TestReconcilePlacesClaimOnLeastLoadedPoolAccount(t *testing.T) {
+	scheme := runtime.NewScheme()
+	if err := corev1.AddToScheme(scheme); err != nil {
+		t.Fatalf("AddToScheme corev1: %v", err)
+	}
+	if err := storagev1.AddToScheme(scheme); err != nil {
+		t.Fatalf("AddToScheme storagev1: %v", err)
+	}
+
+	sc := &storagev1.StorageClass{
+		ObjectMeta:  metav1.ObjectMeta{Name: "azurefile"},
+		Provisioner: k8s.ManagedProvisioner,
+	}
+
+	// An existing 50 GiB share already lives on poola.
+	other := basePVC()
+	other.Name = "other"
+	other.UID = "other-uid"
+	existingPV, err := k8s.BuildPV(other, shareNameForTest(other), "rg", "poola", azure.FileServiceHost("poola"), corev1.PersistentVolumeReclaimDelete)
+	if err != nil {
+		t.Fatalf("BuildPV error = %v", err)
+	}
+	existingPV.Spec.Capacity[corev1.ResourceStorage] = resource.MustParse("50Gi")
+
+	pvc := basePVC()
+	pvc.Spec.StorageClassName = stringPtr("azurefile")
+
+	k8sClient := fake.NewClientBuilder().WithScheme(scheme).WithObjects(sc, pvc, existingPV).Build()
+	poolA := &azure.FakeShareClient{}
+	poolB := &azure.FakeShareClient{}
+	accounts := azure.NewRegistry(nil)
+	accounts.Register("poola", poolA)
+	accounts.Register("poolb", poolB)
+	recorder := record.NewFakeRecorder(20)
+
+	reconciler := &PVCReconciler{
+		Client:   k8sClient,
+		Scheme:   scheme,
+		Recorder: recorder,
+		Config: ReconcilerConfig{
+			ResourceGroup:  "rg",
+			StorageAccount: "account",
+			Server:         "server",
+		},
+		Shares:   &azure.FakeShareClient{},
+		Accounts: accounts,
+		Placer: azure.NewPlacer([]azure.PoolAccount{
+			{Name: "poola", ResourceGroup: "rg", MaxCapacityGiB: 100},
+			{Name: "poolb", ResourceGroup: "rg", MaxCapacityGiB: 100},
+		}),
+	}
+
+	ctx := ctrl.LoggerInto(context.Background(), logging.NewLogger())
+	request := reconcile.Request{NamespacedName: client.ObjectKeyFromObject(pvc)}
+	if _, err := reconciler.Reconcile(ctx, request); err != nil {
+		t.Fatalf("Reconcile error = %v", err)
+	}
+
+	shareName := shareNameForTest(pvc)
+	if _, ok := poolB.Shares[shareName]; !ok {
+		t.Fatalf("share not created in least-loaded account")
+	}
+	if len(poolA.Shares) != 0 {
+		t.Fatalf("poola Shares = %#v, want none", poolA.Shares)
+	}
+	if !hasEvent(recorder, constants.EventAccountSelected) {
+		t.Fatalf("event %q not recorded", constants.EventAccountSelected)
+	}
+
+	provisioned := &corev1.PersistentVolumeClaim{}
+	if err := k8sClient.Get(ctx, request.NamespacedName, provisioned); err != nil {
+		t.Fatalf("Get PVC error = %v", err)
+	}
+	wantHandle := k8s.VolumeHandle("rg", "poolb", shareName)
+	if provisioned.Annotations[constants.VolumeHandleAnnotation] != wantHandle {
+		t.Fatalf("volume-handle annotation = %q, want %q", provisioned.Annotations[constants.VolumeHandleAnnotation], wantHandle)
+	}
+}
+
+func TestReconcilePlacementCountsProvisionedQuota(t *testing.T) {
+	// poolb always ends up less loaded once premium floors and in-flight placements are counted.
+	cases := map[string]func(t *testing.T) []client.Object{
+		"premium floor": func(t *testing.T) []client.Object {
+			premium := basePVC()
+			premium.Name = "premium"
+			premium.UID = "premium-uid"
+			premium.Spec.StorageClassName = stringPtr("premium")
+			premiumPV, err := k8s.BuildPV(premium, shareNameForTest(premium), "rg", "poola", azure.FileServiceHost("poola"), corev1.PersistentVolumeReclaimDelete)
+			if err != nil {
+				t.Fatalf("BuildPV error = %v", err)
+			}
+			standard := basePVC()
+			standard.Name = "standard"
+			standard.UID = "standard-uid"
+			standard.Spec.StorageClassName = stringPtr("azurefile")
+			standard.Spec.Resources.Requests[corev1.ResourceStorage] = resource.MustParse("50Gi")
+			standardPV, err := k8s.BuildPV(standard, shareNameForTest(standard), "rg", "poolb", azure.FileServiceHost("poolb"), corev1.PersistentVolumeReclaimDelete)
+			if err != nil {
+				t.Fatalf("BuildPV error = %v", err)
+			}
+			return []client.Object{premium, premiumPV, standard, standardPV}
+		},
+		"placement in flight": func(t *testing.T) []client.Object {
+			pending := basePVC()
+			pending.Name = "pending"
+			pending.UID = "pending-uid"
+			pending.Spec.StorageClassName = stringPtr("azurefile")
+			pending.Spec.Resources.Requests[corev1.ResourceStorage] = resource.MustParse("50Gi")
+			pending.Annotations = map[string]string{constants.VolumeHandleAnnotation: k8s.VolumeHandle("rg", "poola", shareNameForTest(pending))}
+			bound := basePVC()
+			bound.Name = "bound"
+			bound.UID = "bound-uid"
+			bound.Spec.StorageClassName = stringPtr("azurefile")
+			bound.Spec.Resources.Requests[corev1.ResourceStorage] = resource.MustParse("20Gi")
+			boundPV, err := k8s.BuildPV(bound, shareNameForTest(bound), "rg", "poolb", azure.FileServiceHost("poolb"), corev1.PersistentVolumeReclaimDelete)
+			if err != nil {
+				t.Fatalf("BuildPV error = %v", err)
+			}
+			return []client.Object{pending, bound, boundPV}
+		},
+	}
+
+	for name, objects := range cases {
+		t.Run(name, func(t *testing.T) {
+			scheme := runtime.NewScheme()
+			if err := corev1.AddToScheme(scheme); err != nil {
+				t.Fatalf("AddToScheme corev1: %v", err)
+			}
+			if err := storagev1.AddToScheme(scheme); err != nil {
+				t.Fatalf("AddToScheme storagev1: %v", err)
+			}
+
+			standardClass := &storagev1.StorageClass{ObjectMeta: metav1.ObjectMeta{Name: "azurefile"}, Provisioner: k8s.ManagedProvisioner}
+			premiumClass := &storagev1.StorageClass{
+				ObjectMeta:  metav1.ObjectMeta{Name: "premium"},
+				Provisioner: k8s.ManagedProvisioner,
+				Parameters:  map[string]string{"skuName": "Premium_LRS"},
+			}
+			pvc := basePVC()
+			pvc.Spec.StorageClassName = stringPtr("azurefile")
+
+			k8sClient := fake.NewClientBuilder().WithScheme(scheme).
+				WithObjects(append(objects(t), standardClass, premiumClass, pvc)...).Build()
+			poolA := &azure.FakeShareClient{}
+			poolB := &azure.FakeShareClient{}
+			accounts := azure.NewRegistry(nil)
+			accounts.Register("poola", poolA)
+			accounts.Register("poolb", poolB)
+			reconciler := &PVCReconciler{
+				Client:   k8sClient,
+				Scheme:   scheme,
+				Recorder: record.NewFakeRecorder(20),
+				Config:   ReconcilerConfig{ResourceGroup: "rg", StorageAccount: "account", Server: "server"},
+				Shares:   &azure.FakeShareClient{},
+				Accounts: accounts,
+				Placer: azure.NewPlacer([]azure.PoolAccount{
+					{Name: "poola", ResourceGroup: "rg", MaxCapacityGiB: 200},
+					{Name: "poolb", ResourceGroup: "rg", MaxCapacityGiB: 200},
+				}),
+			}
+
+			ctx := ctrl.LoggerInto(context.Background(), logging.NewLogger())
+			if _, err := reconciler.Reconcile(ctx, reconcile.Request{NamespacedName: client.ObjectKeyFromObject(pvc)}); err != nil {
+				t.Fatalf("Reconcile error = %v", err)
+			}
+			if _, ok := poolB.Shares[shareNameForTest(pvc)]; !ok || len(poolA.Shares) != 0 {
+				t.Fatalf("poola shares = %v, poolb shares = %v, want the share on poolb", poolA.Shares, poolB.Shares)
+			}
+		})
+	}
+}
+
+func TestReconcileRequeuesWhenPoolIsFull(t *testing.T) {
+	scheme := runtime.NewScheme()
+	if err := corev1.AddToScheme(scheme); err != nil {
+		t.Fatalf("AddToScheme corev1: %v", err)
+	}
+	if err := storagev1.AddToScheme(scheme); err != nil {
+		t.Fatalf("AddToScheme storagev1: %v", err)
+	}
+
+	sc := &storagev1.StorageClass{
+		ObjectMeta:  metav1.ObjectMeta{Name: "azurefile"},
+		Provisioner: k8s.ManagedProvisioner,
+	}
+	pvc := basePVC()
+	pvc.Spec.StorageClassName = stringPtr("azurefile")
+
+	k8sClient := fake.NewClientBuilder().WithScheme(scheme).WithObjects(sc, pvc).Build()
+	recorder := record.NewFakeRecorder(20)
+	reconciler := &PVCReconciler{
+		Client:   k8sClient,
+		Scheme:   scheme,
+		Recorder: recorder,
+		Config: ReconcilerConfig{
+			ResourceGroup:  "rg",
+			StorageAccount: "account",
+			Server:         "server",
+		},
+		Shares:   &azure.FakeShareClient{},
+		Accounts: azure.NewRegistry(nil),
+		Placer:   azure.NewPlacer([]azure.PoolAccount{{Name: "poola", ResourceGroup: "rg", MaxIOPS: 1}}),
+	}
+
+	ctx := ctrl.LoggerInto(context.Background(), logging.NewLogger())
+	_, err := reconciler.Reconcile(ctx, reconcile.Request{NamespacedName: client.ObjectKeyFromObject(pvc)})
+	if !errors.Is(err, azure.ErrNoAccountCapacity) {
+		t.Fatalf("Reconcile error = %v, want %v", err, azure.ErrNoAccountCapacity)
+	}
+	if !hasEvent(recorder, constants.EventAccountPoolFull) {
+		t.Fatalf("event %q not recorded", constants.EventAccountPoolFull)
+	}
+}
+
+func TestReconcileVerifiesStorageAccount(t *testing.T) {
+	scheme := runtime.NewScheme()
+	if err := corev1.AddToScheme(scheme); err != nil {
+		t.Fatalf("AddToScheme corev1: %v", err)
+	}
+	if err := storagev1.AddToScheme(scheme); err != nil {
+		t.Fatalf("AddToScheme storagev1: %v", err)
+	}
+
+	sc := &storagev1.StorageClass{
+		ObjectMeta:  metav1.ObjectMeta{Name: "azurefile"},
+		Provisioner: k8s.ManagedProvisioner,
+		Parameters:  map[string]string{k8s.ParamSkuName: "Premium_LRS"},
+	}
+	pvc := basePVC()
+	pvc.Spec.StorageClassName = stringPtr("azurefile")
+
+	k8sClient := fake.NewClientBuilder().WithScheme(scheme).WithObjects(sc, pvc).Build()
+	shareClient := &azure.FakeShareClient{}
+	recorder := record.NewFakeRecorder(20)
+	manager := &azure.FakeAccountManager{Accounts: map[string]azure.AccountProperties{
+		"account": {Name: "account", ResourceGroup: "rg", Kind: azure.AccountKindStorageV2, SkuName: "Standard_LRS", HTTPSOnly: true, MinimumTLSVersion: azure.MinimumTLSVersion},
+	}}
+
+	reconciler := &PVCReconciler{
+		Client:   k8sClient,
+		Scheme:   scheme,
+		Recorder: recorder,
+		Config: ReconcilerConfig{
+			ResourceGroup:  "rg",
+			StorageAccount: "account",
+			Server:         "server",
+		},
+		Shares:   shareClient,
+		Verifier: azure.NewAccountVerifier(manager, azure.AccountSpec{Location: "westeurope"}, true),
+	}
+
+	ctx := ctrl.LoggerInto(context.Background(), logging.NewLogger())
+	if _, err := reconciler.Reconcile(ctx, reconcile.Request{NamespacedName: client.ObjectKeyFromObject(pvc)}); err != nil {
+		t.Fatalf("Reconcile error = %v", err)
+	}
+	if len(shareClient.Shares) != 0 {
+		t.Fatalf("Shares = %#v, want none on a standard account for a premium class", shareClient.Shares)
+	}
+	if !hasEvent(recorder, constants.EventAccountInvalid) {
+		t.Fatalf("event %q not recorded", constants.EventAccountInvalid)
+	}
+	if manager.CreateCount != 0 {
+		t.Fatalf("CreateCount = %d, want 0 for an existing account", manager.CreateCount)
+	}
+}
@@ -0,0 +1,203 @@
+package controller
+
+import (
+	"context"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	storagev1 "k8s.io/api/storage/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/tools/record"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	"aks-azureFiles-controller/internal/azure"
+	"aks-azureFiles-controller/internal/constants"
+	"aks-azureFiles-controller/internal/k8s"
+	"aks-azureFiles-controller/internal/logging"
+)
+
+func TestReconcileAdoptsShare(t *testing.T) {
+	cases := map[string]struct {
+		transfer      bool
+		allowTransfer bool
+		wantPolicy    corev1.PersistentVolumeReclaimPolicy
+		wantShareKept bool
+	}{
+		"kept on deletion":      {wantPolicy: corev1.PersistentVolumeReclaimRetain, wantShareKept: true},
+		"ownership transferred": {transfer: true, allowTransfer: true, wantPolicy: corev1.PersistentVolumeReclaimDelete},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			scheme := runtime.NewScheme()
+			if err := corev1.AddToScheme(scheme); err != nil {
+				t.Fatalf("AddToScheme corev1: %v", err)
+			}
+			if err := storagev1.AddToScheme(scheme); err != nil {
+				t.Fatalf("AddToScheme storagev1: %v", err)
+			}
+
+			sc := &storagev1.StorageClass{
+				ObjectMeta:  metav1.ObjectMeta{Name: "azurefile"},
+				Provisioner: k8s.ManagedProvisioner,
+				Parameters:  map[string]string{k8s.ParamAllowedAccounts: "legacyacct", k8s.ParamMinQuotaGiB: "100"},
+			}
+			if tc.allowTransfer {
+				sc.Parameters[k8s.ParamAllowOwnershipTransfer] = "true"
+			}
+			pvc := basePVC()
+			pvc.Spec.StorageClassName = stringPtr("azurefile")
+			pvc.Annotations = map[string]string{constants.AdoptShareAnnotation: "legacyacct/legacy-data"}
+			if tc.transfer {
+				pvc.Annotations[constants.TransferOwnershipAnnotation] = "true"
+			}
+
+			legacyShares := &azure.FakeShareClient{
+				Shares:  map[string]int32{"legacy-data": 5},
+				Options: map[string]azure.EnsureShareOptions{"legacy-data": {Metadata: map[string]string{"team": "analytics"}}},
+			}
+			accounts := azure.NewRegistry(nil)
+			accounts.Register("legacyacct", legacyShares)
+			recorder := record.NewFakeRecorder(30)
+			k8sClient := fake.NewClientBuilder().WithScheme(scheme).WithObjects(sc, pvc).Build()
+			reconciler := &PVCReconciler{
+				Client:   k8sClient,
+				Scheme:   scheme,
+				Recorder: recorder,
+				Config:   ReconcilerConfig{ResourceGroup: "rg", StorageAccount: "account", Server: "server"},
+				Shares:   &azure.FakeShareClient{},
+				Accounts: accounts,
+			}
+
+			ctx := ctrl.LoggerInto(context.Background(), logging.NewLogger())
+			key := client.ObjectKeyFromObject(pvc)
+			if _, err := reconciler.Reconcile(ctx, reconcile.Request{NamespacedName: key}); err != nil {
+				t.Fatalf("Reconcile error = %v", err)
+			}
+			if !hasEvent(recorder, constants.EventShareAdopted) {
+				t.Fatalf("expected %s event", constants.EventShareAdopted)
+			}
+			if legacyShares.Shares["legacy-data"] != 5 || legacyShares.QuotaCount["legacy-data"] != 0 {
+				t.Fatalf("share quota = %d (%d updates), want untouched", legacyShares.Shares["legacy-data"], legacyShares.QuotaCount["legacy-data"])
+			}
+			metadata := legacyShares.Options["legacy-data"].Metadata
+			if tc.transfer {
+				if metadata["team"] != "analytics" || metadata[azure.MetadataOwnerNamespace] != "team" || metadata[azure.MetadataPVCUID] != "uid-123" || metadata[azure.MetadataProvisionedBy] != k8s.ManagedProvisioner {
+					t.Fatalf("share metadata = %v, want existing keys plus the claim's provenance", metadata)
+				}
+			} else if len(metadata) != 2 || metadata["team"] != "analytics" || metadata[azure.MetadataOwnerNamespace] != "team" {
+				t.Fatalf("share metadata = %v, want existing keys plus the claim's namespace only", metadata)
+			}
+
+			pvList := &corev1.PersistentVolumeList{}
+			if err := k8sClient.List(ctx, pvList); err != nil || len(pvList.Items) != 1 {
+				t.Fatalf("PVs = %d, %v, want one", len(pvList.Items), err)
+			}
+			pv := pvList.Items[0]
+			if pv.Spec.PersistentVolumeReclaimPolicy != tc.wantPolicy {
+				t.Fatalf("ReclaimPolicy = %q, want %q", pv.Spec.PersistentVolumeReclaimPolicy, tc.wantPolicy)
+			}
+			if want := k8s.VolumeHandle("rg", "legacyacct", "legacy-data"); pv.Spec.CSI.VolumeHandle != want {
+				t.Fatalf("VolumeHandle = %q, want %q", pv.Spec.CSI.VolumeHandle, want)
+			}
+
+			if err := k8sClient.Delete(ctx, pvc); err != nil {
+				t.Fatalf("Delete PVC error = %v", err)
+			}
+			if _, err := reconciler.Reconcile(ctx, reconcile.Request{NamespacedName: key}); err != nil {
+				t.Fatalf("Reconcile deletion error = %v", err)
+			}
+			if _, ok := legacyShares.Shares["legacy-data"]; ok != tc.wantShareKept {
+				t.Fatalf("share kept = %v, want %v", ok, tc.wantShareKept)
+			}
+		})
+	}
+}
+
+func TestReconcileShareAdoptionFailures(t *testing.T) {
+	cases := map[string]struct {
+		shares     map[string]int32
+		metadata   map[string]string
+		transfer   bool
+		wantReason string
+	}{
+		"missing share": {
+			wantReason: constants.EventShareAdoptionFailed,
+		},
+		"quota below request": {
+			shares:     map[string]int32{"legacy-data": 5},
+			wantReason: constants.EventShareAdoptionFailed,
+		},
+		"other namespace": {
+			shares:     map[string]int32{"legacy-data": 50},
+			metadata:   map[string]string{azure.MetadataOwnerNamespace: "finance"},
+			wantReason: constants.EventOwnershipConflict,
+		},
+		"transfer not allowed by the class": {
+			shares:     map[string]int32{"legacy-data": 50},
+			transfer:   true,
+			wantReason: constants.EventShareAdoptionFailed,
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			scheme := runtime.NewScheme()
+			if err := corev1.AddToScheme(scheme); err != nil {
+				t.Fatalf("AddToScheme corev1: %v", err)
+			}
+			if err := storagev1.AddToScheme(scheme); err != nil {
+				t.Fatalf("AddToScheme storagev1: %v", err)
+			}
+
+			sc := &storagev1.StorageClass{ObjectMeta: metav1.ObjectMeta{Name: "azurefile"}, Provisioner: k8s.ManagedProvisioner}
+			pvc := basePVC()
+			pvc.Spec.StorageClassName = stringPtr("azurefile")
+			pvc.Spec.Resources.Requests[corev1.ResourceStorage] = resource.MustParse("10Gi")
+			pvc.Annotations = map[string]string{constants.AdoptShareAnnotation: "legacy-data"}
+			if tc.transfer {
+				pvc.Annotations[constants.TransferOwnershipAnnotation] = "true"
+			}
+
+			shareClient := &azure.FakeShareClient{
+				Shares:  tc.shares,
+				Options: map[string]azure.EnsureShareOptions{"legacy-data": {Metadata: tc.metadata}},
+			}
+			k8sClient := fake.NewClientBuilder().WithScheme(scheme).WithObjects(sc, pvc).WithStatusSubresource(pvc).Build()
+			reconciler := &PVCReconciler{
+				Client:   k8sClient,
+				Scheme:   scheme,
+				Recorder: record.NewFakeRecorder(20),
+				Config:   ReconcilerConfig{ResourceGroup: "rg", StorageAccount: "account", Server: "server"},
+				Shares:   shareClient,
+			}
+
+			ctx := ctrl.LoggerInto(context.Background(), logging.NewLogger())
+			key := client.ObjectKeyFromObject(pvc)
+			if _, err := reconciler.Reconcile(ctx, reconcile.Request{NamespacedName: key}); err != nil {
+				t.Fatalf("Reconcile error = %v", err)
+			}
+
+			updated := &corev1.PersistentVolumeClaim{}
+			if err := k8sClient.Get(ctx, key, updated); err != nil {
+				t.Fatalf("Get PVC error = %v", err)
+			}
+			assertCondition(t, updated, constants.ConditionProvisioningFailed, corev1.ConditionTrue, tc.wantReason)
+			pvList := &corev1.PersistentVolumeList{}
+			if err := k8sClient.List(ctx, pvList); err != nil || len(pvList.Items) != 0 {
+				t.Fatalf("PVs = %d, %v, want none", len(pvList.Items), err)
+			}
+			if _, exists := shareClient.Shares["legacy-data"]; exists != (tc.shares != nil) {
+				t.Fatalf("share exists = %v, want %v", exists, tc.shares != nil)
+			}
+			if got := shareClient.Options["legacy-data"].Metadata[azure.MetadataPVCUID]; got != "" {
+				t.Fatalf("pvc_uid metadata = %q, want share left unstamped", got)
+			}
+		})
+	}
+}
@@ -0,0 +1,156 @@
+package controller
+
+import (
+	"context"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	storagev1 "k8s.io/api/storage/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/tools/record"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	"aks-azureFiles-controller/internal/azure"
+	"aks-azureFiles-controller/internal/constants"
+	"aks-azureFiles-controller/internal/k8s"
+	"aks-azureFiles-controller/internal/logging"
+)
+
+func TestReconcileWaitsForFirstConsumer(t *testing.T) {
+	scheme := runtime.NewScheme()
+	if err := corev1.AddToScheme(scheme); err != nil {
+		t.Fatalf("AddToScheme corev1: %v", err)
+	}
+	if err := storagev1.AddToScheme(scheme); err != nil {
+		t.Fatalf("AddToScheme storagev1: %v", err)
+	}
+
+	mode := storagev1.VolumeBindingWaitForFirstConsumer
+	sc := &storagev1.StorageClass{
+		ObjectMeta:        metav1.ObjectMeta{Name: "azurefile"},
+		Provisioner:       k8s.ManagedProvisioner,
+		VolumeBindingMode: &mode,
+	}
+	node := &corev1.Node{ObjectMeta: metav1.ObjectMeta{
+		Name:   "node-1",
+		Labels: map[string]string{corev1.LabelTopologyRegion: "westeurope"},
+	}}
+
+	pvc := basePVC()
+	pvc.Spec.StorageClassName = stringPtr("azurefile")
+
+	k8sClient := fake.NewClientBuilder().WithScheme(scheme).WithObjects(sc, node, pvc).Build()
+	shareClient := &azure.FakeShareClient{}
+	recorder := record.NewFakeRecorder(20)
+
+	reconciler := &PVCReconciler{
+		Client:   k8sClient,
+		Scheme:   scheme,
+		Recorder: recorder,
+		Config: ReconcilerConfig{
+			ResourceGroup:  "rg",
+			StorageAccount: "account",
+			Server:         "server",
+		},
+		Shares: shareClient,
+	}
+
+	ctx := ctrl.LoggerInto(context.Background(), logging.NewLogger())
+	request := reconcile.Request{NamespacedName: client.ObjectKeyFromObject(pvc)}
+	if _, err := reconciler.Reconcile(ctx, request); err != nil {
+		t.Fatalf("Reconcile error = %v", err)
+	}
+	if len(shareClient.Shares) != 0 {
+		t.Fatalf("Shares = %#v, want none before scheduling", shareClient.Shares)
+	}
+	if !hasEvent(recorder, constants.EventWaitingForConsumer) {
+		t.Fatalf("event %q not recorded", constants.EventWaitingForConsumer)
+	}
+
+	scheduled := &corev1.PersistentVolumeClaim{}
+	if err := k8sClient.Get(ctx, request.NamespacedName, scheduled); err != nil {
+		t.Fatalf("Get PVC error = %v", err)
+	}
+	scheduled.Annotations = map[string]string{constants.SelectedNodeAnnotation: "node-1"}
+	if err := k8sClient.Update(ctx, scheduled); err != nil {
+		t.Fatalf("Update PVC error = %v", err)
+	}
+
+	if _, err := reconciler.Reconcile(ctx, request); err != nil {
+		t.Fatalf("Reconcile error = %v", err)
+	}
+	if _, ok := shareClient.Shares[shareNameForTest(pvc)]; !ok {
+		t.Fatalf("share not created after scheduling")
+	}
+
+	pvList := &corev1.PersistentVolumeList{}
+	if err := k8sClient.List(ctx, pvList); err != nil {
+		t.Fatalf("List PVs error = %v", err)
+	}
+	if len(pvList.Items) != 1 {
+		t.Fatalf("PV count = %d, want 1", len(pvList.Items))
+	}
+	affinity := pvList.Items[0].Spec.NodeAffinity
+	if affinity == nil || affinity.Required == nil {
+		t.Fatalf("NodeAffinity = %#v, want region affinity", affinity)
+	}
+	if got := affinity.Required.NodeSelectorTerms[0].MatchExpressions[0].Values[0]; got != "westeurope" {
+		t.Fatalf("NodeAffinity region = %q, want %q", got, "westeurope")
+	}
+}
+
+func TestReconcileSelectedNodeMissing(t *testing.T) {
+	scheme := runtime.NewScheme()
+	if err := corev1.AddToScheme(scheme); err != nil {
+		t.Fatalf("AddToScheme corev1: %v", err)
+	}
+	if err := storagev1.AddToScheme(scheme); err != nil {
+		t.Fatalf("AddToScheme storagev1: %v", err)
+	}
+
+	mode := storagev1.VolumeBindingWaitForFirstConsumer
+	sc := &storagev1.StorageClass{
+		ObjectMeta:        metav1.ObjectMeta{Name: "azurefile"},
+		Provisioner:       k8s.ManagedProvisioner,
+		VolumeBindingMode: &mode,
+	}
+
+	pvc := basePVC()
+	pvc.Spec.StorageClassName = stringPtr("azurefile")
+	pvc.Annotations = map[string]string{constants.SelectedNodeAnnotation: "gone"}
+
+	k8sClient := fake.NewClientBuilder().WithScheme(scheme).WithObjects(sc, pvc).Build()
+	shareClient := &azure.FakeShareClient{}
+
+	reconciler := &PVCReconciler{
+		Client:   k8sClient,
+		Scheme:   scheme,
+		Recorder: record.NewFakeRecorder(20),
+		Config: ReconcilerConfig{
+			ResourceGroup:  "rg",
+			StorageAccount: "account",
+			Server:         "server",
+		},
+		Shares: shareClient,
+	}
+
+	ctx := ctrl.LoggerInto(context.Background(), logging.NewLogger())
+	if _, err := reconciler.Reconcile(ctx, reconcile.Request{NamespacedName: client.ObjectKeyFromObject(pvc)}); err != nil {
+		t.Fatalf("Reconcile error = %v", err)
+	}
+
+	updated := &corev1.PersistentVolumeClaim{}
+	if err := k8sClient.Get(ctx, client.ObjectKeyFromObject(pvc), updated); err != nil {
+		t.Fatalf("Get PVC error = %v", err)
+	}
+	if _, ok := updated.Annotations[constants.SelectedNodeAnnotation]; ok {
+		t.Fatalf("selected-node annotation not removed")
+	}
+	if len(shareClient.Shares) != 0 {
+		t.Fatalf("Shares = %#v, want none", shareClient.Shares)
+	}
+}
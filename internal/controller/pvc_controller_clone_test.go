@@ -0,0 +1,365 @@
+package controller
+
+import (
+	"context"
+	"slices"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	storagev1 "k8s.io/api/storage/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/tools/record"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	"aks-azureFiles-controller/internal/azure"
+	"aks-azureFiles-controller/internal/constants"
+	"aks-azureFiles-controller/internal/k8s"
+	"aks-azureFiles-controller/internal/logging"
+	"aks-azureFiles-controller/internal/snapshotv1"
+)
+
+func cloneScheme(t *testing.T) *runtime.Scheme {
+	t.Helper()
+	scheme := runtime.NewScheme()
+	if err := corev1.AddToScheme(scheme); err != nil {
+		t.Fatalf("AddToScheme corev1: %v", err)
+	}
+	if err := storagev1.AddToScheme(scheme); err != nil {
+		t.Fatalf("AddToScheme storagev1: %v", err)
+	}
+	if err := snapshotv1.AddToScheme(scheme); err != nil {
+		t.Fatalf("AddToScheme snapshotv1: %v", err)
+	}
+	return scheme
+}
+
+// sourceClaim returns a claim named source bound to a PV of the driver with the volume handle.
+func sourceClaim(handle string) (*corev1.PersistentVolumeClaim, *corev1.PersistentVolume) {
+	source := basePVC()
+	source.Name = "source"
+	source.UID = types.UID("uid-source")
+	source.Spec.VolumeName = "pv-source"
+	pv := &corev1.PersistentVolume{
+		ObjectMeta: metav1.ObjectMeta{Name: "pv-source"},
+		Spec: corev1.PersistentVolumeSpec{
+			ClaimRef: &corev1.ObjectReference{Namespace: source.Namespace, Name: source.Name, UID: source.UID},
+			PersistentVolumeSource: corev1.PersistentVolumeSource{
+				CSI: &corev1.CSIPersistentVolumeSource{Driver: constants.AzureFileCSIDriver, VolumeHandle: handle},
+			},
+		},
+	}
+	return source, pv
+}
+
+func TestReconcileClonesDataSource(t *testing.T) {
+	tests := []struct {
+		name string
+		// setup seeds the source share and returns the objects and data source naming it.
+		setup func(t *testing.T, shares *azure.FakeShareClient) ([]client.Object, *corev1.TypedLocalObjectReference)
+		// wantSnapshots is the number of snapshots of the source share left after the copy.
+		wantSnapshots int
+	}{
+		{
+			name: "persistent volume claim",
+			setup: func(t *testing.T, _ *azure.FakeShareClient) ([]client.Object, *corev1.TypedLocalObjectReference) {
+				source, pv := sourceClaim(k8s.VolumeHandle("rg", "account", "team-source"))
+				return []client.Object{source, pv}, &corev1.TypedLocalObjectReference{Kind: "PersistentVolumeClaim", Name: "source"}
+			},
+		},
+		{
+			name: "volume snapshot",
+			setup: func(t *testing.T, shares *azure.FakeShareClient) ([]client.Object, *corev1.TypedLocalObjectReference) {
+				snapshotID, err := shares.CreateSnapshot(context.Background(), "team-source")
+				if err != nil {
+					t.Fatalf("CreateSnapshot error = %v", err)
+				}
+				// Files written after the snapshot are not copied.
+				shares.Files["team-source"] = append(shares.Files["team-source"], "later.txt")
+				handle := k8s.SnapshotHandle("rg", "account", "team-source", snapshotID)
+				content := &snapshotv1.VolumeSnapshotContent{
+					ObjectMeta: metav1.ObjectMeta{Name: "snapcontent-1"},
+					Spec: snapshotv1.VolumeSnapshotContentSpec{
+						Driver:            k8s.ManagedProvisioner,
+						VolumeSnapshotRef: corev1.ObjectReference{Namespace: "team", Name: "nightly"},
+					},
+					Status: &snapshotv1.VolumeSnapshotContentStatus{SnapshotHandle: &handle},
+				}
+				ready := true
+				snapshot := &snapshotv1.VolumeSnapshot{
+					ObjectMeta: metav1.ObjectMeta{Name: "nightly", Namespace: "team"},
+					Status:     &snapshotv1.VolumeSnapshotStatus{BoundVolumeSnapshotContentName: stringPtr("snapcontent-1"), ReadyToUse: &ready},
+				}
+				group := snapshotv1.GroupVersion.Group
+				return []client.Object{content, snapshot}, &corev1.TypedLocalObjectReference{APIGroup: &group, Kind: "VolumeSnapshot", Name: "nightly"}
+			},
+			wantSnapshots: 1,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := ctrl.LoggerInto(context.Background(), logging.NewLogger())
+			shareClient := &azure.FakeShareClient{Files: map[string][]string{"team-source": {"a.txt", "dir/b.txt"}}}
+			if err := shareClient.EnsureShare(ctx, "team-source", 1, nil); err != nil {
+				t.Fatalf("EnsureShare error = %v", err)
+			}
+			objects, dataSource := tt.setup(t, shareClient)
+
+			sc := &storagev1.StorageClass{ObjectMeta: metav1.ObjectMeta{Name: "azurefile"}, Provisioner: k8s.ManagedProvisioner}
+			pvc := basePVC()
+			pvc.Spec.StorageClassName = stringPtr("azurefile")
+			pvc.Spec.DataSource = dataSource
+			k8sClient := fake.NewClientBuilder().WithScheme(cloneScheme(t)).WithObjects(append(objects, sc, pvc)...).Build()
+			recorder := record.NewFakeRecorder(50)
+			reconciler := &PVCReconciler{
+				Client:   k8sClient,
+				Scheme:   k8sClient.Scheme(),
+				Recorder: recorder,
+				Config:   ReconcilerConfig{ResourceGroup: "rg", StorageAccount: "account", Server: "server"},
+				Shares:   shareClient,
+			}
+
+			key := client.ObjectKeyFromObject(pvc)
+			result, err := reconciler.Reconcile(ctx, reconcile.Request{NamespacedName: key})
+			if err != nil {
+				t.Fatalf("Reconcile error = %v", err)
+			}
+			if result.RequeueAfter != cloneRequeue {
+				t.Fatalf("RequeueAfter = %v, want %v while copying", result.RequeueAfter, cloneRequeue)
+			}
+			if !hasEvent(recorder, constants.EventCloneStarted) {
+				t.Fatalf("event %q not recorded", constants.EventCloneStarted)
+			}
+			copying := &corev1.PersistentVolumeClaim{}
+			if err := k8sClient.Get(ctx, key, copying); err != nil {
+				t.Fatalf("Get PVC error = %v", err)
+			}
+			if got := copying.Annotations[constants.CloneProgressAnnotation]; got != "0/2" {
+				t.Fatalf("%s = %q, want 0/2", constants.CloneProgressAnnotation, got)
+			}
+			assertCondition(t, copying, constants.ConditionShareReady, corev1.ConditionFalse, constants.EventCloneInProgress)
+			// Files written to the source while it is copied are not copied.
+			shareClient.Files["team-source"] = append(shareClient.Files["team-source"], "during.txt")
+			pvs := &corev1.PersistentVolumeList{}
+			if err := k8sClient.List(ctx, pvs, client.MatchingLabels{k8s.LabelPVCName: pvc.Name}); err != nil || len(pvs.Items) != 0 {
+				t.Fatalf("PVs = %d, %v, want none before the copy completes", len(pvs.Items), err)
+			}
+
+			if _, err := reconciler.Reconcile(ctx, reconcile.Request{NamespacedName: key}); err != nil {
+				t.Fatalf("Reconcile error = %v", err)
+			}
+			if !hasEvent(recorder, constants.EventCloneCompleted) {
+				t.Fatalf("event %q not recorded", constants.EventCloneCompleted)
+			}
+			if got := shareClient.Files[shareNameForTest(pvc)]; !slices.Equal(got, []string{"a.txt", "dir/b.txt"}) {
+				t.Fatalf("cloned files = %v, want a.txt and dir/b.txt", got)
+			}
+			if err := k8sClient.List(ctx, pvs, client.MatchingLabels{k8s.LabelPVCName: pvc.Name}); err != nil || len(pvs.Items) != 1 {
+				t.Fatalf("PVs = %d, %v, want one after the copy completes", len(pvs.Items), err)
+			}
+			cloned := &corev1.PersistentVolumeClaim{}
+			if err := k8sClient.Get(ctx, key, cloned); err != nil {
+				t.Fatalf("Get PVC error = %v", err)
+			}
+			if got := cloned.Annotations[constants.CloneProgressAnnotation]; got != cloneComplete {
+				t.Fatalf("%s = %q, want %s", constants.CloneProgressAnnotation, got, cloneComplete)
+			}
+			if got := cloned.Annotations[constants.CloneSnapshotAnnotation]; got != "" {
+				t.Fatalf("%s = %q, want it removed", constants.CloneSnapshotAnnotation, got)
+			}
+			if got := shareClient.Snapshots["team-source"]; len(got) != tt.wantSnapshots {
+				t.Fatalf("source snapshots = %v, want %d", got, tt.wantSnapshots)
+			}
+		})
+	}
+}
+
+func TestReconcileCloneFailures(t *testing.T) {
+	notReady := false
+	snapshotGroup := snapshotv1.GroupVersion.Group
+	tests := []struct {
+		name         string
+		dataSource   *corev1.TypedLocalObjectReference
+		sourceHandle string
+		// sourceOwner tags the source shares with an owner namespace.
+		sourceOwner string
+		// pvClaimUID binds the source PV to another claim.
+		pvClaimUID string
+		// contentSnapshot makes the snapshot ready with a content bound to the named snapshot.
+		contentSnapshot string
+		wantReason      string
+		wantRequeue     bool
+	}{
+		{
+			name:         "other account",
+			dataSource:   &corev1.TypedLocalObjectReference{Kind: "PersistentVolumeClaim", Name: "source"},
+			sourceHandle: k8s.VolumeHandle("rg", "elsewhere", "team-source"),
+			wantReason:   constants.EventCloneFailed,
+		},
+		{
+			name:       "unsupported kind",
+			dataSource: &corev1.TypedLocalObjectReference{Kind: "ConfigMap", Name: "source"},
+			wantReason: constants.EventCloneFailed,
+		},
+		{
+			name:         "source larger than the claim",
+			dataSource:   &corev1.TypedLocalObjectReference{Kind: "PersistentVolumeClaim", Name: "source"},
+			sourceHandle: k8s.VolumeHandle("rg", "account", "team-large"),
+			wantReason:   constants.EventCloneFailed,
+		},
+		{
+			name:         "source pv bound to another claim",
+			dataSource:   &corev1.TypedLocalObjectReference{Kind: "PersistentVolumeClaim", Name: "source"},
+			sourceHandle: k8s.VolumeHandle("rg", "account", "team-source"),
+			pvClaimUID:   "uid-other",
+			wantReason:   constants.EventCloneFailed,
+		},
+		{
+			name:         "source share of another namespace",
+			dataSource:   &corev1.TypedLocalObjectReference{Kind: "PersistentVolumeClaim", Name: "source"},
+			sourceHandle: k8s.VolumeHandle("rg", "account", "team-source"),
+			sourceOwner:  "ops",
+			wantReason:   constants.EventCloneFailed,
+		},
+		{
+			name:            "content bound to another snapshot",
+			dataSource:      &corev1.TypedLocalObjectReference{APIGroup: &snapshotGroup, Kind: "VolumeSnapshot", Name: "nightly"},
+			contentSnapshot: "weekly",
+			wantReason:      constants.EventCloneFailed,
+		},
+		{
+			name:        "source not provisioned",
+			dataSource:  &corev1.TypedLocalObjectReference{Kind: "PersistentVolumeClaim", Name: "source"},
+			wantReason:  constants.EventCloneSourceNotReady,
+			wantRequeue: true,
+		},
+		{
+			name:        "snapshot not ready",
+			dataSource:  &corev1.TypedLocalObjectReference{APIGroup: &snapshotGroup, Kind: "VolumeSnapshot", Name: "nightly"},
+			wantReason:  constants.EventCloneSourceNotReady,
+			wantRequeue: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := ctrl.LoggerInto(context.Background(), logging.NewLogger())
+			shareClient := &azure.FakeShareClient{}
+			var opts *azure.EnsureShareOptions
+			if tt.sourceOwner != "" {
+				opts = &azure.EnsureShareOptions{Metadata: map[string]string{azure.MetadataOwnerNamespace: tt.sourceOwner}}
+			}
+			for name, quota := range map[string]int32{"team-source": 1, "team-large": 5} {
+				if err := shareClient.EnsureShare(ctx, name, quota, opts); err != nil {
+					t.Fatalf("EnsureShare error = %v", err)
+				}
+			}
+
+			sc := &storagev1.StorageClass{ObjectMeta: metav1.ObjectMeta{Name: "azurefile"}, Provisioner: k8s.ManagedProvisioner}
+			source := basePVC()
+			source.Name = "source"
+			objects := []client.Object{sc}
+			if tt.sourceHandle != "" {
+				var pv *corev1.PersistentVolume
+				source, pv = sourceClaim(tt.sourceHandle)
+				if tt.pvClaimUID != "" {
+					pv.Spec.ClaimRef.UID = types.UID(tt.pvClaimUID)
+				}
+				objects = append(objects, pv)
+			}
+			snapshot := &snapshotv1.VolumeSnapshot{
+				ObjectMeta: metav1.ObjectMeta{Name: "nightly", Namespace: "team"},
+				Status:     &snapshotv1.VolumeSnapshotStatus{ReadyToUse: &notReady},
+			}
+			if tt.contentSnapshot != "" {
+				ready := true
+				handle := k8s.SnapshotHandle("rg", "account", "team-source", "snap-1")
+				snapshot.Status = &snapshotv1.VolumeSnapshotStatus{BoundVolumeSnapshotContentName: stringPtr("snapcontent-1"), ReadyToUse: &ready}
+				objects = append(objects, &snapshotv1.VolumeSnapshotContent{
+					ObjectMeta: metav1.ObjectMeta{Name: "snapcontent-1"},
+					Spec: snapshotv1.VolumeSnapshotContentSpec{
+						Driver:            k8s.ManagedProvisioner,
+						VolumeSnapshotRef: corev1.ObjectReference{Namespace: "team", Name: tt.contentSnapshot},
+					},
+					Status: &snapshotv1.VolumeSnapshotContentStatus{SnapshotHandle: &handle},
+				})
+			}
+			pvc := basePVC()
+			pvc.Spec.StorageClassName = stringPtr("azurefile")
+			pvc.Spec.DataSource = tt.dataSource
+			k8sClient := fake.NewClientBuilder().WithScheme(cloneScheme(t)).WithObjects(append(objects, source, snapshot, pvc)...).Build()
+			recorder := record.NewFakeRecorder(50)
+			reconciler := &PVCReconciler{
+				Client:   k8sClient,
+				Scheme:   k8sClient.Scheme(),
+				Recorder: recorder,
+				Config:   ReconcilerConfig{ResourceGroup: "rg", StorageAccount: "account", Server: "server"},
+				Shares:   shareClient,
+			}
+
+			key := client.ObjectKeyFromObject(pvc)
+			result, err := reconciler.Reconcile(ctx, reconcile.Request{NamespacedName: key})
+			if err != nil {
+				t.Fatalf("Reconcile error = %v", err)
+			}
+			if got := result.RequeueAfter == cloneRequeue; got != tt.wantRequeue {
+				t.Fatalf("RequeueAfter = %v, want requeue %v", result.RequeueAfter, tt.wantRequeue)
+			}
+			updated := &corev1.PersistentVolumeClaim{}
+			if err := k8sClient.Get(ctx, key, updated); err != nil {
+				t.Fatalf("Get PVC error = %v", err)
+			}
+			if tt.wantRequeue {
+				assertCondition(t, updated, constants.ConditionShareReady, corev1.ConditionFalse, tt.wantReason)
+			} else {
+				assertCondition(t, updated, constants.ConditionProvisioningFailed, corev1.ConditionTrue, tt.wantReason)
+			}
+			if got := shareClient.Files[shareNameForTest(pvc)]; len(got) != 0 {
+				t.Fatalf("cloned files = %v, want none", got)
+			}
+		})
+	}
+}
+
+func TestReconcileDeletionReleasesCloneSnapshot(t *testing.T) {
+	ctx := ctrl.LoggerInto(context.Background(), logging.NewLogger())
+	shareClient := &azure.FakeShareClient{Files: map[string][]string{"team-source": {"a.txt"}}}
+	if err := shareClient.EnsureShare(ctx, "team-source", 1, nil); err != nil {
+		t.Fatalf("EnsureShare error = %v", err)
+	}
+	source, pv := sourceClaim(k8s.VolumeHandle("rg", "account", "team-source"))
+	sc := &storagev1.StorageClass{ObjectMeta: metav1.ObjectMeta{Name: "azurefile"}, Provisioner: k8s.ManagedProvisioner}
+	pvc := basePVC()
+	pvc.Spec.StorageClassName = stringPtr("azurefile")
+	pvc.Spec.DataSource = &corev1.TypedLocalObjectReference{Kind: "PersistentVolumeClaim", Name: "source"}
+	k8sClient := fake.NewClientBuilder().WithScheme(cloneScheme(t)).WithObjects(sc, source, pv, pvc).Build()
+	reconciler := &PVCReconciler{
+		Client:   k8sClient,
+		Scheme:   k8sClient.Scheme(),
+		Recorder: record.NewFakeRecorder(50),
+		Config:   ReconcilerConfig{ResourceGroup: "rg", StorageAccount: "account", Server: "server"},
+		Shares:   shareClient,
+	}
+
+	key := client.ObjectKeyFromObject(pvc)
+	if _, err := reconciler.Reconcile(ctx, reconcile.Request{NamespacedName: key}); err != nil {
+		t.Fatalf("Reconcile error = %v", err)
+	}
+	if got := shareClient.Snapshots["team-source"]; len(got) != 1 {
+		t.Fatalf("source snapshots = %v, want one while copying", got)
+	}
+	if err := k8sClient.Delete(ctx, pvc); err != nil {
+		t.Fatalf("Delete PVC error = %v", err)
+	}
+	if _, err := reconciler.Reconcile(ctx, reconcile.Request{NamespacedName: key}); err != nil {
+		t.Fatalf("Reconcile deletion error = %v", err)
+	}
+	if got := shareClient.Snapshots["team-source"]; len(got) != 0 {
+		t.Fatalf("source snapshots = %v, want the clone snapshot deleted", got)
+	}
+}
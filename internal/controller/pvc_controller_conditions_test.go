@@ -0,0 +1,242 @@
+package controller
+
+import (
+	"context"
+	"strconv"
+	"strings"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	storagev1 "k8s.io/api/storage/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/tools/record"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	"aks-azureFiles-controller/internal/azure"
+	"aks-azureFiles-controller/internal/constants"
+	"aks-azureFiles-controller/internal/k8s"
+	"aks-azureFiles-controller/internal/logging"
+)
+
+func TestReconcileReportsProvisioningConditions(t *testing.T) {
+	scheme := runtime.NewScheme()
+	if err := corev1.AddToScheme(scheme); err != nil {
+		t.Fatalf("AddToScheme corev1: %v", err)
+	}
+	if err := storagev1.AddToScheme(scheme); err != nil {
+		t.Fatalf("AddToScheme storagev1: %v", err)
+	}
+
+	sc := &storagev1.StorageClass{ObjectMeta: metav1.ObjectMeta{Name: "azurefile"}, Provisioner: k8s.ManagedProvisioner}
+	pvc := basePVC()
+	pvc.Spec.StorageClassName = stringPtr("azurefile")
+
+	k8sClient := fake.NewClientBuilder().WithScheme(scheme).WithObjects(sc, pvc).Build()
+	recorder := record.NewFakeRecorder(20)
+	reconciler := &PVCReconciler{
+		Client:   k8sClient,
+		Scheme:   scheme,
+		Recorder: recorder,
+		Config:   ReconcilerConfig{ResourceGroup: "rg", StorageAccount: "account", Server: "server"},
+		Shares:   &azure.FakeShareClient{},
+	}
+
+	ctx := ctrl.LoggerInto(context.Background(), logging.NewLogger())
+	request := reconcile.Request{NamespacedName: client.ObjectKeyFromObject(pvc)}
+	if _, err := reconciler.Reconcile(ctx, request); err != nil {
+		t.Fatalf("Reconcile error = %v", err)
+	}
+
+	updated := &corev1.PersistentVolumeClaim{}
+	if err := k8sClient.Get(ctx, request.NamespacedName, updated); err != nil {
+		t.Fatalf("Get PVC error = %v", err)
+	}
+	assertCondition(t, updated, constants.ConditionShareReady, corev1.ConditionTrue, constants.EventShareReady)
+	assertCondition(t, updated, constants.ConditionProvisioningFailed, corev1.ConditionFalse, constants.EventShareReady)
+	assertCondition(t, updated, constants.ConditionPVBound, corev1.ConditionFalse, constants.EventPVCreated)
+	if got, want := updated.Annotations[constants.ObservedGenerationAnnotation], strconv.FormatInt(updated.Generation, 10); got != want {
+		t.Fatalf("observed generation = %q, want %q", got, want)
+	}
+	readySince := findPVCCondition(updated, corev1.PersistentVolumeClaimConditionType(constants.ConditionShareReady)).LastTransitionTime
+
+	// The PV controller binds the claim; the next reconcile reports it once.
+	pvList := &corev1.PersistentVolumeList{}
+	if err := k8sClient.List(ctx, pvList); err != nil || len(pvList.Items) != 1 {
+		t.Fatalf("List PVs = %v, %v, want one PV", pvList.Items, err)
+	}
+	updated.Spec.VolumeName = pvList.Items[0].Name
+	if err := k8sClient.Update(ctx, updated); err != nil {
+		t.Fatalf("Update PVC error = %v", err)
+	}
+	updated.Status.Phase = corev1.ClaimBound
+	if err := k8sClient.Status().Update(ctx, updated); err != nil {
+		t.Fatalf("Update PVC status error = %v", err)
+	}
+
+	for range 2 {
+		if _, err := reconciler.Reconcile(ctx, request); err != nil {
+			t.Fatalf("Reconcile error = %v", err)
+		}
+	}
+	bound := &corev1.PersistentVolumeClaim{}
+	if err := k8sClient.Get(ctx, request.NamespacedName, bound); err != nil {
+		t.Fatalf("Get PVC error = %v", err)
+	}
+	assertCondition(t, bound, constants.ConditionPVBound, corev1.ConditionTrue, constants.EventPVBound)
+	if got := findPVCCondition(bound, corev1.PersistentVolumeClaimConditionType(constants.ConditionShareReady)).LastTransitionTime; !got.Equal(&readySince) {
+		t.Fatalf("ShareReady LastTransitionTime = %v, want unchanged %v", got, readySince)
+	}
+	if got := countEvents(recorder, constants.EventPVBound); got != 1 {
+		t.Fatalf("PVBound events = %d, want 1", got)
+	}
+}
+
+func TestReconcileReportsProvisioningFailed(t *testing.T) {
+	scheme := runtime.NewScheme()
+	if err := corev1.AddToScheme(scheme); err != nil {
+		t.Fatalf("AddToScheme corev1: %v", err)
+	}
+	if err := storagev1.AddToScheme(scheme); err != nil {
+		t.Fatalf("AddToScheme storagev1: %v", err)
+	}
+
+	sc := &storagev1.StorageClass{
+		ObjectMeta:  metav1.ObjectMeta{Name: "azurefile"},
+		Provisioner: k8s.ManagedProvisioner,
+		Parameters:  map[string]string{"skuname": "Standard_LRS"},
+	}
+	pvc := basePVC()
+	pvc.Spec.StorageClassName = stringPtr("azurefile")
+
+	k8sClient := fake.NewClientBuilder().WithScheme(scheme).WithObjects(sc, pvc).Build()
+	reconciler := &PVCReconciler{
+		Client:   k8sClient,
+		Scheme:   scheme,
+		Recorder: record.NewFakeRecorder(10),
+		Config:   ReconcilerConfig{ResourceGroup: "rg", StorageAccount: "account", Server: "server"},
+		Shares:   &azure.FakeShareClient{},
+	}
+
+	ctx := ctrl.LoggerInto(context.Background(), logging.NewLogger())
+	request := reconcile.Request{NamespacedName: client.ObjectKeyFromObject(pvc)}
+	if _, err := reconciler.Reconcile(ctx, request); err != nil {
+		t.Fatalf("Reconcile error = %v", err)
+	}
+
+	updated := &corev1.PersistentVolumeClaim{}
+	if err := k8sClient.Get(ctx, request.NamespacedName, updated); err != nil {
+		t.Fatalf("Get PVC error = %v", err)
+	}
+	assertCondition(t, updated, constants.ConditionProvisioningFailed, corev1.ConditionTrue, constants.EventShareValidation)
+	if findPVCCondition(updated, corev1.PersistentVolumeClaimConditionType(constants.ConditionShareReady)) != nil {
+		t.Fatalf("ShareReady condition set, want none")
+	}
+}
+
+func TestReconcileReportsWaitingForConsumer(t *testing.T) {
+	scheme := runtime.NewScheme()
+	if err := corev1.AddToScheme(scheme); err != nil {
+		t.Fatalf("AddToScheme corev1: %v", err)
+	}
+	if err := storagev1.AddToScheme(scheme); err != nil {
+		t.Fatalf("AddToScheme storagev1: %v", err)
+	}
+
+	mode := storagev1.VolumeBindingWaitForFirstConsumer
+	sc := &storagev1.StorageClass{
+		ObjectMeta:        metav1.ObjectMeta{Name: "azurefile"},
+		Provisioner:       k8s.ManagedProvisioner,
+		VolumeBindingMode: &mode,
+	}
+	pvc := basePVC()
+	pvc.Spec.StorageClassName = stringPtr("azurefile")
+
+	k8sClient := fake.NewClientBuilder().WithScheme(scheme).WithObjects(sc, pvc).Build()
+	reconciler := &PVCReconciler{
+		Client:   k8sClient,
+		Scheme:   scheme,
+		Recorder: record.NewFakeRecorder(10),
+		Config:   ReconcilerConfig{ResourceGroup: "rg", StorageAccount: "account", Server: "server"},
+		Shares:   &azure.FakeShareClient{},
+	}
+
+	ctx := ctrl.LoggerInto(context.Background(), logging.NewLogger())
+	request := reconcile.Request{NamespacedName: client.ObjectKeyFromObject(pvc)}
+	if _, err := reconciler.Reconcile(ctx, request); err != nil {
+		t.Fatalf("Reconcile error = %v", err)
+	}
+
+	updated := &corev1.PersistentVolumeClaim{}
+	if err := k8sClient.Get(ctx, request.NamespacedName, updated); err != nil {
+		t.Fatalf("Get PVC error = %v", err)
+	}
+	assertCondition(t, updated, constants.ConditionShareReady, corev1.ConditionFalse, constants.EventWaitingForConsumer)
+}
+
+func TestSetConditionsKeepsConcurrentConditions(t *testing.T) {
+	scheme := runtime.NewScheme()
+	if err := corev1.AddToScheme(scheme); err != nil {
+		t.Fatalf("AddToScheme corev1: %v", err)
+	}
+
+	pvc := basePVC()
+	k8sClient := fake.NewClientBuilder().WithScheme(scheme).WithObjects(pvc).WithStatusSubresource(pvc).Build()
+	reconciler := &PVCReconciler{Client: k8sClient, Scheme: scheme, Recorder: record.NewFakeRecorder(10)}
+
+	ctx := ctrl.LoggerInto(context.Background(), logging.NewLogger())
+	stale := &corev1.PersistentVolumeClaim{}
+	if err := k8sClient.Get(ctx, client.ObjectKeyFromObject(pvc), stale); err != nil {
+		t.Fatalf("Get PVC error = %v", err)
+	}
+
+	// The resizer reports a condition after the reconciler read the claim.
+	current := stale.DeepCopy()
+	current.Status.Conditions = append(current.Status.Conditions, corev1.PersistentVolumeClaimCondition{
+		Type:   corev1.PersistentVolumeClaimResizing,
+		Status: corev1.ConditionTrue,
+	})
+	if err := k8sClient.Status().Update(ctx, current); err != nil {
+		t.Fatalf("Update PVC status error = %v", err)
+	}
+
+	if err := reconciler.setConditions(ctx, stale, claimCondition(constants.ConditionShareReady, corev1.ConditionTrue, constants.EventShareReady, "ready")); err != nil {
+		t.Fatalf("setConditions error = %v", err)
+	}
+	updated := &corev1.PersistentVolumeClaim{}
+	if err := k8sClient.Get(ctx, client.ObjectKeyFromObject(pvc), updated); err != nil {
+		t.Fatalf("Get PVC error = %v", err)
+	}
+	assertCondition(t, updated, constants.ConditionShareReady, corev1.ConditionTrue, constants.EventShareReady)
+	if findPVCCondition(updated, corev1.PersistentVolumeClaimResizing) == nil {
+		t.Fatalf("Resizing condition lost, conditions = %#v", updated.Status.Conditions)
+	}
+}
+
+func assertCondition(t *testing.T, pvc *corev1.PersistentVolumeClaim, conditionType string, status corev1.ConditionStatus, reason string) {
+	t.Helper()
+	condition := findPVCCondition(pvc, corev1.PersistentVolumeClaimConditionType(conditionType))
+	if condition == nil {
+		t.Fatalf("condition %s missing, conditions = %#v", conditionType, pvc.Status.Conditions)
+	}
+	if condition.Status != status || condition.Reason != reason {
+		t.Fatalf("condition %s = %s/%s, want %s/%s", conditionType, condition.Status, condition.Reason, status, reason)
+	}
+}
+
+func countEvents(recorder *record.FakeRecorder, reason string) int {
+	count := 0
+	for {
+		select {
+		case event := <-recorder.Events:
+			if strings.Contains(event, " "+reason+" ") {
+				count++
+			}
+		default:
+			return count
+		}
+	}
+}
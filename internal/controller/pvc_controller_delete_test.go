@@ -23,6 +23,7 @@ import (
 	"aks-azureFiles-controller/internal/k8s"
 	"aks-azureFiles-controller/internal/logging"
 	"aks-azureFiles-controller/internal/naming"
+	"aks-azureFiles-controller/internal/snapshotv1"
 )
 
 func TestReconcileAddsFinalizer(t *testing.T) {
@@ -154,6 +155,145 @@ func TestReconcileDeletionIdempotent(t *testing.T) {
 	}
 }
 
+func TestReconcileRetainPolicyKeepsShareAndPV(t *testing.T) {
+	scheme := runtime.NewScheme()
+	if err := corev1.AddToScheme(scheme); err != nil {
+		t.Fatalf("AddToScheme corev1: %v", err)
+	}
+	if err := storagev1.AddToScheme(scheme); err != nil {
+		t.Fatalf("AddToScheme storagev1: %v", err)
+	}
+
+	retain := corev1.PersistentVolumeReclaimRetain
+	sc := &storagev1.StorageClass{
+		ObjectMeta:    metav1.ObjectMeta{Name: "azurefile"},
+		Provisioner:   k8s.ManagedProvisioner,
+		ReclaimPolicy: &retain,
+	}
+
+	pvc := basePVC()
+	pvc.Spec.StorageClassName = stringPtr("azurefile")
+
+	k8sClient := fake.NewClientBuilder().WithScheme(scheme).WithObjects(sc, pvc).Build()
+	shareClient := &azure.FakeShareClient{}
+
+	reconciler := &PVCReconciler{
+		Client:   k8sClient,
+		Scheme:   scheme,
+		Recorder: record.NewFakeRecorder(20),
+		Config: ReconcilerConfig{
+			ResourceGroup:  "rg",
+			StorageAccount: "account",
+			Server:         "server",
+		},
+		Shares: shareClient,
+	}
+
+	ctx := ctrl.LoggerInto(context.Background(), logging.NewLogger())
+	request := reconcile.Request{NamespacedName: client.ObjectKeyFromObject(pvc)}
+	if _, err := reconciler.Reconcile(ctx, request); err != nil {
+		t.Fatalf("Reconcile error = %v", err)
+	}
+
+	pvList := &corev1.PersistentVolumeList{}
+	if err := k8sClient.List(ctx, pvList); err != nil {
+		t.Fatalf("List PVs error = %v", err)
+	}
+	if len(pvList.Items) != 1 {
+		t.Fatalf("PV count = %d, want 1", len(pvList.Items))
+	}
+	if pvList.Items[0].Spec.PersistentVolumeReclaimPolicy != corev1.PersistentVolumeReclaimRetain {
+		t.Fatalf("ReclaimPolicy = %q, want %q", pvList.Items[0].Spec.PersistentVolumeReclaimPolicy, corev1.PersistentVolumeReclaimRetain)
+	}
+
+	provisioned := &corev1.PersistentVolumeClaim{}
+	if err := k8sClient.Get(ctx, request.NamespacedName, provisioned); err != nil {
+		t.Fatalf("Get PVC error = %v", err)
+	}
+	if err := k8sClient.Delete(ctx, provisioned); err != nil {
+		t.Fatalf("Delete PVC error = %v", err)
+	}
+	if _, err := reconciler.Reconcile(ctx, request); err != nil {
+		t.Fatalf("Reconcile error = %v", err)
+	}
+
+	if err := k8sClient.Get(ctx, request.NamespacedName, &corev1.PersistentVolumeClaim{}); !errors.IsNotFound(err) {
+		t.Fatalf("Get PVC error = %v, want NotFound", err)
+	}
+	if err := k8sClient.List(ctx, pvList); err != nil {
+		t.Fatalf("List PVs error = %v", err)
+	}
+	if len(pvList.Items) != 1 {
+		t.Fatalf("PV count = %d, want 1", len(pvList.Items))
+	}
+	if _, ok := shareClient.Shares[shareNameForTest(pvc)]; !ok {
+		t.Fatalf("share deleted, want retained")
+	}
+}
+
+func TestReconcileRetainAnnotationOverridesDeletePolicy(t *testing.T) {
+	scheme := runtime.NewScheme()
+	if err := corev1.AddToScheme(scheme); err != nil {
+		t.Fatalf("AddToScheme corev1: %v", err)
+	}
+	if err := storagev1.AddToScheme(scheme); err != nil {
+		t.Fatalf("AddToScheme storagev1: %v", err)
+	}
+
+	sc := &storagev1.StorageClass{
+		ObjectMeta:  metav1.ObjectMeta{Name: "azurefile"},
+		Provisioner: k8s.ManagedProvisioner,
+	}
+
+	pvc := basePVC()
+	pvc.Spec.StorageClassName = stringPtr("azurefile")
+	pvc.Finalizers = []string{constants.FinalizerName}
+	now := metav1.NewTime(time.Now())
+	pvc.DeletionTimestamp = &now
+
+	shareName := shareNameForTest(pvc)
+	pvc.Annotations = map[string]string{
+		constants.ShareNameAnnotation:   shareName,
+		constants.RetainShareAnnotation: "true",
+	}
+
+	pv, err := k8s.BuildPV(pvc, shareName, "rg", "account", "server", corev1.PersistentVolumeReclaimDelete)
+	if err != nil {
+		t.Fatalf("BuildPV error = %v", err)
+	}
+
+	k8sClient := fake.NewClientBuilder().WithScheme(scheme).WithObjects(sc, pvc, pv).Build()
+	shareClient := &azure.FakeShareClient{Shares: map[string]int32{shareName: 1}}
+
+	reconciler := &PVCReconciler{
+		Client:   k8sClient,
+		Scheme:   scheme,
+		Recorder: record.NewFakeRecorder(10),
+		Config: ReconcilerConfig{
+			ResourceGroup:  "rg",
+			StorageAccount: "account",
+			Server:         "server",
+		},
+		Shares: shareClient,
+	}
+
+	ctx := ctrl.LoggerInto(context.Background(), logging.NewLogger())
+	if _, err := reconciler.Reconcile(ctx, reconcile.Request{NamespacedName: client.ObjectKeyFromObject(pvc)}); err != nil {
+		t.Fatalf("Reconcile error = %v", err)
+	}
+
+	if _, ok := shareClient.Shares[shareName]; !ok {
+		t.Fatalf("share deleted, want retained")
+	}
+	retained := &corev1.PersistentVolume{}
+	if err := k8sClient.Get(ctx, client.ObjectKey{Name: pv.Name}, retained); err != nil {
+		t.Fatalf("Get PV error = %v", err)
+	}
+	if retained.Spec.PersistentVolumeReclaimPolicy != corev1.PersistentVolumeReclaimRetain {
+		t.Fatalf("ReclaimPolicy = %q, want %q", retained.Spec.PersistentVolumeReclaimPolicy, corev1.PersistentVolumeReclaimRetain)
+	}
+}
+
 func basePVC() *corev1.PersistentVolumeClaim {
 	return &corev1.PersistentVolumeClaim{
 		ObjectMeta: metav1.ObjectMeta{
@@ -180,3 +320,247 @@ func containsFinalizer(finalizers []string, name string) bool {
 	}
 	return false
 }
+
+func TestReconcileSnapshotBeforeDelete(t *testing.T) {
+	cases := map[string]struct {
+		params        map[string]string
+		annotations   map[string]string
+		gracePeriod   time.Duration
+		wantScheduled bool
+	}{
+		"class snapshots then schedules deletion": {
+			params:        map[string]string{k8s.ParamSnapshotBeforeDelete: k8s.SnapshotThenDelete},
+			gracePeriod:   time.Hour,
+			wantScheduled: true,
+		},
+		"annotation retains with snapshot": {
+			annotations: map[string]string{constants.SnapshotBeforeDeleteAnnotation: k8s.SnapshotRetain},
+		},
+	}
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			scheme := runtime.NewScheme()
+			if err := corev1.AddToScheme(scheme); err != nil {
+				t.Fatalf("AddToScheme corev1: %v", err)
+			}
+			if err := storagev1.AddToScheme(scheme); err != nil {
+				t.Fatalf("AddToScheme storagev1: %v", err)
+			}
+
+			sc := &storagev1.StorageClass{ObjectMeta: metav1.ObjectMeta{Name: "azurefile"}, Provisioner: k8s.ManagedProvisioner, Parameters: tc.params}
+			pvc := basePVC()
+			pvc.Spec.StorageClassName = stringPtr("azurefile")
+			pvc.Annotations = tc.annotations
+
+			shareClient := &azure.FakeShareClient{}
+			k8sClient := fake.NewClientBuilder().WithScheme(scheme).WithObjects(sc, pvc).Build()
+			recorder := record.NewFakeRecorder(20)
+			reconciler := &PVCReconciler{
+				Client:   k8sClient,
+				Scheme:   scheme,
+				Recorder: recorder,
+				Config:   ReconcilerConfig{ResourceGroup: "rg", StorageAccount: "account", Server: "server", DeletionGracePeriod: tc.gracePeriod},
+				Shares:   shareClient,
+			}
+
+			ctx := ctrl.LoggerInto(context.Background(), logging.NewLogger())
+			key := client.ObjectKeyFromObject(pvc)
+			for range 2 {
+				if _, err := reconciler.Reconcile(ctx, reconcile.Request{NamespacedName: key}); err != nil {
+					t.Fatalf("Reconcile error = %v", err)
+				}
+			}
+			shareName := shareNameForTest(pvc)
+
+			provisioned := &corev1.PersistentVolumeClaim{}
+			if err := k8sClient.Get(ctx, key, provisioned); err != nil {
+				t.Fatalf("Get PVC error = %v", err)
+			}
+			if err := k8sClient.Delete(ctx, provisioned); err != nil {
+				t.Fatalf("Delete PVC error = %v", err)
+			}
+			for range 2 {
+				if _, err := reconciler.Reconcile(ctx, reconcile.Request{NamespacedName: key}); err != nil {
+					t.Fatalf("Reconcile deletion error = %v", err)
+				}
+			}
+			if got := countEvents(recorder, constants.EventShareSnapshotCreated); got != 1 {
+				t.Fatalf("%s events = %d, want 1", constants.EventShareSnapshotCreated, got)
+			}
+			if err := k8sClient.Get(ctx, key, &corev1.PersistentVolumeClaim{}); !errors.IsNotFound(err) {
+				t.Fatalf("Get PVC error = %v, want not found", err)
+			}
+
+			props, err := shareClient.GetShare(ctx, shareName)
+			if err != nil {
+				t.Fatalf("GetShare error = %v, want the share kept with its snapshot", err)
+			}
+			metadata := props.Metadata
+			if got := len(shareClient.Snapshots[shareName]); got != 1 {
+				t.Fatalf("snapshots = %d, want 1", got)
+			}
+			pvList := &corev1.PersistentVolumeList{}
+			if tc.wantScheduled {
+				if metadata[azure.MetadataDeleteAfter] == "" {
+					t.Fatalf("metadata = %v, want %s scheduled", metadata, azure.MetadataDeleteAfter)
+				}
+				if err := k8sClient.List(ctx, pvList); err != nil || len(pvList.Items) != 0 {
+					t.Fatalf("List PVs = %v, %v, want the PV deleted", pvList.Items, err)
+				}
+			} else {
+				if err := k8sClient.List(ctx, pvList); err != nil || len(pvList.Items) != 1 {
+					t.Fatalf("List PVs = %v, %v, want the retained PV", pvList.Items, err)
+				}
+				if got := pvList.Items[0].Spec.PersistentVolumeReclaimPolicy; got != corev1.PersistentVolumeReclaimRetain {
+					t.Fatalf("PV reclaim policy = %q, want Retain", got)
+				}
+			}
+			if metadata[azure.MetadataDeletionSnapshot] == "" {
+				t.Fatalf("metadata = %v, want %s recorded", metadata, azure.MetadataDeletionSnapshot)
+			}
+		})
+	}
+}
+
+func TestReconcileSnapshotThenDeleteRequiresGracePeriod(t *testing.T) {
+	scheme := runtime.NewScheme()
+	if err := corev1.AddToScheme(scheme); err != nil {
+		t.Fatalf("AddToScheme corev1: %v", err)
+	}
+	if err := storagev1.AddToScheme(scheme); err != nil {
+		t.Fatalf("AddToScheme storagev1: %v", err)
+	}
+	ctx := ctrl.LoggerInto(context.Background(), logging.NewLogger())
+
+	t.Run("class refuses provisioning", func(t *testing.T) {
+		sc := &storagev1.StorageClass{
+			ObjectMeta:  metav1.ObjectMeta{Name: "azurefile"},
+			Provisioner: k8s.ManagedProvisioner,
+			Parameters:  map[string]string{k8s.ParamSnapshotBeforeDelete: k8s.SnapshotThenDelete},
+		}
+		pvc := basePVC()
+		pvc.Spec.StorageClassName = stringPtr("azurefile")
+
+		shareClient := &azure.FakeShareClient{}
+		k8sClient := fake.NewClientBuilder().WithScheme(scheme).WithObjects(sc, pvc).Build()
+		reconciler := &PVCReconciler{
+			Client:   k8sClient,
+			Scheme:   scheme,
+			Recorder: record.NewFakeRecorder(10),
+			Config:   ReconcilerConfig{ResourceGroup: "rg", StorageAccount: "account", Server: "server"},
+			Shares:   shareClient,
+		}
+
+		request := reconcile.Request{NamespacedName: client.ObjectKeyFromObject(pvc)}
+		if _, err := reconciler.Reconcile(ctx, request); err != nil {
+			t.Fatalf("Reconcile error = %v", err)
+		}
+		updated := &corev1.PersistentVolumeClaim{}
+		if err := k8sClient.Get(ctx, request.NamespacedName, updated); err != nil {
+			t.Fatalf("Get PVC error = %v", err)
+		}
+		assertCondition(t, updated, constants.ConditionProvisioningFailed, corev1.ConditionTrue, constants.EventSnapshotPolicyInvalid)
+		if len(shareClient.Shares) != 0 {
+			t.Fatalf("shares = %v, want none", shareClient.Shares)
+		}
+	})
+
+	t.Run("annotation blocks deletion", func(t *testing.T) {
+		sc := &storagev1.StorageClass{ObjectMeta: metav1.ObjectMeta{Name: "azurefile"}, Provisioner: k8s.ManagedProvisioner}
+		pvc := basePVC()
+		pvc.Spec.StorageClassName = stringPtr("azurefile")
+		pvc.Finalizers = []string{constants.FinalizerName}
+		now := metav1.NewTime(time.Now())
+		pvc.DeletionTimestamp = &now
+		shareName := shareNameForTest(pvc)
+		pvc.Annotations = map[string]string{
+			constants.ShareNameAnnotation:            shareName,
+			constants.VolumeHandleAnnotation:         k8s.VolumeHandle("rg", "account", shareName),
+			constants.SnapshotBeforeDeleteAnnotation: k8s.SnapshotThenDelete,
+		}
+
+		shareClient := &azure.FakeShareClient{
+			Shares:  map[string]int32{shareName: 1},
+			Options: map[string]azure.EnsureShareOptions{shareName: {Metadata: shareMetadata(pvc, "")}},
+		}
+		k8sClient := fake.NewClientBuilder().WithScheme(scheme).WithObjects(sc, pvc).Build()
+		recorder := record.NewFakeRecorder(10)
+		reconciler := &PVCReconciler{
+			Client:   k8sClient,
+			Scheme:   scheme,
+			Recorder: recorder,
+			Config:   ReconcilerConfig{ResourceGroup: "rg", StorageAccount: "account", Server: "server"},
+			Shares:   shareClient,
+		}
+
+		request := reconcile.Request{NamespacedName: client.ObjectKeyFromObject(pvc)}
+		if _, err := reconciler.Reconcile(ctx, request); err == nil {
+			t.Fatalf("Reconcile error = nil, want the snapshot policy refused")
+		}
+		if got := countEvents(recorder, constants.EventSnapshotPolicyInvalid); got != 1 {
+			t.Fatalf("%s events = %d, want 1", constants.EventSnapshotPolicyInvalid, got)
+		}
+		if _, ok := shareClient.Shares[shareName]; !ok {
+			t.Fatalf("share deleted, want kept")
+		}
+		if got := len(shareClient.Snapshots[shareName]); got != 0 {
+			t.Fatalf("snapshots = %d, want none", got)
+		}
+		updated := &corev1.PersistentVolumeClaim{}
+		if err := k8sClient.Get(ctx, request.NamespacedName, updated); err != nil {
+			t.Fatalf("Get PVC error = %v, want the claim kept", err)
+		}
+		if !containsFinalizer(updated.Finalizers, constants.FinalizerName) {
+			t.Fatalf("finalizers = %v, want %s kept", updated.Finalizers, constants.FinalizerName)
+		}
+	})
+}
+
+func TestReconcileDeletionRetainsShareWithVolumeSnapshots(t *testing.T) {
+	sc := &storagev1.StorageClass{ObjectMeta: metav1.ObjectMeta{Name: "azurefile"}, Provisioner: k8s.ManagedProvisioner}
+	pvc := basePVC()
+	pvc.Spec.StorageClassName = stringPtr("azurefile")
+	pvc.Finalizers = []string{constants.FinalizerName}
+	now := metav1.NewTime(time.Now())
+	pvc.DeletionTimestamp = &now
+	shareName := shareNameForTest(pvc)
+	pvc.Annotations = map[string]string{
+		constants.ShareNameAnnotation:    shareName,
+		constants.VolumeHandleAnnotation: k8s.VolumeHandle("rg", "account", shareName),
+	}
+	handle := k8s.SnapshotHandle("rg", "account", shareName, "2024-05-01T10:00:00.0000000Z")
+	content := &snapshotv1.VolumeSnapshotContent{
+		ObjectMeta: metav1.ObjectMeta{Name: "snapcontent-1"},
+		Spec:       snapshotv1.VolumeSnapshotContentSpec{Driver: k8s.ManagedProvisioner},
+		Status:     &snapshotv1.VolumeSnapshotContentStatus{SnapshotHandle: &handle},
+	}
+
+	shareClient := &azure.FakeShareClient{
+		Shares:  map[string]int32{shareName: 1},
+		Options: map[string]azure.EnsureShareOptions{shareName: {Metadata: shareMetadata(pvc, "")}},
+	}
+	k8sClient := fake.NewClientBuilder().WithScheme(cloneScheme(t)).WithObjects(sc, pvc, content).Build()
+	recorder := record.NewFakeRecorder(20)
+	reconciler := &PVCReconciler{
+		Client:   k8sClient,
+		Scheme:   k8sClient.Scheme(),
+		Recorder: recorder,
+		Config:   ReconcilerConfig{ResourceGroup: "rg", StorageAccount: "account", Server: "server", VolumeSnapshots: true},
+		Shares:   shareClient,
+	}
+
+	ctx := ctrl.LoggerInto(context.Background(), logging.NewLogger())
+	key := client.ObjectKeyFromObject(pvc)
+	if _, err := reconciler.Reconcile(ctx, reconcile.Request{NamespacedName: key}); err != nil {
+		t.Fatalf("Reconcile error = %v", err)
+	}
+	if _, ok := shareClient.Shares[shareName]; !ok {
+		t.Fatalf("share deleted, want it retained with its volume snapshot")
+	}
+	if !hasEvent(recorder, constants.EventShareRetained) {
+		t.Fatalf("event %q not recorded", constants.EventShareRetained)
+	}
+	if err := k8sClient.Get(ctx, key, &corev1.PersistentVolumeClaim{}); !errors.IsNotFound(err) {
+		t.Fatalf("Get PVC error = %v, want not found", err)
+	}
+}
@@ -0,0 +1,143 @@
+package controller
+
+import (
+	"context"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	corev1 "k8s.io/api/core/v1"
+	storagev1 "k8s.io/api/storage/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/tools/record"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	"aks-azureFiles-controller/internal/azure"
+	"aks-azureFiles-controller/internal/constants"
+	"aks-azureFiles-controller/internal/k8s"
+	"aks-azureFiles-controller/internal/logging"
+)
+
+func TestReconcileRemediatesPVMismatch(t *testing.T) {
+	staleClaim := func(pv *corev1.PersistentVolume) {
+		pv.Spec.ClaimRef.UID = types.UID("uid-old")
+		pv.Status.Phase = corev1.VolumeReleased
+	}
+	ownBound := func(pv *corev1.PersistentVolume) {
+		pv.Spec.CSI.VolumeAttributes["shareName"] = "other-share"
+		pv.Status.Phase = corev1.VolumeBound
+	}
+	releasedRetain := func(pv *corev1.PersistentVolume) {
+		staleClaim(pv)
+		pv.Spec.PersistentVolumeReclaimPolicy = corev1.PersistentVolumeReclaimRetain
+	}
+	otherClaim := func(pv *corev1.PersistentVolume) {
+		pv.Spec.ClaimRef = &corev1.ObjectReference{Kind: "PersistentVolumeClaim", Namespace: "finance", Name: "ledger", UID: types.UID("uid-other")}
+		pv.Status.Phase = corev1.VolumeBound
+	}
+
+	cases := map[string]struct {
+		policy     PVMismatchPolicy
+		mutate     func(*corev1.PersistentVolume)
+		wantAction PVMismatchPolicy
+		wantEvent  string
+		wantBound  bool
+	}{
+		"default halts":                   {policy: "", mutate: staleClaim, wantAction: PVMismatchHalt, wantEvent: constants.EventPVMismatchHalted},
+		"recreate released pv":            {policy: PVMismatchRecreate, mutate: staleClaim, wantAction: PVMismatchRecreate, wantEvent: constants.EventPVRecreated, wantBound: true},
+		"recreate refuses retained pv":    {policy: PVMismatchRecreate, mutate: releasedRetain, wantAction: PVMismatchHalt, wantEvent: constants.EventPVMismatchHalted},
+		"recreate refuses claim's own pv": {policy: PVMismatchRecreate, mutate: ownBound, wantAction: PVMismatchHalt, wantEvent: constants.EventPVMismatchHalted, wantBound: true},
+		"recreate refuses bound pv":       {policy: PVMismatchRecreate, mutate: otherClaim, wantAction: PVMismatchHalt, wantEvent: constants.EventPVMismatchHalted},
+		"adopt stale claim reference":     {policy: PVMismatchAdopt, mutate: staleClaim, wantAction: PVMismatchAdopt, wantEvent: constants.EventPVAdopted, wantBound: true},
+		"adopt refuses other claim":       {policy: PVMismatchAdopt, mutate: otherClaim, wantAction: PVMismatchHalt, wantEvent: constants.EventPVMismatchHalted},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			scheme := runtime.NewScheme()
+			if err := corev1.AddToScheme(scheme); err != nil {
+				t.Fatalf("AddToScheme corev1: %v", err)
+			}
+			if err := storagev1.AddToScheme(scheme); err != nil {
+				t.Fatalf("AddToScheme storagev1: %v", err)
+			}
+
+			sc := &storagev1.StorageClass{ObjectMeta: metav1.ObjectMeta{Name: "azurefile"}, Provisioner: k8s.ManagedProvisioner}
+			pvc := basePVC()
+			pvc.Spec.StorageClassName = stringPtr("azurefile")
+
+			k8sClient := fake.NewClientBuilder().WithScheme(scheme).WithObjects(sc, pvc).Build()
+			recorder := record.NewFakeRecorder(50)
+			metrics := NewReconcileMetrics()
+			reconciler := &PVCReconciler{
+				Client:   k8sClient,
+				Scheme:   scheme,
+				Recorder: recorder,
+				Config: ReconcilerConfig{
+					ResourceGroup:    "rg",
+					StorageAccount:   "account",
+					Server:           "server",
+					PVMismatchPolicy: tc.policy,
+				},
+				Shares:  &azure.FakeShareClient{},
+				Metrics: metrics,
+			}
+
+			ctx := ctrl.LoggerInto(context.Background(), logging.NewLogger())
+			request := reconcile.Request{NamespacedName: client.ObjectKeyFromObject(pvc)}
+			if _, err := reconciler.Reconcile(ctx, request); err != nil {
+				t.Fatalf("Reconcile error = %v", err)
+			}
+			pvList := &corev1.PersistentVolumeList{}
+			if err := k8sClient.List(ctx, pvList); err != nil || len(pvList.Items) != 1 {
+				t.Fatalf("List PVs = %v, %v, want one PV", pvList.Items, err)
+			}
+			pv := &pvList.Items[0]
+			tc.mutate(pv)
+			status := pv.Status
+			if err := k8sClient.Update(ctx, pv); err != nil {
+				t.Fatalf("Update PV error = %v", err)
+			}
+			pv.Status = status
+			if err := k8sClient.Status().Update(ctx, pv); err != nil {
+				t.Fatalf("Update PV status error = %v", err)
+			}
+
+			if _, err := reconciler.Reconcile(ctx, request); err != nil {
+				t.Fatalf("Reconcile error = %v", err)
+			}
+			if got := testutil.ToFloat64(metrics.pvMismatch.WithLabelValues(string(tc.wantAction))); got != 1 {
+				t.Fatalf("pv_mismatch_total{action=%q} = %v, want 1", tc.wantAction, got)
+			}
+			if !hasEvent(recorder, tc.wantEvent) {
+				t.Fatalf("event %q not recorded", tc.wantEvent)
+			}
+
+			// The requeued reconcile finishes provisioning after a remediation.
+			if _, err := reconciler.Reconcile(ctx, request); err != nil {
+				t.Fatalf("Reconcile error = %v", err)
+			}
+
+			current := &corev1.PersistentVolume{}
+			if err := k8sClient.Get(ctx, client.ObjectKeyFromObject(pv), current); err != nil {
+				t.Fatalf("Get PV error = %v", err)
+			}
+			if bound := current.Spec.ClaimRef.UID == pvc.UID; bound != tc.wantBound {
+				t.Fatalf("PV ClaimRef = %v, want bound to claim %v", current.Spec.ClaimRef, tc.wantBound)
+			}
+
+			updated := &corev1.PersistentVolumeClaim{}
+			if err := k8sClient.Get(ctx, request.NamespacedName, updated); err != nil {
+				t.Fatalf("Get PVC error = %v", err)
+			}
+			failed := conditionTrue(updated, constants.ConditionProvisioningFailed)
+			if halted := tc.wantAction == PVMismatchHalt; failed != halted {
+				t.Fatalf("ProvisioningFailed = %v, want %v", failed, halted)
+			}
+		})
+	}
+}
@@ -0,0 +1,160 @@
+package controller
+
+import (
+	"context"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	storagev1 "k8s.io/api/storage/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/tools/record"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	"aks-azureFiles-controller/internal/azure"
+	"aks-azureFiles-controller/internal/constants"
+	"aks-azureFiles-controller/internal/k8s"
+	"aks-azureFiles-controller/internal/logging"
+)
+
+func TestReconcileProvisionsNFSShare(t *testing.T) {
+	scheme := runtime.NewScheme()
+	if err := corev1.AddToScheme(scheme); err != nil {
+		t.Fatalf("AddToScheme corev1: %v", err)
+	}
+	if err := storagev1.AddToScheme(scheme); err != nil {
+		t.Fatalf("AddToScheme storagev1: %v", err)
+	}
+
+	sc := &storagev1.StorageClass{
+		ObjectMeta:  metav1.ObjectMeta{Name: "azurefile-nfs"},
+		Provisioner: k8s.ManagedProvisioner,
+		Parameters: map[string]string{
+			k8s.ParamSkuName:          "Premium_LRS",
+			k8s.ParamEnabledProtocols: "nfs",
+			k8s.ParamRootSquash:       "RootSquash",
+		},
+	}
+
+	pvc := basePVC()
+	pvc.Spec.StorageClassName = stringPtr("azurefile-nfs")
+	pvc.Spec.AccessModes = []corev1.PersistentVolumeAccessMode{corev1.ReadWriteMany}
+
+	k8sClient := fake.NewClientBuilder().WithScheme(scheme).WithObjects(sc, pvc).Build()
+	shareClient := &azure.FakeShareClient{}
+
+	reconciler := &PVCReconciler{
+		Client:   k8sClient,
+		Scheme:   scheme,
+		Recorder: record.NewFakeRecorder(10),
+		Config: ReconcilerConfig{
+			ResourceGroup:  "rg",
+			StorageAccount: "account",
+			Server:         "server",
+		},
+		Shares: shareClient,
+	}
+
+	ctx := ctrl.LoggerInto(context.Background(), logging.NewLogger())
+	if _, err := reconciler.Reconcile(ctx, reconcile.Request{NamespacedName: client.ObjectKeyFromObject(pvc)}); err != nil {
+		t.Fatalf("Reconcile error = %v", err)
+	}
+
+	shareName := shareNameForTest(pvc)
+	opts := shareClient.Options[shareName]
+	if opts.EnabledProtocol != k8s.ProtocolNFS || opts.RootSquash != "RootSquash" {
+		t.Fatalf("share options = %#v, want NFS with RootSquash", opts)
+	}
+	// Premium shares are at least 100 GiB.
+	if shareClient.Shares[shareName] != 100 {
+		t.Fatalf("Share quota = %d, want 100", shareClient.Shares[shareName])
+	}
+
+	pvList := &corev1.PersistentVolumeList{}
+	if err := k8sClient.List(ctx, pvList); err != nil {
+		t.Fatalf("List PVs error = %v", err)
+	}
+	if len(pvList.Items) != 1 {
+		t.Fatalf("PV count = %d, want 1", len(pvList.Items))
+	}
+	if got := pvList.Items[0].Spec.CSI.VolumeAttributes["protocol"]; got != "nfs" {
+		t.Fatalf("protocol attribute = %q, want %q", got, "nfs")
+	}
+}
+
+func TestReconcileRejectsUnsupportedNFSClaims(t *testing.T) {
+	block := corev1.PersistentVolumeBlock
+	cases := map[string]struct {
+		params map[string]string
+		mutate func(*corev1.PersistentVolumeClaim)
+		reason string
+	}{
+		"nfs on standard sku": {
+			params: map[string]string{k8s.ParamSkuName: "Standard_LRS", k8s.ParamEnabledProtocols: "NFS"},
+			reason: constants.EventShareValidation,
+		},
+		"block volume": {
+			params: map[string]string{k8s.ParamSkuName: "Premium_LRS", k8s.ParamEnabledProtocols: "NFS"},
+			mutate: func(pvc *corev1.PersistentVolumeClaim) { pvc.Spec.VolumeMode = &block },
+			reason: constants.EventPVCInvalid,
+		},
+		"missing access modes": {
+			params: map[string]string{k8s.ParamSkuName: "Premium_LRS", k8s.ParamEnabledProtocols: "NFS"},
+			mutate: func(pvc *corev1.PersistentVolumeClaim) { pvc.Spec.AccessModes = nil },
+			reason: constants.EventPVCInvalid,
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			scheme := runtime.NewScheme()
+			if err := corev1.AddToScheme(scheme); err != nil {
+				t.Fatalf("AddToScheme corev1: %v", err)
+			}
+			if err := storagev1.AddToScheme(scheme); err != nil {
+				t.Fatalf("AddToScheme storagev1: %v", err)
+			}
+
+			sc := &storagev1.StorageClass{
+				ObjectMeta:  metav1.ObjectMeta{Name: "azurefile-nfs"},
+				Provisioner: k8s.ManagedProvisioner,
+				Parameters:  tc.params,
+			}
+			pvc := basePVC()
+			pvc.Spec.StorageClassName = stringPtr("azurefile-nfs")
+			if tc.mutate != nil {
+				tc.mutate(pvc)
+			}
+
+			k8sClient := fake.NewClientBuilder().WithScheme(scheme).WithObjects(sc, pvc).Build()
+			shareClient := &azure.FakeShareClient{}
+			recorder := record.NewFakeRecorder(10)
+
+			reconciler := &PVCReconciler{
+				Client:   k8sClient,
+				Scheme:   scheme,
+				Recorder: recorder,
+				Config: ReconcilerConfig{
+					ResourceGroup:  "rg",
+					StorageAccount: "account",
+					Server:         "server",
+				},
+				Shares: shareClient,
+			}
+
+			ctx := ctrl.LoggerInto(context.Background(), logging.NewLogger())
+			if _, err := reconciler.Reconcile(ctx, reconcile.Request{NamespacedName: client.ObjectKeyFromObject(pvc)}); err != nil {
+				t.Fatalf("Reconcile error = %v", err)
+			}
+			if len(shareClient.Shares) != 0 {
+				t.Fatalf("Shares = %#v, want none", shareClient.Shares)
+			}
+			if !hasEvent(recorder, tc.reason) {
+				t.Fatalf("event %q not recorded", tc.reason)
+			}
+		})
+	}
+}
@@ -0,0 +1,328 @@
+package controller
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	storagev1 "k8s.io/api/storage/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/tools/record"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	"aks-azureFiles-controller/internal/azure"
+	"aks-azureFiles-controller/internal/constants"
+	"aks-azureFiles-controller/internal/k8s"
+	"aks-azureFiles-controller/internal/logging"
+)
+
+func TestReconcileTagsShareWithOwner(t *testing.T) {
+	scheme := runtime.NewScheme()
+	if err := corev1.AddToScheme(scheme); err != nil {
+		t.Fatalf("AddToScheme corev1: %v", err)
+	}
+	if err := storagev1.AddToScheme(scheme); err != nil {
+		t.Fatalf("AddToScheme storagev1: %v", err)
+	}
+
+	sc := &storagev1.StorageClass{ObjectMeta: metav1.ObjectMeta{Name: "azurefile"}, Provisioner: k8s.ManagedProvisioner}
+	pvc := basePVC()
+	pvc.Spec.StorageClassName = stringPtr("azurefile")
+
+	k8sClient := fake.NewClientBuilder().WithScheme(scheme).WithObjects(sc, pvc).Build()
+	shareClient := &azure.FakeShareClient{}
+	reconciler := &PVCReconciler{
+		Client:   k8sClient,
+		Scheme:   scheme,
+		Recorder: record.NewFakeRecorder(10),
+		Config:   ReconcilerConfig{ResourceGroup: "rg", StorageAccount: "account", Server: "server"},
+		Shares:   shareClient,
+	}
+
+	ctx := ctrl.LoggerInto(context.Background(), logging.NewLogger())
+	if _, err := reconciler.Reconcile(ctx, reconcile.Request{NamespacedName: client.ObjectKeyFromObject(pvc)}); err != nil {
+		t.Fatalf("Reconcile error = %v", err)
+	}
+
+	metadata := shareClient.Options[shareNameForTest(pvc)].Metadata
+	if metadata[azure.MetadataOwnerNamespace] != "team" || metadata[azure.MetadataPVCUID] != "uid-123" {
+		t.Fatalf("share metadata = %v, want owner team/uid-123", metadata)
+	}
+}
+
+func TestReconcileShareOverrideOwnership(t *testing.T) {
+	cases := map[string]struct {
+		owner     string
+		allowList string
+		wantPV    bool
+	}{
+		"unowned share":          {owner: "", wantPV: true},
+		"same namespace":         {owner: "team", wantPV: true},
+		"other namespace":        {owner: "finance", wantPV: false},
+		"allow-listed namespace": {owner: "finance", allowList: "finance", wantPV: true},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			scheme := runtime.NewScheme()
+			if err := corev1.AddToScheme(scheme); err != nil {
+				t.Fatalf("AddToScheme corev1: %v", err)
+			}
+			if err := storagev1.AddToScheme(scheme); err != nil {
+				t.Fatalf("AddToScheme storagev1: %v", err)
+			}
+
+			sc := &storagev1.StorageClass{ObjectMeta: metav1.ObjectMeta{Name: "azurefile"}, Provisioner: k8s.ManagedProvisioner}
+			if tc.allowList != "" {
+				sc.Parameters = map[string]string{k8s.ParamAllowedOwners: tc.allowList}
+			}
+			pvc := basePVC()
+			pvc.Spec.StorageClassName = stringPtr("azurefile")
+			pvc.Annotations = map[string]string{constants.ShareOverrideAnnotation: "ledger"}
+
+			existing := azure.EnsureShareOptions{}
+			if tc.owner != "" {
+				existing.Metadata = map[string]string{azure.MetadataOwnerNamespace: tc.owner, azure.MetadataPVCUID: "uid-other"}
+			}
+			shareClient := &azure.FakeShareClient{
+				Shares:  map[string]int32{"ledger": 5},
+				Options: map[string]azure.EnsureShareOptions{"ledger": existing},
+			}
+			recorder := record.NewFakeRecorder(20)
+			k8sClient := fake.NewClientBuilder().WithScheme(scheme).WithObjects(sc, pvc).Build()
+			reconciler := &PVCReconciler{
+				Client:   k8sClient,
+				Scheme:   scheme,
+				Recorder: recorder,
+				Config:   ReconcilerConfig{ResourceGroup: "rg", StorageAccount: "account", Server: "server"},
+				Shares:   shareClient,
+			}
+
+			ctx := ctrl.LoggerInto(context.Background(), logging.NewLogger())
+			if _, err := reconciler.Reconcile(ctx, reconcile.Request{NamespacedName: client.ObjectKeyFromObject(pvc)}); err != nil {
+				t.Fatalf("Reconcile error = %v", err)
+			}
+
+			pvList := &corev1.PersistentVolumeList{}
+			if err := k8sClient.List(ctx, pvList); err != nil {
+				t.Fatalf("List PVs error = %v", err)
+			}
+			if got := len(pvList.Items) == 1; got != tc.wantPV {
+				t.Fatalf("PV created = %v, want %v", got, tc.wantPV)
+			}
+			if tc.wantPV {
+				return
+			}
+			if !hasEvent(recorder, constants.EventOwnershipConflict) {
+				t.Fatalf("expected %s event", constants.EventOwnershipConflict)
+			}
+			if shareClient.Shares["ledger"] != 5 || shareClient.QuotaCount["ledger"] != 0 {
+				t.Fatalf("share quota = %d (%d updates), want untouched", shareClient.Shares["ledger"], shareClient.QuotaCount["ledger"])
+			}
+			updated := &corev1.PersistentVolumeClaim{}
+			if err := k8sClient.Get(ctx, client.ObjectKeyFromObject(pvc), updated); err != nil {
+				t.Fatalf("Get PVC error = %v", err)
+			}
+			if updated.Annotations[constants.ShareNameAnnotation] != "" {
+				t.Fatalf("share name annotation = %q, want none", updated.Annotations[constants.ShareNameAnnotation])
+			}
+		})
+	}
+}
+
+func TestDeletionRetainsShareWithForeignProvenance(t *testing.T) {
+	cases := map[string]map[string]string{
+		"untagged share":  nil,
+		"other claim":     {azure.MetadataProvisionedBy: k8s.ManagedProvisioner, azure.MetadataOwnerNamespace: "team", azure.MetadataPVCUID: "uid-other"},
+		"other cluster":   {azure.MetadataProvisionedBy: k8s.ManagedProvisioner, azure.MetadataOwnerNamespace: "team", azure.MetadataPVCUID: "uid-123", azure.MetadataClusterID: "staging"},
+		"other tool":      {azure.MetadataOwnerNamespace: "team", azure.MetadataPVCUID: "uid-123", azure.MetadataClusterID: "prod"},
+		"different owner": {azure.MetadataProvisionedBy: k8s.ManagedProvisioner, azure.MetadataOwnerNamespace: "finance", azure.MetadataPVCUID: "uid-123", azure.MetadataClusterID: "prod"},
+	}
+
+	for name, metadata := range cases {
+		t.Run(name, func(t *testing.T) {
+			scheme := runtime.NewScheme()
+			if err := corev1.AddToScheme(scheme); err != nil {
+				t.Fatalf("AddToScheme corev1: %v", err)
+			}
+			if err := storagev1.AddToScheme(scheme); err != nil {
+				t.Fatalf("AddToScheme storagev1: %v", err)
+			}
+
+			sc := &storagev1.StorageClass{ObjectMeta: metav1.ObjectMeta{Name: "azurefile"}, Provisioner: k8s.ManagedProvisioner}
+			pvc := basePVC()
+			pvc.Spec.StorageClassName = stringPtr("azurefile")
+			pvc.Finalizers = []string{constants.FinalizerName}
+			now := metav1.NewTime(time.Now())
+			pvc.DeletionTimestamp = &now
+			pvc.Annotations = map[string]string{constants.ShareNameAnnotation: "ledger"}
+
+			pv, err := k8s.BuildPV(pvc, "ledger", "rg", "account", "server", corev1.PersistentVolumeReclaimDelete)
+			if err != nil {
+				t.Fatalf("BuildPV error = %v", err)
+			}
+
+			shareClient := &azure.FakeShareClient{
+				Shares:  map[string]int32{"ledger": 5},
+				Options: map[string]azure.EnsureShareOptions{"ledger": {Metadata: metadata}},
+			}
+			recorder := record.NewFakeRecorder(20)
+			k8sClient := fake.NewClientBuilder().WithScheme(scheme).WithObjects(sc, pvc, pv).Build()
+			reconciler := &PVCReconciler{
+				Client:   k8sClient,
+				Scheme:   scheme,
+				Recorder: recorder,
+				Config:   ReconcilerConfig{ResourceGroup: "rg", StorageAccount: "account", Server: "server", ClusterID: "prod"},
+				Shares:   shareClient,
+			}
+
+			ctx := ctrl.LoggerInto(context.Background(), logging.NewLogger())
+			if _, err := reconciler.Reconcile(ctx, reconcile.Request{NamespacedName: client.ObjectKeyFromObject(pvc)}); err != nil {
+				t.Fatalf("Reconcile error = %v", err)
+			}
+
+			if _, ok := shareClient.Shares["ledger"]; !ok {
+				t.Fatalf("share deleted, want retained")
+			}
+			if !hasEvent(recorder, constants.EventShareRetained) {
+				t.Fatalf("expected %s event", constants.EventShareRetained)
+			}
+			retained := &corev1.PersistentVolume{}
+			if err := k8sClient.Get(ctx, client.ObjectKey{Name: pv.Name}, retained); err != nil {
+				t.Fatalf("Get PV error = %v", err)
+			}
+			if retained.Spec.PersistentVolumeReclaimPolicy != corev1.PersistentVolumeReclaimRetain {
+				t.Fatalf("ReclaimPolicy = %q, want %q", retained.Spec.PersistentVolumeReclaimPolicy, corev1.PersistentVolumeReclaimRetain)
+			}
+			if err := k8sClient.Get(ctx, client.ObjectKeyFromObject(pvc), &corev1.PersistentVolumeClaim{}); err == nil {
+				t.Fatalf("PVC still present, want finalizer removed")
+			}
+		})
+	}
+}
+
+func TestDeletionRemovesShareProvisionedForClaim(t *testing.T) {
+	scheme := runtime.NewScheme()
+	if err := corev1.AddToScheme(scheme); err != nil {
+		t.Fatalf("AddToScheme corev1: %v", err)
+	}
+	if err := storagev1.AddToScheme(scheme); err != nil {
+		t.Fatalf("AddToScheme storagev1: %v", err)
+	}
+
+	sc := &storagev1.StorageClass{ObjectMeta: metav1.ObjectMeta{Name: "azurefile"}, Provisioner: k8s.ManagedProvisioner}
+	pvc := basePVC()
+	pvc.Spec.StorageClassName = stringPtr("azurefile")
+
+	k8sClient := fake.NewClientBuilder().WithScheme(scheme).WithObjects(sc, pvc).Build()
+	shareClient := &azure.FakeShareClient{}
+	reconciler := &PVCReconciler{
+		Client:   k8sClient,
+		Scheme:   scheme,
+		Recorder: record.NewFakeRecorder(20),
+		Config:   ReconcilerConfig{ResourceGroup: "rg", StorageAccount: "account", Server: "server", ClusterID: "prod"},
+		Shares:   shareClient,
+	}
+
+	ctx := ctrl.LoggerInto(context.Background(), logging.NewLogger())
+	key := client.ObjectKeyFromObject(pvc)
+	if _, err := reconciler.Reconcile(ctx, reconcile.Request{NamespacedName: key}); err != nil {
+		t.Fatalf("Reconcile error = %v", err)
+	}
+	shareName := shareNameForTest(pvc)
+	if got := shareClient.Options[shareName].Metadata[azure.MetadataClusterID]; got != "prod" {
+		t.Fatalf("cluster_id metadata = %q, want %q", got, "prod")
+	}
+
+	if err := k8sClient.Delete(ctx, pvc); err != nil {
+		t.Fatalf("Delete PVC error = %v", err)
+	}
+	if _, err := reconciler.Reconcile(ctx, reconcile.Request{NamespacedName: key}); err != nil {
+		t.Fatalf("Reconcile deletion error = %v", err)
+	}
+	if _, ok := shareClient.Shares[shareName]; ok {
+		t.Fatalf("share still present after deletion")
+	}
+}
+
+func TestReconcileBackfillsLegacyShareMetadata(t *testing.T) {
+	cases := map[string]struct {
+		override     string
+		wantBackfill bool
+	}{
+		"generated name": {wantBackfill: true},
+		"override":       {override: "ledger"},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			scheme := runtime.NewScheme()
+			if err := corev1.AddToScheme(scheme); err != nil {
+				t.Fatalf("AddToScheme corev1: %v", err)
+			}
+			if err := storagev1.AddToScheme(scheme); err != nil {
+				t.Fatalf("AddToScheme storagev1: %v", err)
+			}
+
+			sc := &storagev1.StorageClass{ObjectMeta: metav1.ObjectMeta{Name: "azurefile"}, Provisioner: k8s.ManagedProvisioner}
+			pvc := basePVC()
+			pvc.Spec.StorageClassName = stringPtr("azurefile")
+			shareName := shareNameForTest(pvc)
+			if tc.override != "" {
+				pvc.Annotations = map[string]string{constants.ShareOverrideAnnotation: tc.override}
+				shareName = tc.override
+			}
+			pv, err := k8s.BuildPV(pvc, shareName, "rg", "account", "server", corev1.PersistentVolumeReclaimDelete)
+			if err != nil {
+				t.Fatalf("BuildPV error = %v", err)
+			}
+			pvc.Spec.VolumeName = pv.Name
+
+			// The share predates provenance metadata.
+			shareClient := &azure.FakeShareClient{
+				Shares:  map[string]int32{shareName: 5},
+				Options: map[string]azure.EnsureShareOptions{shareName: {Metadata: map[string]string{"team": "analytics"}}},
+			}
+			recorder := record.NewFakeRecorder(20)
+			k8sClient := fake.NewClientBuilder().WithScheme(scheme).WithObjects(sc, pvc, pv).Build()
+			reconciler := &PVCReconciler{
+				Client:   k8sClient,
+				Scheme:   scheme,
+				Recorder: recorder,
+				Config:   ReconcilerConfig{ResourceGroup: "rg", StorageAccount: "account", Server: "server", ClusterID: "prod"},
+				Shares:   shareClient,
+			}
+
+			ctx := ctrl.LoggerInto(context.Background(), logging.NewLogger())
+			key := client.ObjectKeyFromObject(pvc)
+			if _, err := reconciler.Reconcile(ctx, reconcile.Request{NamespacedName: key}); err != nil {
+				t.Fatalf("Reconcile error = %v", err)
+			}
+			metadata := shareClient.Options[shareName].Metadata
+			if backfilled := metadata[azure.MetadataPVCUID] == string(pvc.UID); backfilled != tc.wantBackfill {
+				t.Fatalf("share metadata = %v, want backfilled %v", metadata, tc.wantBackfill)
+			}
+			if metadata["team"] != "analytics" {
+				t.Fatalf("share metadata = %v, want existing keys kept", metadata)
+			}
+			if got := hasEvent(recorder, constants.EventShareMetadataBackfilled); got != tc.wantBackfill {
+				t.Fatalf("%s event = %v, want %v", constants.EventShareMetadataBackfilled, got, tc.wantBackfill)
+			}
+
+			if err := k8sClient.Delete(ctx, pvc); err != nil {
+				t.Fatalf("Delete PVC error = %v", err)
+			}
+			if _, err := reconciler.Reconcile(ctx, reconcile.Request{NamespacedName: key}); err != nil {
+				t.Fatalf("Reconcile deletion error = %v", err)
+			}
+			if _, kept := shareClient.Shares[shareName]; kept == tc.wantBackfill {
+				t.Fatalf("share kept = %v after deletion, want %v", kept, !tc.wantBackfill)
+			}
+		})
+	}
+}
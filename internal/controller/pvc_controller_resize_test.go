@@ -0,0 +1,196 @@
+package controller
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	storagev1 "k8s.io/api/storage/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/tools/record"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	"aks-azureFiles-controller/internal/azure"
+	"aks-azureFiles-controller/internal/constants"
+	"aks-azureFiles-controller/internal/k8s"
+	"aks-azureFiles-controller/internal/logging"
+)
+
+func TestReconcileExpandsShare(t *testing.T) {
+	scheme := runtime.NewScheme()
+	if err := corev1.AddToScheme(scheme); err != nil {
+		t.Fatalf("AddToScheme corev1: %v", err)
+	}
+	if err := storagev1.AddToScheme(scheme); err != nil {
+		t.Fatalf("AddToScheme storagev1: %v", err)
+	}
+
+	allowExpansion := true
+	sc := &storagev1.StorageClass{
+		ObjectMeta:           metav1.ObjectMeta{Name: "azurefile"},
+		Provisioner:          k8s.ManagedProvisioner,
+		AllowVolumeExpansion: &allowExpansion,
+	}
+
+	pvc := basePVC()
+	pvc.Spec.StorageClassName = stringPtr("azurefile")
+	pvc.Finalizers = []string{constants.FinalizerName}
+	shareName := shareNameForTest(pvc)
+
+	pv, err := k8s.BuildPV(pvc, shareName, "rg", "account", "server", corev1.PersistentVolumeReclaimDelete)
+	if err != nil {
+		t.Fatalf("BuildPV error = %v", err)
+	}
+
+	pvc.Spec.Resources.Requests[corev1.ResourceStorage] = resource.MustParse("3Gi")
+	pvc.Status = corev1.PersistentVolumeClaimStatus{
+		Phase:    corev1.ClaimBound,
+		Capacity: corev1.ResourceList{corev1.ResourceStorage: resource.MustParse("1Gi")},
+	}
+
+	k8sClient := fake.NewClientBuilder().
+		WithScheme(scheme).
+		WithObjects(sc, pvc, pv).
+		WithStatusSubresource(&corev1.PersistentVolumeClaim{}).
+		Build()
+	shareClient := &azure.FakeShareClient{Shares: map[string]int32{shareName: 1}}
+
+	reconciler := &PVCReconciler{
+		Client:   k8sClient,
+		Scheme:   scheme,
+		Recorder: record.NewFakeRecorder(20),
+		Config: ReconcilerConfig{
+			ResourceGroup:  "rg",
+			StorageAccount: "account",
+			Server:         "server",
+		},
+		Shares: shareClient,
+	}
+
+	ctx := ctrl.LoggerInto(context.Background(), logging.NewLogger())
+	if _, err := reconciler.Reconcile(ctx, reconcile.Request{NamespacedName: client.ObjectKeyFromObject(pvc)}); err != nil {
+		t.Fatalf("Reconcile error = %v", err)
+	}
+
+	if shareClient.Shares[shareName] != 3 {
+		t.Fatalf("Share quota = %d, want 3", shareClient.Shares[shareName])
+	}
+
+	updatedPV := &corev1.PersistentVolume{}
+	if err := k8sClient.Get(ctx, client.ObjectKey{Name: pv.Name}, updatedPV); err != nil {
+		t.Fatalf("Get PV error = %v", err)
+	}
+	pvCapacity := updatedPV.Spec.Capacity[corev1.ResourceStorage]
+	if pvCapacity.Cmp(resource.MustParse("3Gi")) != 0 {
+		t.Fatalf("PV capacity = %s, want 3Gi", pvCapacity.String())
+	}
+
+	updated := &corev1.PersistentVolumeClaim{}
+	if err := k8sClient.Get(ctx, client.ObjectKeyFromObject(pvc), updated); err != nil {
+		t.Fatalf("Get PVC error = %v", err)
+	}
+	statusCapacity := updated.Status.Capacity[corev1.ResourceStorage]
+	if statusCapacity.Cmp(resource.MustParse("3Gi")) != 0 {
+		t.Fatalf("PVC status capacity = %s, want 3Gi", statusCapacity.String())
+	}
+	if findPVCCondition(updated, corev1.PersistentVolumeClaimResizing) != nil {
+		t.Fatalf("Resizing condition still present: %#v", updated.Status.Conditions)
+	}
+	if _, ok := updated.Status.AllocatedResourceStatuses[corev1.ResourceStorage]; ok {
+		t.Fatalf("AllocatedResourceStatuses still set: %#v", updated.Status.AllocatedResourceStatuses)
+	}
+}
+
+func TestReconcileRefusesShrink(t *testing.T) {
+	scheme := runtime.NewScheme()
+	if err := corev1.AddToScheme(scheme); err != nil {
+		t.Fatalf("AddToScheme corev1: %v", err)
+	}
+	if err := storagev1.AddToScheme(scheme); err != nil {
+		t.Fatalf("AddToScheme storagev1: %v", err)
+	}
+
+	sc := &storagev1.StorageClass{
+		ObjectMeta:  metav1.ObjectMeta{Name: "azurefile"},
+		Provisioner: k8s.ManagedProvisioner,
+	}
+
+	pvc := basePVC()
+	pvc.Spec.StorageClassName = stringPtr("azurefile")
+	pvc.Finalizers = []string{constants.FinalizerName}
+	pvc.Spec.Resources.Requests[corev1.ResourceStorage] = resource.MustParse("5Gi")
+	shareName := shareNameForTest(pvc)
+
+	pv, err := k8s.BuildPV(pvc, shareName, "rg", "account", "server", corev1.PersistentVolumeReclaimDelete)
+	if err != nil {
+		t.Fatalf("BuildPV error = %v", err)
+	}
+	pvc.Spec.Resources.Requests[corev1.ResourceStorage] = resource.MustParse("1Gi")
+
+	k8sClient := fake.NewClientBuilder().
+		WithScheme(scheme).
+		WithObjects(sc, pvc, pv).
+		WithStatusSubresource(&corev1.PersistentVolumeClaim{}).
+		Build()
+	shareClient := &azure.FakeShareClient{Shares: map[string]int32{shareName: 5}}
+	recorder := record.NewFakeRecorder(20)
+
+	reconciler := &PVCReconciler{
+		Client:   k8sClient,
+		Scheme:   scheme,
+		Recorder: recorder,
+		Config: ReconcilerConfig{
+			ResourceGroup:  "rg",
+			StorageAccount: "account",
+			Server:         "server",
+		},
+		Shares: shareClient,
+	}
+
+	ctx := ctrl.LoggerInto(context.Background(), logging.NewLogger())
+	for range 2 {
+		if _, err := reconciler.Reconcile(ctx, reconcile.Request{NamespacedName: client.ObjectKeyFromObject(pvc)}); err != nil {
+			t.Fatalf("Reconcile error = %v", err)
+		}
+	}
+
+	if shareClient.QuotaCount[shareName] != 0 {
+		t.Fatalf("SetShareQuota count = %d, want 0", shareClient.QuotaCount[shareName])
+	}
+	if got := countEvents(recorder, constants.EventShrinkRefused); got != 1 {
+		t.Fatalf("%s events = %d, want 1 across both reconciles", constants.EventShrinkRefused, got)
+	}
+	refused := &corev1.PersistentVolumeClaim{}
+	if err := k8sClient.Get(ctx, client.ObjectKeyFromObject(pvc), refused); err != nil {
+		t.Fatalf("Get PVC error = %v", err)
+	}
+	assertCondition(t, refused, constants.ConditionShrinkRefused, corev1.ConditionTrue, constants.EventShrinkRefused)
+
+	updatedPV := &corev1.PersistentVolume{}
+	if err := k8sClient.Get(ctx, client.ObjectKey{Name: pv.Name}, updatedPV); err != nil {
+		t.Fatalf("Get PV error = %v", err)
+	}
+	pvCapacity := updatedPV.Spec.Capacity[corev1.ResourceStorage]
+	if pvCapacity.Cmp(resource.MustParse("5Gi")) != 0 {
+		t.Fatalf("PV capacity = %s, want 5Gi", pvCapacity.String())
+	}
+}
+
+func hasEvent(recorder *record.FakeRecorder, reason string) bool {
+	for {
+		select {
+		case event := <-recorder.Events:
+			if strings.Contains(event, " "+reason+" ") {
+				return true
+			}
+		default:
+			return false
+		}
+	}
+}
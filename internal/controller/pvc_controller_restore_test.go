@@ -0,0 +1,233 @@
+package controller
+
+import (
+	"context"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	corev1 "k8s.io/api/core/v1"
+	storagev1 "k8s.io/api/storage/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/tools/record"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	"aks-azureFiles-controller/internal/azure"
+	"aks-azureFiles-controller/internal/constants"
+	"aks-azureFiles-controller/internal/k8s"
+	"aks-azureFiles-controller/internal/logging"
+)
+
+// softDeletedShare creates and deletes a share as the claim with the given UID would have.
+func softDeletedShare(t *testing.T, shares *azure.FakeShareClient, name, namespace, uid string) {
+	t.Helper()
+	ctx := context.Background()
+	metadata := map[string]string{
+		azure.MetadataProvisionedBy:  k8s.ManagedProvisioner,
+		azure.MetadataOwnerNamespace: namespace,
+		azure.MetadataPVCUID:         uid,
+	}
+	if err := shares.EnsureShare(ctx, name, 5, &azure.EnsureShareOptions{Metadata: metadata}); err != nil {
+		t.Fatalf("EnsureShare error = %v", err)
+	}
+	if err := shares.DeleteShare(ctx, name, nil); err != nil {
+		t.Fatalf("DeleteShare error = %v", err)
+	}
+}
+
+func TestReconcileRestoresSoftDeletedShare(t *testing.T) {
+	scheme := runtime.NewScheme()
+	if err := corev1.AddToScheme(scheme); err != nil {
+		t.Fatalf("AddToScheme corev1: %v", err)
+	}
+	if err := storagev1.AddToScheme(scheme); err != nil {
+		t.Fatalf("AddToScheme storagev1: %v", err)
+	}
+
+	sc := &storagev1.StorageClass{ObjectMeta: metav1.ObjectMeta{Name: "azurefile"}, Provisioner: k8s.ManagedProvisioner}
+	pvc := basePVC()
+	pvc.Spec.StorageClassName = stringPtr("azurefile")
+	pvc.Annotations = map[string]string{constants.RestoreFromAnnotation: "uid-old"}
+
+	shareClient := &azure.FakeShareClient{}
+	softDeletedShare(t, shareClient, "team-ledger", "other", "uid-old")
+	softDeletedShare(t, shareClient, "team-ledger-old", "team", "uid-old")
+
+	k8sClient := fake.NewClientBuilder().WithScheme(scheme).WithObjects(sc, pvc).Build()
+	recorder := record.NewFakeRecorder(20)
+	reconciler := &PVCReconciler{
+		Client:   k8sClient,
+		Scheme:   scheme,
+		Recorder: recorder,
+		Config:   ReconcilerConfig{ResourceGroup: "rg", StorageAccount: "account", Server: "server"},
+		Shares:   shareClient,
+	}
+
+	ctx := ctrl.LoggerInto(context.Background(), logging.NewLogger())
+	key := client.ObjectKeyFromObject(pvc)
+	for range 2 {
+		if _, err := reconciler.Reconcile(ctx, reconcile.Request{NamespacedName: key}); err != nil {
+			t.Fatalf("Reconcile error = %v", err)
+		}
+	}
+	if !hasEvent(recorder, constants.EventShareRestored) {
+		t.Fatalf("event %q not recorded", constants.EventShareRestored)
+	}
+
+	updated := &corev1.PersistentVolumeClaim{}
+	if err := k8sClient.Get(ctx, key, updated); err != nil {
+		t.Fatalf("Get PVC error = %v", err)
+	}
+	if got := updated.Annotations[constants.ShareNameAnnotation]; got != "team-ledger-old" {
+		t.Fatalf("share name = %q, want the restored team-ledger-old", got)
+	}
+	if quota, ok := shareClient.Shares["team-ledger-old"]; !ok || quota != 5 {
+		t.Fatalf("restored share quota = %d (present %v), want 5", quota, ok)
+	}
+	if _, ok := shareClient.Shares["team-ledger"]; ok {
+		t.Fatalf("share of another namespace restored")
+	}
+	pvList := &corev1.PersistentVolumeList{}
+	if err := k8sClient.List(ctx, pvList); err != nil || len(pvList.Items) != 1 {
+		t.Fatalf("List PVs = %v, %v, want one PV", pvList.Items, err)
+	}
+	if got := pvList.Items[0].Spec.CSI.VolumeAttributes["shareName"]; got != "team-ledger-old" {
+		t.Fatalf("PV shareName = %q, want team-ledger-old", got)
+	}
+
+	// The restored share is stamped with the new claim's UID and is deleted with it.
+	if got := shareClient.Options["team-ledger-old"].Metadata[azure.MetadataPVCUID]; got != string(pvc.UID) {
+		t.Fatalf("%s = %q, want the restoring claim %s", azure.MetadataPVCUID, got, pvc.UID)
+	}
+	if err := k8sClient.Delete(ctx, updated); err != nil {
+		t.Fatalf("Delete PVC error = %v", err)
+	}
+	if _, err := reconciler.Reconcile(ctx, reconcile.Request{NamespacedName: key}); err != nil {
+		t.Fatalf("Reconcile deletion error = %v", err)
+	}
+	if _, ok := shareClient.Shares["team-ledger-old"]; ok {
+		t.Fatalf("restored share retained, want deleted with the claim")
+	}
+}
+
+func TestReconcileRestoreWithoutDeletedShare(t *testing.T) {
+	scheme := runtime.NewScheme()
+	if err := corev1.AddToScheme(scheme); err != nil {
+		t.Fatalf("AddToScheme corev1: %v", err)
+	}
+	if err := storagev1.AddToScheme(scheme); err != nil {
+		t.Fatalf("AddToScheme storagev1: %v", err)
+	}
+
+	sc := &storagev1.StorageClass{ObjectMeta: metav1.ObjectMeta{Name: "azurefile"}, Provisioner: k8s.ManagedProvisioner}
+	pvc := basePVC()
+	pvc.Spec.StorageClassName = stringPtr("azurefile")
+	pvc.Annotations = map[string]string{constants.RestoreFromAnnotation: "uid-gone"}
+
+	shareClient := &azure.FakeShareClient{}
+	k8sClient := fake.NewClientBuilder().WithScheme(scheme).WithObjects(sc, pvc).Build()
+	recorder := record.NewFakeRecorder(20)
+	reconciler := &PVCReconciler{
+		Client:   k8sClient,
+		Scheme:   scheme,
+		Recorder: recorder,
+		Config:   ReconcilerConfig{ResourceGroup: "rg", StorageAccount: "account", Server: "server"},
+		Shares:   shareClient,
+	}
+
+	ctx := ctrl.LoggerInto(context.Background(), logging.NewLogger())
+	request := reconcile.Request{NamespacedName: client.ObjectKeyFromObject(pvc)}
+	if _, err := reconciler.Reconcile(ctx, request); err != nil {
+		t.Fatalf("Reconcile error = %v", err)
+	}
+	if !hasEvent(recorder, constants.EventShareRestoreFailed) {
+		t.Fatalf("event %q not recorded", constants.EventShareRestoreFailed)
+	}
+	if len(shareClient.Shares) != 0 {
+		t.Fatalf("shares = %v, want no empty share created in place of the restore", shareClient.Shares)
+	}
+	updated := &corev1.PersistentVolumeClaim{}
+	if err := k8sClient.Get(ctx, request.NamespacedName, updated); err != nil {
+		t.Fatalf("Get PVC error = %v", err)
+	}
+	assertCondition(t, updated, constants.ConditionProvisioningFailed, corev1.ConditionTrue, constants.EventShareRestoreFailed)
+}
+
+func TestReconcileRestoreRefusesLiveSource(t *testing.T) {
+	scheme := runtime.NewScheme()
+	if err := corev1.AddToScheme(scheme); err != nil {
+		t.Fatalf("AddToScheme corev1: %v", err)
+	}
+	if err := storagev1.AddToScheme(scheme); err != nil {
+		t.Fatalf("AddToScheme storagev1: %v", err)
+	}
+
+	sc := &storagev1.StorageClass{ObjectMeta: metav1.ObjectMeta{Name: "azurefile"}, Provisioner: k8s.ManagedProvisioner}
+	source := basePVC()
+	source.Name = "ledger"
+	source.UID = types.UID("uid-live")
+	pvc := basePVC()
+	pvc.Spec.StorageClassName = stringPtr("azurefile")
+	pvc.Annotations = map[string]string{
+		constants.RestoreFromAnnotation: "uid-live",
+		constants.ShareNameAnnotation:   "team-ledger",
+	}
+
+	ctx := ctrl.LoggerInto(context.Background(), logging.NewLogger())
+	shareClient := &azure.FakeShareClient{}
+	if err := shareClient.EnsureShare(ctx, "team-ledger", 5, &azure.EnsureShareOptions{Metadata: shareMetadata(source, "")}); err != nil {
+		t.Fatalf("EnsureShare error = %v", err)
+	}
+
+	k8sClient := fake.NewClientBuilder().WithScheme(scheme).WithObjects(sc, source, pvc).WithStatusSubresource(pvc).Build()
+	reconciler := &PVCReconciler{
+		Client:   k8sClient,
+		Scheme:   scheme,
+		Recorder: record.NewFakeRecorder(20),
+		Config:   ReconcilerConfig{ResourceGroup: "rg", StorageAccount: "account", Server: "server"},
+		Shares:   shareClient,
+	}
+
+	key := client.ObjectKeyFromObject(pvc)
+	if _, err := reconciler.Reconcile(ctx, reconcile.Request{NamespacedName: key}); err != nil {
+		t.Fatalf("Reconcile error = %v", err)
+	}
+	updated := &corev1.PersistentVolumeClaim{}
+	if err := k8sClient.Get(ctx, key, updated); err != nil {
+		t.Fatalf("Get PVC error = %v", err)
+	}
+	assertCondition(t, updated, constants.ConditionProvisioningFailed, corev1.ConditionTrue, constants.EventShareRestoreFailed)
+	if got := shareClient.Options["team-ledger"].Metadata[azure.MetadataPVCUID]; got != "uid-live" {
+		t.Fatalf("%s = %q, want the live claim's uid-live kept", azure.MetadataPVCUID, got)
+	}
+}
+
+func TestSoftDeleteReporterSync(t *testing.T) {
+	shareClient := &azure.FakeShareClient{}
+	softDeletedShare(t, shareClient, "team-data", "team", "uid-old")
+
+	metrics := NewReconcileMetrics()
+	reporter := &SoftDeleteReporter{Shares: map[string]azure.ShareClient{"account": shareClient}, Metrics: metrics}
+	ctx := ctrl.LoggerInto(context.Background(), logging.NewLogger())
+	if err := reporter.Sync(ctx); err != nil {
+		t.Fatalf("Sync error = %v", err)
+	}
+	if got := testutil.ToFloat64(metrics.softDeleted.WithLabelValues("account", "team-data", "1", "team", "uid-old")); got != 7 {
+		t.Fatalf("retention days = %v, want 7", got)
+	}
+
+	// Restored shares drop out of the report.
+	if err := shareClient.RestoreShare(ctx, "team-data", "1"); err != nil {
+		t.Fatalf("RestoreShare error = %v", err)
+	}
+	if err := reporter.Sync(ctx); err != nil {
+		t.Fatalf("Sync error = %v", err)
+	}
+	if got := testutil.CollectAndCount(metrics.softDeleted); got != 0 {
+		t.Fatalf("reported shares = %d, want 0", got)
+	}
+}
@@ -0,0 +1,224 @@
+package controller
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	storagev1 "k8s.io/api/storage/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/tools/record"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	"aks-azureFiles-controller/internal/azure"
+	"aks-azureFiles-controller/internal/constants"
+	"aks-azureFiles-controller/internal/k8s"
+	"aks-azureFiles-controller/internal/logging"
+	"aks-azureFiles-controller/internal/naming"
+)
+
+func TestReconcileClassifiesShareErrors(t *testing.T) {
+	cases := map[string]struct {
+		err          error
+		wantRequeue  time.Duration
+		wantErr      bool
+		wantReason   string
+		wantTerminal bool
+	}{
+		"throttled with retry-after": {
+			err:         &azure.ThrottledError{RetryAfter: 7 * time.Second, Err: errors.New("429")},
+			wantRequeue: 7 * time.Second,
+			wantReason:  constants.EventAzureThrottled,
+		},
+		"throttled without retry-after": {
+			err:         &azure.ThrottledError{Err: errors.New("503")},
+			wantRequeue: throttledRequeue,
+			wantReason:  constants.EventAzureThrottled,
+		},
+		"share being deleted": {
+			err:         fmt.Errorf("create: %w", azure.ErrShareBeingDeleted),
+			wantRequeue: shareBeingDeletedRequeue,
+			wantReason:  constants.EventShareBeingDeleted,
+		},
+		"authorization": {
+			err:          fmt.Errorf("create: %w", azure.ErrAuthorization),
+			wantRequeue:  authorizationRequeue,
+			wantReason:   constants.EventAzureUnauthorized,
+			wantTerminal: true,
+		},
+		"quota exceeded": {
+			err:          fmt.Errorf("create: %w", azure.ErrQuotaExceeded),
+			wantReason:   constants.EventShareQuotaExceeded,
+			wantTerminal: true,
+		},
+		"unclassified": {
+			err:        errors.New("connection reset"),
+			wantErr:    true,
+			wantReason: constants.EventShareError,
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			scheme := runtime.NewScheme()
+			if err := corev1.AddToScheme(scheme); err != nil {
+				t.Fatalf("AddToScheme corev1: %v", err)
+			}
+			if err := storagev1.AddToScheme(scheme); err != nil {
+				t.Fatalf("AddToScheme storagev1: %v", err)
+			}
+
+			sc := &storagev1.StorageClass{ObjectMeta: metav1.ObjectMeta{Name: "azurefile"}, Provisioner: k8s.ManagedProvisioner}
+			pvc := basePVC()
+			pvc.Spec.StorageClassName = stringPtr("azurefile")
+
+			k8sClient := fake.NewClientBuilder().WithScheme(scheme).WithObjects(sc, pvc).Build()
+			recorder := record.NewFakeRecorder(20)
+			reconciler := &PVCReconciler{
+				Client:   k8sClient,
+				Scheme:   scheme,
+				Recorder: recorder,
+				Config:   ReconcilerConfig{ResourceGroup: "rg", StorageAccount: "account", Server: "server"},
+				Shares:   &azure.FakeShareClient{EnsureErr: map[string]error{shareNameForTest(pvc): tc.err}},
+			}
+
+			ctx := ctrl.LoggerInto(context.Background(), logging.NewLogger())
+			request := reconcile.Request{NamespacedName: client.ObjectKeyFromObject(pvc)}
+			result, err := reconciler.Reconcile(ctx, request)
+			if (err != nil) != tc.wantErr {
+				t.Fatalf("Reconcile error = %v, wantErr %v", err, tc.wantErr)
+			}
+			if result.RequeueAfter != tc.wantRequeue {
+				t.Fatalf("RequeueAfter = %s, want %s", result.RequeueAfter, tc.wantRequeue)
+			}
+			if !hasEvent(recorder, tc.wantReason) {
+				t.Fatalf("event %q not recorded", tc.wantReason)
+			}
+
+			updated := &corev1.PersistentVolumeClaim{}
+			if err := k8sClient.Get(ctx, request.NamespacedName, updated); err != nil {
+				t.Fatalf("Get PVC error = %v", err)
+			}
+			if got := conditionTrue(updated, constants.ConditionProvisioningFailed); got != tc.wantTerminal {
+				t.Fatalf("ProvisioningFailed = %v, want %v", got, tc.wantTerminal)
+			}
+		})
+	}
+}
+
+func TestDeletionRequeuesThrottledShareDeletion(t *testing.T) {
+	scheme := runtime.NewScheme()
+	if err := corev1.AddToScheme(scheme); err != nil {
+		t.Fatalf("AddToScheme corev1: %v", err)
+	}
+	if err := storagev1.AddToScheme(scheme); err != nil {
+		t.Fatalf("AddToScheme storagev1: %v", err)
+	}
+
+	sc := &storagev1.StorageClass{ObjectMeta: metav1.ObjectMeta{Name: "azurefile"}, Provisioner: k8s.ManagedProvisioner}
+	pvc := basePVC()
+	pvc.Spec.StorageClassName = stringPtr("azurefile")
+
+	k8sClient := fake.NewClientBuilder().WithScheme(scheme).WithObjects(sc, pvc).Build()
+	shareClient := &azure.FakeShareClient{}
+	recorder := record.NewFakeRecorder(20)
+	reconciler := &PVCReconciler{
+		Client:   k8sClient,
+		Scheme:   scheme,
+		Recorder: recorder,
+		Config:   ReconcilerConfig{ResourceGroup: "rg", StorageAccount: "account", Server: "server"},
+		Shares:   shareClient,
+	}
+
+	ctx := ctrl.LoggerInto(context.Background(), logging.NewLogger())
+	key := client.ObjectKeyFromObject(pvc)
+	if _, err := reconciler.Reconcile(ctx, reconcile.Request{NamespacedName: key}); err != nil {
+		t.Fatalf("Reconcile error = %v", err)
+	}
+	if err := k8sClient.Delete(ctx, pvc); err != nil {
+		t.Fatalf("Delete PVC error = %v", err)
+	}
+
+	shareClient.DeleteErr = map[string]error{shareNameForTest(pvc): &azure.ThrottledError{RetryAfter: time.Minute, Err: errors.New("429")}}
+	result, err := reconciler.Reconcile(ctx, reconcile.Request{NamespacedName: key})
+	if err != nil || result.RequeueAfter != time.Minute {
+		t.Fatalf("Reconcile deletion = %v, %v, want requeue after %s", result, err, time.Minute)
+	}
+	if !hasEvent(recorder, constants.EventAzureThrottled) {
+		t.Fatalf("event %q not recorded", constants.EventAzureThrottled)
+	}
+	held := &corev1.PersistentVolumeClaim{}
+	if err := k8sClient.Get(ctx, key, held); err != nil || !containsFinalizer(held.Finalizers, constants.FinalizerName) {
+		t.Fatalf("PVC finalizer released while share deletion was throttled (err %v)", err)
+	}
+
+	// A deletion already in progress counts as done.
+	shareClient.DeleteErr = map[string]error{shareNameForTest(pvc): fmt.Errorf("delete: %w", azure.ErrShareBeingDeleted)}
+	if _, err := reconciler.Reconcile(ctx, reconcile.Request{NamespacedName: key}); err != nil {
+		t.Fatalf("Reconcile deletion error = %v", err)
+	}
+	if err := k8sClient.Get(ctx, key, held); err == nil {
+		t.Fatalf("PVC still present, want finalizer released")
+	}
+}
+
+func TestReconcileUniqueShareNameAvoidsShareBeingDeleted(t *testing.T) {
+	scheme := runtime.NewScheme()
+	if err := corev1.AddToScheme(scheme); err != nil {
+		t.Fatalf("AddToScheme corev1: %v", err)
+	}
+	if err := storagev1.AddToScheme(scheme); err != nil {
+		t.Fatalf("AddToScheme storagev1: %v", err)
+	}
+
+	sc := &storagev1.StorageClass{
+		ObjectMeta:  metav1.ObjectMeta{Name: "azurefile"},
+		Provisioner: k8s.ManagedProvisioner,
+		Parameters:  map[string]string{k8s.ParamShareNameSuffix: k8s.ShareNameSuffixUID},
+	}
+	pvc := basePVC()
+	pvc.UID = "uid-456"
+	pvc.Spec.StorageClassName = stringPtr("azurefile")
+
+	// The previous claim of the same name left its plain share behind, still being deleted.
+	shareClient := &azure.FakeShareClient{EnsureErr: map[string]error{
+		shareNameForTest(pvc): fmt.Errorf("create: %w", azure.ErrShareBeingDeleted),
+	}}
+	k8sClient := fake.NewClientBuilder().WithScheme(scheme).WithObjects(sc, pvc).Build()
+	reconciler := &PVCReconciler{
+		Client:   k8sClient,
+		Scheme:   scheme,
+		Recorder: record.NewFakeRecorder(20),
+		Config:   ReconcilerConfig{ResourceGroup: "rg", StorageAccount: "account", Server: "server"},
+		Shares:   shareClient,
+	}
+
+	ctx := ctrl.LoggerInto(context.Background(), logging.NewLogger())
+	request := reconcile.Request{NamespacedName: client.ObjectKeyFromObject(pvc)}
+	if _, err := reconciler.Reconcile(ctx, request); err != nil {
+		t.Fatalf("Reconcile error = %v", err)
+	}
+
+	want, err := naming.ComputeUniqueShareName(pvc.Namespace, pvc.Name, string(pvc.UID))
+	if err != nil {
+		t.Fatalf("ComputeUniqueShareName error = %v", err)
+	}
+	updated := &corev1.PersistentVolumeClaim{}
+	if err := k8sClient.Get(ctx, request.NamespacedName, updated); err != nil {
+		t.Fatalf("Get PVC error = %v", err)
+	}
+	if got := updated.Annotations[constants.ShareNameAnnotation]; got != want {
+		t.Fatalf("share name = %q, want %q", got, want)
+	}
+	if _, ok := shareClient.Shares[want]; !ok {
+		t.Fatalf("share %q not created, shares = %v", want, shareClient.Shares)
+	}
+	assertCondition(t, updated, constants.ConditionShareReady, corev1.ConditionTrue, constants.EventShareReady)
+}
@@ -0,0 +1,129 @@
+package controller
+
+import (
+	"context"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	storagev1 "k8s.io/api/storage/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/tools/record"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	"aks-azureFiles-controller/internal/azure"
+	"aks-azureFiles-controller/internal/k8s"
+	"aks-azureFiles-controller/internal/logging"
+)
+
+func TestReconcileWritesNodeStageSecret(t *testing.T) {
+	scheme := runtime.NewScheme()
+	if err := corev1.AddToScheme(scheme); err != nil {
+		t.Fatalf("AddToScheme corev1: %v", err)
+	}
+	if err := storagev1.AddToScheme(scheme); err != nil {
+		t.Fatalf("AddToScheme storagev1: %v", err)
+	}
+
+	sc := &storagev1.StorageClass{
+		ObjectMeta:  metav1.ObjectMeta{Name: "azurefile"},
+		Provisioner: k8s.ManagedProvisioner,
+	}
+	pvc := basePVC()
+	pvc.Spec.StorageClassName = stringPtr("azurefile")
+
+	k8sClient := fake.NewClientBuilder().WithScheme(scheme).WithObjects(sc, pvc).Build()
+	keys := &azure.FakeAccountManager{Keys: map[string]string{"account": "key-1"}}
+
+	reconciler := &PVCReconciler{
+		Client:   k8sClient,
+		Scheme:   scheme,
+		Recorder: record.NewFakeRecorder(10),
+		Config: ReconcilerConfig{
+			ResourceGroup:  "rg",
+			StorageAccount: "account",
+			Server:         "server",
+		},
+		Shares:      &azure.FakeShareClient{},
+		NodeSecrets: &NodeSecretConfig{Keys: keys, Namespace: "azurefile-secrets"},
+	}
+
+	ctx := ctrl.LoggerInto(context.Background(), logging.NewLogger())
+	request := reconcile.Request{NamespacedName: client.ObjectKeyFromObject(pvc)}
+	if _, err := reconciler.Reconcile(ctx, request); err != nil {
+		t.Fatalf("Reconcile error = %v", err)
+	}
+
+	secret := &corev1.Secret{}
+	secretKey := client.ObjectKey{Namespace: "azurefile-secrets", Name: k8s.KeySecretName("account")}
+	if err := k8sClient.Get(ctx, secretKey, secret); err != nil {
+		t.Fatalf("Get Secret error = %v", err)
+	}
+	if string(secret.Data[k8s.SecretAccountNameKey]) != "account" || string(secret.Data[k8s.SecretAccountKeyKey]) != "key-1" {
+		t.Fatalf("Secret data = %v, want account/key-1", secret.Data)
+	}
+
+	pvList := &corev1.PersistentVolumeList{}
+	if err := k8sClient.List(ctx, pvList); err != nil {
+		t.Fatalf("List PVs error = %v", err)
+	}
+	if len(pvList.Items) != 1 {
+		t.Fatalf("PV count = %d, want 1", len(pvList.Items))
+	}
+	ref := pvList.Items[0].Spec.CSI.NodeStageSecretRef
+	if ref == nil || ref.Namespace != secretKey.Namespace || ref.Name != secretKey.Name {
+		t.Fatalf("NodeStageSecretRef = %#v, want %s", ref, secretKey)
+	}
+
+	// A second reconcile reuses the Secret without fetching the key again.
+	if _, err := reconciler.Reconcile(ctx, request); err != nil {
+		t.Fatalf("Reconcile error = %v", err)
+	}
+	if keys.KeyCount != 1 {
+		t.Fatalf("KeyCount = %d, want 1", keys.KeyCount)
+	}
+}
+
+func TestKeySecretRotatorRotatesAndCollects(t *testing.T) {
+	scheme := runtime.NewScheme()
+	if err := corev1.AddToScheme(scheme); err != nil {
+		t.Fatalf("AddToScheme corev1: %v", err)
+	}
+
+	used := k8s.BuildKeySecret("azurefile-secrets", "rg", "account", "old-key")
+	orphan := k8s.BuildKeySecret("azurefile-secrets", "rg", "orphanacct", "orphan-key")
+
+	pvc := basePVC()
+	pv, err := k8s.BuildPV(pvc, shareNameForTest(pvc), "rg", "account", "server", corev1.PersistentVolumeReclaimDelete,
+		k8s.WithNodeStageSecret(used.Namespace, used.Name))
+	if err != nil {
+		t.Fatalf("BuildPV error = %v", err)
+	}
+
+	k8sClient := fake.NewClientBuilder().WithScheme(scheme).WithObjects(used, orphan, pv).Build()
+	keys := &azure.FakeAccountManager{}
+	keys.SetKey("account", "new-key")
+
+	rotator := &KeySecretRotator{Client: k8sClient, Keys: keys}
+	ctx := ctrl.LoggerInto(context.Background(), logging.NewLogger())
+	if err := rotator.Sync(ctx); err != nil {
+		t.Fatalf("Sync error = %v", err)
+	}
+
+	rotated := &corev1.Secret{}
+	if err := k8sClient.Get(ctx, client.ObjectKeyFromObject(used), rotated); err != nil {
+		t.Fatalf("Get Secret error = %v", err)
+	}
+	if string(rotated.Data[k8s.SecretAccountKeyKey]) != "new-key" {
+		t.Fatalf("account key = %q, want %q", rotated.Data[k8s.SecretAccountKeyKey], "new-key")
+	}
+
+	err = k8sClient.Get(ctx, client.ObjectKeyFromObject(orphan), &corev1.Secret{})
+	if !apierrors.IsNotFound(err) {
+		t.Fatalf("Get orphan Secret error = %v, want not found", err)
+	}
+}
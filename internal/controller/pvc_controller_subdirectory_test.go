@@ -0,0 +1,278 @@
+package controller
+
+import (
+	"context"
+	"slices"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	storagev1 "k8s.io/api/storage/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/tools/record"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	"aks-azureFiles-controller/internal/azure"
+	"aks-azureFiles-controller/internal/constants"
+	"aks-azureFiles-controller/internal/k8s"
+	"aks-azureFiles-controller/internal/logging"
+	"aks-azureFiles-controller/internal/naming"
+)
+
+func TestReconcileProvisionsSubdirectories(t *testing.T) {
+	scheme := runtime.NewScheme()
+	if err := corev1.AddToScheme(scheme); err != nil {
+		t.Fatalf("AddToScheme corev1: %v", err)
+	}
+	if err := storagev1.AddToScheme(scheme); err != nil {
+		t.Fatalf("AddToScheme storagev1: %v", err)
+	}
+
+	sc := &storagev1.StorageClass{
+		ObjectMeta:  metav1.ObjectMeta{Name: "azurefile"},
+		Provisioner: k8s.ManagedProvisioner,
+		Parameters:  map[string]string{k8s.ParamSubdirectoryShare: k8s.SubdirectoryShareClass},
+	}
+	// team/a-data and team-a/data join to the same name, so only the UID keeps their directories apart.
+	data := basePVC()
+	data.Name = "a-data"
+	data.Spec.StorageClassName = stringPtr("azurefile")
+	data.Spec.Resources.Requests[corev1.ResourceStorage] = resource.MustParse("10Gi")
+	logs := basePVC()
+	logs.Namespace = "team-a"
+	logs.UID = types.UID("uid-456")
+	logs.Spec.StorageClassName = stringPtr("azurefile")
+	logs.Spec.Resources.Requests[corev1.ResourceStorage] = resource.MustParse("20Gi")
+	dataDirectory, err := naming.ComputeDirectoryName(data.Namespace, data.Name, string(data.UID))
+	if err != nil {
+		t.Fatalf("ComputeDirectoryName error = %v", err)
+	}
+	logsDirectory, err := naming.ComputeDirectoryName(logs.Namespace, logs.Name, string(logs.UID))
+	if err != nil {
+		t.Fatalf("ComputeDirectoryName error = %v", err)
+	}
+	if dataDirectory == logsDirectory {
+		t.Fatalf("directories of both claims = %s, want distinct", dataDirectory)
+	}
+
+	shareClient := &azure.FakeShareClient{}
+	recorder := record.NewFakeRecorder(50)
+	k8sClient := fake.NewClientBuilder().WithScheme(scheme).WithObjects(sc, data, logs).Build()
+	reconciler := &PVCReconciler{
+		Client:   k8sClient,
+		Scheme:   scheme,
+		Recorder: recorder,
+		Config:   ReconcilerConfig{ResourceGroup: "rg", StorageAccount: "account", Server: "server"},
+		Shares:   shareClient,
+	}
+
+	ctx := ctrl.LoggerInto(context.Background(), logging.NewLogger())
+	const backing = "shared-azurefile"
+	for _, tc := range []struct {
+		pvc       *corev1.PersistentVolumeClaim
+		directory string
+		wantQuota int32
+	}{
+		{pvc: data, directory: dataDirectory, wantQuota: 10},
+		{pvc: logs, directory: logsDirectory, wantQuota: 30},
+	} {
+		if _, err := reconciler.Reconcile(ctx, reconcile.Request{NamespacedName: client.ObjectKeyFromObject(tc.pvc)}); err != nil {
+			t.Fatalf("Reconcile %s error = %v", tc.pvc.Name, err)
+		}
+		if got := shareClient.Shares[backing]; got != tc.wantQuota {
+			t.Fatalf("backing share quota after %s = %d, want %d", tc.pvc.Name, got, tc.wantQuota)
+		}
+		if !slices.Contains(shareClient.Directories[backing], tc.directory) {
+			t.Fatalf("directories = %v, want %s", shareClient.Directories[backing], tc.directory)
+		}
+
+		pvList := &corev1.PersistentVolumeList{}
+		if err := k8sClient.List(ctx, pvList, client.MatchingLabels{k8s.LabelPVCName: tc.pvc.Name}); err != nil || len(pvList.Items) != 1 {
+			t.Fatalf("PVs of %s = %d, %v, want one", tc.pvc.Name, len(pvList.Items), err)
+		}
+		csi := pvList.Items[0].Spec.CSI
+		if want := k8s.VolumeHandle("rg", "account", backing) + "#" + tc.directory; csi.VolumeHandle != want {
+			t.Fatalf("VolumeHandle = %q, want %q", csi.VolumeHandle, want)
+		}
+		if csi.VolumeAttributes["shareName"] != backing || csi.VolumeAttributes["folderName"] != tc.directory {
+			t.Fatalf("VolumeAttributes = %v, want share %s and folder %s", csi.VolumeAttributes, backing, tc.directory)
+		}
+	}
+	if metadata := shareClient.Options[backing].Metadata; metadata[azure.MetadataProvisionedBy] != k8s.ManagedProvisioner || metadata[azure.MetadataBackingShareClass] != "azurefile" || metadata[azure.MetadataPVCUID] != "" || metadata[azure.MetadataOwnerNamespace] != "" {
+		t.Fatalf("backing share metadata = %v, want provisioned_by and the class without a claim or namespace owner", metadata)
+	}
+
+	key := client.ObjectKeyFromObject(data)
+	if err := k8sClient.Delete(ctx, data); err != nil {
+		t.Fatalf("Delete PVC error = %v", err)
+	}
+	if _, err := reconciler.Reconcile(ctx, reconcile.Request{NamespacedName: key}); err != nil {
+		t.Fatalf("Reconcile deletion error = %v", err)
+	}
+	if !hasEvent(recorder, constants.EventDirectoryDeleted) {
+		t.Fatalf("expected %s event", constants.EventDirectoryDeleted)
+	}
+	if _, ok := shareClient.Shares[backing]; !ok {
+		t.Fatalf("backing share deleted, want it kept")
+	}
+	if got := shareClient.Directories[backing]; !slices.Equal(got, []string{logsDirectory}) {
+		t.Fatalf("directories = %v, want only %s", got, logsDirectory)
+	}
+	pvList := &corev1.PersistentVolumeList{}
+	if err := k8sClient.List(ctx, pvList); err != nil || len(pvList.Items) != 1 {
+		t.Fatalf("PVs = %d, %v, want only the logs PV", len(pvList.Items), err)
+	}
+}
+
+func TestReconcileSubdirectoryClaimInvalid(t *testing.T) {
+	scheme := runtime.NewScheme()
+	if err := corev1.AddToScheme(scheme); err != nil {
+		t.Fatalf("AddToScheme corev1: %v", err)
+	}
+	if err := storagev1.AddToScheme(scheme); err != nil {
+		t.Fatalf("AddToScheme storagev1: %v", err)
+	}
+
+	sc := &storagev1.StorageClass{
+		ObjectMeta:  metav1.ObjectMeta{Name: "azurefile"},
+		Provisioner: k8s.ManagedProvisioner,
+		Parameters:  map[string]string{k8s.ParamSubdirectoryShare: k8s.SubdirectoryShareNamespace},
+	}
+	pvc := basePVC()
+	pvc.Spec.StorageClassName = stringPtr("azurefile")
+	pvc.Annotations = map[string]string{constants.ShareOverrideAnnotation: "ledger"}
+
+	shareClient := &azure.FakeShareClient{}
+	k8sClient := fake.NewClientBuilder().WithScheme(scheme).WithObjects(sc, pvc).WithStatusSubresource(pvc).Build()
+	reconciler := &PVCReconciler{
+		Client:   k8sClient,
+		Scheme:   scheme,
+		Recorder: record.NewFakeRecorder(20),
+		Config:   ReconcilerConfig{ResourceGroup: "rg", StorageAccount: "account", Server: "server"},
+		Shares:   shareClient,
+	}
+
+	ctx := ctrl.LoggerInto(context.Background(), logging.NewLogger())
+	key := client.ObjectKeyFromObject(pvc)
+	if _, err := reconciler.Reconcile(ctx, reconcile.Request{NamespacedName: key}); err != nil {
+		t.Fatalf("Reconcile error = %v", err)
+	}
+	updated := &corev1.PersistentVolumeClaim{}
+	if err := k8sClient.Get(ctx, key, updated); err != nil {
+		t.Fatalf("Get PVC error = %v", err)
+	}
+	assertCondition(t, updated, constants.ConditionProvisioningFailed, corev1.ConditionTrue, constants.EventPVCInvalid)
+	if len(shareClient.Shares) != 0 {
+		t.Fatalf("shares = %v, want none", shareClient.Shares)
+	}
+}
+
+func TestReconcileBackingShareIsolation(t *testing.T) {
+	const backing = "shared-azurefile"
+	cases := map[string]struct {
+		class       string
+		namespace   string
+		name        string
+		annotations map[string]string
+		metadata    map[string]string
+		wantReason  string
+	}{
+		"override onto backing share": {
+			class:       "plain",
+			annotations: map[string]string{constants.ShareOverrideAnnotation: backing},
+			metadata:    map[string]string{azure.MetadataBackingShareClass: "azurefile"},
+			wantReason:  constants.EventShareNameInvalid,
+		},
+		"override onto a future backing share": {
+			class:       "plain",
+			annotations: map[string]string{constants.ShareOverrideAnnotation: "shared-other"},
+			wantReason:  constants.EventShareNameInvalid,
+		},
+		"default name under the backing prefix": {
+			class:      "plain",
+			namespace:  "shared",
+			name:       "azurefile",
+			wantReason: constants.EventShareNameInvalid,
+		},
+		"adopt backing share": {
+			class:       "plain",
+			annotations: map[string]string{constants.AdoptShareAnnotation: backing},
+			metadata:    map[string]string{azure.MetadataBackingShareClass: "azurefile"},
+			wantReason:  constants.EventOwnershipConflict,
+		},
+		"backing share of another class": {
+			class:      "azurefile",
+			metadata:   map[string]string{azure.MetadataBackingShareClass: "other"},
+			wantReason: constants.EventOwnershipConflict,
+		},
+		"unmarked share under the backing name": {
+			class:      "azurefile",
+			wantReason: constants.EventOwnershipConflict,
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			scheme := runtime.NewScheme()
+			if err := corev1.AddToScheme(scheme); err != nil {
+				t.Fatalf("AddToScheme corev1: %v", err)
+			}
+			if err := storagev1.AddToScheme(scheme); err != nil {
+				t.Fatalf("AddToScheme storagev1: %v", err)
+			}
+
+			subdirectories := &storagev1.StorageClass{
+				ObjectMeta:  metav1.ObjectMeta{Name: "azurefile"},
+				Provisioner: k8s.ManagedProvisioner,
+				Parameters:  map[string]string{k8s.ParamSubdirectoryShare: k8s.SubdirectoryShareClass},
+			}
+			plain := &storagev1.StorageClass{ObjectMeta: metav1.ObjectMeta{Name: "plain"}, Provisioner: k8s.ManagedProvisioner}
+			pvc := basePVC()
+			pvc.Spec.StorageClassName = stringPtr(tc.class)
+			pvc.Annotations = tc.annotations
+			if tc.namespace != "" {
+				pvc.Namespace, pvc.Name = tc.namespace, tc.name
+			}
+
+			shareClient := &azure.FakeShareClient{
+				Shares:      map[string]int32{backing: 100},
+				Options:     map[string]azure.EnsureShareOptions{backing: {Metadata: tc.metadata}},
+				Directories: map[string][]string{backing: {"ops-logs"}},
+			}
+			k8sClient := fake.NewClientBuilder().WithScheme(scheme).WithObjects(subdirectories, plain, pvc).WithStatusSubresource(pvc).Build()
+			reconciler := &PVCReconciler{
+				Client:   k8sClient,
+				Scheme:   scheme,
+				Recorder: record.NewFakeRecorder(20),
+				Config:   ReconcilerConfig{ResourceGroup: "rg", StorageAccount: "account", Server: "server"},
+				Shares:   shareClient,
+			}
+
+			ctx := ctrl.LoggerInto(context.Background(), logging.NewLogger())
+			key := client.ObjectKeyFromObject(pvc)
+			if _, err := reconciler.Reconcile(ctx, reconcile.Request{NamespacedName: key}); err != nil {
+				t.Fatalf("Reconcile error = %v", err)
+			}
+			updated := &corev1.PersistentVolumeClaim{}
+			if err := k8sClient.Get(ctx, key, updated); err != nil {
+				t.Fatalf("Get PVC error = %v", err)
+			}
+			assertCondition(t, updated, constants.ConditionProvisioningFailed, corev1.ConditionTrue, tc.wantReason)
+			if len(shareClient.Shares) != 1 {
+				t.Fatalf("shares = %v, want only the backing share", shareClient.Shares)
+			}
+			pvList := &corev1.PersistentVolumeList{}
+			if err := k8sClient.List(ctx, pvList); err != nil || len(pvList.Items) != 0 {
+				t.Fatalf("PVs = %d, %v, want none", len(pvList.Items), err)
+			}
+			if got := shareClient.Directories[backing]; !slices.Equal(got, []string{"ops-logs"}) {
+				t.Fatalf("directories = %v, want only ops-logs", got)
+			}
+		})
+	}
+}
@@ -173,6 +173,104 @@ func TestReconcileIdempotent(t *testing.T) {
 	}
 }
 
+func TestReconcileAppliesStorageClassParameters(t *testing.T) {
+	scheme := runtime.NewScheme()
+	if err := corev1.AddToScheme(scheme); err != nil {
+		t.Fatalf("AddToScheme corev1: %v", err)
+	}
+	if err := storagev1.AddToScheme(scheme); err != nil {
+		t.Fatalf("AddToScheme storagev1: %v", err)
+	}
+
+	sc := &storagev1.StorageClass{
+		ObjectMeta:  metav1.ObjectMeta{Name: "azurefile"},
+		Provisioner: k8s.ManagedProvisioner,
+		Parameters: map[string]string{
+			k8s.ParamSkuName:     "Standard_LRS",
+			k8s.ParamAccessTier:  "Cool",
+			k8s.ParamMinQuotaGiB: "10",
+		},
+	}
+
+	pvc := basePVC()
+	pvc.Spec.StorageClassName = stringPtr("azurefile")
+
+	k8sClient := fake.NewClientBuilder().WithScheme(scheme).WithObjects(sc, pvc).Build()
+	shareClient := &azure.FakeShareClient{}
+
+	reconciler := &PVCReconciler{
+		Client:   k8sClient,
+		Scheme:   scheme,
+		Recorder: record.NewFakeRecorder(10),
+		Config: ReconcilerConfig{
+			ResourceGroup:  "rg",
+			StorageAccount: "account",
+			Server:         "server",
+		},
+		Shares: shareClient,
+	}
+
+	ctx := ctrl.LoggerInto(context.Background(), logging.NewLogger())
+	if _, err := reconciler.Reconcile(ctx, reconcile.Request{NamespacedName: client.ObjectKeyFromObject(pvc)}); err != nil {
+		t.Fatalf("Reconcile error = %v", err)
+	}
+
+	shareName := shareNameForTest(pvc)
+	if shareClient.Shares[shareName] != 10 {
+		t.Fatalf("Share quota = %d, want 10", shareClient.Shares[shareName])
+	}
+	if shareClient.Options[shareName].AccessTier != "Cool" {
+		t.Fatalf("AccessTier = %q, want %q", shareClient.Options[shareName].AccessTier, "Cool")
+	}
+}
+
+func TestReconcileRejectsUnknownParameters(t *testing.T) {
+	scheme := runtime.NewScheme()
+	if err := corev1.AddToScheme(scheme); err != nil {
+		t.Fatalf("AddToScheme corev1: %v", err)
+	}
+	if err := storagev1.AddToScheme(scheme); err != nil {
+		t.Fatalf("AddToScheme storagev1: %v", err)
+	}
+
+	sc := &storagev1.StorageClass{
+		ObjectMeta:  metav1.ObjectMeta{Name: "azurefile"},
+		Provisioner: k8s.ManagedProvisioner,
+		Parameters:  map[string]string{"skuname": "Standard_LRS"},
+	}
+
+	pvc := basePVC()
+	pvc.Spec.StorageClassName = stringPtr("azurefile")
+
+	k8sClient := fake.NewClientBuilder().WithScheme(scheme).WithObjects(sc, pvc).Build()
+	shareClient := &azure.FakeShareClient{}
+	recorder := record.NewFakeRecorder(10)
+
+	reconciler := &PVCReconciler{
+		Client:   k8sClient,
+		Scheme:   scheme,
+		Recorder: recorder,
+		Config: ReconcilerConfig{
+			ResourceGroup:  "rg",
+			StorageAccount: "account",
+			Server:         "server",
+		},
+		Shares: shareClient,
+	}
+
+	ctx := ctrl.LoggerInto(context.Background(), logging.NewLogger())
+	if _, err := reconciler.Reconcile(ctx, reconcile.Request{NamespacedName: client.ObjectKeyFromObject(pvc)}); err != nil {
+		t.Fatalf("Reconcile error = %v", err)
+	}
+
+	if len(shareClient.Shares) != 0 {
+		t.Fatalf("Shares = %#v, want none", shareClient.Shares)
+	}
+	if !hasEvent(recorder, constants.EventShareValidation) {
+		t.Fatalf("event %q not recorded", constants.EventShareValidation)
+	}
+}
+
 func stringPtr(value string) *string {
 	return &value
 }
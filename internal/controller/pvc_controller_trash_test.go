@@ -0,0 +1,200 @@
+package controller
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	storagev1 "k8s.io/api/storage/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/tools/record"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	"aks-azureFiles-controller/internal/azure"
+	"aks-azureFiles-controller/internal/constants"
+	"aks-azureFiles-controller/internal/k8s"
+	"aks-azureFiles-controller/internal/logging"
+)
+
+func TestReconcileDeferredDeletionAndReclaim(t *testing.T) {
+	scheme := runtime.NewScheme()
+	if err := corev1.AddToScheme(scheme); err != nil {
+		t.Fatalf("AddToScheme corev1: %v", err)
+	}
+	if err := storagev1.AddToScheme(scheme); err != nil {
+		t.Fatalf("AddToScheme storagev1: %v", err)
+	}
+
+	sc := &storagev1.StorageClass{ObjectMeta: metav1.ObjectMeta{Name: "azurefile"}, Provisioner: k8s.ManagedProvisioner}
+	pvc := basePVC()
+	pvc.Spec.StorageClassName = stringPtr("azurefile")
+
+	shareClient := &azure.FakeShareClient{}
+	k8sClient := fake.NewClientBuilder().WithScheme(scheme).WithObjects(sc, pvc).Build()
+	recorder := record.NewFakeRecorder(20)
+	reconciler := &PVCReconciler{
+		Client:   k8sClient,
+		Scheme:   scheme,
+		Recorder: recorder,
+		Config:   ReconcilerConfig{ResourceGroup: "rg", StorageAccount: "account", Server: "server", DeletionGracePeriod: time.Hour},
+		Shares:   shareClient,
+	}
+
+	ctx := ctrl.LoggerInto(context.Background(), logging.NewLogger())
+	key := client.ObjectKeyFromObject(pvc)
+	for range 2 {
+		if _, err := reconciler.Reconcile(ctx, reconcile.Request{NamespacedName: key}); err != nil {
+			t.Fatalf("Reconcile error = %v", err)
+		}
+	}
+	shareName := shareNameForTest(pvc)
+
+	provisioned := &corev1.PersistentVolumeClaim{}
+	if err := k8sClient.Get(ctx, key, provisioned); err != nil {
+		t.Fatalf("Get PVC error = %v", err)
+	}
+	if err := k8sClient.Delete(ctx, provisioned); err != nil {
+		t.Fatalf("Delete PVC error = %v", err)
+	}
+	if _, err := reconciler.Reconcile(ctx, reconcile.Request{NamespacedName: key}); err != nil {
+		t.Fatalf("Reconcile deletion error = %v", err)
+	}
+	if !hasEvent(recorder, constants.EventShareScheduledForDeletion) {
+		t.Fatalf("event %q not recorded", constants.EventShareScheduledForDeletion)
+	}
+	if err := k8sClient.Get(ctx, key, &corev1.PersistentVolumeClaim{}); !apierrors.IsNotFound(err) {
+		t.Fatalf("Get PVC error = %v, want not found after the finalizer is released", err)
+	}
+	props, err := shareClient.GetShare(ctx, shareName)
+	if err != nil {
+		t.Fatalf("GetShare error = %v, want the share kept during the grace period", err)
+	}
+	deadline, err := time.Parse(time.RFC3339, props.Metadata[azure.MetadataDeleteAfter])
+	if err != nil || deadline.Before(time.Now().Add(59*time.Minute)) {
+		t.Fatalf("%s = %q, want about an hour from now", azure.MetadataDeleteAfter, props.Metadata[azure.MetadataDeleteAfter])
+	}
+
+	// A claim recreated with the same name takes the share back.
+	recreated := basePVC()
+	recreated.UID = types.UID("uid-456")
+	recreated.Spec.StorageClassName = stringPtr("azurefile")
+	if err := k8sClient.Create(ctx, recreated); err != nil {
+		t.Fatalf("Create PVC error = %v", err)
+	}
+	for range 2 {
+		if _, err := reconciler.Reconcile(ctx, reconcile.Request{NamespacedName: key}); err != nil {
+			t.Fatalf("Reconcile error = %v", err)
+		}
+	}
+	if !hasEvent(recorder, constants.EventShareReclaimed) {
+		t.Fatalf("event %q not recorded", constants.EventShareReclaimed)
+	}
+	props, err = shareClient.GetShare(ctx, shareName)
+	if err != nil {
+		t.Fatalf("GetShare error = %v", err)
+	}
+	if got, ok := props.Metadata[azure.MetadataDeleteAfter]; ok {
+		t.Fatalf("%s = %q, want cleared", azure.MetadataDeleteAfter, got)
+	}
+	if got := props.Metadata[azure.MetadataPVCUID]; got != "uid-456" {
+		t.Fatalf("%s = %q, want the new claim uid-456", azure.MetadataPVCUID, got)
+	}
+}
+
+func TestReconcileRestoreReclaimsPendingShare(t *testing.T) {
+	scheme := runtime.NewScheme()
+	if err := corev1.AddToScheme(scheme); err != nil {
+		t.Fatalf("AddToScheme corev1: %v", err)
+	}
+	if err := storagev1.AddToScheme(scheme); err != nil {
+		t.Fatalf("AddToScheme storagev1: %v", err)
+	}
+
+	sc := &storagev1.StorageClass{ObjectMeta: metav1.ObjectMeta{Name: "azurefile"}, Provisioner: k8s.ManagedProvisioner}
+	pvc := basePVC()
+	pvc.Spec.StorageClassName = stringPtr("azurefile")
+	pvc.Annotations = map[string]string{constants.RestoreFromAnnotation: "uid-old"}
+
+	ctx := ctrl.LoggerInto(context.Background(), logging.NewLogger())
+	shareClient := &azure.FakeShareClient{}
+	metadata := map[string]string{
+		azure.MetadataProvisionedBy:  k8s.ManagedProvisioner,
+		azure.MetadataOwnerNamespace: "team",
+		azure.MetadataPVCUID:         "uid-old",
+		azure.MetadataDeleteAfter:    time.Now().Add(time.Hour).UTC().Format(time.RFC3339),
+	}
+	if err := shareClient.EnsureShare(ctx, "team-ledger", 5, &azure.EnsureShareOptions{Metadata: metadata}); err != nil {
+		t.Fatalf("EnsureShare error = %v", err)
+	}
+
+	k8sClient := fake.NewClientBuilder().WithScheme(scheme).WithObjects(sc, pvc).Build()
+	recorder := record.NewFakeRecorder(20)
+	reconciler := &PVCReconciler{
+		Client:   k8sClient,
+		Scheme:   scheme,
+		Recorder: recorder,
+		Config:   ReconcilerConfig{ResourceGroup: "rg", StorageAccount: "account", Server: "server", DeletionGracePeriod: time.Hour},
+		Shares:   shareClient,
+	}
+
+	key := client.ObjectKeyFromObject(pvc)
+	for range 2 {
+		if _, err := reconciler.Reconcile(ctx, reconcile.Request{NamespacedName: key}); err != nil {
+			t.Fatalf("Reconcile error = %v", err)
+		}
+	}
+	if !hasEvent(recorder, constants.EventShareReclaimed) {
+		t.Fatalf("event %q not recorded", constants.EventShareReclaimed)
+	}
+	updated := &corev1.PersistentVolumeClaim{}
+	if err := k8sClient.Get(ctx, key, updated); err != nil {
+		t.Fatalf("Get PVC error = %v", err)
+	}
+	if got := updated.Annotations[constants.ShareNameAnnotation]; got != "team-ledger" {
+		t.Fatalf("share name = %q, want the pending team-ledger", got)
+	}
+	props, err := shareClient.GetShare(ctx, "team-ledger")
+	if err != nil {
+		t.Fatalf("GetShare error = %v", err)
+	}
+	if got, ok := props.Metadata[azure.MetadataDeleteAfter]; ok {
+		t.Fatalf("%s = %q, want cleared", azure.MetadataDeleteAfter, got)
+	}
+}
+
+func TestShareReaperSync(t *testing.T) {
+	ctx := ctrl.LoggerInto(context.Background(), logging.NewLogger())
+	shareClient := &azure.FakeShareClient{}
+	past := time.Now().Add(-time.Minute).UTC().Format(time.RFC3339)
+	future := time.Now().Add(time.Hour).UTC().Format(time.RFC3339)
+	for name, metadata := range map[string]map[string]string{
+		"expired": {azure.MetadataProvisionedBy: k8s.ManagedProvisioner, azure.MetadataClusterID: "aks-a", azure.MetadataDeleteAfter: past},
+		"pending": {azure.MetadataProvisionedBy: k8s.ManagedProvisioner, azure.MetadataClusterID: "aks-a", azure.MetadataDeleteAfter: future},
+		"foreign": {azure.MetadataProvisionedBy: k8s.ManagedProvisioner, azure.MetadataClusterID: "aks-b", azure.MetadataDeleteAfter: past},
+		"live":    {azure.MetadataProvisionedBy: k8s.ManagedProvisioner, azure.MetadataClusterID: "aks-a"},
+	} {
+		if err := shareClient.EnsureShare(ctx, name, 5, &azure.EnsureShareOptions{Metadata: metadata}); err != nil {
+			t.Fatalf("EnsureShare %s error = %v", name, err)
+		}
+	}
+
+	reaper := &ShareReaper{Shares: map[string]azure.ShareClient{"account": shareClient}, ClusterID: "aks-a"}
+	if err := reaper.Sync(ctx); err != nil {
+		t.Fatalf("Sync error = %v", err)
+	}
+	if _, ok := shareClient.Shares["expired"]; ok {
+		t.Fatalf("expired share kept, want deleted")
+	}
+	for _, name := range []string{"pending", "foreign", "live"} {
+		if _, ok := shareClient.Shares[name]; !ok {
+			t.Fatalf("share %s deleted, want kept", name)
+		}
+	}
+}
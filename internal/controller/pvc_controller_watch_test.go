@@ -0,0 +1,121 @@
+package controller
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	storagev1 "k8s.io/api/storage/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/tools/record"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	"aks-azureFiles-controller/internal/azure"
+	"aks-azureFiles-controller/internal/k8s"
+	"aks-azureFiles-controller/internal/logging"
+)
+
+func TestPVCForPV(t *testing.T) {
+	reconciler := &PVCReconciler{}
+	pv := &corev1.PersistentVolume{ObjectMeta: metav1.ObjectMeta{
+		Name:   "pv-1",
+		Labels: map[string]string{k8s.LabelPVCNamespace: "team", k8s.LabelPVCName: "data"},
+	}}
+
+	got := reconciler.pvcForPV(context.Background(), pv)
+	want := []reconcile.Request{{NamespacedName: client.ObjectKey{Namespace: "team", Name: "data"}}}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("pvcForPV = %v, want %v", got, want)
+	}
+
+	foreign := &corev1.PersistentVolume{ObjectMeta: metav1.ObjectMeta{Name: "pv-2"}}
+	if got := reconciler.pvcForPV(context.Background(), foreign); len(got) != 0 {
+		t.Fatalf("pvcForPV foreign = %v, want none", got)
+	}
+}
+
+func TestPendingPVCsForStorageClass(t *testing.T) {
+	scheme := runtime.NewScheme()
+	if err := corev1.AddToScheme(scheme); err != nil {
+		t.Fatalf("AddToScheme corev1: %v", err)
+	}
+
+	pending := basePVC()
+	pending.Spec.StorageClassName = stringPtr("azurefile")
+	bound := basePVC()
+	bound.Name = "bound"
+	bound.Spec.StorageClassName = stringPtr("azurefile")
+	bound.Spec.VolumeName = "pv-bound"
+	other := basePVC()
+	other.Name = "other"
+	other.Spec.StorageClassName = stringPtr("disk")
+
+	k8sClient := fake.NewClientBuilder().
+		WithScheme(scheme).
+		WithObjects(pending, bound, other).
+		WithIndex(&corev1.PersistentVolumeClaim{}, storageClassIndex, indexStorageClass).
+		Build()
+	reconciler := &PVCReconciler{Client: k8sClient}
+
+	sc := &storagev1.StorageClass{ObjectMeta: metav1.ObjectMeta{Name: "azurefile"}, Provisioner: k8s.ManagedProvisioner}
+	got := reconciler.pendingPVCsForStorageClass(context.Background(), sc)
+	want := []reconcile.Request{{NamespacedName: client.ObjectKeyFromObject(pending)}}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("pendingPVCsForStorageClass = %v, want %v", got, want)
+	}
+}
+
+func TestReconcileRecreatesDeletedPV(t *testing.T) {
+	scheme := runtime.NewScheme()
+	if err := corev1.AddToScheme(scheme); err != nil {
+		t.Fatalf("AddToScheme corev1: %v", err)
+	}
+	if err := storagev1.AddToScheme(scheme); err != nil {
+		t.Fatalf("AddToScheme storagev1: %v", err)
+	}
+
+	sc := &storagev1.StorageClass{ObjectMeta: metav1.ObjectMeta{Name: "azurefile"}, Provisioner: k8s.ManagedProvisioner}
+	pvc := basePVC()
+	pvc.Spec.StorageClassName = stringPtr("azurefile")
+
+	k8sClient := fake.NewClientBuilder().WithScheme(scheme).WithObjects(sc, pvc).Build()
+	reconciler := &PVCReconciler{
+		Client:   k8sClient,
+		Scheme:   scheme,
+		Recorder: record.NewFakeRecorder(20),
+		Config:   ReconcilerConfig{ResourceGroup: "rg", StorageAccount: "account", Server: "server"},
+		Shares:   &azure.FakeShareClient{},
+	}
+
+	ctx := ctrl.LoggerInto(context.Background(), logging.NewLogger())
+	request := reconcile.Request{NamespacedName: client.ObjectKeyFromObject(pvc)}
+	if _, err := reconciler.Reconcile(ctx, request); err != nil {
+		t.Fatalf("Reconcile error = %v", err)
+	}
+	pvList := &corev1.PersistentVolumeList{}
+	if err := k8sClient.List(ctx, pvList); err != nil || len(pvList.Items) != 1 {
+		t.Fatalf("List PVs = %v, %v, want one PV", pvList.Items, err)
+	}
+	pv := pvList.Items[0]
+	if err := k8sClient.Delete(ctx, &pv); err != nil {
+		t.Fatalf("Delete PV error = %v", err)
+	}
+
+	// The PV watch maps the deletion back to the claim, whose reconcile restores the PV.
+	requests := reconciler.pvcForPV(ctx, &pv)
+	if len(requests) != 1 || requests[0] != request {
+		t.Fatalf("pvcForPV = %v, want %v", requests, request)
+	}
+	if _, err := reconciler.Reconcile(ctx, requests[0]); err != nil {
+		t.Fatalf("Reconcile error = %v", err)
+	}
+	restored := &corev1.PersistentVolume{}
+	if err := k8sClient.Get(ctx, client.ObjectKey{Name: pv.Name}, restored); err != nil {
+		t.Fatalf("Get restored PV error = %v", err)
+	}
+}
@@ -0,0 +1,140 @@
+package controller
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/go-logr/logr"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"aks-azureFiles-controller/internal/azure"
+	"aks-azureFiles-controller/internal/constants"
+)
+
+// reconcileShareQuota grows the Azure share quota when the claim requests more than the share provides.
+// Shares are never shrunk here; shrink requests are refused in reconcileCapacity.
+func (r *PVCReconciler) reconcileShareQuota(ctx context.Context, logger logr.Logger, pvc *corev1.PersistentVolumeClaim, shares azure.ShareClient, props *azure.ShareProperties, quotaGiB int32) error {
+	if props.QuotaGiB >= quotaGiB {
+		return nil
+	}
+
+	if err := r.markResizing(ctx, pvc); err != nil {
+		return fmt.Errorf("mark resizing: %w", err)
+	}
+
+	logger.Info("expanding share quota", "fromGiB", props.QuotaGiB, "toGiB", quotaGiB)
+	r.Recorder.Eventf(pvc, corev1.EventTypeNormal, constants.EventResizing, "Expanding Azure File share quota from %d GiB to %d GiB", props.QuotaGiB, quotaGiB)
+	if err := shares.SetShareQuota(ctx, props.Name, quotaGiB); err != nil {
+		r.Recorder.Event(pvc, corev1.EventTypeWarning, constants.EventResizeFailed, "Failed to expand Azure File share quota")
+		return fmt.Errorf("set share quota: %w", err)
+	}
+	return nil
+}
+
+// reconcileCapacity propagates the requested size to the PV and the PVC status once the share quota covers it.
+// Requests below the current PV capacity are refused because Azure File shares cannot shrink safely.
+func (r *PVCReconciler) reconcileCapacity(ctx context.Context, logger logr.Logger, pvc *corev1.PersistentVolumeClaim, pv *corev1.PersistentVolume) error {
+	requested := pvc.Spec.Resources.Requests[corev1.ResourceStorage]
+	current := pv.Spec.Capacity[corev1.ResourceStorage]
+
+	switch requested.Cmp(current) {
+	case -1:
+		message := fmt.Sprintf("Requested size %s is smaller than current capacity %s; Azure File shares cannot be shrunk", requested.String(), current.String())
+		if existing := findPVCCondition(pvc, constants.ConditionShrinkRefused); existing == nil || existing.Status != corev1.ConditionTrue || existing.Message != message {
+			logger.Info("shrink refused", "requested", requested.String(), "capacity", current.String())
+			r.Recorder.Event(pvc, corev1.EventTypeWarning, constants.EventShrinkRefused, message)
+		}
+		return r.setConditions(ctx, pvc, claimCondition(constants.ConditionShrinkRefused, corev1.ConditionTrue, constants.EventShrinkRefused, message))
+	case 1:
+		patch := client.MergeFrom(pv.DeepCopy())
+		if pv.Spec.Capacity == nil {
+			pv.Spec.Capacity = corev1.ResourceList{}
+		}
+		pv.Spec.Capacity[corev1.ResourceStorage] = requested.DeepCopy()
+		if err := r.Client.Patch(ctx, pv, patch); err != nil {
+			return fmt.Errorf("patch pv capacity: %w", err)
+		}
+		logger.Info("expanded pv capacity", "capacity", requested.String())
+	}
+
+	if findPVCCondition(pvc, constants.ConditionShrinkRefused) != nil {
+		if err := r.setConditions(ctx, pvc, claimCondition(constants.ConditionShrinkRefused, corev1.ConditionFalse, constants.EventShareReady, "")); err != nil {
+			return err
+		}
+	}
+	if pvc.Status.Phase != corev1.ClaimBound {
+		return nil
+	}
+
+	grown := false
+	err := r.patchClaimStatus(ctx, pvc, func() bool {
+		statusCapacity := pvc.Status.Capacity[corev1.ResourceStorage]
+		grown = statusCapacity.Cmp(requested) < 0
+		if !grown && findPVCCondition(pvc, corev1.PersistentVolumeClaimResizing) == nil {
+			return false
+		}
+		if pvc.Status.Capacity == nil {
+			pvc.Status.Capacity = corev1.ResourceList{}
+		}
+		if grown {
+			pvc.Status.Capacity[corev1.ResourceStorage] = requested.DeepCopy()
+		}
+		pvc.Status.Conditions = removePVCCondition(pvc.Status.Conditions, corev1.PersistentVolumeClaimResizing)
+		delete(pvc.Status.AllocatedResourceStatuses, corev1.ResourceStorage)
+		return true
+	})
+	if err != nil {
+		return fmt.Errorf("patch pvc status: %w", err)
+	}
+
+	if grown {
+		r.Recorder.Eventf(pvc, corev1.EventTypeNormal, constants.EventResizeSuccessful, "Resize of Azure File share to %s completed", requested.String())
+	}
+	return nil
+}
+
+// markResizing records an in-progress controller expansion on a bound claim, mirroring the external resizer.
+func (r *PVCReconciler) markResizing(ctx context.Context, pvc *corev1.PersistentVolumeClaim) error {
+	now := metav1.Now()
+	err := r.patchClaimStatus(ctx, pvc, func() bool {
+		if pvc.Status.Phase != corev1.ClaimBound || findPVCCondition(pvc, corev1.PersistentVolumeClaimResizing) != nil {
+			return false
+		}
+		pvc.Status.Conditions = append(pvc.Status.Conditions, corev1.PersistentVolumeClaimCondition{
+			Type:               corev1.PersistentVolumeClaimResizing,
+			Status:             corev1.ConditionTrue,
+			LastProbeTime:      now,
+			LastTransitionTime: now,
+		})
+		if pvc.Status.AllocatedResourceStatuses == nil {
+			pvc.Status.AllocatedResourceStatuses = map[corev1.ResourceName]corev1.ClaimResourceStatus{}
+		}
+		pvc.Status.AllocatedResourceStatuses[corev1.ResourceStorage] = corev1.PersistentVolumeClaimControllerResizeInProgress
+		return true
+	})
+	if err != nil {
+		return fmt.Errorf("patch pvc status: %w", err)
+	}
+	return nil
+}
+
+func findPVCCondition(pvc *corev1.PersistentVolumeClaim, conditionType corev1.PersistentVolumeClaimConditionType) *corev1.PersistentVolumeClaimCondition {
+	for i := range pvc.Status.Conditions {
+		if pvc.Status.Conditions[i].Type == conditionType {
+			return &pvc.Status.Conditions[i]
+		}
+	}
+	return nil
+}
+
+func removePVCCondition(conditions []corev1.PersistentVolumeClaimCondition, conditionType corev1.PersistentVolumeClaimConditionType) []corev1.PersistentVolumeClaimCondition {
+	kept := make([]corev1.PersistentVolumeClaimCondition, 0, len(conditions))
+	for _, condition := range conditions {
+		if condition.Type != conditionType {
+			kept = append(kept, condition)
+		}
+	}
+	return kept
+}
@@ -0,0 +1,138 @@
+package controller
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"maps"
+
+	"github.com/go-logr/logr"
+	corev1 "k8s.io/api/core/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"aks-azureFiles-controller/internal/azure"
+	"aks-azureFiles-controller/internal/constants"
+)
+
+var (
+	ErrNoDeletedShare     = errors.New("no soft-deleted share to restore")
+	ErrRestoreSourceInUse = errors.New("restore source claim still exists")
+)
+
+// restoreShare brings back the share of the claim named by the restore annotation and returns its name: the share
+// an earlier reconcile recorded, then a share still pending deletion, then the most recently soft-deleted one. Only
+// shares created in the claim's namespace for a claim that no longer exists are restored. The name is recorded
+// right away, so later reconciles keep using the restored share, and the share is then stamped with the claim's
+// own metadata, so it is deleted with the claim by its metadata rather than by the annotation.
+func (r *PVCReconciler) restoreShare(ctx context.Context, logger logr.Logger, pvc *corev1.PersistentVolumeClaim, shares azure.ShareClient, sourceUID string) (string, error) {
+	if err := r.checkRestoreSource(ctx, pvc, sourceUID); err != nil {
+		return "", err
+	}
+	name, err := r.findRestoreShare(ctx, logger, pvc, shares, sourceUID)
+	if err != nil {
+		return "", err
+	}
+	if pvc.Annotations[constants.ShareNameAnnotation] != name {
+		if err := r.recordShareName(ctx, pvc, name); err != nil {
+			return "", err
+		}
+	}
+
+	props, err := shares.GetShare(ctx, name)
+	if err != nil {
+		return "", fmt.Errorf("get share: %w", err)
+	}
+	if props.Metadata[azure.MetadataPVCUID] != string(pvc.UID) {
+		metadata := maps.Clone(props.Metadata)
+		if metadata == nil {
+			metadata = map[string]string{}
+		}
+		maps.Copy(metadata, shareMetadata(pvc, r.Config.ClusterID))
+		if err := shares.SetShareMetadata(ctx, name, metadata); err != nil {
+			return "", fmt.Errorf("stamp restored share: %w", err)
+		}
+	}
+	return name, nil
+}
+
+// checkRestoreSource refuses to restore the share of a claim that still exists in the namespace, whose share is
+// in use rather than deleted.
+func (r *PVCReconciler) checkRestoreSource(ctx context.Context, pvc *corev1.PersistentVolumeClaim, sourceUID string) error {
+	pvcList := &corev1.PersistentVolumeClaimList{}
+	if err := r.Client.List(ctx, pvcList, client.InNamespace(pvc.Namespace)); err != nil {
+		return fmt.Errorf("list pvcs: %w", err)
+	}
+	for _, claim := range pvcList.Items {
+		if string(claim.UID) == sourceUID {
+			return fmt.Errorf("claim %s is %s/%s: %w", sourceUID, claim.Namespace, claim.Name, ErrRestoreSourceInUse)
+		}
+	}
+	return nil
+}
+
+// findRestoreShare returns the share to restore. A recorded share is only used while its metadata still names the
+// source claim, or already the claim, in the claim's namespace.
+func (r *PVCReconciler) findRestoreShare(ctx context.Context, logger logr.Logger, pvc *corev1.PersistentVolumeClaim, shares azure.ShareClient, sourceUID string) (string, error) {
+	if name := pvc.Annotations[constants.ShareNameAnnotation]; name != "" {
+		props, err := shares.GetShare(ctx, name)
+		if err != nil && !errors.Is(err, azure.ErrShareNotFound) {
+			return "", fmt.Errorf("get share: %w", err)
+		}
+		if err == nil && props.Metadata[azure.MetadataOwnerNamespace] == pvc.Namespace {
+			if uid := props.Metadata[azure.MetadataPVCUID]; uid == sourceUID || uid == string(pvc.UID) {
+				return name, nil
+			}
+		}
+	}
+
+	live, err := shares.ListShares(ctx)
+	if err != nil {
+		return "", fmt.Errorf("list shares: %w", err)
+	}
+	if name := pendingDeletionShare(live, pvc.Namespace, sourceUID); name != "" {
+		// The deletion deadline is cleared when the share is re-claimed.
+		return name, nil
+	}
+
+	deleted, err := shares.ListDeletedShares(ctx)
+	if err != nil {
+		return "", fmt.Errorf("list deleted shares: %w", err)
+	}
+	candidate := latestDeletedShare(deleted, pvc.Namespace, sourceUID)
+	if candidate == nil {
+		return "", fmt.Errorf("claim %s in namespace %q: %w", sourceUID, pvc.Namespace, ErrNoDeletedShare)
+	}
+	if err := shares.RestoreShare(ctx, candidate.Name, candidate.Version); err != nil {
+		return "", fmt.Errorf("restore share: %w", err)
+	}
+	logger.Info("restored soft-deleted share", "share", candidate.Name, "version", candidate.Version)
+	r.Recorder.Eventf(pvc, corev1.EventTypeNormal, constants.EventShareRestored, "Restored soft-deleted Azure File share %s of claim %s", candidate.Name, sourceUID)
+	return candidate.Name, nil
+}
+
+func (r *PVCReconciler) recordShareName(ctx context.Context, pvc *corev1.PersistentVolumeClaim, shareName string) error {
+	patch := client.MergeFrom(pvc.DeepCopy())
+	if pvc.Annotations == nil {
+		pvc.Annotations = map[string]string{}
+	}
+	pvc.Annotations[constants.ShareNameAnnotation] = shareName
+	if err := r.Client.Patch(ctx, pvc, patch); err != nil {
+		return fmt.Errorf("patch pvc annotations: %w", err)
+	}
+	return nil
+}
+
+// latestDeletedShare returns the most recently deleted share created for the claim UID in the namespace.
+func latestDeletedShare(deleted []azure.DeletedShare, namespace, uid string) *azure.DeletedShare {
+	var latest *azure.DeletedShare
+	for i := range deleted {
+		share := &deleted[i]
+		if share.Metadata[azure.MetadataPVCUID] != uid || share.Metadata[azure.MetadataOwnerNamespace] != namespace {
+			continue
+		}
+		if latest == nil || share.DeletedTime.After(latest.DeletedTime) {
+			latest = share
+		}
+	}
+	return latest
+}
@@ -0,0 +1,96 @@
+package controller
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/go-logr/logr"
+	corev1 "k8s.io/api/core/v1"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	"aks-azureFiles-controller/internal/azure"
+	"aks-azureFiles-controller/internal/constants"
+	"aks-azureFiles-controller/internal/k8s"
+)
+
+// Requeue delays for Azure errors that are retried on their own schedule instead of the
+// controller-runtime backoff.
+const (
+	// throttledRequeue applies when a throttled response named no Retry-After.
+	throttledRequeue = 30 * time.Second
+	// shareBeingDeletedRequeue covers the minute or so Azure needs to finish deleting a share.
+	shareBeingDeletedRequeue = 15 * time.Second
+	// authorizationRequeue re-checks after a role assignment may have been fixed or propagated.
+	authorizationRequeue = 5 * time.Minute
+)
+
+// azureRetryDelay returns the requeue delay for throttling, a share being deleted and missing
+// permissions, and false for errors that use the default backoff or are terminal.
+func azureRetryDelay(err error) (time.Duration, bool) {
+	var throttled *azure.ThrottledError
+	switch {
+	case errors.As(err, &throttled):
+		if throttled.RetryAfter > 0 {
+			return throttled.RetryAfter, true
+		}
+		return throttledRequeue, true
+	case errors.Is(err, azure.ErrShareBeingDeleted):
+		return shareBeingDeletedRequeue, true
+	case errors.Is(err, azure.ErrAuthorization):
+		return authorizationRequeue, true
+	}
+	return 0, false
+}
+
+// azureErrorReason is the event and condition reason for a classified Azure error.
+func azureErrorReason(err error) string {
+	switch {
+	case errors.Is(err, azure.ErrThrottled):
+		return constants.EventAzureThrottled
+	case errors.Is(err, azure.ErrShareBeingDeleted):
+		return constants.EventShareBeingDeleted
+	case errors.Is(err, azure.ErrAuthorization):
+		return constants.EventAzureUnauthorized
+	case errors.Is(err, azure.ErrQuotaExceeded):
+		return constants.EventShareQuotaExceeded
+	case errors.Is(err, azure.ErrInvalidShareInput), errors.Is(err, ErrInvalidPVCRequest):
+		return constants.EventShareValidation
+	}
+	return constants.EventShareError
+}
+
+// handleShareError turns a failed share operation during provisioning into a result:
+//   - invalid input and exceeded quotas are terminal;
+//   - missing permissions are reported like terminal errors but re-checked after authorizationRequeue;
+//   - throttling and shares being deleted are requeued after their delay;
+//   - anything else is returned for the default backoff.
+func (r *PVCReconciler) handleShareError(ctx context.Context, logger logr.Logger, pvc *corev1.PersistentVolumeClaim, err error, outcome *string) (reconcile.Result, error) {
+	reason := azureErrorReason(err)
+	switch reason {
+	case constants.EventShareValidation, constants.EventShareQuotaExceeded:
+		*outcome = "terminal"
+		return r.terminalError(ctx, logger, pvc, reason, err)
+	case constants.EventAzureUnauthorized:
+		*outcome = "terminal"
+		_, condErr := r.terminalError(ctx, logger, pvc, reason, err)
+		return reconcile.Result{RequeueAfter: authorizationRequeue}, condErr
+	}
+
+	delay, ok := azureRetryDelay(err)
+	if !ok {
+		r.Recorder.Event(pvc, corev1.EventTypeWarning, reason, err.Error())
+		return reconcile.Result{}, r.shareNotReady(ctx, pvc, reason, err)
+	}
+	if reason == constants.EventShareBeingDeleted {
+		r.Recorder.Eventf(pvc, corev1.EventTypeWarning, reason,
+			"Azure File share is still being deleted, likely by a previous claim with the same name; retrying in %s. "+
+				"StorageClasses with %s=%s give recreated claims a new share", delay, k8s.ParamShareNameSuffix, k8s.ShareNameSuffixUID)
+	} else {
+		r.Recorder.Eventf(pvc, corev1.EventTypeWarning, reason, "%v; retrying in %s", err, delay)
+	}
+	*outcome = "wait"
+	logger.Info("azure share operation deferred", "reason", reason, "retryAfter", delay)
+	notReady := claimCondition(constants.ConditionShareReady, corev1.ConditionFalse, reason, err.Error())
+	return reconcile.Result{RequeueAfter: delay}, r.setConditions(ctx, pvc, notReady)
+}
@@ -0,0 +1,161 @@
+package controller
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"aks-azureFiles-controller/internal/azure"
+	"aks-azureFiles-controller/internal/constants"
+	"aks-azureFiles-controller/internal/k8s"
+)
+
+// secretGCGrace keeps freshly created key Secrets alive until the PV referencing them exists.
+const secretGCGrace = 10 * time.Minute
+
+// NodeSecretConfig enables account key Secrets referenced by the PVs' NodeStageSecretRef.
+type NodeSecretConfig struct {
+	Keys azure.AccountManager
+	// Namespace holds the Secrets, normally the controller's own so claim owners cannot read account keys.
+	Namespace string
+}
+
+// ensureKeySecret makes sure the key Secret for the claim's account exists and returns a reference to it.
+// Existing Secrets are left alone here; KeySecretRotator keeps their keys current.
+func (r *PVCReconciler) ensureKeySecret(ctx context.Context, location shareLocation) (*corev1.SecretReference, error) {
+	namespace := r.NodeSecrets.Namespace
+	if namespace == "" {
+		return nil, fmt.Errorf("node secrets enabled without a namespace for them")
+	}
+	ref := &corev1.SecretReference{Namespace: namespace, Name: k8s.KeySecretName(location.StorageAccount)}
+
+	existing := &corev1.Secret{}
+	err := r.Client.Get(ctx, client.ObjectKey{Namespace: ref.Namespace, Name: ref.Name}, existing)
+	if err == nil {
+		return ref, nil
+	}
+	if !apierrors.IsNotFound(err) {
+		return nil, fmt.Errorf("get secret: %w", err)
+	}
+
+	key, err := r.NodeSecrets.Keys.GetAccountKey(ctx, location.ResourceGroup, location.StorageAccount)
+	if err != nil {
+		return nil, fmt.Errorf("get account key: %w", err)
+	}
+	secret := k8s.BuildKeySecret(namespace, location.ResourceGroup, location.StorageAccount, key)
+	if err := r.Client.Create(ctx, secret); err != nil && !apierrors.IsAlreadyExists(err) {
+		return nil, fmt.Errorf("create secret: %w", err)
+	}
+	return ref, nil
+}
+
+// KeySecretRotator periodically refreshes managed key Secrets after account key rotation
+// and deletes the ones no PV references anymore.
+type KeySecretRotator struct {
+	Client   client.Client
+	Keys     azure.AccountManager
+	Interval time.Duration
+}
+
+// Start runs Sync every Interval until the context is cancelled.
+func (r *KeySecretRotator) Start(ctx context.Context) error {
+	logger := ctrl.LoggerFrom(ctx).WithName("key-secret-rotator")
+	ticker := time.NewTicker(r.Interval)
+	defer ticker.Stop()
+
+	for {
+		if err := r.Sync(ctx); err != nil {
+			logger.Error(err, "sync key secrets")
+		}
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+		}
+	}
+}
+
+// NeedLeaderElection keeps rotation on the leader only.
+func (r *KeySecretRotator) NeedLeaderElection() bool {
+	return true
+}
+
+// Sync rotates and garbage-collects all managed key Secrets once.
+func (r *KeySecretRotator) Sync(ctx context.Context) error {
+	referenced, err := r.referencedSecrets(ctx)
+	if err != nil {
+		return err
+	}
+
+	secrets := &corev1.SecretList{}
+	if err := r.Client.List(ctx, secrets, client.HasLabels{k8s.LabelStorageAccount}); err != nil {
+		return fmt.Errorf("list secrets: %w", err)
+	}
+
+	keys := map[string]string{}
+	var errs []error
+	for i := range secrets.Items {
+		secret := &secrets.Items[i]
+		if !referenced[client.ObjectKeyFromObject(secret)] {
+			if time.Since(secret.CreationTimestamp.Time) < secretGCGrace {
+				continue
+			}
+			if err := r.Client.Delete(ctx, secret); err != nil && !apierrors.IsNotFound(err) {
+				errs = append(errs, fmt.Errorf("delete secret %s/%s: %w", secret.Namespace, secret.Name, err))
+			}
+			continue
+		}
+
+		account := secret.Labels[k8s.LabelStorageAccount]
+		key, ok := keys[account]
+		if !ok {
+			key, err = r.Keys.GetAccountKey(ctx, secret.Annotations[k8s.AnnotationResourceGroup], account)
+			if err != nil {
+				errs = append(errs, fmt.Errorf("get key of account %q: %w", account, err))
+				continue
+			}
+			keys[account] = key
+		}
+		if bytes.Equal(secret.Data[k8s.SecretAccountKeyKey], []byte(key)) {
+			continue
+		}
+
+		patch := client.MergeFrom(secret.DeepCopy())
+		if secret.Data == nil {
+			secret.Data = map[string][]byte{}
+		}
+		secret.Data[k8s.SecretAccountNameKey] = []byte(account)
+		secret.Data[k8s.SecretAccountKeyKey] = []byte(key)
+		if err := r.Client.Patch(ctx, secret, patch); err != nil {
+			errs = append(errs, fmt.Errorf("update secret %s/%s: %w", secret.Namespace, secret.Name, err))
+		}
+	}
+	if len(errs) > 0 {
+		return fmt.Errorf("sync key secrets: %w", errors.Join(errs...))
+	}
+	return nil
+}
+
+// referencedSecrets returns the Secrets used as NodeStageSecretRef by Azure File PVs.
+func (r *KeySecretRotator) referencedSecrets(ctx context.Context) (map[client.ObjectKey]bool, error) {
+	pvList := &corev1.PersistentVolumeList{}
+	if err := r.Client.List(ctx, pvList); err != nil {
+		return nil, fmt.Errorf("list pvs: %w", err)
+	}
+	referenced := map[client.ObjectKey]bool{}
+	for _, pv := range pvList.Items {
+		csi := pv.Spec.CSI
+		if csi == nil || csi.Driver != constants.AzureFileCSIDriver || csi.NodeStageSecretRef == nil {
+			continue
+		}
+		referenced[client.ObjectKey{Namespace: csi.NodeStageSecretRef.Namespace, Name: csi.NodeStageSecretRef.Name}] = true
+	}
+	return referenced, nil
+}
@@ -0,0 +1,247 @@
+package controller
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"time"
+
+	"github.com/go-logr/logr"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/tools/record"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/builder"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/manager"
+	"sigs.k8s.io/controller-runtime/pkg/predicate"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	"aks-azureFiles-controller/internal/azure"
+	"aks-azureFiles-controller/internal/constants"
+	"aks-azureFiles-controller/internal/k8s"
+	"aks-azureFiles-controller/internal/snapshotv1"
+)
+
+// VolumeSnapshotContentReconciler backs VolumeSnapshotContents with Azure share snapshots, doing the job
+// of a CSI snapshotter sidecar for the provisioner. The snapshot controller creates the contents for
+// VolumeSnapshots whose VolumeSnapshotClass names k8s.ManagedProvisioner as its driver.
+type VolumeSnapshotContentReconciler struct {
+	Client   client.Client
+	Recorder record.EventRecorder
+	Accounts *azure.Registry
+}
+
+// Reconcile is idempotent and safe to retry.
+// Flow:
+// 1. Skip contents of other drivers.
+// 2. On deletion, delete the Azure snapshot if the deletionPolicy is Delete, then release the finalizer.
+// 3. Ensure the finalizer exists while the deletionPolicy is Delete.
+// 4. Take a snapshot of the source volume's share (or look up the pre-provisioned snapshot) and report
+// snapshotHandle, creationTime, restoreSize and readyToUse in the status.
+func (r *VolumeSnapshotContentReconciler) Reconcile(ctx context.Context, req reconcile.Request) (reconcile.Result, error) {
+	logger := log.FromContext(ctx).WithValues("volumeSnapshotContent", req.Name)
+
+	content := &snapshotv1.VolumeSnapshotContent{}
+	if err := r.Client.Get(ctx, req.NamespacedName, content); err != nil {
+		if apierrors.IsNotFound(err) {
+			return reconcile.Result{}, nil
+		}
+		return reconcile.Result{}, fmt.Errorf("get volumesnapshotcontent: %w", err)
+	}
+
+	// 1. Check driver
+	if content.Spec.Driver != k8s.ManagedProvisioner {
+		return reconcile.Result{}, nil
+	}
+
+	// 2. Deletion
+	if content.DeletionTimestamp != nil {
+		return r.handleContentDeletion(ctx, logger, content)
+	}
+
+	// 3. Finalizer
+	if content.Spec.DeletionPolicy == snapshotv1.VolumeSnapshotContentDelete && !slices.Contains(content.Finalizers, constants.SnapshotContentFinalizer) {
+		patch := client.MergeFrom(content.DeepCopy())
+		content.Finalizers = append(content.Finalizers, constants.SnapshotContentFinalizer)
+		if err := r.Client.Patch(ctx, content, patch); err != nil {
+			return reconcile.Result{}, fmt.Errorf("patch volumesnapshotcontent finalizer: %w", err)
+		}
+	}
+
+	// 4. Snapshot
+	if content.Status != nil && content.Status.ReadyToUse != nil && *content.Status.ReadyToUse {
+		return reconcile.Result{}, nil
+	}
+	status, err := r.snapshotStatus(ctx, logger, content)
+	if err != nil {
+		return r.snapshotError(ctx, logger, content, err)
+	}
+	patch := client.MergeFrom(content.DeepCopy())
+	content.Status = status
+	if err := r.Client.Status().Patch(ctx, content, patch); err != nil {
+		return reconcile.Result{}, fmt.Errorf("patch volumesnapshotcontent status: %w", err)
+	}
+	return reconcile.Result{}, nil
+}
+
+// SetupWithManager wires the controller into the manager.
+func (r *VolumeSnapshotContentReconciler) SetupWithManager(mgr manager.Manager) error {
+	return ctrl.NewControllerManagedBy(mgr).
+		For(&snapshotv1.VolumeSnapshotContent{}, builder.WithPredicates(predicate.NewPredicateFuncs(func(obj client.Object) bool {
+			content, ok := obj.(*snapshotv1.VolumeSnapshotContent)
+			return ok && content.Spec.Driver == k8s.ManagedProvisioner
+		}))).
+		Complete(r)
+}
+
+// snapshotStatus snapshots the share of a dynamically provisioned content, or checks that the share of a
+// pre-provisioned snapshot handle exists. The restore size is the share's quota. The ID of a new snapshot is
+// recorded in the content's ContentSnapshotAnnotation before the status is written, so a retry after a failed
+// status write reuses it instead of taking another snapshot.
+func (r *VolumeSnapshotContentReconciler) snapshotStatus(ctx context.Context, logger logr.Logger, content *snapshotv1.VolumeSnapshotContent) (*snapshotv1.VolumeSnapshotContentStatus, error) {
+	var resourceGroup, account, shareName, snapshot string
+	var err error
+	source := content.Spec.Source
+	switch {
+	case source.SnapshotHandle != nil:
+		resourceGroup, account, shareName, snapshot, err = k8s.ParseSnapshotHandle(*source.SnapshotHandle)
+	case source.VolumeHandle != nil:
+		resourceGroup, account, shareName, err = k8s.ParseVolumeHandle(*source.VolumeHandle)
+	default:
+		err = errors.New("source names neither a volume nor a snapshot handle")
+	}
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", azure.ErrInvalidShareInput, err)
+	}
+
+	shares, err := r.Accounts.ForAccount(resourceGroup, account)
+	if err != nil {
+		return nil, fmt.Errorf("resolve share client: %w", err)
+	}
+	props, err := shares.GetShare(ctx, shareName)
+	if err != nil {
+		return nil, err
+	}
+	if snapshot == "" {
+		snapshot = content.Annotations[constants.ContentSnapshotAnnotation]
+	}
+	if snapshot == "" {
+		snapshot, err = shares.CreateSnapshot(ctx, shareName)
+		if err != nil {
+			return nil, err
+		}
+		patch := client.MergeFrom(content.DeepCopy())
+		if content.Annotations == nil {
+			content.Annotations = map[string]string{}
+		}
+		content.Annotations[constants.ContentSnapshotAnnotation] = snapshot
+		if err := r.Client.Patch(ctx, content, patch); err != nil {
+			// Without the record a retry would take another snapshot, so this one would be orphaned.
+			if deleteErr := shares.DeleteSnapshot(ctx, shareName, snapshot); deleteErr != nil {
+				logger.Error(deleteErr, "delete unrecorded snapshot", "share", shareName, "snapshot", snapshot)
+			}
+			return nil, fmt.Errorf("patch volumesnapshotcontent annotations: %w", err)
+		}
+		logger.Info("share snapshot created", "share", shareName, "snapshot", snapshot)
+		r.Recorder.Eventf(content, corev1.EventTypeNormal, constants.EventSnapshotCreated, "Created snapshot %s of Azure File share %s", snapshot, shareName)
+	}
+
+	handle := k8s.SnapshotHandle(resourceGroup, account, shareName, snapshot)
+	created := time.Now()
+	if taken, err := time.Parse(time.RFC3339Nano, snapshot); err == nil {
+		created = taken
+	}
+	restoreSize := int64(props.QuotaGiB) << 30
+	ready := true
+	return &snapshotv1.VolumeSnapshotContentStatus{
+		SnapshotHandle: &handle,
+		CreationTime:   ptrTo(created.UnixNano()),
+		RestoreSize:    &restoreSize,
+		ReadyToUse:     &ready,
+	}, nil
+}
+
+// snapshotError records a failed snapshot in the content's status and an event. Invalid handles and
+// missing shares are not retried; Azure errors follow azureRetryDelay.
+func (r *VolumeSnapshotContentReconciler) snapshotError(ctx context.Context, logger logr.Logger, content *snapshotv1.VolumeSnapshotContent, err error) (reconcile.Result, error) {
+	logger.Error(err, "snapshot failed", "reason", azureErrorReason(err))
+	r.Recorder.Eventf(content, corev1.EventTypeWarning, constants.EventSnapshotFailed, "Failed to snapshot Azure File share: %v", err)
+
+	patch := client.MergeFrom(content.DeepCopy())
+	if content.Status == nil {
+		content.Status = &snapshotv1.VolumeSnapshotContentStatus{}
+	}
+	message := err.Error()
+	content.Status.Error = &snapshotv1.VolumeSnapshotError{Time: ptrTo(metav1.Now()), Message: &message}
+	content.Status.ReadyToUse = ptrTo(false)
+	if patchErr := r.Client.Status().Patch(ctx, content, patch); patchErr != nil {
+		return reconcile.Result{}, fmt.Errorf("patch volumesnapshotcontent status: %w", patchErr)
+	}
+
+	switch {
+	case errors.Is(err, azure.ErrInvalidShareInput), errors.Is(err, azure.ErrShareNotFound):
+		return reconcile.Result{}, nil
+	}
+	if delay, ok := azureRetryDelay(err); ok {
+		return reconcile.Result{RequeueAfter: delay}, nil
+	}
+	return reconcile.Result{}, fmt.Errorf("snapshot share: %w", err)
+}
+
+// handleContentDeletion deletes the Azure snapshot of a content with deletionPolicy Delete and releases
+// the finalizer. Retained contents and contents that never got a snapshot only release the finalizer.
+// A snapshot recorded in the annotation but not yet in the status is deleted as well.
+func (r *VolumeSnapshotContentReconciler) handleContentDeletion(ctx context.Context, logger logr.Logger, content *snapshotv1.VolumeSnapshotContent) (reconcile.Result, error) {
+	if !slices.Contains(content.Finalizers, constants.SnapshotContentFinalizer) {
+		return reconcile.Result{}, nil
+	}
+
+	handle := ""
+	if content.Status != nil && content.Status.SnapshotHandle != nil {
+		handle = *content.Status.SnapshotHandle
+	} else if content.Spec.Source.SnapshotHandle != nil {
+		handle = *content.Spec.Source.SnapshotHandle
+	} else if snapshot := content.Annotations[constants.ContentSnapshotAnnotation]; snapshot != "" && content.Spec.Source.VolumeHandle != nil {
+		if resourceGroup, account, shareName, err := k8s.ParseVolumeHandle(*content.Spec.Source.VolumeHandle); err == nil {
+			handle = k8s.SnapshotHandle(resourceGroup, account, shareName, snapshot)
+		}
+	}
+	if content.Spec.DeletionPolicy == snapshotv1.VolumeSnapshotContentDelete && handle != "" {
+		resourceGroup, account, shareName, snapshot, err := k8s.ParseSnapshotHandle(handle)
+		if err != nil {
+			logger.Info("snapshot handle malformed; nothing to delete", "reason", err.Error())
+		} else if err := r.deleteSnapshot(ctx, resourceGroup, account, shareName, snapshot); err != nil {
+			r.Recorder.Eventf(content, corev1.EventTypeWarning, constants.EventSnapshotFailed, "Failed to delete Azure File share snapshot: %v", err)
+			if delay, ok := azureRetryDelay(err); ok {
+				return reconcile.Result{RequeueAfter: delay}, nil
+			}
+			return reconcile.Result{}, fmt.Errorf("delete snapshot: %w", err)
+		} else {
+			logger.Info("share snapshot deleted", "share", shareName, "snapshot", snapshot)
+			r.Recorder.Eventf(content, corev1.EventTypeNormal, constants.EventSnapshotDeleted, "Deleted snapshot %s of Azure File share %s", snapshot, shareName)
+		}
+	}
+
+	patch := client.MergeFrom(content.DeepCopy())
+	content.Finalizers = slices.DeleteFunc(content.Finalizers, func(f string) bool { return f == constants.SnapshotContentFinalizer })
+	if err := r.Client.Patch(ctx, content, patch); err != nil {
+		return reconcile.Result{}, fmt.Errorf("remove volumesnapshotcontent finalizer: %w", err)
+	}
+	return reconcile.Result{}, nil
+}
+
+func (r *VolumeSnapshotContentReconciler) deleteSnapshot(ctx context.Context, resourceGroup, account, shareName, snapshot string) error {
+	shares, err := r.Accounts.ForAccount(resourceGroup, account)
+	if err != nil {
+		return fmt.Errorf("resolve share client: %w", err)
+	}
+	return shares.DeleteSnapshot(ctx, shareName, snapshot)
+}
+
+func ptrTo[T any](value T) *T {
+	return &value
+}
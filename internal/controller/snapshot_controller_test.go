@@ -0,0 +1,202 @@
+package controller
+
+import (
+	"context"
+	"testing"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/tools/record"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/client/interceptor"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	"aks-azureFiles-controller/internal/azure"
+	"aks-azureFiles-controller/internal/constants"
+	"aks-azureFiles-controller/internal/k8s"
+	"aks-azureFiles-controller/internal/logging"
+	"aks-azureFiles-controller/internal/snapshotv1"
+)
+
+func snapshotContent(name, driver string, policy snapshotv1.DeletionPolicy) *snapshotv1.VolumeSnapshotContent {
+	return &snapshotv1.VolumeSnapshotContent{
+		ObjectMeta: metav1.ObjectMeta{Name: name},
+		Spec: snapshotv1.VolumeSnapshotContentSpec{
+			DeletionPolicy: policy,
+			Driver:         driver,
+			Source:         snapshotv1.VolumeSnapshotContentSource{VolumeHandle: stringPtr(k8s.VolumeHandle("rg", "account", "team-data"))},
+		},
+	}
+}
+
+func TestReconcileVolumeSnapshotContent(t *testing.T) {
+	tests := []struct {
+		name          string
+		driver        string
+		policy        snapshotv1.DeletionPolicy
+		wantReady     bool
+		wantSnapshots int
+	}{
+		{name: "delete policy", driver: k8s.ManagedProvisioner, policy: snapshotv1.VolumeSnapshotContentDelete, wantReady: true},
+		{name: "retain policy", driver: k8s.ManagedProvisioner, policy: snapshotv1.VolumeSnapshotContentRetain, wantReady: true, wantSnapshots: 1},
+		{name: "other driver", driver: "file.csi.azure.com", policy: snapshotv1.VolumeSnapshotContentDelete},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			scheme := runtime.NewScheme()
+			if err := snapshotv1.AddToScheme(scheme); err != nil {
+				t.Fatalf("AddToScheme snapshotv1: %v", err)
+			}
+
+			ctx := ctrl.LoggerInto(context.Background(), logging.NewLogger())
+			shareClient := &azure.FakeShareClient{}
+			if err := shareClient.EnsureShare(ctx, "team-data", 5, nil); err != nil {
+				t.Fatalf("EnsureShare error = %v", err)
+			}
+			accounts := azure.NewRegistry(nil)
+			accounts.Register("account", shareClient)
+
+			content := snapshotContent("snapcontent-1", tt.driver, tt.policy)
+			k8sClient := fake.NewClientBuilder().WithScheme(scheme).WithObjects(content).WithStatusSubresource(content).Build()
+			recorder := record.NewFakeRecorder(20)
+			reconciler := &VolumeSnapshotContentReconciler{Client: k8sClient, Recorder: recorder, Accounts: accounts}
+
+			key := client.ObjectKeyFromObject(content)
+			for range 2 {
+				if _, err := reconciler.Reconcile(ctx, reconcile.Request{NamespacedName: key}); err != nil {
+					t.Fatalf("Reconcile error = %v", err)
+				}
+			}
+
+			got := &snapshotv1.VolumeSnapshotContent{}
+			if err := k8sClient.Get(ctx, key, got); err != nil {
+				t.Fatalf("Get content error = %v", err)
+			}
+			if !tt.wantReady {
+				if got.Status != nil || len(shareClient.Snapshots["team-data"]) != 0 {
+					t.Fatalf("status = %+v, snapshots = %v, want the content ignored", got.Status, shareClient.Snapshots["team-data"])
+				}
+				return
+			}
+			if got.Status == nil || got.Status.ReadyToUse == nil || !*got.Status.ReadyToUse {
+				t.Fatalf("status = %+v, want readyToUse", got.Status)
+			}
+			if countEvents(recorder, constants.EventSnapshotCreated) != 1 {
+				t.Fatalf("event %q not recorded once", constants.EventSnapshotCreated)
+			}
+			snapshots := shareClient.Snapshots["team-data"]
+			if len(snapshots) != 1 {
+				t.Fatalf("snapshots = %v, want one", snapshots)
+			}
+			wantHandle := k8s.SnapshotHandle("rg", "account", "team-data", snapshots[0])
+			if got.Status.SnapshotHandle == nil || *got.Status.SnapshotHandle != wantHandle {
+				t.Fatalf("snapshotHandle = %v, want %q", got.Status.SnapshotHandle, wantHandle)
+			}
+			if got.Status.RestoreSize == nil || *got.Status.RestoreSize != 5<<30 {
+				t.Fatalf("restoreSize = %v, want %d", got.Status.RestoreSize, int64(5<<30))
+			}
+			if got.Status.CreationTime == nil {
+				t.Fatalf("creationTime not set")
+			}
+
+			if err := k8sClient.Delete(ctx, got); err != nil {
+				t.Fatalf("Delete content error = %v", err)
+			}
+			if _, err := reconciler.Reconcile(ctx, reconcile.Request{NamespacedName: key}); err != nil {
+				t.Fatalf("Reconcile deletion error = %v", err)
+			}
+			if err := k8sClient.Get(ctx, key, &snapshotv1.VolumeSnapshotContent{}); !apierrors.IsNotFound(err) {
+				t.Fatalf("Get content error = %v, want not found after deletion", err)
+			}
+			if n := len(shareClient.Snapshots["team-data"]); n != tt.wantSnapshots {
+				t.Fatalf("snapshots left = %d, want %d", n, tt.wantSnapshots)
+			}
+		})
+	}
+}
+
+func TestReconcileVolumeSnapshotContentFailure(t *testing.T) {
+	scheme := runtime.NewScheme()
+	if err := snapshotv1.AddToScheme(scheme); err != nil {
+		t.Fatalf("AddToScheme snapshotv1: %v", err)
+	}
+
+	accounts := azure.NewRegistry(nil)
+	accounts.Register("account", &azure.FakeShareClient{})
+	content := snapshotContent("snapcontent-1", k8s.ManagedProvisioner, snapshotv1.VolumeSnapshotContentDelete)
+	k8sClient := fake.NewClientBuilder().WithScheme(scheme).WithObjects(content).WithStatusSubresource(content).Build()
+	recorder := record.NewFakeRecorder(20)
+	reconciler := &VolumeSnapshotContentReconciler{Client: k8sClient, Recorder: recorder, Accounts: accounts}
+
+	ctx := ctrl.LoggerInto(context.Background(), logging.NewLogger())
+	key := client.ObjectKeyFromObject(content)
+	if _, err := reconciler.Reconcile(ctx, reconcile.Request{NamespacedName: key}); err != nil {
+		t.Fatalf("Reconcile error = %v, want a missing share not retried", err)
+	}
+	if !hasEvent(recorder, constants.EventSnapshotFailed) {
+		t.Fatalf("event %q not recorded", constants.EventSnapshotFailed)
+	}
+	got := &snapshotv1.VolumeSnapshotContent{}
+	if err := k8sClient.Get(ctx, key, got); err != nil {
+		t.Fatalf("Get content error = %v", err)
+	}
+	if got.Status == nil || got.Status.Error == nil || got.Status.Error.Message == nil {
+		t.Fatalf("status = %+v, want an error", got.Status)
+	}
+}
+
+func TestReconcileVolumeSnapshotContentStatusRetry(t *testing.T) {
+	scheme := runtime.NewScheme()
+	if err := snapshotv1.AddToScheme(scheme); err != nil {
+		t.Fatalf("AddToScheme snapshotv1: %v", err)
+	}
+
+	ctx := ctrl.LoggerInto(context.Background(), logging.NewLogger())
+	shareClient := &azure.FakeShareClient{}
+	if err := shareClient.EnsureShare(ctx, "team-data", 5, nil); err != nil {
+		t.Fatalf("EnsureShare error = %v", err)
+	}
+	accounts := azure.NewRegistry(nil)
+	accounts.Register("account", shareClient)
+
+	content := snapshotContent("snapcontent-1", k8s.ManagedProvisioner, snapshotv1.VolumeSnapshotContentDelete)
+	failed := false
+	k8sClient := fake.NewClientBuilder().WithScheme(scheme).WithObjects(content).WithStatusSubresource(content).
+		WithInterceptorFuncs(interceptor.Funcs{
+			SubResourcePatch: func(ctx context.Context, c client.Client, subResourceName string, obj client.Object, patch client.Patch, opts ...client.SubResourcePatchOption) error {
+				if !failed {
+					failed = true
+					return apierrors.NewServiceUnavailable("status write failed")
+				}
+				return c.SubResource(subResourceName).Patch(ctx, obj, patch, opts...)
+			},
+		}).Build()
+	reconciler := &VolumeSnapshotContentReconciler{Client: k8sClient, Recorder: record.NewFakeRecorder(20), Accounts: accounts}
+
+	key := client.ObjectKeyFromObject(content)
+	if _, err := reconciler.Reconcile(ctx, reconcile.Request{NamespacedName: key}); err == nil {
+		t.Fatalf("Reconcile error = nil, want the failed status write")
+	}
+	if _, err := reconciler.Reconcile(ctx, reconcile.Request{NamespacedName: key}); err != nil {
+		t.Fatalf("Reconcile error = %v", err)
+	}
+
+	snapshots := shareClient.Snapshots["team-data"]
+	if len(snapshots) != 1 {
+		t.Fatalf("snapshots = %v, want one across the retry", snapshots)
+	}
+	got := &snapshotv1.VolumeSnapshotContent{}
+	if err := k8sClient.Get(ctx, key, got); err != nil {
+		t.Fatalf("Get content error = %v", err)
+	}
+	if got.Annotations[constants.ContentSnapshotAnnotation] != snapshots[0] {
+		t.Fatalf("annotations = %v, want %s recorded", got.Annotations, snapshots[0])
+	}
+	if want := k8s.SnapshotHandle("rg", "account", "team-data", snapshots[0]); got.Status == nil || got.Status.SnapshotHandle == nil || *got.Status.SnapshotHandle != want {
+		t.Fatalf("status = %+v, want snapshotHandle %q", got.Status, want)
+	}
+}
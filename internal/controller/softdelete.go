@@ -0,0 +1,61 @@
+package controller
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	ctrl "sigs.k8s.io/controller-runtime"
+
+	"aks-azureFiles-controller/internal/azure"
+)
+
+// SoftDeleteReporter periodically publishes the soft-deleted shares the storage accounts still retain,
+// so teams can find the claim UID to restore from without access to the Azure portal.
+type SoftDeleteReporter struct {
+	// Shares holds the client of every scanned account, keyed by account name.
+	Shares   map[string]azure.ShareClient
+	Metrics  *ReconcileMetrics
+	Interval time.Duration
+}
+
+// Start runs Sync every Interval until the context is cancelled.
+func (r *SoftDeleteReporter) Start(ctx context.Context) error {
+	logger := ctrl.LoggerFrom(ctx).WithName("soft-delete-reporter")
+	ticker := time.NewTicker(r.Interval)
+	defer ticker.Stop()
+
+	for {
+		if err := r.Sync(ctx); err != nil {
+			logger.Error(err, "report soft-deleted shares")
+		}
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+		}
+	}
+}
+
+// NeedLeaderElection keeps scanning on the leader only.
+func (r *SoftDeleteReporter) NeedLeaderElection() bool {
+	return true
+}
+
+// Sync lists the soft-deleted shares of every account once. Accounts that cannot be listed keep their last report.
+func (r *SoftDeleteReporter) Sync(ctx context.Context) error {
+	var errs []error
+	for account, shares := range r.Shares {
+		deleted, err := shares.ListDeletedShares(ctx)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("list deleted shares of account %q: %w", account, err))
+			continue
+		}
+		r.Metrics.ObserveSoftDeletedShares(account, deleted)
+	}
+	if len(errs) > 0 {
+		return fmt.Errorf("report soft-deleted shares: %w", errors.Join(errs...))
+	}
+	return nil
+}
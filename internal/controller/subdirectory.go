@@ -0,0 +1,171 @@
+package controller
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/go-logr/logr"
+	corev1 "k8s.io/api/core/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	"aks-azureFiles-controller/internal/azure"
+	"aks-azureFiles-controller/internal/constants"
+	"aks-azureFiles-controller/internal/k8s"
+	"aks-azureFiles-controller/internal/naming"
+)
+
+var ErrSubdirectoryClaimInvalid = errors.New("claim not supported by subdirectory classes")
+
+// checkSubdirectoryClaim refuses the annotations and data sources that name or fill a share of the claim's own,
+// which a claim of a subdirectory class does not get.
+func checkSubdirectoryClaim(pvc *corev1.PersistentVolumeClaim) error {
+	for _, key := range []string{constants.ShareOverrideAnnotation, constants.AdoptShareAnnotation, constants.RestoreFromAnnotation} {
+		if pvc.Annotations[key] != "" {
+			return fmt.Errorf("annotation %s cannot be used with %s classes: %w", key, k8s.ParamSubdirectoryShare, ErrSubdirectoryClaimInvalid)
+		}
+	}
+	if claimDataSource(pvc) != nil {
+		return fmt.Errorf("a data source cannot be used with %s classes: %w", k8s.ParamSubdirectoryShare, ErrSubdirectoryClaimInvalid)
+	}
+	return nil
+}
+
+// backingShareName returns the share holding the directories of the class, or of the class in the claim's
+// namespace when the class is namespace-scoped.
+func backingShareName(pvc *corev1.PersistentVolumeClaim, storageClass string, params k8s.ShareParameters) (string, error) {
+	if params.SubdirectoryShare == k8s.SubdirectoryShareNamespace {
+		return naming.ComputeBackingShareName(storageClass, pvc.Namespace)
+	}
+	return naming.ComputeBackingShareName(storageClass, "")
+}
+
+// claimDirectoryName names the claim's directory in a backing share. The claim UID is hashed into the name, since
+// namespace and name alone join alike for claims such as team-a/data and team/a-data.
+func claimDirectoryName(pvc *corev1.PersistentVolumeClaim) (string, error) {
+	return naming.ComputeDirectoryName(pvc.Namespace, pvc.Name, string(pvc.UID))
+}
+
+// ensureClaimDirectory makes sure the backing share exists and holds the claim's directory, and returns the share
+// with the quota it needs: the capacity of every other claim directory in it plus the request, raised to the class
+// floors. The backing share carries no claim UID, so it is never deleted with a claim.
+func (r *PVCReconciler) ensureClaimDirectory(ctx context.Context, logger logr.Logger, pvc *corev1.PersistentVolumeClaim, location shareLocation, shares azure.ShareClient, params k8s.ShareParameters, shareName, directory string, requestedGiB int32) (*azure.ShareProperties, int32, error) {
+	usedGiB, err := r.backingShareUsage(ctx, pvc, location, shareName)
+	if err != nil {
+		return nil, 0, err
+	}
+	quotaGiB := params.EffectiveQuotaGiB(int32(usedGiB + int64(requestedGiB)))
+
+	r.Recorder.Eventf(pvc, corev1.EventTypeNormal, constants.EventShareEnsuring, "Ensuring directory %s exists in Azure File share %s", directory, shareName)
+	logger.Info("ensuring backing share", "directory", directory, "quotaGiB", quotaGiB)
+	opts := &azure.EnsureShareOptions{
+		AccessTier:      params.AccessTier,
+		EnabledProtocol: params.Protocol,
+		Metadata:        backingShareMetadata(pvc, params, r.Config.ClusterID),
+	}
+	if err := shares.EnsureShare(ctx, shareName, quotaGiB, opts); err != nil {
+		return nil, 0, fmt.Errorf("ensure share: %w", err)
+	}
+	props, err := shares.GetShare(ctx, shareName)
+	if err != nil {
+		return nil, 0, fmt.Errorf("get share: %w", err)
+	}
+	if err := checkShareOwnership(pvc, props, params); err != nil {
+		return nil, 0, err
+	}
+	if err := shares.EnsureDirectory(ctx, shareName, directory); err != nil {
+		return nil, 0, fmt.Errorf("ensure directory: %w", err)
+	}
+	return props, quotaGiB, nil
+}
+
+// backingShareUsage sums the capacity of the PVs of other claims with a directory in the backing share.
+// Released PVs still count, as their directories keep their data.
+func (r *PVCReconciler) backingShareUsage(ctx context.Context, pvc *corev1.PersistentVolumeClaim, location shareLocation, shareName string) (int64, error) {
+	pvList := &corev1.PersistentVolumeList{}
+	if err := r.Client.List(ctx, pvList, client.MatchingLabels{k8s.LabelShareName: shareName}); err != nil {
+		return 0, fmt.Errorf("list pvs: %w", err)
+	}
+
+	var usedGiB int64
+	for _, pv := range pvList.Items {
+		if pv.Spec.CSI == nil || pv.Spec.CSI.Driver != constants.AzureFileCSIDriver {
+			continue
+		}
+		if pv.Spec.ClaimRef != nil && pv.Spec.ClaimRef.UID == pvc.UID {
+			continue
+		}
+		resourceGroup, account, _, directory, err := k8s.ParseDirectoryVolumeHandle(pv.Spec.CSI.VolumeHandle)
+		if err != nil || directory == "" || resourceGroup != location.ResourceGroup || account != location.StorageAccount {
+			continue
+		}
+		usedGiB += k8s.CeilGiB(pv.Spec.Capacity[corev1.ResourceStorage])
+	}
+	return usedGiB, nil
+}
+
+// backingShareMetadata tags the backing share as provisioned by the controller and with its class, so
+// checkShareOwnership keeps claims of other classes out, and namespace-scoped backing shares with their
+// namespace so it keeps other namespaces out as well.
+func backingShareMetadata(pvc *corev1.PersistentVolumeClaim, params k8s.ShareParameters, clusterID string) map[string]string {
+	metadata := map[string]string{
+		azure.MetadataProvisionedBy:     k8s.ManagedProvisioner,
+		azure.MetadataBackingShareClass: k8s.StorageClassName(pvc),
+	}
+	if params.SubdirectoryShare == k8s.SubdirectoryShareNamespace {
+		metadata[azure.MetadataOwnerNamespace] = pvc.Namespace
+	}
+	if clusterID != "" {
+		metadata[azure.MetadataClusterID] = clusterID
+	}
+	return metadata
+}
+
+// claimDirectory returns the backing share and the directory a claim being deleted was given in it, read from
+// its PV or, without one, derived from the claim and its subdirectory class. The user-editable annotations are
+// not consulted, so a claim cannot point the deletion at another claim's directory. directory is "" for claims
+// with a share of their own.
+func (r *PVCReconciler) claimDirectory(ctx context.Context, pvc *corev1.PersistentVolumeClaim, pv *corev1.PersistentVolume) (shareName, directory string) {
+	if pv != nil && pv.Spec.CSI != nil {
+		_, _, shareName, directory, _ := k8s.ParseDirectoryVolumeHandle(pv.Spec.CSI.VolumeHandle)
+		return shareName, directory
+	}
+	storageClass, err := k8s.GetStorageClass(ctx, r.Client, pvc)
+	if err != nil || storageClass == nil {
+		return "", ""
+	}
+	params, err := k8s.ParseShareParameters(storageClass.Parameters)
+	if err != nil || !params.UsesSubdirectories() {
+		return "", ""
+	}
+	if shareName, err = backingShareName(pvc, storageClass.Name, params); err != nil {
+		return "", ""
+	}
+	if directory, err = claimDirectoryName(pvc); err != nil {
+		return "", ""
+	}
+	return shareName, directory
+}
+
+// deleteClaimDirectory removes the claim's directory and its contents from the backing share, which is kept.
+// A zero result with a nil error means the directory is gone.
+func (r *PVCReconciler) deleteClaimDirectory(ctx context.Context, logger logr.Logger, pvc *corev1.PersistentVolumeClaim, location shareLocation, shareName, directory string) (reconcile.Result, error) {
+	shares, err := r.shareClientFor(location)
+	if err != nil {
+		r.Recorder.Event(pvc, corev1.EventTypeWarning, constants.EventShareClientMissing, "No share client for the claim's storage account")
+		return reconcile.Result{}, fmt.Errorf("resolve share client: %w", err)
+	}
+	if err := shares.DeleteDirectory(ctx, shareName, directory); err != nil && !errors.Is(err, azure.ErrShareNotFound) {
+		reason := azureErrorReason(err)
+		r.Recorder.Eventf(pvc, corev1.EventTypeWarning, reason, "Failed to delete directory %s of Azure File share %s: %v", directory, shareName, err)
+		if delay, ok := azureRetryDelay(err); ok {
+			logger.Info("directory deletion deferred", "reason", reason, "retryAfter", delay)
+			return reconcile.Result{RequeueAfter: delay}, nil
+		}
+		return reconcile.Result{}, fmt.Errorf("delete directory: %w", err)
+	}
+	logger.Info("directory deleted", "directory", directory)
+	r.Recorder.Eventf(pvc, corev1.EventTypeNormal, constants.EventDirectoryDeleted, "Directory %s deleted from Azure File share %s", directory, shareName)
+	return reconcile.Result{}, nil
+}
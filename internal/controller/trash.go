@@ -0,0 +1,214 @@
+package controller
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"maps"
+	"time"
+
+	"github.com/go-logr/logr"
+	corev1 "k8s.io/api/core/v1"
+	storagev1 "k8s.io/api/storage/v1"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"aks-azureFiles-controller/internal/azure"
+	"aks-azureFiles-controller/internal/constants"
+	"aks-azureFiles-controller/internal/k8s"
+)
+
+// scheduleShareDeletion tags a released share with its deletion deadline instead of deleting it.
+// The same provenance as for a direct deletion is required. A share that is already tagged keeps
+// its deadline; a missing share returns the zero time.
+func (r *PVCReconciler) scheduleShareDeletion(ctx context.Context, shares azure.ShareClient, shareName string, opts *azure.DeleteShareOptions) (time.Time, error) {
+	props, err := shares.GetShare(ctx, shareName)
+	if errors.Is(err, azure.ErrShareNotFound) {
+		return time.Time{}, nil
+	}
+	if err != nil {
+		return time.Time{}, err
+	}
+	if err := azure.CheckProvenance(shareName, props.Metadata, opts); err != nil {
+		return time.Time{}, err
+	}
+	if tagged, err := time.Parse(time.RFC3339, props.Metadata[azure.MetadataDeleteAfter]); err == nil {
+		return tagged, nil
+	}
+
+	deadline := time.Now().Add(r.Config.DeletionGracePeriod).UTC().Truncate(time.Second)
+	metadata := maps.Clone(props.Metadata)
+	if metadata == nil {
+		metadata = map[string]string{}
+	}
+	metadata[azure.MetadataDeleteAfter] = deadline.Format(time.RFC3339)
+	if err := shares.SetShareMetadata(ctx, shareName, metadata); err != nil {
+		return time.Time{}, err
+	}
+	return deadline, nil
+}
+
+// reclaimShare clears the deletion deadline of a share a claim attaches to again within the grace period.
+// A share of the claim's own namespace is handed over to the claim, so it is deleted with it later, unless
+// the claim adopted it without taking over ownership.
+func (r *PVCReconciler) reclaimShare(ctx context.Context, logger logr.Logger, pvc *corev1.PersistentVolumeClaim, shares azure.ShareClient, props *azure.ShareProperties, params k8s.ShareParameters) error {
+	deadline, ok := props.Metadata[azure.MetadataDeleteAfter]
+	if !ok {
+		return nil
+	}
+	metadata := maps.Clone(props.Metadata)
+	delete(metadata, azure.MetadataDeleteAfter)
+	if metadata[azure.MetadataOwnerNamespace] == pvc.Namespace && (!adoptsShare(pvc) || transfersOwnership(pvc, params)) {
+		maps.Copy(metadata, shareMetadata(pvc, r.Config.ClusterID))
+	}
+	if err := shares.SetShareMetadata(ctx, props.Name, metadata); err != nil {
+		return err
+	}
+	props.Metadata = metadata
+	logger.Info("re-claimed share pending deletion", "deleteAfter", deadline)
+	r.Recorder.Eventf(pvc, corev1.EventTypeNormal, constants.EventShareReclaimed, "Azure File share %s was pending deletion after %s and has been re-claimed", props.Name, deadline)
+	return nil
+}
+
+// pendingDeletionShare returns the name of the share created for the claim UID in the namespace that
+// is waiting for its deletion deadline, or "" when there is none.
+func pendingDeletionShare(shares []azure.ShareProperties, namespace, uid string) string {
+	for _, share := range shares {
+		if share.Metadata[azure.MetadataDeleteAfter] != "" &&
+			share.Metadata[azure.MetadataPVCUID] == uid &&
+			share.Metadata[azure.MetadataOwnerNamespace] == namespace {
+			return share.Name
+		}
+	}
+	return ""
+}
+
+// ShareReaper deletes shares whose deletion deadline has passed. It scans the default and pool accounts
+// and every account a managed StorageClass names.
+type ShareReaper struct {
+	Client client.Client
+	// Shares holds the clients of the default and pool accounts, keyed by account name.
+	Shares   map[string]azure.ShareClient
+	Accounts *azure.Registry
+	// ResourceGroup is used for StorageClass accounts without a resourceGroup parameter.
+	ResourceGroup string
+	// ClusterID, when set, limits the reaper to shares created by this cluster.
+	ClusterID string
+	Interval  time.Duration
+}
+
+// Start runs Sync every Interval until the context is cancelled.
+func (r *ShareReaper) Start(ctx context.Context) error {
+	logger := ctrl.LoggerFrom(ctx).WithName("share-reaper")
+	ticker := time.NewTicker(r.Interval)
+	defer ticker.Stop()
+
+	for {
+		if err := r.Sync(ctx); err != nil {
+			logger.Error(err, "delete expired shares")
+		}
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+		}
+	}
+}
+
+// NeedLeaderElection keeps deletion on the leader only.
+func (r *ShareReaper) NeedLeaderElection() bool {
+	return true
+}
+
+// Sync deletes every expired share once. The deadline is part of the required provenance, so a share
+// re-claimed after it was listed is left alone.
+func (r *ShareReaper) Sync(ctx context.Context) error {
+	logger := ctrl.LoggerFrom(ctx).WithName("share-reaper")
+	clients, errs := r.accountClients(ctx)
+	now := time.Now()
+	for account, shares := range clients {
+		listed, err := shares.ListShares(ctx)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("list shares of account %q: %w", account, err))
+			continue
+		}
+		for _, share := range listed {
+			deadline := share.Metadata[azure.MetadataDeleteAfter]
+			if deadline == "" {
+				continue
+			}
+			due, err := time.Parse(time.RFC3339, deadline)
+			if err != nil {
+				errs = append(errs, fmt.Errorf("share %q of account %q: parse %s: %w", share.Name, account, azure.MetadataDeleteAfter, err))
+				continue
+			}
+			if now.Before(due) {
+				continue
+			}
+
+			required := map[string]string{
+				azure.MetadataProvisionedBy: k8s.ManagedProvisioner,
+				azure.MetadataDeleteAfter:   deadline,
+			}
+			if r.ClusterID != "" {
+				required[azure.MetadataClusterID] = r.ClusterID
+			}
+			err = shares.DeleteShare(ctx, share.Name, &azure.DeleteShareOptions{RequireMetadata: required})
+			switch {
+			case errors.Is(err, azure.ErrShareProvenanceMismatch), errors.Is(err, azure.ErrShareBeingDeleted):
+				continue
+			case err != nil:
+				errs = append(errs, fmt.Errorf("delete share %q of account %q: %w", share.Name, account, err))
+			default:
+				logger.Info("deleted expired share", "storageAccount", account, "share", share.Name, "deleteAfter", deadline)
+			}
+		}
+	}
+	if len(errs) > 0 {
+		return fmt.Errorf("delete expired shares: %w", errors.Join(errs...))
+	}
+	return nil
+}
+
+// accountClients adds the accounts of managed StorageClasses to the configured ones.
+func (r *ShareReaper) accountClients(ctx context.Context) (map[string]azure.ShareClient, []error) {
+	clients := maps.Clone(r.Shares)
+	if clients == nil {
+		clients = map[string]azure.ShareClient{}
+	}
+	if r.Client == nil || r.Accounts == nil {
+		return clients, nil
+	}
+
+	classes := &storagev1.StorageClassList{}
+	if err := r.Client.List(ctx, classes); err != nil {
+		return clients, []error{fmt.Errorf("list storageclasses: %w", err)}
+	}
+	var errs []error
+	for i := range classes.Items {
+		storageClass := &classes.Items[i]
+		if k8s.GetProvisioner(storageClass) != k8s.ManagedProvisioner {
+			continue
+		}
+		params, err := k8s.ParseShareParameters(storageClass.Parameters)
+		if err != nil {
+			continue
+		}
+		resourceGroup := params.ResourceGroup
+		if resourceGroup == "" {
+			resourceGroup = r.ResourceGroup
+		}
+		for _, account := range append([]string{params.StorageAccount}, params.AllowedStorageAccounts...) {
+			if _, ok := clients[account]; ok || account == "" {
+				continue
+			}
+			shares, err := r.Accounts.ForAccount(resourceGroup, account)
+			if err != nil {
+				errs = append(errs, fmt.Errorf("storageclass %q: %w", storageClass.Name, err))
+				continue
+			}
+			clients[account] = shares
+		}
+	}
+	return clients, errs
+}
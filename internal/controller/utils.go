@@ -31,12 +31,16 @@ func (r *PVCReconciler) ensureFinalizer(ctx context.Context, pvc *corev1.Persist
 	return nil
 }
 
-func (r *PVCReconciler) terminalError(logger logr.Logger, pvc *corev1.PersistentVolumeClaim, reason string, err error) (reconcile.Result, error) {
+// terminalError reports a failure retrying cannot fix through an event and the ProvisioningFailed condition.
+// The claim is not requeued; only writing the condition can fail and be retried.
+func (r *PVCReconciler) terminalError(ctx context.Context, logger logr.Logger, pvc *corev1.PersistentVolumeClaim, reason string, err error) (reconcile.Result, error) {
 	logger.WithValues("reason", "terminal").Error(err, "terminal error")
-	if pvc != nil {
-		r.Recorder.Event(pvc, corev1.EventTypeWarning, reason, err.Error())
+	if pvc == nil {
+		return reconcile.Result{}, nil
 	}
-	return reconcile.Result{}, nil
+	r.Recorder.Event(pvc, corev1.EventTypeWarning, reason, err.Error())
+	failed := claimCondition(constants.ConditionProvisioningFailed, corev1.ConditionTrue, reason, err.Error())
+	return reconcile.Result{}, r.setConditions(ctx, pvc, failed)
 }
 
 // pvMatches checks if the existing PersistentVolume matches the expectation for this PVC.
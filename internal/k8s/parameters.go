@@ -0,0 +1,346 @@
+package k8s
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"k8s.io/apimachinery/pkg/util/validation"
+)
+
+// StorageClass parameter keys understood by the provisioner.
+const (
+	ParamSkuName                = "skuName"
+	ParamAccessTier             = "accessTier"
+	ParamEnabledProtocols       = "enabledProtocols"
+	ParamMinQuotaGiB            = "minQuotaGiB"
+	ParamRootSquash             = "rootSquash"
+	ParamStorageAccount         = "storageAccount"
+	ParamResourceGroup          = "resourceGroup"
+	ParamAllowedAccounts        = "allowedStorageAccounts"
+	ParamAllowedOwners          = "allowedShareOwnerNamespaces"
+	ParamShareNameSuffix        = "shareNameSuffix"
+	ParamSnapshotBeforeDelete   = "snapshotBeforeDelete"
+	ParamSubdirectoryShare      = "subdirectoryShare"
+	ParamAllowOwnershipTransfer = "allowShareOwnershipTransfer"
+
+	// reservedParamPrefix marks parameters consumed by other components (e.g. CSI secrets).
+	reservedParamPrefix = "csi.storage.k8s.io/"
+
+	// premiumMinQuotaGiB is the smallest share Azure accepts on premium (FileStorage) accounts.
+	premiumMinQuotaGiB = 100
+
+	// defaultNFSRootSquash is Azure's root squash default, applied explicitly to NFS shares.
+	defaultNFSRootSquash = "NoRootSquash"
+)
+
+// ShareNameSuffixUID appends a hash of the claim UID to generated share names.
+const ShareNameSuffixUID = "uid"
+
+// Scopes of the backing share claims of a subdirectory class are given a directory in.
+const (
+	SubdirectoryShareClass     = "class"
+	SubdirectoryShareNamespace = "namespace"
+)
+
+// Share protocols.
+const (
+	ProtocolSMB = "SMB"
+	ProtocolNFS = "NFS"
+)
+
+var ErrInvalidParameters = errors.New("invalid storageclass parameters")
+
+var knownSkus = []string{
+	"Standard_LRS",
+	"Standard_GRS",
+	"Standard_RAGRS",
+	"Standard_ZRS",
+	"Standard_GZRS",
+	"Standard_RAGZRS",
+	"Premium_LRS",
+	"Premium_ZRS",
+}
+
+var knownAccessTiers = []string{"Hot", "Cool", "TransactionOptimized", "Premium"}
+
+var knownProtocols = []string{ProtocolSMB, ProtocolNFS}
+
+var knownRootSquash = []string{"NoRootSquash", "RootSquash", "AllSquash"}
+
+var knownShareNameSuffixes = []string{ShareNameSuffixUID}
+
+var knownSubdirectoryShares = []string{SubdirectoryShareClass, SubdirectoryShareNamespace}
+
+var knownBooleans = []string{"true", "false"}
+
+// Snapshot policies applied when a claim with reclaim policy Delete is deleted.
+const (
+	// SnapshotNone deletes the share without a snapshot.
+	SnapshotNone = "false"
+	// SnapshotThenDelete snapshots the share, then schedules its deletion; the snapshot lives until the share is
+	// reaped. It requires a deletion grace period, see CheckSnapshotPolicy.
+	SnapshotThenDelete = "true"
+	// SnapshotRetain snapshots the share and keeps it instead of deleting it.
+	SnapshotRetain = "retain"
+)
+
+var knownSnapshotPolicies = []string{SnapshotNone, SnapshotThenDelete, SnapshotRetain}
+
+// ShareParameters is the typed form of a StorageClass parameters map.
+// Empty fields mean "use the Azure default" (or the controller default for account selection).
+type ShareParameters struct {
+	SkuName     string
+	AccessTier  string
+	Protocol    string
+	MinQuotaGiB int32
+	RootSquash  string
+
+	StorageAccount         string
+	ResourceGroup          string
+	AllowedStorageAccounts []string
+
+	AllowedOwnerNamespaces []string
+	ShareNameSuffix        string
+	SnapshotBeforeDelete   string
+	SubdirectoryShare      string
+	// AllowOwnershipTransfer lets claims take over the shares they adopt with the transfer-share-ownership annotation.
+	AllowOwnershipTransfer bool
+}
+
+// ParseShareParameters validates StorageClass parameters and returns their typed form.
+// Values are matched case-insensitively and normalized to their canonical Azure spelling.
+// Unknown keys are rejected so that typos do not silently fall back to defaults.
+func ParseShareParameters(params map[string]string) (ShareParameters, error) {
+	var parsed ShareParameters
+	var unknown []string
+
+	for key, value := range params {
+		if strings.HasPrefix(key, reservedParamPrefix) {
+			continue
+		}
+
+		var err error
+		switch key {
+		case ParamSkuName:
+			parsed.SkuName, err = matchValue(key, value, knownSkus)
+		case ParamAccessTier:
+			parsed.AccessTier, err = matchValue(key, value, knownAccessTiers)
+		case ParamEnabledProtocols:
+			parsed.Protocol, err = matchValue(key, value, knownProtocols)
+		case ParamRootSquash:
+			parsed.RootSquash, err = matchValue(key, value, knownRootSquash)
+		case ParamMinQuotaGiB:
+			parsed.MinQuotaGiB, err = parseQuota(key, value)
+		case ParamStorageAccount:
+			parsed.StorageAccount, err = parseAccountName(key, value)
+		case ParamResourceGroup:
+			parsed.ResourceGroup, err = parseResourceGroup(key, value)
+		case ParamAllowedAccounts:
+			parsed.AllowedStorageAccounts, err = parseAccountList(key, value)
+		case ParamAllowedOwners:
+			parsed.AllowedOwnerNamespaces, err = parseNamespaceList(key, value)
+		case ParamShareNameSuffix:
+			parsed.ShareNameSuffix, err = matchValue(key, value, knownShareNameSuffixes)
+		case ParamSnapshotBeforeDelete:
+			parsed.SnapshotBeforeDelete, err = ParseSnapshotPolicy(key, value)
+		case ParamSubdirectoryShare:
+			parsed.SubdirectoryShare, err = matchValue(key, value, knownSubdirectoryShares)
+		case ParamAllowOwnershipTransfer:
+			var allow string
+			allow, err = matchValue(key, value, knownBooleans)
+			parsed.AllowOwnershipTransfer = allow == "true"
+		default:
+			unknown = append(unknown, key)
+		}
+		if err != nil {
+			return ShareParameters{}, err
+		}
+	}
+
+	if len(unknown) > 0 {
+		sort.Strings(unknown)
+		return ShareParameters{}, fmt.Errorf("unknown parameters %s: %w", strings.Join(unknown, ", "), ErrInvalidParameters)
+	}
+
+	if parsed.RootSquash != "" && parsed.Protocol != ProtocolNFS {
+		return ShareParameters{}, fmt.Errorf("%s requires %s=%s: %w", ParamRootSquash, ParamEnabledProtocols, ProtocolNFS, ErrInvalidParameters)
+	}
+	if parsed.AccessTier != "" && parsed.SkuName != "" && (parsed.AccessTier == "Premium") != parsed.IsPremium() {
+		return ShareParameters{}, fmt.Errorf("%s %q is not available on sku %q: %w", ParamAccessTier, parsed.AccessTier, parsed.SkuName, ErrInvalidParameters)
+	}
+	if parsed.UsesSubdirectories() {
+		if parsed.IsNFS() {
+			return ShareParameters{}, fmt.Errorf("%s requires SMB shares, the directories are created through the Azure Files REST API: %w", ParamSubdirectoryShare, ErrInvalidParameters)
+		}
+		if parsed.SnapshotBeforeDelete != "" && parsed.SnapshotBeforeDelete != SnapshotNone {
+			return ShareParameters{}, fmt.Errorf("%s cannot be combined with %s, a claim's directory has no snapshot of its own: %w", ParamSubdirectoryShare, ParamSnapshotBeforeDelete, ErrInvalidParameters)
+		}
+	}
+	if parsed.IsNFS() {
+		if !parsed.IsPremium() {
+			return ShareParameters{}, fmt.Errorf("%s=%s requires a premium %s (got %q): %w", ParamEnabledProtocols, ProtocolNFS, ParamSkuName, parsed.SkuName, ErrInvalidParameters)
+		}
+		if parsed.RootSquash == "" {
+			parsed.RootSquash = defaultNFSRootSquash
+		}
+	}
+
+	return parsed, nil
+}
+
+// AllowsStorageAccount reports whether a per-PVC account selection is permitted by the class.
+// The class's own account is always allowed.
+func (p ShareParameters) AllowsStorageAccount(account string) bool {
+	if account == p.StorageAccount {
+		return true
+	}
+	for _, allowed := range p.AllowedStorageAccounts {
+		if allowed == account {
+			return true
+		}
+	}
+	return false
+}
+
+// AllowsOwnerNamespace reports whether claims of the class may use a share owned by another namespace.
+func (p ShareParameters) AllowsOwnerNamespace(namespace string) bool {
+	for _, allowed := range p.AllowedOwnerNamespaces {
+		if allowed == namespace {
+			return true
+		}
+	}
+	return false
+}
+
+// UniqueShareNames reports whether generated share names carry a claim UID suffix.
+func (p ShareParameters) UniqueShareNames() bool {
+	return p.ShareNameSuffix == ShareNameSuffixUID
+}
+
+// UsesSubdirectories reports whether claims of the class get a directory in a shared backing share instead of a share of their own.
+func (p ShareParameters) UsesSubdirectories() bool {
+	return p.SubdirectoryShare != ""
+}
+
+// IsNFS reports whether shares of the class are exported over NFS 4.1.
+func (p ShareParameters) IsNFS() bool {
+	return p.Protocol == ProtocolNFS
+}
+
+// IsPremium reports whether the parameters target a premium (FileStorage) account.
+func (p ShareParameters) IsPremium() bool {
+	return strings.HasPrefix(p.SkuName, "Premium_")
+}
+
+// EffectiveQuotaGiB applies the configured and sku-specific quota floors to a requested quota.
+func (p ShareParameters) EffectiveQuotaGiB(requested int32) int32 {
+	quota := requested
+	if quota < p.MinQuotaGiB {
+		quota = p.MinQuotaGiB
+	}
+	if p.IsPremium() && quota < premiumMinQuotaGiB {
+		quota = premiumMinQuotaGiB
+	}
+	return quota
+}
+
+// ParseSnapshotPolicy validates a snapshotBeforeDelete value from a StorageClass or claim annotation.
+func ParseSnapshotPolicy(key, value string) (string, error) {
+	return matchValue(key, value, knownSnapshotPolicies)
+}
+
+// CheckSnapshotPolicy refuses SnapshotThenDelete without a deletion grace period: deleting the share right
+// away deletes its snapshots with it.
+func CheckSnapshotPolicy(key, policy string, gracePeriod time.Duration) error {
+	if policy == SnapshotThenDelete && gracePeriod <= 0 {
+		return fmt.Errorf("%s %q requires a share deletion grace period, the snapshot is deleted with the share: %w", key, policy, ErrInvalidParameters)
+	}
+	return nil
+}
+
+func matchValue(key, value string, allowed []string) (string, error) {
+	trimmed := strings.TrimSpace(value)
+	for _, candidate := range allowed {
+		if strings.EqualFold(trimmed, candidate) {
+			return candidate, nil
+		}
+	}
+	return "", fmt.Errorf("%s %q not supported (supported: %s): %w", key, value, strings.Join(allowed, ", "), ErrInvalidParameters)
+}
+
+func parseQuota(key, value string) (int32, error) {
+	parsed, err := strconv.ParseInt(strings.TrimSpace(value), 10, 32)
+	if err != nil || parsed <= 0 {
+		return 0, fmt.Errorf("%s %q must be a positive integer: %w", key, value, ErrInvalidParameters)
+	}
+	return int32(parsed), nil
+}
+
+// ValidateAccountName checks Azure storage account naming rules: 3-24 lowercase letters and digits.
+func ValidateAccountName(name string) error {
+	if len(name) < 3 || len(name) > 24 {
+		return fmt.Errorf("storage account %q must be 3-24 characters: %w", name, ErrInvalidParameters)
+	}
+	for _, r := range name {
+		if (r < 'a' || r > 'z') && (r < '0' || r > '9') {
+			return fmt.Errorf("storage account %q may only contain lowercase letters and digits: %w", name, ErrInvalidParameters)
+		}
+	}
+	return nil
+}
+
+func parseAccountName(key, value string) (string, error) {
+	name := strings.TrimSpace(value)
+	if err := ValidateAccountName(name); err != nil {
+		return "", fmt.Errorf("%s: %w", key, err)
+	}
+	return name, nil
+}
+
+func parseAccountList(key, value string) ([]string, error) {
+	var accounts []string
+	for _, item := range strings.Split(value, ",") {
+		if strings.TrimSpace(item) == "" {
+			continue
+		}
+		name, err := parseAccountName(key, item)
+		if err != nil {
+			return nil, err
+		}
+		accounts = append(accounts, name)
+	}
+	if len(accounts) == 0 {
+		return nil, fmt.Errorf("%s must list at least one account: %w", key, ErrInvalidParameters)
+	}
+	return accounts, nil
+}
+
+func parseNamespaceList(key, value string) ([]string, error) {
+	var namespaces []string
+	for _, item := range strings.Split(value, ",") {
+		name := strings.TrimSpace(item)
+		if name == "" {
+			continue
+		}
+		if errs := validation.IsDNS1123Label(name); len(errs) > 0 {
+			return nil, fmt.Errorf("%s: namespace %q: %s: %w", key, name, strings.Join(errs, "; "), ErrInvalidParameters)
+		}
+		namespaces = append(namespaces, name)
+	}
+	if len(namespaces) == 0 {
+		return nil, fmt.Errorf("%s must list at least one namespace: %w", key, ErrInvalidParameters)
+	}
+	return namespaces, nil
+}
+
+func parseResourceGroup(key, value string) (string, error) {
+	name := strings.TrimSpace(value)
+	if name == "" || len(name) > 90 {
+		return "", fmt.Errorf("%s %q must be 1-90 characters: %w", key, value, ErrInvalidParameters)
+	}
+	return name, nil
+}
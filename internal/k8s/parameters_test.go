@@ -0,0 +1,144 @@
+package k8s
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func TestParseShareParameters(t *testing.T) {
+	got, err := ParseShareParameters(map[string]string{
+		ParamSkuName:                                 "premium_lrs",
+		ParamAccessTier:                              "premium",
+		ParamEnabledProtocols:                        "nfs",
+		ParamRootSquash:                              "allsquash",
+		ParamMinQuotaGiB:                             "200",
+		ParamStorageAccount:                          "primaryacct",
+		ParamResourceGroup:                           "storage-rg",
+		ParamAllowedAccounts:                         "teamacct, otheracct",
+		ParamAllowedOwners:                           "shared-data",
+		ParamShareNameSuffix:                         "UID",
+		ParamSnapshotBeforeDelete:                    "Retain",
+		ParamAllowOwnershipTransfer:                  "True",
+		"csi.storage.k8s.io/provisioner-secret-name": "ignored",
+	})
+	if err != nil {
+		t.Fatalf("ParseShareParameters error = %v", err)
+	}
+
+	want := ShareParameters{
+		SkuName:     "Premium_LRS",
+		AccessTier:  "Premium",
+		Protocol:    ProtocolNFS,
+		MinQuotaGiB: 200,
+		RootSquash:  "AllSquash",
+
+		StorageAccount:         "primaryacct",
+		ResourceGroup:          "storage-rg",
+		AllowedStorageAccounts: []string{"teamacct", "otheracct"},
+
+		AllowedOwnerNamespaces: []string{"shared-data"},
+		ShareNameSuffix:        ShareNameSuffixUID,
+		SnapshotBeforeDelete:   SnapshotRetain,
+		AllowOwnershipTransfer: true,
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("ParseShareParameters = %#v, want %#v", got, want)
+	}
+}
+
+func TestParseShareParametersEmpty(t *testing.T) {
+	got, err := ParseShareParameters(nil)
+	if err != nil {
+		t.Fatalf("ParseShareParameters error = %v", err)
+	}
+	if !reflect.DeepEqual(got, ShareParameters{}) {
+		t.Fatalf("ParseShareParameters = %#v, want zero value", got)
+	}
+}
+
+func TestParseShareParametersInvalid(t *testing.T) {
+	cases := map[string]map[string]string{
+		"unknown key":           {"skuname": "Standard_LRS"},
+		"unknown sku":           {ParamSkuName: "Ultra_LRS"},
+		"unknown tier":          {ParamAccessTier: "Archive"},
+		"unknown protocol":      {ParamEnabledProtocols: "REST"},
+		"negative quota":        {ParamMinQuotaGiB: "-1"},
+		"non-numeric quota":     {ParamMinQuotaGiB: "lots"},
+		"root squash on smb":    {ParamRootSquash: "RootSquash"},
+		"premium tier on std":   {ParamSkuName: "Standard_LRS", ParamAccessTier: "Premium"},
+		"invalid namespace":     {ParamAllowedOwners: "Shared_Data"},
+		"empty namespaces":      {ParamAllowedOwners: " , "},
+		"unknown suffix":        {ParamShareNameSuffix: "random"},
+		"unknown snapshot":      {ParamSnapshotBeforeDelete: "always"},
+		"hot tier on premium":   {ParamSkuName: "Premium_LRS", ParamAccessTier: "Hot"},
+		"unknown root squash":   {ParamEnabledProtocols: "NFS", ParamRootSquash: "Squash"},
+		"uppercase account":     {ParamStorageAccount: "MyAccount"},
+		"short account":         {ParamStorageAccount: "ab"},
+		"empty allow-list":      {ParamAllowedAccounts: " , "},
+		"empty rg":              {ParamResourceGroup: " "},
+		"nfs on standard sku":   {ParamSkuName: "Standard_LRS", ParamEnabledProtocols: "NFS"},
+		"nfs without sku":       {ParamEnabledProtocols: "NFS"},
+		"non-boolean transfer":  {ParamAllowOwnershipTransfer: "yes"},
+		"unknown subdir scope":  {ParamSubdirectoryShare: "cluster"},
+		"subdirs on nfs":        {ParamSkuName: "Premium_LRS", ParamEnabledProtocols: "NFS", ParamSubdirectoryShare: "class"},
+		"subdirs with snapshot": {ParamSubdirectoryShare: "namespace", ParamSnapshotBeforeDelete: "true"},
+	}
+
+	for name, params := range cases {
+		t.Run(name, func(t *testing.T) {
+			_, err := ParseShareParameters(params)
+			if !errors.Is(err, ErrInvalidParameters) {
+				t.Fatalf("ParseShareParameters error = %v, want %v", err, ErrInvalidParameters)
+			}
+		})
+	}
+}
+
+func TestParseShareParametersNFSDefaultsRootSquash(t *testing.T) {
+	got, err := ParseShareParameters(map[string]string{ParamSkuName: "Premium_ZRS", ParamEnabledProtocols: "NFS"})
+	if err != nil {
+		t.Fatalf("ParseShareParameters error = %v", err)
+	}
+	if !got.IsNFS() || got.RootSquash != "NoRootSquash" {
+		t.Fatalf("ParseShareParameters = %#v, want NFS with NoRootSquash", got)
+	}
+}
+
+func TestEffectiveQuotaGiB(t *testing.T) {
+	if got := (ShareParameters{}).EffectiveQuotaGiB(5); got != 5 {
+		t.Fatalf("EffectiveQuotaGiB = %d, want 5", got)
+	}
+	if got := (ShareParameters{MinQuotaGiB: 10}).EffectiveQuotaGiB(5); got != 10 {
+		t.Fatalf("EffectiveQuotaGiB = %d, want 10", got)
+	}
+	if got := (ShareParameters{SkuName: "Premium_LRS"}).EffectiveQuotaGiB(5); got != premiumMinQuotaGiB {
+		t.Fatalf("EffectiveQuotaGiB = %d, want %d", got, premiumMinQuotaGiB)
+	}
+	if got := (ShareParameters{SkuName: "Premium_LRS"}).EffectiveQuotaGiB(500); got != 500 {
+		t.Fatalf("EffectiveQuotaGiB = %d, want 500", got)
+	}
+}
+
+func TestAllowsStorageAccount(t *testing.T) {
+	params := ShareParameters{StorageAccount: "primary", AllowedStorageAccounts: []string{"team"}}
+	if !params.AllowsStorageAccount("primary") {
+		t.Fatalf("AllowsStorageAccount(primary) = false, want true")
+	}
+	if !params.AllowsStorageAccount("team") {
+		t.Fatalf("AllowsStorageAccount(team) = false, want true")
+	}
+	if params.AllowsStorageAccount("other") {
+		t.Fatalf("AllowsStorageAccount(other) = true, want false")
+	}
+}
+
+func TestAllowsOwnerNamespace(t *testing.T) {
+	params := ShareParameters{AllowedOwnerNamespaces: []string{"shared-data"}}
+	if !params.AllowsOwnerNamespace("shared-data") {
+		t.Fatalf("AllowsOwnerNamespace(shared-data) = false, want true")
+	}
+	if params.AllowsOwnerNamespace("other") {
+		t.Fatalf("AllowsOwnerNamespace(other) = true, want false")
+	}
+}
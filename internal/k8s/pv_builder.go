@@ -5,6 +5,7 @@ import (
 	"encoding/hex"
 	"errors"
 	"fmt"
+	"strings"
 
 	corev1 "k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/api/resource"
@@ -18,8 +19,66 @@ const (
 	pvNameHashLength = 12
 )
 
+// Labels stamped on every PV built by BuildPV.
+const (
+	LabelPVCNamespace = "azurefile.yourlab.dev/pvc-namespace"
+	LabelPVCName      = "azurefile.yourlab.dev/pvc-name"
+	LabelShareName    = "azurefile.yourlab.dev/share-name"
+)
+
 var ErrInvalidPVInput = errors.New("invalid pv input")
 
+// PVOption customizes a PersistentVolume built by BuildPV.
+type PVOption func(*corev1.PersistentVolume)
+
+// WithTopology restricts the PV to nodes in the given region, or the given zone when no region is known.
+// Azure File shares are regional, so the zone is only used as a fallback.
+func WithTopology(topology Topology) PVOption {
+	return func(pv *corev1.PersistentVolume) {
+		key, value := corev1.LabelTopologyRegion, topology.Region
+		if value == "" {
+			key, value = corev1.LabelTopologyZone, topology.Zone
+		}
+		if value == "" {
+			return
+		}
+		pv.Spec.NodeAffinity = &corev1.VolumeNodeAffinity{
+			Required: &corev1.NodeSelector{
+				NodeSelectorTerms: []corev1.NodeSelectorTerm{{
+					MatchExpressions: []corev1.NodeSelectorRequirement{{
+						Key:      key,
+						Operator: corev1.NodeSelectorOpIn,
+						Values:   []string{value},
+					}},
+				}},
+			},
+		}
+	}
+}
+
+// WithProtocol sets the CSI protocol attribute for NFS shares. SMB is the driver default and needs no attribute.
+func WithProtocol(protocol string) PVOption {
+	return func(pv *corev1.PersistentVolume) {
+		if protocol != ProtocolNFS {
+			return
+		}
+		pv.Spec.CSI.VolumeAttributes["protocol"] = "nfs"
+	}
+}
+
+// WithSubdirectory mounts a directory of the share instead of its root. The directory is appended to the
+// volume handle, so the PVs of claims sharing a backing share stay distinct.
+func WithSubdirectory(dir string) PVOption {
+	return func(pv *corev1.PersistentVolume) {
+		if dir == "" {
+			return
+		}
+		pv.Spec.CSI.VolumeHandle = fmt.Sprintf("%s#%s", pv.Spec.CSI.VolumeHandle, dir)
+		pv.Spec.CSI.VolumeAttributes["folderName"] = dir
+		pv.Annotations["azurefile.yourlab.dev/volume-handle"] = pv.Spec.CSI.VolumeHandle
+	}
+}
+
 // BuildPV constructs a PersistentVolume that binds to the PVC and Azure File share.
 // Invariants: deterministic name/spec for same inputs and no external side effects.
 func BuildPV(
@@ -29,6 +88,7 @@ func BuildPV(
 	storageAccount string,
 	server string,
 	reclaimPolicy corev1.PersistentVolumeReclaimPolicy,
+	opts ...PVOption,
 ) (*corev1.PersistentVolume, error) {
 	if pvc == nil {
 		return nil, fmt.Errorf("pvc is nil: %w", ErrInvalidPVInput)
@@ -46,19 +106,19 @@ func BuildPV(
 	}
 
 	pvName := pvNameFor(pvc, shareName, storageAccount, resourceGroup)
-	volumeHandle := fmt.Sprintf("%s#%s#%s", resourceGroup, storageAccount, shareName)
+	volumeHandle := VolumeHandle(resourceGroup, storageAccount, shareName)
 
 	labels := map[string]string{
-		"azurefile.yourlab.dev/pvc-namespace": pvc.Namespace,
-		"azurefile.yourlab.dev/pvc-name":      pvc.Name,
-		"azurefile.yourlab.dev/share-name":    shareName,
+		LabelPVCNamespace: pvc.Namespace,
+		LabelPVCName:      pvc.Name,
+		LabelShareName:    shareName,
 	}
 
 	annotations := map[string]string{
 		"azurefile.yourlab.dev/volume-handle": volumeHandle,
 	}
 
-	return &corev1.PersistentVolume{
+	pv := &corev1.PersistentVolume{
 		ObjectMeta: metav1.ObjectMeta{
 			Name:        pvName,
 			Labels:      labels,
@@ -89,7 +149,54 @@ func BuildPV(
 				},
 			},
 		},
-	}, nil
+	}
+	for _, opt := range opts {
+		opt(pv)
+	}
+	return pv, nil
+}
+
+// VolumeHandle formats the CSI volume handle for a share: "<resourceGroup>#<storageAccount>#<shareName>".
+func VolumeHandle(resourceGroup, storageAccount, shareName string) string {
+	return fmt.Sprintf("%s#%s#%s", resourceGroup, storageAccount, shareName)
+}
+
+// ParseVolumeHandle splits a volume handle built by VolumeHandle.
+func ParseVolumeHandle(handle string) (resourceGroup, storageAccount, shareName string, err error) {
+	parts := strings.Split(handle, "#")
+	if len(parts) != 3 || parts[0] == "" || parts[1] == "" || parts[2] == "" {
+		return "", "", "", fmt.Errorf("volume handle %q malformed: %w", handle, ErrInvalidPVInput)
+	}
+	return parts[0], parts[1], parts[2], nil
+}
+
+// ParseDirectoryVolumeHandle splits a volume handle built by VolumeHandle or by a PV built WithSubdirectory
+// ("<resourceGroup>#<storageAccount>#<shareName>#<directory>"). directory is empty for share volumes.
+func ParseDirectoryVolumeHandle(handle string) (resourceGroup, storageAccount, shareName, directory string, err error) {
+	parts := strings.Split(handle, "#")
+	if len(parts) == 4 && parts[3] != "" {
+		directory = parts[3]
+		handle = strings.Join(parts[:3], "#")
+	}
+	resourceGroup, storageAccount, shareName, err = ParseVolumeHandle(handle)
+	if err != nil {
+		return "", "", "", "", err
+	}
+	return resourceGroup, storageAccount, shareName, directory, nil
+}
+
+// SnapshotHandle formats the CSI snapshot handle of a share snapshot: "<resourceGroup>#<storageAccount>#<shareName>#<snapshot>".
+func SnapshotHandle(resourceGroup, storageAccount, shareName, snapshot string) string {
+	return fmt.Sprintf("%s#%s", VolumeHandle(resourceGroup, storageAccount, shareName), snapshot)
+}
+
+// ParseSnapshotHandle splits a snapshot handle built by SnapshotHandle.
+func ParseSnapshotHandle(handle string) (resourceGroup, storageAccount, shareName, snapshot string, err error) {
+	parts := strings.Split(handle, "#")
+	if len(parts) != 4 || parts[0] == "" || parts[1] == "" || parts[2] == "" || parts[3] == "" {
+		return "", "", "", "", fmt.Errorf("snapshot handle %q malformed: %w", handle, ErrInvalidPVInput)
+	}
+	return parts[0], parts[1], parts[2], parts[3], nil
 }
 
 func pvNameFor(pvc *corev1.PersistentVolumeClaim, shareName, storageAccount, resourceGroup string) string {
@@ -121,3 +121,82 @@ func TestBuildPVInvalid(t *testing.T) {
 		t.Fatalf("BuildPV error = nil, want error")
 	}
 }
+
+func TestParseVolumeHandle(t *testing.T) {
+	rg, account, share, err := ParseVolumeHandle(VolumeHandle("rg", "account", "share"))
+	if err != nil {
+		t.Fatalf("ParseVolumeHandle error = %v", err)
+	}
+	if rg != "rg" || account != "account" || share != "share" {
+		t.Fatalf("ParseVolumeHandle = %q, %q, %q, want rg, account, share", rg, account, share)
+	}
+
+	for _, handle := range []string{"", "rg#account", "rg##share", "a#b#c#d"} {
+		if _, _, _, err := ParseVolumeHandle(handle); err == nil {
+			t.Fatalf("ParseVolumeHandle(%q) error = nil, want error", handle)
+		}
+	}
+}
+
+func TestParseDirectoryVolumeHandle(t *testing.T) {
+	rg, account, share, dir, err := ParseDirectoryVolumeHandle(VolumeHandle("rg", "account", "share") + "#team-data")
+	if err != nil {
+		t.Fatalf("ParseDirectoryVolumeHandle error = %v", err)
+	}
+	if rg != "rg" || account != "account" || share != "share" || dir != "team-data" {
+		t.Fatalf("ParseDirectoryVolumeHandle = %q, %q, %q, %q", rg, account, share, dir)
+	}
+	if _, _, _, dir, err := ParseDirectoryVolumeHandle(VolumeHandle("rg", "account", "share")); err != nil || dir != "" {
+		t.Fatalf("ParseDirectoryVolumeHandle share handle = %q, %v, want no directory", dir, err)
+	}
+
+	for _, handle := range []string{"", "rg#account", "rg#account#share#", "a#b#c#d#e"} {
+		if _, _, _, _, err := ParseDirectoryVolumeHandle(handle); err == nil {
+			t.Fatalf("ParseDirectoryVolumeHandle(%q) error = nil, want error", handle)
+		}
+	}
+}
+
+func TestParseSnapshotHandle(t *testing.T) {
+	handle := SnapshotHandle("rg", "account", "share", "2024-05-01T10:00:00.0000000Z")
+	rg, account, share, snapshot, err := ParseSnapshotHandle(handle)
+	if err != nil {
+		t.Fatalf("ParseSnapshotHandle error = %v", err)
+	}
+	if rg != "rg" || account != "account" || share != "share" || snapshot != "2024-05-01T10:00:00.0000000Z" {
+		t.Fatalf("ParseSnapshotHandle = %q, %q, %q, %q", rg, account, share, snapshot)
+	}
+
+	for _, handle := range []string{"", "rg#account#share", "rg#account#share#", "a#b#c#d#e"} {
+		if _, _, _, _, err := ParseSnapshotHandle(handle); err == nil {
+			t.Fatalf("ParseSnapshotHandle(%q) error = nil, want error", handle)
+		}
+	}
+}
+
+func TestBuildPVWithProtocol(t *testing.T) {
+	pvc := &corev1.PersistentVolumeClaim{
+		ObjectMeta: metav1.ObjectMeta{Name: "data", Namespace: "team", UID: types.UID("uid-123")},
+		Spec: corev1.PersistentVolumeClaimSpec{
+			Resources: corev1.VolumeResourceRequirements{
+				Requests: corev1.ResourceList{corev1.ResourceStorage: resource.MustParse("100Gi")},
+			},
+		},
+	}
+
+	nfs, err := BuildPV(pvc, "share", "rg", "account", "server", corev1.PersistentVolumeReclaimDelete, WithProtocol(ProtocolNFS))
+	if err != nil {
+		t.Fatalf("BuildPV error = %v", err)
+	}
+	if nfs.Spec.CSI.VolumeAttributes["protocol"] != "nfs" {
+		t.Fatalf("protocol = %q, want %q", nfs.Spec.CSI.VolumeAttributes["protocol"], "nfs")
+	}
+
+	smb, err := BuildPV(pvc, "share", "rg", "account", "server", corev1.PersistentVolumeReclaimDelete, WithProtocol(ProtocolSMB))
+	if err != nil {
+		t.Fatalf("BuildPV error = %v", err)
+	}
+	if _, ok := smb.Spec.CSI.VolumeAttributes["protocol"]; ok {
+		t.Fatalf("protocol attribute set for SMB share")
+	}
+}
@@ -3,9 +3,12 @@ package k8s
 import (
 	"errors"
 	"fmt"
+	"strings"
 
 	corev1 "k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/api/resource"
+
+	"aks-azureFiles-controller/internal/constants"
 )
 
 var ErrInvalidPVCRequest = errors.New("invalid pvc request")
@@ -21,15 +24,87 @@ func QuotaGiBFromPVC(pvc *corev1.PersistentVolumeClaim) (int32, error) {
 		return 0, fmt.Errorf("storage request missing: %w", ErrInvalidPVCRequest)
 	}
 
-	bytes := storage.Value()
-	if bytes <= 0 {
+	if storage.Value() <= 0 {
 		return 0, fmt.Errorf("storage request invalid: %w", ErrInvalidPVCRequest)
 	}
 
-	const gib = int64(1024 * 1024 * 1024)
-	quota := (bytes + gib - 1) / gib
+	quota := CeilGiB(storage)
 	if quota > int64(^uint32(0)>>1) {
 		return 0, fmt.Errorf("storage request too large: %w", ErrInvalidPVCRequest)
 	}
 	return int32(quota), nil
 }
+
+// supportedAccessModes are the access modes Azure File shares can serve, over SMB and NFS alike.
+var supportedAccessModes = map[corev1.PersistentVolumeAccessMode]bool{
+	corev1.ReadWriteOnce:    true,
+	corev1.ReadOnlyMany:     true,
+	corev1.ReadWriteMany:    true,
+	corev1.ReadWriteOncePod: true,
+}
+
+// ValidateClaim rejects claims an Azure File share cannot satisfy: block volumes, and missing or unknown access modes.
+func ValidateClaim(pvc *corev1.PersistentVolumeClaim) error {
+	if pvc == nil {
+		return fmt.Errorf("pvc is nil: %w", ErrInvalidPVCRequest)
+	}
+	if pvc.Spec.VolumeMode != nil && *pvc.Spec.VolumeMode == corev1.PersistentVolumeBlock {
+		return fmt.Errorf("volumeMode Block is not supported by Azure File shares: %w", ErrInvalidPVCRequest)
+	}
+	if len(pvc.Spec.AccessModes) == 0 {
+		return fmt.Errorf("at least one access mode is required: %w", ErrInvalidPVCRequest)
+	}
+	for _, mode := range pvc.Spec.AccessModes {
+		if !supportedAccessModes[mode] {
+			return fmt.Errorf("access mode %q is not supported: %w", mode, ErrInvalidPVCRequest)
+		}
+	}
+	return nil
+}
+
+// ClaimSnapshotPolicy resolves the snapshot policy applied when the claim is deleted and the key it was set by.
+// The 'snapshot-before-delete' annotation wins over the StorageClass; invalid annotation values are ignored.
+func ClaimSnapshotPolicy(pvc *corev1.PersistentVolumeClaim, params ShareParameters) (key, policy string) {
+	if policy, err := ParseSnapshotPolicy(constants.SnapshotBeforeDeleteAnnotation, pvc.Annotations[constants.SnapshotBeforeDeleteAnnotation]); err == nil {
+		return constants.SnapshotBeforeDeleteAnnotation, policy
+	}
+	if params.SnapshotBeforeDelete == "" {
+		return ParamSnapshotBeforeDelete, SnapshotNone
+	}
+	return ParamSnapshotBeforeDelete, params.SnapshotBeforeDelete
+}
+
+// ParseAdoptedShare splits an adopt-share annotation value of the form "share" or "account/share".
+// The share name must already be a valid Azure File share name: 3-63 lowercase letters, digits and
+// single hyphens, starting and ending with a letter or digit. account is "" when not given.
+func ParseAdoptedShare(value string) (account, shareName string, err error) {
+	shareName = value
+	if before, after, found := strings.Cut(value, "/"); found {
+		account, shareName = before, after
+		if err := ValidateAccountName(account); err != nil {
+			return "", "", fmt.Errorf("adopted share %q: %w", value, err)
+		}
+	}
+	if len(shareName) < 3 || len(shareName) > 63 {
+		return "", "", fmt.Errorf("adopted share %q must be 3-63 characters: %w", shareName, ErrInvalidPVCRequest)
+	}
+	for i, r := range shareName {
+		switch {
+		case r >= 'a' && r <= 'z', r >= '0' && r <= '9':
+		case r == '-' && i > 0 && i < len(shareName)-1 && shareName[i-1] != '-':
+		default:
+			return "", "", fmt.Errorf("adopted share %q is not a valid Azure File share name: %w", shareName, ErrInvalidPVCRequest)
+		}
+	}
+	return account, shareName, nil
+}
+
+// CeilGiB converts a quantity to whole GiB, rounding up.
+func CeilGiB(quantity resource.Quantity) int64 {
+	const gib = int64(1024 * 1024 * 1024)
+	bytes := quantity.Value()
+	if bytes <= 0 {
+		return 0
+	}
+	return (bytes + gib - 1) / gib
+}
@@ -0,0 +1,86 @@
+package k8s
+
+import (
+	"errors"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+)
+
+func TestQuotaGiBFromPVC(t *testing.T) {
+	pvc := &corev1.PersistentVolumeClaim{
+		Spec: corev1.PersistentVolumeClaimSpec{
+			Resources: corev1.VolumeResourceRequirements{
+				Requests: corev1.ResourceList{corev1.ResourceStorage: resource.MustParse("1500Mi")},
+			},
+		},
+	}
+	got, err := QuotaGiBFromPVC(pvc)
+	if err != nil {
+		t.Fatalf("QuotaGiBFromPVC error = %v", err)
+	}
+	if got != 2 {
+		t.Fatalf("QuotaGiBFromPVC = %d, want 2", got)
+	}
+}
+
+func TestValidateClaim(t *testing.T) {
+	block := corev1.PersistentVolumeBlock
+	filesystem := corev1.PersistentVolumeFilesystem
+
+	valid := &corev1.PersistentVolumeClaim{Spec: corev1.PersistentVolumeClaimSpec{
+		AccessModes: []corev1.PersistentVolumeAccessMode{corev1.ReadWriteMany, corev1.ReadOnlyMany},
+		VolumeMode:  &filesystem,
+	}}
+	if err := ValidateClaim(valid); err != nil {
+		t.Fatalf("ValidateClaim error = %v", err)
+	}
+
+	cases := map[string]corev1.PersistentVolumeClaimSpec{
+		"block":        {AccessModes: []corev1.PersistentVolumeAccessMode{corev1.ReadWriteOnce}, VolumeMode: &block},
+		"no modes":     {},
+		"unknown mode": {AccessModes: []corev1.PersistentVolumeAccessMode{"ReadWriteSometimes"}},
+	}
+	for name, spec := range cases {
+		t.Run(name, func(t *testing.T) {
+			err := ValidateClaim(&corev1.PersistentVolumeClaim{Spec: spec})
+			if !errors.Is(err, ErrInvalidPVCRequest) {
+				t.Fatalf("ValidateClaim error = %v, want %v", err, ErrInvalidPVCRequest)
+			}
+		})
+	}
+}
+
+func TestParseAdoptedShare(t *testing.T) {
+	tests := []struct {
+		value       string
+		wantAccount string
+		wantShare   string
+		wantErr     bool
+	}{
+		{value: "team-data", wantShare: "team-data"},
+		{value: "legacyacct/team-data", wantAccount: "legacyacct", wantShare: "team-data"},
+		{value: "Team-Data", wantErr: true},
+		{value: "team--data", wantErr: true},
+		{value: "-team", wantErr: true},
+		{value: "ab", wantErr: true},
+		{value: "Legacy/team-data", wantErr: true},
+		{value: "legacyacct/", wantErr: true},
+	}
+	for _, tt := range tests {
+		account, shareName, err := ParseAdoptedShare(tt.value)
+		if tt.wantErr {
+			if err == nil {
+				t.Fatalf("ParseAdoptedShare(%q) error = nil, want error", tt.value)
+			}
+			continue
+		}
+		if err != nil {
+			t.Fatalf("ParseAdoptedShare(%q) error = %v", tt.value, err)
+		}
+		if account != tt.wantAccount || shareName != tt.wantShare {
+			t.Fatalf("ParseAdoptedShare(%q) = %q, %q, want %q, %q", tt.value, account, shareName, tt.wantAccount, tt.wantShare)
+		}
+	}
+}
@@ -0,0 +1,49 @@
+package k8s
+
+import (
+	"fmt"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// Keys of the Secret that file.csi.azure.com reads for SMB mounts.
+const (
+	SecretAccountNameKey = "azurestorageaccountname"
+	SecretAccountKeyKey  = "azurestorageaccountkey"
+)
+
+// Metadata stamped on key Secrets managed by the controller.
+const (
+	LabelStorageAccount     = "azurefile.yourlab.dev/storage-account"
+	AnnotationResourceGroup = "azurefile.yourlab.dev/resource-group"
+)
+
+// KeySecretName returns the name of the key Secret for an account, following the CSI driver's naming convention.
+func KeySecretName(storageAccount string) string {
+	return fmt.Sprintf("azure-storage-account-%s-secret", storageAccount)
+}
+
+// BuildKeySecret constructs the key Secret for an account in the given namespace.
+func BuildKeySecret(namespace, resourceGroup, storageAccount, key string) *corev1.Secret {
+	return &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:        KeySecretName(storageAccount),
+			Namespace:   namespace,
+			Labels:      map[string]string{LabelStorageAccount: storageAccount},
+			Annotations: map[string]string{AnnotationResourceGroup: resourceGroup},
+		},
+		Type: corev1.SecretTypeOpaque,
+		Data: map[string][]byte{
+			SecretAccountNameKey: []byte(storageAccount),
+			SecretAccountKeyKey:  []byte(key),
+		},
+	}
+}
+
+// WithNodeStageSecret makes the CSI driver read the account key from the referenced Secret when staging the volume.
+func WithNodeStageSecret(namespace, name string) PVOption {
+	return func(pv *corev1.PersistentVolume) {
+		pv.Spec.CSI.NodeStageSecretRef = &corev1.SecretReference{Namespace: namespace, Name: name}
+	}
+}
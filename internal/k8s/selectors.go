@@ -23,8 +23,16 @@ func IsManagedPVC(pvc *corev1.PersistentVolumeClaim) bool {
 	return *pvc.Spec.StorageClassName != ""
 }
 
+// StorageClassName returns the claim's StorageClass name, or "" when it has none.
+func StorageClassName(pvc *corev1.PersistentVolumeClaim) string {
+	if pvc == nil || pvc.Spec.StorageClassName == nil {
+		return ""
+	}
+	return *pvc.Spec.StorageClassName
+}
+
 // GetStorageClass loads the StorageClass referenced by the PVC.
-func GetStorageClass(ctx context.Context, c client.Client, pvc *corev1.PersistentVolumeClaim) (*storagev1.StorageClass, error) {
+func GetStorageClass(ctx context.Context, c client.Reader, pvc *corev1.PersistentVolumeClaim) (*storagev1.StorageClass, error) {
 	if pvc == nil || pvc.Spec.StorageClassName == nil || *pvc.Spec.StorageClassName == "" {
 		return nil, nil
 	}
@@ -44,3 +52,11 @@ func GetProvisioner(sc *storagev1.StorageClass) string {
 	}
 	return sc.Provisioner
 }
+
+// ReclaimPolicy returns the StorageClass reclaim policy, defaulting to Delete like the API server does.
+func ReclaimPolicy(sc *storagev1.StorageClass) corev1.PersistentVolumeReclaimPolicy {
+	if sc == nil || sc.ReclaimPolicy == nil || *sc.ReclaimPolicy == "" {
+		return corev1.PersistentVolumeReclaimDelete
+	}
+	return *sc.ReclaimPolicy
+}
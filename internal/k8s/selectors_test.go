@@ -143,3 +143,20 @@ func TestGetStorageClassUsesClusterScope(t *testing.T) {
 		t.Fatalf("client.Get error = %v", err)
 	}
 }
+
+func TestReclaimPolicy(t *testing.T) {
+	if got := ReclaimPolicy(nil); got != corev1.PersistentVolumeReclaimDelete {
+		t.Fatalf("ReclaimPolicy(nil) = %q, want %q", got, corev1.PersistentVolumeReclaimDelete)
+	}
+
+	sc := &storagev1.StorageClass{}
+	if got := ReclaimPolicy(sc); got != corev1.PersistentVolumeReclaimDelete {
+		t.Fatalf("ReclaimPolicy(unset) = %q, want %q", got, corev1.PersistentVolumeReclaimDelete)
+	}
+
+	retain := corev1.PersistentVolumeReclaimRetain
+	sc.ReclaimPolicy = &retain
+	if got := ReclaimPolicy(sc); got != corev1.PersistentVolumeReclaimRetain {
+		t.Fatalf("ReclaimPolicy(retain) = %q, want %q", got, corev1.PersistentVolumeReclaimRetain)
+	}
+}
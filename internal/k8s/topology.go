@@ -0,0 +1,31 @@
+package k8s
+
+import (
+	corev1 "k8s.io/api/core/v1"
+	storagev1 "k8s.io/api/storage/v1"
+)
+
+// Topology is the placement of the node selected by the scheduler.
+type Topology struct {
+	Region string
+	Zone   string
+}
+
+// TopologyFromNode reads the well-known topology labels from a node.
+func TopologyFromNode(node *corev1.Node) Topology {
+	if node == nil {
+		return Topology{}
+	}
+	return Topology{
+		Region: node.Labels[corev1.LabelTopologyRegion],
+		Zone:   node.Labels[corev1.LabelTopologyZone],
+	}
+}
+
+// IsWaitForFirstConsumer returns true when the StorageClass delays provisioning until a pod is scheduled.
+func IsWaitForFirstConsumer(sc *storagev1.StorageClass) bool {
+	if sc == nil || sc.VolumeBindingMode == nil {
+		return false
+	}
+	return *sc.VolumeBindingMode == storagev1.VolumeBindingWaitForFirstConsumer
+}
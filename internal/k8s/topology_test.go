@@ -0,0 +1,74 @@
+package k8s
+
+import (
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	storagev1 "k8s.io/api/storage/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+)
+
+func TestTopologyFromNode(t *testing.T) {
+	if got := TopologyFromNode(nil); got != (Topology{}) {
+		t.Fatalf("TopologyFromNode(nil) = %#v, want empty", got)
+	}
+
+	node := &corev1.Node{ObjectMeta: metav1.ObjectMeta{Labels: map[string]string{
+		corev1.LabelTopologyRegion: "westeurope",
+		corev1.LabelTopologyZone:   "westeurope-1",
+	}}}
+	want := Topology{Region: "westeurope", Zone: "westeurope-1"}
+	if got := TopologyFromNode(node); got != want {
+		t.Fatalf("TopologyFromNode = %#v, want %#v", got, want)
+	}
+}
+
+func TestIsWaitForFirstConsumer(t *testing.T) {
+	if IsWaitForFirstConsumer(nil) {
+		t.Fatalf("IsWaitForFirstConsumer(nil) = true, want false")
+	}
+
+	sc := &storagev1.StorageClass{}
+	if IsWaitForFirstConsumer(sc) {
+		t.Fatalf("IsWaitForFirstConsumer(unset) = true, want false")
+	}
+
+	mode := storagev1.VolumeBindingWaitForFirstConsumer
+	sc.VolumeBindingMode = &mode
+	if !IsWaitForFirstConsumer(sc) {
+		t.Fatalf("IsWaitForFirstConsumer(WaitForFirstConsumer) = false, want true")
+	}
+}
+
+func TestBuildPVWithTopology(t *testing.T) {
+	pvc := &corev1.PersistentVolumeClaim{
+		ObjectMeta: metav1.ObjectMeta{Name: "data", Namespace: "team", UID: types.UID("uid-123")},
+		Spec: corev1.PersistentVolumeClaimSpec{
+			Resources: corev1.VolumeResourceRequirements{
+				Requests: corev1.ResourceList{corev1.ResourceStorage: resource.MustParse("1Gi")},
+			},
+		},
+	}
+
+	pv, err := BuildPV(pvc, "share", "rg", "account", "server", corev1.PersistentVolumeReclaimDelete, WithTopology(Topology{Region: "westeurope", Zone: "westeurope-1"}))
+	if err != nil {
+		t.Fatalf("BuildPV error = %v", err)
+	}
+	if pv.Spec.NodeAffinity == nil || pv.Spec.NodeAffinity.Required == nil {
+		t.Fatalf("NodeAffinity = %#v, want required terms", pv.Spec.NodeAffinity)
+	}
+	expr := pv.Spec.NodeAffinity.Required.NodeSelectorTerms[0].MatchExpressions[0]
+	if expr.Key != corev1.LabelTopologyRegion || len(expr.Values) != 1 || expr.Values[0] != "westeurope" {
+		t.Fatalf("MatchExpression = %#v, want region westeurope", expr)
+	}
+
+	pv, err = BuildPV(pvc, "share", "rg", "account", "server", corev1.PersistentVolumeReclaimDelete, WithTopology(Topology{}))
+	if err != nil {
+		t.Fatalf("BuildPV error = %v", err)
+	}
+	if pv.Spec.NodeAffinity != nil {
+		t.Fatalf("NodeAffinity = %#v, want nil without topology", pv.Spec.NodeAffinity)
+	}
+}
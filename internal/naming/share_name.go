@@ -10,16 +10,29 @@ import (
 const (
 	maxShareNameLength = 63
 	hashSuffixLength   = 8
+
+	// BackingSharePrefix starts the name of every backing share. Claim share names never start with it,
+	// so no claim can create a backing share before its class does.
+	BackingSharePrefix = "shared-"
 )
 
 // ComputeShareName generates a deterministic Azure File share name.
-// Invariants: output is lowercase, uses only [a-z0-9-], and never exceeds maxShareNameLength.
+// Invariants: output is lowercase, uses only [a-z0-9-], never exceeds maxShareNameLength and never starts
+// with BackingSharePrefix.
 func ComputeShareName(namespace, pvcName string, override string) (string, error) {
 	base := override
 	if base == "" {
 		base = fmt.Sprintf("%s-%s", namespace, pvcName)
 	}
+	name, err := shortenShareName(base)
+	if err != nil {
+		return "", err
+	}
+	return name, checkReserved(name)
+}
 
+// shortenShareName sanitizes base and, when it is too long, cuts it and appends a hash of the whole name.
+func shortenShareName(base string) (string, error) {
 	sanitized, err := Sanitize(base)
 	if err != nil {
 		return "", fmt.Errorf("sanitize share name: %w", err)
@@ -43,7 +56,58 @@ func ComputeShareName(namespace, pvcName string, override string) (string, error
 	return prefix + suffix, nil
 }
 
+// ComputeUniqueShareName generates the default share name followed by a hash of the claim UID,
+// so a claim deleted and recreated under the same name gets a new share.
+// Invariants: same as ComputeShareName; the UID suffix is always kept whole.
+func ComputeUniqueShareName(namespace, pvcName, uid string) (string, error) {
+	name, err := ComputeDirectoryName(namespace, pvcName, uid)
+	if err != nil {
+		return "", err
+	}
+	return name, checkReserved(name)
+}
+
+// ComputeDirectoryName generates the name of a claim's directory in a backing share: the same name as
+// ComputeUniqueShareName, which directories may take even when it starts with BackingSharePrefix.
+func ComputeDirectoryName(namespace, pvcName, uid string) (string, error) {
+	if uid == "" {
+		return "", fmt.Errorf("uid required: %w", ErrInvalidShareName)
+	}
+	sanitized, err := Sanitize(fmt.Sprintf("%s-%s", namespace, pvcName))
+	if err != nil {
+		return "", fmt.Errorf("sanitize share name: %w", err)
+	}
+
+	suffix := "-" + hashString(uid)
+	if maxPrefixLength := maxShareNameLength - len(suffix); len(sanitized) > maxPrefixLength {
+		sanitized = strings.TrimRight(sanitized[:maxPrefixLength], "-")
+	}
+	return sanitized + suffix, nil
+}
+
+// checkReserved refuses share names that start with BackingSharePrefix.
+func checkReserved(name string) error {
+	if strings.HasPrefix(name, BackingSharePrefix) {
+		return fmt.Errorf("share name %q starts with the reserved prefix %q: %w", name, BackingSharePrefix, ErrInvalidShareName)
+	}
+	return nil
+}
+
 func hashString(value string) string {
 	sum := sha256.Sum256([]byte(value))
 	return hex.EncodeToString(sum[:])[:hashSuffixLength]
 }
+
+// ComputeBackingShareName generates the name of the share holding the claim directories of a StorageClass,
+// or of a StorageClass in one namespace when namespace is set.
+// Invariants: same as ComputeShareName, except that the name always starts with BackingSharePrefix.
+func ComputeBackingShareName(storageClass, namespace string) (string, error) {
+	if storageClass == "" {
+		return "", fmt.Errorf("storage class required: %w", ErrInvalidShareName)
+	}
+	base := BackingSharePrefix + storageClass
+	if namespace != "" {
+		base = fmt.Sprintf("%s%s-%s", BackingSharePrefix, storageClass, namespace)
+	}
+	return shortenShareName(base)
+}
@@ -1,6 +1,10 @@
 package naming
 
-import "testing"
+import (
+	"errors"
+	"strings"
+	"testing"
+)
 
 func TestComputeShareNameDefault(t *testing.T) {
 	got, err := ComputeShareName("team", "data", "")
@@ -58,3 +62,74 @@ func TestComputeShareNameInvalid(t *testing.T) {
 		t.Fatalf("ComputeShareName error = nil, want error")
 	}
 }
+
+func TestComputeUniqueShareName(t *testing.T) {
+	first, err := ComputeUniqueShareName("team", "data", "uid-1")
+	if err != nil {
+		t.Fatalf("ComputeUniqueShareName error = %v", err)
+	}
+	if first != "team-data-"+hashString("uid-1") {
+		t.Fatalf("ComputeUniqueShareName = %q, want %q", first, "team-data-"+hashString("uid-1"))
+	}
+	recreated, err := ComputeUniqueShareName("team", "data", "uid-2")
+	if err != nil {
+		t.Fatalf("ComputeUniqueShareName error = %v", err)
+	}
+	if recreated == first {
+		t.Fatalf("ComputeUniqueShareName = %q for both UIDs, want distinct names", first)
+	}
+
+	long := "this-is-a-very-long-namespace-name-for-testing-share-naming"
+	got, err := ComputeUniqueShareName(long, long, "uid-1")
+	if err != nil {
+		t.Fatalf("ComputeUniqueShareName long error = %v", err)
+	}
+	if len(got) > maxShareNameLength || !strings.HasSuffix(got, "-"+hashString("uid-1")) {
+		t.Fatalf("ComputeUniqueShareName long = %q, want at most %d characters ending in the uid hash", got, maxShareNameLength)
+	}
+
+	if _, err := ComputeUniqueShareName("team", "data", ""); err == nil {
+		t.Fatalf("ComputeUniqueShareName without uid error = nil, want error")
+	}
+}
+
+func TestComputeBackingShareName(t *testing.T) {
+	got, err := ComputeBackingShareName("Azure_Files", "")
+	if err != nil {
+		t.Fatalf("ComputeBackingShareName error = %v", err)
+	}
+	if got != "shared-azure-files" {
+		t.Fatalf("ComputeBackingShareName = %q, want shared-azure-files", got)
+	}
+	got, err = ComputeBackingShareName("azurefile", "team")
+	if err != nil {
+		t.Fatalf("ComputeBackingShareName namespace error = %v", err)
+	}
+	if got != "shared-azurefile-team" {
+		t.Fatalf("ComputeBackingShareName namespace = %q, want shared-azurefile-team", got)
+	}
+
+	if _, err := ComputeBackingShareName("", "team"); err == nil {
+		t.Fatalf("ComputeBackingShareName without class error = nil, want error")
+	}
+}
+
+func TestShareNamesReserveBackingPrefix(t *testing.T) {
+	for name, compute := range map[string]func() (string, error){
+		"default":  func() (string, error) { return ComputeShareName("shared", "azurefile", "") },
+		"override": func() (string, error) { return ComputeShareName("team", "data", "Shared_AzureFile") },
+		"unique":   func() (string, error) { return ComputeUniqueShareName("shared", "azurefile", "uid-1") },
+	} {
+		if got, err := compute(); !errors.Is(err, ErrInvalidShareName) {
+			t.Fatalf("%s share name = %q, %v, want ErrInvalidShareName", name, got, err)
+		}
+	}
+
+	got, err := ComputeDirectoryName("shared", "azurefile", "uid-1")
+	if err != nil {
+		t.Fatalf("ComputeDirectoryName error = %v", err)
+	}
+	if got != "shared-azurefile-"+hashString("uid-1") {
+		t.Fatalf("ComputeDirectoryName = %q, want %q", got, "shared-azurefile-"+hashString("uid-1"))
+	}
+}
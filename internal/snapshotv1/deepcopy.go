@@ -0,0 +1,158 @@
+package snapshotv1
+
+import (
+	"k8s.io/apimachinery/pkg/runtime"
+)
+
+// DeepCopyInto copies the receiver into out.
+func (in *VolumeSnapshotContent) DeepCopyInto(out *VolumeSnapshotContent) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
+	in.Spec.DeepCopyInto(&out.Spec)
+	if in.Status != nil {
+		out.Status = new(VolumeSnapshotContentStatus)
+		in.Status.DeepCopyInto(out.Status)
+	}
+}
+
+// DeepCopy returns a deep copy of the VolumeSnapshotContent.
+func (in *VolumeSnapshotContent) DeepCopy() *VolumeSnapshotContent {
+	if in == nil {
+		return nil
+	}
+	out := new(VolumeSnapshotContent)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject implements runtime.Object.
+func (in *VolumeSnapshotContent) DeepCopyObject() runtime.Object {
+	return in.DeepCopy()
+}
+
+// DeepCopyInto copies the receiver into out.
+func (in *VolumeSnapshotContentSpec) DeepCopyInto(out *VolumeSnapshotContentSpec) {
+	*out = *in
+	out.VolumeSnapshotClassName = copyPtr(in.VolumeSnapshotClassName)
+	out.Source.VolumeHandle = copyPtr(in.Source.VolumeHandle)
+	out.Source.SnapshotHandle = copyPtr(in.Source.SnapshotHandle)
+}
+
+// DeepCopyInto copies the receiver into out.
+func (in *VolumeSnapshotContentStatus) DeepCopyInto(out *VolumeSnapshotContentStatus) {
+	*out = *in
+	out.SnapshotHandle = copyPtr(in.SnapshotHandle)
+	out.CreationTime = copyPtr(in.CreationTime)
+	out.RestoreSize = copyPtr(in.RestoreSize)
+	out.ReadyToUse = copyPtr(in.ReadyToUse)
+	out.Error = in.Error.DeepCopy()
+}
+
+// DeepCopy returns a deep copy of the VolumeSnapshotError.
+func (in *VolumeSnapshotError) DeepCopy() *VolumeSnapshotError {
+	if in == nil {
+		return nil
+	}
+	out := &VolumeSnapshotError{Message: copyPtr(in.Message)}
+	if in.Time != nil {
+		out.Time = in.Time.DeepCopy()
+	}
+	return out
+}
+
+// DeepCopyInto copies the receiver into out.
+func (in *VolumeSnapshotContentList) DeepCopyInto(out *VolumeSnapshotContentList) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ListMeta.DeepCopyInto(&out.ListMeta)
+	if in.Items != nil {
+		out.Items = make([]VolumeSnapshotContent, len(in.Items))
+		for i := range in.Items {
+			in.Items[i].DeepCopyInto(&out.Items[i])
+		}
+	}
+}
+
+// DeepCopy returns a deep copy of the VolumeSnapshotContentList.
+func (in *VolumeSnapshotContentList) DeepCopy() *VolumeSnapshotContentList {
+	if in == nil {
+		return nil
+	}
+	out := new(VolumeSnapshotContentList)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject implements runtime.Object.
+func (in *VolumeSnapshotContentList) DeepCopyObject() runtime.Object {
+	return in.DeepCopy()
+}
+
+// DeepCopyInto copies the receiver into out.
+func (in *VolumeSnapshot) DeepCopyInto(out *VolumeSnapshot) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
+	out.Spec.VolumeSnapshotClassName = copyPtr(in.Spec.VolumeSnapshotClassName)
+	out.Spec.Source.PersistentVolumeClaimName = copyPtr(in.Spec.Source.PersistentVolumeClaimName)
+	out.Spec.Source.VolumeSnapshotContentName = copyPtr(in.Spec.Source.VolumeSnapshotContentName)
+	if in.Status != nil {
+		out.Status = &VolumeSnapshotStatus{
+			BoundVolumeSnapshotContentName: copyPtr(in.Status.BoundVolumeSnapshotContentName),
+			ReadyToUse:                     copyPtr(in.Status.ReadyToUse),
+			Error:                          in.Status.Error.DeepCopy(),
+		}
+	}
+}
+
+// DeepCopy returns a deep copy of the VolumeSnapshot.
+func (in *VolumeSnapshot) DeepCopy() *VolumeSnapshot {
+	if in == nil {
+		return nil
+	}
+	out := new(VolumeSnapshot)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject implements runtime.Object.
+func (in *VolumeSnapshot) DeepCopyObject() runtime.Object {
+	return in.DeepCopy()
+}
+
+// DeepCopyInto copies the receiver into out.
+func (in *VolumeSnapshotList) DeepCopyInto(out *VolumeSnapshotList) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ListMeta.DeepCopyInto(&out.ListMeta)
+	if in.Items != nil {
+		out.Items = make([]VolumeSnapshot, len(in.Items))
+		for i := range in.Items {
+			in.Items[i].DeepCopyInto(&out.Items[i])
+		}
+	}
+}
+
+// DeepCopy returns a deep copy of the VolumeSnapshotList.
+func (in *VolumeSnapshotList) DeepCopy() *VolumeSnapshotList {
+	if in == nil {
+		return nil
+	}
+	out := new(VolumeSnapshotList)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject implements runtime.Object.
+func (in *VolumeSnapshotList) DeepCopyObject() runtime.Object {
+	return in.DeepCopy()
+}
+
+func copyPtr[T any](in *T) *T {
+	if in == nil {
+		return nil
+	}
+	out := *in
+	return &out
+}
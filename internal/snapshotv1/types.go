@@ -0,0 +1,117 @@
+// Package snapshotv1 holds the part of the snapshot.storage.k8s.io/v1 API (the CSI external-snapshotter
+// CRDs) that the controller works with. Fields the controller does not use are left out, so objects must
+// only be changed through merge patches, which leave the fields unknown here untouched.
+package snapshotv1
+
+import (
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"sigs.k8s.io/controller-runtime/pkg/scheme"
+)
+
+// GroupVersion of the VolumeSnapshot API.
+var GroupVersion = schema.GroupVersion{Group: "snapshot.storage.k8s.io", Version: "v1"}
+
+var (
+	SchemeBuilder = &scheme.Builder{GroupVersion: GroupVersion}
+	AddToScheme   = SchemeBuilder.AddToScheme
+)
+
+func init() {
+	SchemeBuilder.Register(&VolumeSnapshotContent{}, &VolumeSnapshotContentList{}, &VolumeSnapshot{}, &VolumeSnapshotList{})
+}
+
+// DeletionPolicy decides whether the backing snapshot is deleted with its VolumeSnapshotContent.
+// The snapshot controller copies it from the VolumeSnapshotClass.
+type DeletionPolicy string
+
+const (
+	VolumeSnapshotContentDelete DeletionPolicy = "Delete"
+	VolumeSnapshotContentRetain DeletionPolicy = "Retain"
+)
+
+// VolumeSnapshotContent is the cluster-scoped record of a snapshot taken by a driver.
+type VolumeSnapshotContent struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec   VolumeSnapshotContentSpec    `json:"spec"`
+	Status *VolumeSnapshotContentStatus `json:"status,omitempty"`
+}
+
+// VolumeSnapshotContentSpec names the driver and either the volume to snapshot or an existing snapshot.
+type VolumeSnapshotContentSpec struct {
+	VolumeSnapshotRef       corev1.ObjectReference      `json:"volumeSnapshotRef"`
+	DeletionPolicy          DeletionPolicy              `json:"deletionPolicy"`
+	Driver                  string                      `json:"driver"`
+	VolumeSnapshotClassName *string                     `json:"volumeSnapshotClassName,omitempty"`
+	Source                  VolumeSnapshotContentSource `json:"source"`
+}
+
+// VolumeSnapshotContentSource holds exactly one of VolumeHandle (dynamic) and SnapshotHandle (pre-provisioned).
+type VolumeSnapshotContentSource struct {
+	VolumeHandle   *string `json:"volumeHandle,omitempty"`
+	SnapshotHandle *string `json:"snapshotHandle,omitempty"`
+}
+
+// VolumeSnapshotContentStatus is reported by the driver. CreationTime is in nanoseconds since the epoch
+// and RestoreSize in bytes.
+type VolumeSnapshotContentStatus struct {
+	SnapshotHandle *string              `json:"snapshotHandle,omitempty"`
+	CreationTime   *int64               `json:"creationTime,omitempty"`
+	RestoreSize    *int64               `json:"restoreSize,omitempty"`
+	ReadyToUse     *bool                `json:"readyToUse,omitempty"`
+	Error          *VolumeSnapshotError `json:"error,omitempty"`
+}
+
+// VolumeSnapshotError describes the last failure to take or look up the snapshot.
+type VolumeSnapshotError struct {
+	Time    *metav1.Time `json:"time,omitempty"`
+	Message *string      `json:"message,omitempty"`
+}
+
+// VolumeSnapshotContentList is a list of VolumeSnapshotContents.
+type VolumeSnapshotContentList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+
+	Items []VolumeSnapshotContent `json:"items"`
+}
+
+// VolumeSnapshot is a user's request for a snapshot of a claim. The controller only reads it, to find
+// the VolumeSnapshotContent a claim's dataSource is restored from.
+type VolumeSnapshot struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec   VolumeSnapshotSpec    `json:"spec"`
+	Status *VolumeSnapshotStatus `json:"status,omitempty"`
+}
+
+// VolumeSnapshotSpec names the claim to snapshot or the pre-provisioned content to bind to.
+type VolumeSnapshotSpec struct {
+	Source                  VolumeSnapshotSource `json:"source"`
+	VolumeSnapshotClassName *string              `json:"volumeSnapshotClassName,omitempty"`
+}
+
+// VolumeSnapshotSource holds exactly one of PersistentVolumeClaimName and VolumeSnapshotContentName.
+type VolumeSnapshotSource struct {
+	PersistentVolumeClaimName *string `json:"persistentVolumeClaimName,omitempty"`
+	VolumeSnapshotContentName *string `json:"volumeSnapshotContentName,omitempty"`
+}
+
+// VolumeSnapshotStatus is reported by the snapshot controller.
+type VolumeSnapshotStatus struct {
+	BoundVolumeSnapshotContentName *string              `json:"boundVolumeSnapshotContentName,omitempty"`
+	ReadyToUse                     *bool                `json:"readyToUse,omitempty"`
+	Error                          *VolumeSnapshotError `json:"error,omitempty"`
+}
+
+// VolumeSnapshotList is a list of VolumeSnapshots.
+type VolumeSnapshotList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+
+	Items []VolumeSnapshot `json:"items"`
+}
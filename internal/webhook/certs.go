@@ -0,0 +1,321 @@
+package webhook
+
+import (
+	"bytes"
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"math/big"
+	"os"
+	"path/filepath"
+	"time"
+
+	admissionregistrationv1 "k8s.io/api/admissionregistration/v1"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+)
+
+const (
+	caValidity   = 10 * 365 * 24 * time.Hour
+	certValidity = 365 * 24 * time.Hour
+	// renewBefore is how long before expiry a certificate is replaced.
+	renewBefore = 30 * 24 * time.Hour
+
+	secretCACert = "ca.crt"
+	secretCAKey  = "ca.key"
+)
+
+var ErrInvalidCertificate = errors.New("invalid webhook certificate")
+
+// CertManager issues the webhook serving certificate from a self-signed CA kept in a Secret,
+// writes it to the webhook server's certificate directory and publishes the CA as the caBundle
+// of the ValidatingWebhookConfiguration. Every replica runs it; the Secret makes them share one CA.
+// Client must not be the manager's cached client, which only caches the key Secrets.
+type CertManager struct {
+	Client      client.Client
+	Namespace   string
+	ServiceName string
+	SecretName  string
+	WebhookName string
+	CertDir     string
+	// Interval is how often the certificate is checked for renewal.
+	Interval time.Duration
+}
+
+// Start re-checks the certificate every Interval until ctx is cancelled.
+// The webhook server picks up rewritten files through its certificate watcher.
+func (m *CertManager) Start(ctx context.Context) error {
+	logger := log.FromContext(ctx).WithName("webhook-certs")
+	ticker := time.NewTicker(m.Interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+			if err := m.Ensure(ctx); err != nil {
+				logger.Error(err, "ensure webhook certificate")
+			}
+		}
+	}
+}
+
+// NeedLeaderElection is false: every replica serves the webhook and needs the certificate on disk.
+func (m *CertManager) NeedLeaderElection() bool {
+	return false
+}
+
+// Ensure makes sure a valid certificate exists in the Secret, on disk and in the caBundle.
+func (m *CertManager) Ensure(ctx context.Context) error {
+	secret, err := m.ensureSecret(ctx, time.Now())
+	if err != nil {
+		return err
+	}
+	if err := m.writeFiles(secret); err != nil {
+		return err
+	}
+	return m.ensureCABundle(ctx, secret.Data[secretCACert])
+}
+
+// ensureSecret returns the certificate Secret, creating it or renewing expiring certificates.
+// The CA is kept while it is valid so that replicas still serving the previous certificate stay trusted.
+func (m *CertManager) ensureSecret(ctx context.Context, now time.Time) (*corev1.Secret, error) {
+	secret := &corev1.Secret{}
+	err := m.Client.Get(ctx, client.ObjectKey{Namespace: m.Namespace, Name: m.SecretName}, secret)
+	if apierrors.IsNotFound(err) {
+		data, err := m.issue(nil, now)
+		if err != nil {
+			return nil, err
+		}
+		secret = &corev1.Secret{
+			ObjectMeta: metav1.ObjectMeta{Namespace: m.Namespace, Name: m.SecretName},
+			Type:       corev1.SecretTypeTLS,
+			Data:       data,
+		}
+		if err := m.Client.Create(ctx, secret); err != nil {
+			if apierrors.IsAlreadyExists(err) {
+				// Another replica won the race; use its certificate.
+				return m.ensureSecret(ctx, now)
+			}
+			return nil, fmt.Errorf("create certificate secret: %w", err)
+		}
+		return secret, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("get certificate secret: %w", err)
+	}
+
+	if m.certValid(secret.Data, now) {
+		return secret, nil
+	}
+	// An unusable or expiring CA is replaced together with the serving certificate.
+	ca, _ := parseCA(secret.Data, now)
+	data, err := m.issue(ca, now)
+	if err != nil {
+		return nil, err
+	}
+	secret.Data = data
+	if err := m.Client.Update(ctx, secret); err != nil {
+		return nil, fmt.Errorf("update certificate secret: %w", err)
+	}
+	return secret, nil
+}
+
+// certValid reports whether the serving certificate is signed by the stored CA, covers the
+// service names and is not about to expire.
+func (m *CertManager) certValid(data map[string][]byte, now time.Time) bool {
+	ca, err := parseCA(data, now)
+	if err != nil {
+		return false
+	}
+	cert, err := parseCertificate(data[corev1.TLSCertKey])
+	if err != nil || now.Add(renewBefore).After(cert.NotAfter) {
+		return false
+	}
+	roots := x509.NewCertPool()
+	roots.AddCert(ca.cert)
+	_, err = cert.Verify(x509.VerifyOptions{
+		DNSName:     m.serviceHost(),
+		Roots:       roots,
+		CurrentTime: now,
+	})
+	return err == nil
+}
+
+type certificateAuthority struct {
+	cert *x509.Certificate
+	key  *ecdsa.PrivateKey
+}
+
+// issue signs a new serving certificate, with a new CA when ca is nil, and returns the Secret data.
+func (m *CertManager) issue(ca *certificateAuthority, now time.Time) (map[string][]byte, error) {
+	if ca == nil {
+		key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+		if err != nil {
+			return nil, fmt.Errorf("generate ca key: %w", err)
+		}
+		serial, err := serialNumber()
+		if err != nil {
+			return nil, err
+		}
+		template := &x509.Certificate{
+			SerialNumber:          serial,
+			Subject:               pkix.Name{CommonName: m.ServiceName + "-ca"},
+			NotBefore:             now.Add(-time.Hour),
+			NotAfter:              now.Add(caValidity),
+			KeyUsage:              x509.KeyUsageCertSign | x509.KeyUsageDigitalSignature,
+			BasicConstraintsValid: true,
+			IsCA:                  true,
+		}
+		der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+		if err != nil {
+			return nil, fmt.Errorf("create ca certificate: %w", err)
+		}
+		cert, err := x509.ParseCertificate(der)
+		if err != nil {
+			return nil, fmt.Errorf("parse ca certificate: %w", err)
+		}
+		ca = &certificateAuthority{cert: cert, key: key}
+	}
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return nil, fmt.Errorf("generate serving key: %w", err)
+	}
+	serial, err := serialNumber()
+	if err != nil {
+		return nil, err
+	}
+	template := &x509.Certificate{
+		SerialNumber: serial,
+		Subject:      pkix.Name{CommonName: m.serviceHost()},
+		DNSNames:     m.dnsNames(),
+		NotBefore:    now.Add(-time.Hour),
+		NotAfter:     now.Add(certValidity),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, ca.cert, &key.PublicKey, ca.key)
+	if err != nil {
+		return nil, fmt.Errorf("create serving certificate: %w", err)
+	}
+
+	caKey, err := x509.MarshalECPrivateKey(ca.key)
+	if err != nil {
+		return nil, fmt.Errorf("marshal ca key: %w", err)
+	}
+	servingKey, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		return nil, fmt.Errorf("marshal serving key: %w", err)
+	}
+	return map[string][]byte{
+		secretCACert:            pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: ca.cert.Raw}),
+		secretCAKey:             pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: caKey}),
+		corev1.TLSCertKey:       pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}),
+		corev1.TLSPrivateKeyKey: pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: servingKey}),
+	}, nil
+}
+
+// serviceHost is the name the API server dials for a webhook service reference.
+func (m *CertManager) serviceHost() string {
+	return m.ServiceName + "." + m.Namespace + ".svc"
+}
+
+// dnsNames are the names the serving certificate is valid for.
+func (m *CertManager) dnsNames() []string {
+	return []string{
+		m.serviceHost(),
+		m.serviceHost() + ".cluster.local",
+		m.ServiceName + "." + m.Namespace,
+		m.ServiceName,
+	}
+}
+
+// writeFiles stores the serving certificate and key in CertDir, leaving unchanged files alone.
+func (m *CertManager) writeFiles(secret *corev1.Secret) error {
+	if err := os.MkdirAll(m.CertDir, 0o700); err != nil {
+		return fmt.Errorf("create certificate directory: %w", err)
+	}
+	for _, name := range []string{corev1.TLSCertKey, corev1.TLSPrivateKeyKey} {
+		path := filepath.Join(m.CertDir, name)
+		if current, err := os.ReadFile(path); err == nil && bytes.Equal(current, secret.Data[name]) {
+			continue
+		}
+		if err := os.WriteFile(path, secret.Data[name], 0o600); err != nil {
+			return fmt.Errorf("write %s: %w", name, err)
+		}
+	}
+	return nil
+}
+
+// ensureCABundle publishes the CA on every webhook of the ValidatingWebhookConfiguration.
+func (m *CertManager) ensureCABundle(ctx context.Context, caBundle []byte) error {
+	config := &admissionregistrationv1.ValidatingWebhookConfiguration{}
+	if err := m.Client.Get(ctx, client.ObjectKey{Name: m.WebhookName}, config); err != nil {
+		return fmt.Errorf("get validating webhook configuration: %w", err)
+	}
+	patch := client.MergeFromWithOptions(config.DeepCopy(), client.MergeFromWithOptimisticLock{})
+	changed := false
+	for i := range config.Webhooks {
+		if !bytes.Equal(config.Webhooks[i].ClientConfig.CABundle, caBundle) {
+			config.Webhooks[i].ClientConfig.CABundle = caBundle
+			changed = true
+		}
+	}
+	if !changed {
+		return nil
+	}
+	if err := m.Client.Patch(ctx, config, patch); err != nil {
+		return fmt.Errorf("patch webhook ca bundle: %w", err)
+	}
+	return nil
+}
+
+// parseCA reads the CA from Secret data; CAs close to expiry are treated as invalid.
+func parseCA(data map[string][]byte, now time.Time) (*certificateAuthority, error) {
+	cert, err := parseCertificate(data[secretCACert])
+	if err != nil {
+		return nil, err
+	}
+	if !cert.IsCA || now.Add(renewBefore).After(cert.NotAfter) {
+		return nil, fmt.Errorf("ca expired or not a CA: %w", ErrInvalidCertificate)
+	}
+	block, _ := pem.Decode(data[secretCAKey])
+	if block == nil {
+		return nil, fmt.Errorf("ca key missing: %w", ErrInvalidCertificate)
+	}
+	key, err := x509.ParseECPrivateKey(block.Bytes)
+	if err != nil {
+		return nil, fmt.Errorf("parse ca key: %w", errors.Join(err, ErrInvalidCertificate))
+	}
+	return &certificateAuthority{cert: cert, key: key}, nil
+}
+
+func parseCertificate(data []byte) (*x509.Certificate, error) {
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, fmt.Errorf("certificate missing: %w", ErrInvalidCertificate)
+	}
+	cert, err := x509.ParseCertificate(block.Bytes)
+	if err != nil {
+		return nil, fmt.Errorf("parse certificate: %w", errors.Join(err, ErrInvalidCertificate))
+	}
+	return cert, nil
+}
+
+func serialNumber() (*big.Int, error) {
+	serial, err := rand.Int(rand.Reader, new(big.Int).Lsh(big.NewInt(1), 127))
+	if err != nil {
+		return nil, fmt.Errorf("generate serial number: %w", err)
+	}
+	return serial, nil
+}
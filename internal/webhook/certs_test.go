@@ -0,0 +1,131 @@
+package webhook
+
+import (
+	"bytes"
+	"context"
+	"crypto/x509"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	admissionregistrationv1 "k8s.io/api/admissionregistration/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+func TestCertManagerEnsure(t *testing.T) {
+	manager, k8sClient := newCertManager(t)
+	ctx := context.Background()
+
+	if err := manager.Ensure(ctx); err != nil {
+		t.Fatalf("Ensure error = %v", err)
+	}
+
+	secret := &corev1.Secret{}
+	if err := k8sClient.Get(ctx, client.ObjectKey{Namespace: "system", Name: "webhook-cert"}, secret); err != nil {
+		t.Fatalf("Get secret error = %v", err)
+	}
+	onDisk, err := os.ReadFile(filepath.Join(manager.CertDir, corev1.TLSCertKey))
+	if err != nil {
+		t.Fatalf("ReadFile error = %v", err)
+	}
+	if !bytes.Equal(onDisk, secret.Data[corev1.TLSCertKey]) {
+		t.Fatalf("certificate on disk differs from the secret")
+	}
+
+	config := &admissionregistrationv1.ValidatingWebhookConfiguration{}
+	if err := k8sClient.Get(ctx, client.ObjectKey{Name: "validating"}, config); err != nil {
+		t.Fatalf("Get webhook configuration error = %v", err)
+	}
+	for _, hook := range config.Webhooks {
+		if !bytes.Equal(hook.ClientConfig.CABundle, secret.Data[secretCACert]) {
+			t.Fatalf("webhook %s caBundle not set", hook.Name)
+		}
+	}
+
+	cert, err := parseCertificate(secret.Data[corev1.TLSCertKey])
+	if err != nil {
+		t.Fatalf("parseCertificate error = %v", err)
+	}
+	ca, err := parseCertificate(secret.Data[secretCACert])
+	if err != nil {
+		t.Fatalf("parse ca error = %v", err)
+	}
+	roots := x509.NewCertPool()
+	roots.AddCert(ca)
+	if _, err := cert.Verify(x509.VerifyOptions{DNSName: "webhook.system.svc", Roots: roots}); err != nil {
+		t.Fatalf("Verify error = %v", err)
+	}
+
+	// A second run keeps the existing certificate.
+	if err := manager.Ensure(ctx); err != nil {
+		t.Fatalf("second Ensure error = %v", err)
+	}
+	again := &corev1.Secret{}
+	if err := k8sClient.Get(ctx, client.ObjectKeyFromObject(secret), again); err != nil {
+		t.Fatalf("Get secret error = %v", err)
+	}
+	if !bytes.Equal(again.Data[corev1.TLSCertKey], secret.Data[corev1.TLSCertKey]) {
+		t.Fatalf("certificate reissued, want kept")
+	}
+}
+
+func TestCertManagerRenewsExpiringCertificate(t *testing.T) {
+	manager, k8sClient := newCertManager(t)
+	ctx := context.Background()
+
+	issued := time.Now().Add(-certValidity + renewBefore/2)
+	if _, err := manager.ensureSecret(ctx, issued); err != nil {
+		t.Fatalf("ensureSecret error = %v", err)
+	}
+	secret := &corev1.Secret{}
+	if err := k8sClient.Get(ctx, client.ObjectKey{Namespace: "system", Name: "webhook-cert"}, secret); err != nil {
+		t.Fatalf("Get secret error = %v", err)
+	}
+
+	if err := manager.Ensure(ctx); err != nil {
+		t.Fatalf("Ensure error = %v", err)
+	}
+	renewed := &corev1.Secret{}
+	if err := k8sClient.Get(ctx, client.ObjectKeyFromObject(secret), renewed); err != nil {
+		t.Fatalf("Get secret error = %v", err)
+	}
+	if bytes.Equal(renewed.Data[corev1.TLSCertKey], secret.Data[corev1.TLSCertKey]) {
+		t.Fatalf("expiring certificate not renewed")
+	}
+	if !bytes.Equal(renewed.Data[secretCACert], secret.Data[secretCACert]) {
+		t.Fatalf("valid CA replaced, want kept")
+	}
+}
+
+func newCertManager(t *testing.T) (*CertManager, client.Client) {
+	t.Helper()
+	scheme := runtime.NewScheme()
+	if err := corev1.AddToScheme(scheme); err != nil {
+		t.Fatalf("AddToScheme corev1: %v", err)
+	}
+	if err := admissionregistrationv1.AddToScheme(scheme); err != nil {
+		t.Fatalf("AddToScheme admissionregistrationv1: %v", err)
+	}
+	config := &admissionregistrationv1.ValidatingWebhookConfiguration{
+		ObjectMeta: metav1.ObjectMeta{Name: "validating"},
+		Webhooks: []admissionregistrationv1.ValidatingWebhook{
+			{Name: "pvc.example.com"},
+			{Name: "storageclass.example.com"},
+		},
+	}
+	k8sClient := fake.NewClientBuilder().WithScheme(scheme).WithObjects(config).Build()
+	return &CertManager{
+		Client:      k8sClient,
+		Namespace:   "system",
+		ServiceName: "webhook",
+		SecretName:  "webhook-cert",
+		WebhookName: "validating",
+		CertDir:     t.TempDir(),
+		Interval:    time.Hour,
+	}, k8sClient
+}
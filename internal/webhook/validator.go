@@ -0,0 +1,247 @@
+package webhook
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	storagev1 "k8s.io/api/storage/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	ctrlwebhook "sigs.k8s.io/controller-runtime/pkg/webhook"
+	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
+
+	"aks-azureFiles-controller/internal/constants"
+	"aks-azureFiles-controller/internal/k8s"
+	"aks-azureFiles-controller/internal/naming"
+)
+
+// Paths the validating webhooks are served on; they must match the ValidatingWebhookConfiguration.
+const (
+	PVCPath          = "/validate-persistentvolumeclaim"
+	StorageClassPath = "/validate-storageclass"
+)
+
+var ErrAnnotationProtected = errors.New("annotation is managed by the provisioner")
+var ErrShareOverrideImmutable = errors.New("share override cannot change once the claim is provisioned")
+
+// protectedAnnotations are written by the controller only; deletion trusts them to locate the share.
+var protectedAnnotations = []string{constants.ShareNameAnnotation, constants.VolumeHandleAnnotation, constants.DeletionSnapshotAnnotation, constants.CloneProgressAnnotation, constants.CloneSnapshotAnnotation}
+
+// Register serves the PVC and StorageClass validators on the webhook server.
+func Register(server ctrlwebhook.Server, scheme *runtime.Scheme, reader client.Reader, controllerUsername string, deletionGracePeriod time.Duration) {
+	server.Register(PVCPath, admission.WithCustomValidator(scheme, &corev1.PersistentVolumeClaim{}, &PVCValidator{
+		Client:              reader,
+		ControllerUsername:  controllerUsername,
+		DeletionGracePeriod: deletionGracePeriod,
+	}))
+	server.Register(StorageClassPath, admission.WithCustomValidator(scheme, &storagev1.StorageClass{}, &StorageClassValidator{
+		DeletionGracePeriod: deletionGracePeriod,
+	}))
+}
+
+// PVCValidator rejects claims of managed StorageClasses that the controller would refuse,
+// and keeps users other than the controller from writing its annotations.
+type PVCValidator struct {
+	Client client.Reader
+	// ControllerUsername is the controller's service account user (system:serviceaccount:<ns>:<name>).
+	ControllerUsername string
+	// DeletionGracePeriod is the controller's share deletion grace period; snapshot-before-delete "true" needs one.
+	DeletionGracePeriod time.Duration
+}
+
+var _ admission.CustomValidator = &PVCValidator{}
+
+// ValidateCreate applies the provisioning rules to a new claim.
+func (v *PVCValidator) ValidateCreate(ctx context.Context, obj runtime.Object) (admission.Warnings, error) {
+	pvc, ok := obj.(*corev1.PersistentVolumeClaim)
+	if !ok {
+		return nil, fmt.Errorf("expected a PersistentVolumeClaim, got %T", obj)
+	}
+	if err := v.checkProtectedAnnotations(ctx, nil, pvc); err != nil {
+		return nil, err
+	}
+	return v.validateClaim(ctx, pvc)
+}
+
+// ValidateUpdate guards the controller's annotations and re-checks a changed share override or snapshot
+// policy. The override is fixed once the claim has a share or a volume: the controller would move the claim
+// to the new share and leave the old one behind. The rest of the claim is not re-validated so the controller
+// can always patch existing claims.
+func (v *PVCValidator) ValidateUpdate(ctx context.Context, oldObj, newObj runtime.Object) (admission.Warnings, error) {
+	oldPVC, ok := oldObj.(*corev1.PersistentVolumeClaim)
+	if !ok {
+		return nil, fmt.Errorf("expected a PersistentVolumeClaim, got %T", oldObj)
+	}
+	pvc, ok := newObj.(*corev1.PersistentVolumeClaim)
+	if !ok {
+		return nil, fmt.Errorf("expected a PersistentVolumeClaim, got %T", newObj)
+	}
+	if err := v.checkProtectedAnnotations(ctx, oldPVC, pvc); err != nil {
+		return nil, err
+	}
+	override := pvc.Annotations[constants.ShareOverrideAnnotation]
+	if override != oldPVC.Annotations[constants.ShareOverrideAnnotation] {
+		if oldPVC.Annotations[constants.ShareNameAnnotation] != "" || oldPVC.Spec.VolumeName != "" {
+			return nil, fmt.Errorf("annotation %s: %w", constants.ShareOverrideAnnotation, ErrShareOverrideImmutable)
+		}
+		if _, err := naming.ComputeShareName(pvc.Namespace, pvc.Name, override); err != nil {
+			return nil, fmt.Errorf("annotation %s: %w", constants.ShareOverrideAnnotation, err)
+		}
+	}
+	if policy, ok := pvc.Annotations[constants.SnapshotBeforeDeleteAnnotation]; ok && policy != oldPVC.Annotations[constants.SnapshotBeforeDeleteAnnotation] {
+		parsed, err := k8s.ParseSnapshotPolicy(constants.SnapshotBeforeDeleteAnnotation, policy)
+		if err == nil {
+			err = k8s.CheckSnapshotPolicy(constants.SnapshotBeforeDeleteAnnotation, parsed, v.DeletionGracePeriod)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("annotation %w", err)
+		}
+	}
+	return nil, nil
+}
+
+// ValidateDelete allows every deletion; cleanup is the controller's job.
+func (v *PVCValidator) ValidateDelete(context.Context, runtime.Object) (admission.Warnings, error) {
+	return nil, nil
+}
+
+// validateClaim runs the checks handleProvisioning would otherwise report as terminal events.
+// Claims of other provisioners pass; claims of a missing StorageClass pass with a warning.
+func (v *PVCValidator) validateClaim(ctx context.Context, pvc *corev1.PersistentVolumeClaim) (admission.Warnings, error) {
+	if !k8s.IsManagedPVC(pvc) {
+		return nil, nil
+	}
+	storageClass, err := k8s.GetStorageClass(ctx, v.Client, pvc)
+	if err != nil {
+		if apierrors.IsNotFound(err) {
+			return admission.Warnings{fmt.Sprintf("storageclass %q not found; claim not validated", *pvc.Spec.StorageClassName)}, nil
+		}
+		return nil, fmt.Errorf("get storageclass: %w", err)
+	}
+	if k8s.GetProvisioner(storageClass) != k8s.ManagedProvisioner {
+		return nil, nil
+	}
+
+	var errs []error
+	params, err := k8s.ParseShareParameters(storageClass.Parameters)
+	if err != nil {
+		errs = append(errs, fmt.Errorf("storageclass %q: %w", storageClass.Name, err))
+	}
+	if err := k8s.ValidateClaim(pvc); err != nil {
+		errs = append(errs, err)
+	}
+	if _, err := k8s.QuotaGiBFromPVC(pvc); err != nil {
+		errs = append(errs, err)
+	}
+	// Adopted shares keep their name and claims of subdirectory classes get a directory, not a share.
+	if pvc.Annotations[constants.AdoptShareAnnotation] == "" && !params.UsesSubdirectories() {
+		if _, err := naming.ComputeShareName(pvc.Namespace, pvc.Name, pvc.Annotations[constants.ShareOverrideAnnotation]); err != nil {
+			errs = append(errs, fmt.Errorf("annotation %s: %w", constants.ShareOverrideAnnotation, err))
+		}
+	}
+	if pvc.Annotations[constants.RestoreFromAnnotation] != "" && pvc.Annotations[constants.ShareOverrideAnnotation] != "" {
+		errs = append(errs, fmt.Errorf("annotations %s and %s are mutually exclusive", constants.RestoreFromAnnotation, constants.ShareOverrideAnnotation))
+	}
+	if policy, ok := pvc.Annotations[constants.SnapshotBeforeDeleteAnnotation]; ok {
+		if _, err := k8s.ParseSnapshotPolicy(constants.SnapshotBeforeDeleteAnnotation, policy); err != nil {
+			errs = append(errs, fmt.Errorf("annotation %w", err))
+		}
+	}
+	key, policy := k8s.ClaimSnapshotPolicy(pvc, params)
+	if err := k8s.CheckSnapshotPolicy(key, policy, v.DeletionGracePeriod); err != nil {
+		errs = append(errs, err)
+	}
+	if account := pvc.Annotations[constants.StorageAccountAnnotation]; account != "" && !params.AllowsStorageAccount(account) {
+		errs = append(errs, fmt.Errorf("annotation %s: account %q not in the class allow-list", constants.StorageAccountAnnotation, account))
+	}
+	if adopted := pvc.Annotations[constants.AdoptShareAnnotation]; adopted != "" {
+		account, _, err := k8s.ParseAdoptedShare(adopted)
+		switch {
+		case err != nil:
+			errs = append(errs, fmt.Errorf("annotation %s: %w", constants.AdoptShareAnnotation, err))
+		case account != "" && !params.AllowsStorageAccount(account):
+			errs = append(errs, fmt.Errorf("annotation %s: account %q not in the class allow-list", constants.AdoptShareAnnotation, account))
+		}
+		if pvc.Annotations[constants.ShareOverrideAnnotation] != "" || pvc.Annotations[constants.RestoreFromAnnotation] != "" || pvc.Spec.DataSource != nil || pvc.Spec.DataSourceRef != nil {
+			errs = append(errs, fmt.Errorf("annotation %s cannot be combined with %s, %s or a data source",
+				constants.AdoptShareAnnotation, constants.ShareOverrideAnnotation, constants.RestoreFromAnnotation))
+		}
+	}
+	if pvc.Annotations[constants.TransferOwnershipAnnotation] == "true" && !params.AllowOwnershipTransfer {
+		errs = append(errs, fmt.Errorf("annotation %s requires the class parameter %s", constants.TransferOwnershipAnnotation, k8s.ParamAllowOwnershipTransfer))
+	}
+	if params.UsesSubdirectories() {
+		for _, key := range []string{constants.ShareOverrideAnnotation, constants.AdoptShareAnnotation, constants.RestoreFromAnnotation} {
+			if pvc.Annotations[key] != "" {
+				errs = append(errs, fmt.Errorf("annotation %s cannot be used with %s classes", key, k8s.ParamSubdirectoryShare))
+			}
+		}
+		if pvc.Spec.DataSource != nil || pvc.Spec.DataSourceRef != nil {
+			errs = append(errs, fmt.Errorf("a data source cannot be used with %s classes", k8s.ParamSubdirectoryShare))
+		}
+	}
+	return nil, errors.Join(errs...)
+}
+
+// checkProtectedAnnotations refuses changes to controller-owned annotations by anyone but the controller.
+func (v *PVCValidator) checkProtectedAnnotations(ctx context.Context, oldPVC, pvc *corev1.PersistentVolumeClaim) error {
+	req, err := admission.RequestFromContext(ctx)
+	if err != nil {
+		return fmt.Errorf("read admission request: %w", err)
+	}
+	if req.UserInfo.Username == v.ControllerUsername {
+		return nil
+	}
+	for _, key := range protectedAnnotations {
+		before := ""
+		if oldPVC != nil {
+			before = oldPVC.Annotations[key]
+		}
+		if pvc.Annotations[key] != before {
+			return fmt.Errorf("%s: %w", key, ErrAnnotationProtected)
+		}
+	}
+	return nil
+}
+
+// StorageClassValidator rejects managed StorageClasses with parameters the provisioner does not accept.
+type StorageClassValidator struct {
+	// DeletionGracePeriod is the controller's share deletion grace period; snapshotBeforeDelete "true" needs one.
+	DeletionGracePeriod time.Duration
+}
+
+var _ admission.CustomValidator = &StorageClassValidator{}
+
+// ValidateCreate parses the parameters of a new managed StorageClass and checks its snapshot policy.
+func (v *StorageClassValidator) ValidateCreate(_ context.Context, obj runtime.Object) (admission.Warnings, error) {
+	storageClass, ok := obj.(*storagev1.StorageClass)
+	if !ok {
+		return nil, fmt.Errorf("expected a StorageClass, got %T", obj)
+	}
+	if k8s.GetProvisioner(storageClass) != k8s.ManagedProvisioner {
+		return nil, nil
+	}
+	params, err := k8s.ParseShareParameters(storageClass.Parameters)
+	if err != nil {
+		return nil, err
+	}
+	if err := k8s.CheckSnapshotPolicy(k8s.ParamSnapshotBeforeDelete, params.SnapshotBeforeDelete, v.DeletionGracePeriod); err != nil {
+		return nil, err
+	}
+	return nil, nil
+}
+
+// ValidateUpdate allows every update: parameters are immutable, and classes created before the
+// webhook was installed must stay editable.
+func (v *StorageClassValidator) ValidateUpdate(context.Context, runtime.Object, runtime.Object) (admission.Warnings, error) {
+	return nil, nil
+}
+
+// ValidateDelete allows every deletion.
+func (v *StorageClassValidator) ValidateDelete(context.Context, runtime.Object) (admission.Warnings, error) {
+	return nil, nil
+}
@@ -0,0 +1,354 @@
+package webhook
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	admissionv1 "k8s.io/api/admission/v1"
+	authenticationv1 "k8s.io/api/authentication/v1"
+	corev1 "k8s.io/api/core/v1"
+	storagev1 "k8s.io/api/storage/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
+
+	"aks-azureFiles-controller/internal/constants"
+	"aks-azureFiles-controller/internal/k8s"
+)
+
+const controllerUser = "system:serviceaccount:azurefile-provisioner-system:azurefile-provisioner"
+
+func TestPVCValidatorCreate(t *testing.T) {
+	block := corev1.PersistentVolumeBlock
+	cases := map[string]struct {
+		mutate  func(*corev1.PersistentVolumeClaim)
+		wantErr bool
+	}{
+		"valid claim": {},
+		"block volume": {
+			mutate:  func(pvc *corev1.PersistentVolumeClaim) { pvc.Spec.VolumeMode = &block },
+			wantErr: true,
+		},
+		"no access modes": {
+			mutate:  func(pvc *corev1.PersistentVolumeClaim) { pvc.Spec.AccessModes = nil },
+			wantErr: true,
+		},
+		"no storage request": {
+			mutate:  func(pvc *corev1.PersistentVolumeClaim) { pvc.Spec.Resources.Requests = nil },
+			wantErr: true,
+		},
+		"bad override": {
+			mutate:  func(pvc *corev1.PersistentVolumeClaim) { pvc.Annotations[constants.ShareOverrideAnnotation] = "---" },
+			wantErr: true,
+		},
+		"override under the backing share prefix": {
+			mutate: func(pvc *corev1.PersistentVolumeClaim) {
+				pvc.Annotations[constants.ShareOverrideAnnotation] = "shared-azurefile"
+			},
+			wantErr: true,
+		},
+		"snapshot then delete without a grace period": {
+			mutate: func(pvc *corev1.PersistentVolumeClaim) {
+				pvc.Annotations[constants.SnapshotBeforeDeleteAnnotation] = k8s.SnapshotThenDelete
+			},
+			wantErr: true,
+		},
+		"good override": {
+			mutate: func(pvc *corev1.PersistentVolumeClaim) {
+				pvc.Annotations[constants.ShareOverrideAnnotation] = "Team Data"
+			},
+		},
+		"restore with override": {
+			mutate: func(pvc *corev1.PersistentVolumeClaim) {
+				pvc.Annotations[constants.RestoreFromAnnotation] = "uid-old"
+				pvc.Annotations[constants.ShareOverrideAnnotation] = "ledger"
+			},
+			wantErr: true,
+		},
+		"account not allowed": {
+			mutate: func(pvc *corev1.PersistentVolumeClaim) {
+				pvc.Annotations[constants.StorageAccountAnnotation] = "otheracct"
+			},
+			wantErr: true,
+		},
+		"adopt share": {
+			mutate: func(pvc *corev1.PersistentVolumeClaim) {
+				pvc.Annotations[constants.AdoptShareAnnotation] = "legacy-data"
+			},
+		},
+		"adopt invalid share name": {
+			mutate: func(pvc *corev1.PersistentVolumeClaim) {
+				pvc.Annotations[constants.AdoptShareAnnotation] = "Legacy Data"
+			},
+			wantErr: true,
+		},
+		"adopt from account not allowed": {
+			mutate: func(pvc *corev1.PersistentVolumeClaim) {
+				pvc.Annotations[constants.AdoptShareAnnotation] = "otheracct/legacy-data"
+			},
+			wantErr: true,
+		},
+		"adopt with override": {
+			mutate: func(pvc *corev1.PersistentVolumeClaim) {
+				pvc.Annotations[constants.AdoptShareAnnotation] = "legacy-data"
+				pvc.Annotations[constants.ShareOverrideAnnotation] = "ledger"
+			},
+			wantErr: true,
+		},
+		"subdirectory class": {
+			mutate: func(pvc *corev1.PersistentVolumeClaim) { pvc.Spec.StorageClassName = stringPtr("shared") },
+		},
+		"subdirectory class with override": {
+			mutate: func(pvc *corev1.PersistentVolumeClaim) {
+				pvc.Spec.StorageClassName = stringPtr("shared")
+				pvc.Annotations[constants.ShareOverrideAnnotation] = "ledger"
+			},
+			wantErr: true,
+		},
+		"subdirectory class with data source": {
+			mutate: func(pvc *corev1.PersistentVolumeClaim) {
+				pvc.Spec.StorageClassName = stringPtr("shared")
+				pvc.Spec.DataSource = &corev1.TypedLocalObjectReference{Kind: "PersistentVolumeClaim", Name: "source"}
+			},
+			wantErr: true,
+		},
+		"transfer not allowed by the class": {
+			mutate: func(pvc *corev1.PersistentVolumeClaim) {
+				pvc.Annotations[constants.AdoptShareAnnotation] = "legacy-data"
+				pvc.Annotations[constants.TransferOwnershipAnnotation] = "true"
+			},
+			wantErr: true,
+		},
+		"bad snapshot policy": {
+			mutate: func(pvc *corev1.PersistentVolumeClaim) {
+				pvc.Annotations[constants.SnapshotBeforeDeleteAnnotation] = "always"
+			},
+			wantErr: true,
+		},
+		"snapshot policy": {
+			mutate: func(pvc *corev1.PersistentVolumeClaim) {
+				pvc.Annotations[constants.SnapshotBeforeDeleteAnnotation] = "retain"
+			},
+		},
+		"share name set by user": {
+			mutate:  func(pvc *corev1.PersistentVolumeClaim) { pvc.Annotations[constants.ShareNameAnnotation] = "ledger" },
+			wantErr: true,
+		},
+		"invalid class": {
+			mutate:  func(pvc *corev1.PersistentVolumeClaim) { pvc.Spec.StorageClassName = stringPtr("broken") },
+			wantErr: true,
+		},
+		"other provisioner": {
+			mutate: func(pvc *corev1.PersistentVolumeClaim) {
+				pvc.Spec.StorageClassName = stringPtr("disk")
+				pvc.Spec.VolumeMode = &block
+			},
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			pvc := testPVC()
+			if tc.mutate != nil {
+				tc.mutate(pvc)
+			}
+
+			_, err := newPVCValidator(t).ValidateCreate(requestContext("alice"), pvc)
+			if (err != nil) != tc.wantErr {
+				t.Fatalf("ValidateCreate error = %v, wantErr %v", err, tc.wantErr)
+			}
+		})
+	}
+}
+
+func TestPVCValidatorCreateMissingClass(t *testing.T) {
+	pvc := testPVC()
+	pvc.Spec.StorageClassName = stringPtr("later")
+
+	warnings, err := newPVCValidator(t).ValidateCreate(requestContext("alice"), pvc)
+	if err != nil {
+		t.Fatalf("ValidateCreate error = %v", err)
+	}
+	if len(warnings) != 1 {
+		t.Fatalf("warnings = %v, want one", warnings)
+	}
+}
+
+func TestPVCValidatorProtectsShareName(t *testing.T) {
+	oldPVC := testPVC()
+	oldPVC.Annotations[constants.ShareNameAnnotation] = "team-data"
+	pvc := oldPVC.DeepCopy()
+	pvc.Annotations[constants.ShareNameAnnotation] = "ledger"
+
+	validator := newPVCValidator(t)
+	if _, err := validator.ValidateUpdate(requestContext("alice"), oldPVC, pvc); !errors.Is(err, ErrAnnotationProtected) {
+		t.Fatalf("ValidateUpdate by user error = %v, want %v", err, ErrAnnotationProtected)
+	}
+	if _, err := validator.ValidateUpdate(requestContext(controllerUser), oldPVC, pvc); err != nil {
+		t.Fatalf("ValidateUpdate by controller error = %v", err)
+	}
+
+	// Unrelated edits by users stay allowed.
+	labelled := oldPVC.DeepCopy()
+	labelled.Labels = map[string]string{"app": "db"}
+	if _, err := validator.ValidateUpdate(requestContext("alice"), oldPVC, labelled); err != nil {
+		t.Fatalf("ValidateUpdate labels error = %v", err)
+	}
+}
+
+func TestPVCValidatorFreezesShareOverride(t *testing.T) {
+	cases := map[string]struct {
+		mutate  func(*corev1.PersistentVolumeClaim)
+		wantErr bool
+	}{
+		"pending claim": {},
+		"share recorded": {
+			mutate:  func(pvc *corev1.PersistentVolumeClaim) { pvc.Annotations[constants.ShareNameAnnotation] = "team-data" },
+			wantErr: true,
+		},
+		"claim bound": {
+			mutate:  func(pvc *corev1.PersistentVolumeClaim) { pvc.Spec.VolumeName = "pvc-team-data" },
+			wantErr: true,
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			oldPVC := testPVC()
+			if tc.mutate != nil {
+				tc.mutate(oldPVC)
+			}
+			pvc := oldPVC.DeepCopy()
+			pvc.Annotations[constants.ShareOverrideAnnotation] = "ledger"
+
+			_, err := newPVCValidator(t).ValidateUpdate(requestContext("alice"), oldPVC, pvc)
+			if got := errors.Is(err, ErrShareOverrideImmutable); got != tc.wantErr {
+				t.Fatalf("ValidateUpdate error = %v, want immutable %v", err, tc.wantErr)
+			}
+		})
+	}
+}
+
+func TestPVCValidatorChecksSnapshotPolicyUpdate(t *testing.T) {
+	cases := map[string]struct {
+		policy      string
+		gracePeriod time.Duration
+		wantErr     bool
+	}{
+		"retain":               {policy: k8s.SnapshotRetain},
+		"snapshot then delete": {policy: k8s.SnapshotThenDelete, wantErr: true},
+		"snapshot then delete with a grace period": {policy: k8s.SnapshotThenDelete, gracePeriod: time.Hour},
+		"unknown policy": {policy: "always", wantErr: true},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			oldPVC := testPVC()
+			oldPVC.Annotations[constants.ShareNameAnnotation] = "team-data"
+			pvc := oldPVC.DeepCopy()
+			pvc.Annotations[constants.SnapshotBeforeDeleteAnnotation] = tc.policy
+
+			validator := newPVCValidator(t)
+			validator.DeletionGracePeriod = tc.gracePeriod
+			_, err := validator.ValidateUpdate(requestContext("alice"), oldPVC, pvc)
+			if got := errors.Is(err, k8s.ErrInvalidParameters); got != tc.wantErr {
+				t.Fatalf("ValidateUpdate error = %v, wantErr %v", err, tc.wantErr)
+			}
+		})
+	}
+}
+
+func TestStorageClassValidator(t *testing.T) {
+	validator := &StorageClassValidator{}
+	valid := &storagev1.StorageClass{
+		ObjectMeta:  metav1.ObjectMeta{Name: "azurefile"},
+		Provisioner: k8s.ManagedProvisioner,
+		Parameters:  map[string]string{k8s.ParamSkuName: "Premium_LRS"},
+	}
+	if _, err := validator.ValidateCreate(context.Background(), valid); err != nil {
+		t.Fatalf("ValidateCreate valid error = %v", err)
+	}
+
+	invalid := valid.DeepCopy()
+	invalid.Parameters = map[string]string{"skuname": "Premium_LRS"}
+	if _, err := validator.ValidateCreate(context.Background(), invalid); !errors.Is(err, k8s.ErrInvalidParameters) {
+		t.Fatalf("ValidateCreate invalid error = %v, want %v", err, k8s.ErrInvalidParameters)
+	}
+
+	snapshots := valid.DeepCopy()
+	snapshots.Parameters = map[string]string{k8s.ParamSnapshotBeforeDelete: k8s.SnapshotThenDelete}
+	if _, err := validator.ValidateCreate(context.Background(), snapshots); !errors.Is(err, k8s.ErrInvalidParameters) {
+		t.Fatalf("ValidateCreate without grace period error = %v, want %v", err, k8s.ErrInvalidParameters)
+	}
+	graceful := &StorageClassValidator{DeletionGracePeriod: time.Hour}
+	if _, err := graceful.ValidateCreate(context.Background(), snapshots); err != nil {
+		t.Fatalf("ValidateCreate with grace period error = %v", err)
+	}
+
+	foreign := invalid.DeepCopy()
+	foreign.Provisioner = "disk.csi.azure.com"
+	if _, err := validator.ValidateCreate(context.Background(), foreign); err != nil {
+		t.Fatalf("ValidateCreate foreign error = %v", err)
+	}
+}
+
+func newPVCValidator(t *testing.T) *PVCValidator {
+	t.Helper()
+	scheme := runtime.NewScheme()
+	if err := storagev1.AddToScheme(scheme); err != nil {
+		t.Fatalf("AddToScheme storagev1: %v", err)
+	}
+	classes := []*storagev1.StorageClass{
+		{
+			ObjectMeta:  metav1.ObjectMeta{Name: "azurefile"},
+			Provisioner: k8s.ManagedProvisioner,
+			Parameters:  map[string]string{k8s.ParamAllowedAccounts: "teamacct"},
+		},
+		{
+			ObjectMeta:  metav1.ObjectMeta{Name: "shared"},
+			Provisioner: k8s.ManagedProvisioner,
+			Parameters:  map[string]string{k8s.ParamSubdirectoryShare: k8s.SubdirectoryShareClass},
+		},
+		{
+			ObjectMeta:  metav1.ObjectMeta{Name: "broken"},
+			Provisioner: k8s.ManagedProvisioner,
+			Parameters:  map[string]string{k8s.ParamSkuName: "Ultra_LRS"},
+		},
+		{
+			ObjectMeta:  metav1.ObjectMeta{Name: "disk"},
+			Provisioner: "disk.csi.azure.com",
+		},
+	}
+	builder := fake.NewClientBuilder().WithScheme(scheme)
+	for _, sc := range classes {
+		builder = builder.WithObjects(sc)
+	}
+	return &PVCValidator{Client: builder.Build(), ControllerUsername: controllerUser}
+}
+
+func requestContext(username string) context.Context {
+	return admission.NewContextWithRequest(context.Background(), admission.Request{
+		AdmissionRequest: admissionv1.AdmissionRequest{UserInfo: authenticationv1.UserInfo{Username: username}},
+	})
+}
+
+func testPVC() *corev1.PersistentVolumeClaim {
+	return &corev1.PersistentVolumeClaim{
+		ObjectMeta: metav1.ObjectMeta{Name: "data", Namespace: "team", Annotations: map[string]string{}},
+		Spec: corev1.PersistentVolumeClaimSpec{
+			StorageClassName: stringPtr("azurefile"),
+			AccessModes:      []corev1.PersistentVolumeAccessMode{corev1.ReadWriteMany},
+			Resources: corev1.VolumeResourceRequirements{
+				Requests: corev1.ResourceList{corev1.ResourceStorage: resource.MustParse("5Gi")},
+			},
+		},
+	}
+}
+
+func stringPtr(value string) *string {
+	return &value
+}